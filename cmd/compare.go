@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/snapshot"
+)
+
+const compareUsage = `usage: site-audit compare [-map-hosts from=to,...] <before> <after>`
+
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("site-audit compare", flag.ContinueOnError)
+	mapHosts := fs.String("map-hosts", "", "Comma separated from=to hosts or origins, such as staging.example.com=www.example.com, applied to both snapshots so the urls of two deployments line up")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, compareUsage)
+		return exitError
+	}
+	hostMap, err := snapshot.ParseHostMap(*mapHosts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	before, err := snapshot.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	after, err := snapshot.Load(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	printDiff(os.Stdout, snapshot.Compare(hostMap.Apply(before), hostMap.Apply(after)))
+	return exitOK
+}
+
+func printDiff(w io.Writer, d snapshot.Diff) {
+	if d.IsEmpty() {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+	printSection(w, "Added pages", d.AddedNodes, func(u string) string { return "+ " + u })
+	printSection(w, "Removed pages", d.RemovedNodes, func(u string) string { return "- " + u })
+	printSection(w, "Status changes", d.StatusChanges, func(c snapshot.StatusChange) string {
+		return fmt.Sprintf("~ %s %d -> %d", c.URL, c.Before, c.After)
+	})
+	printSection(w, "Metadata changes", d.MetadataChanges, func(c snapshot.MetadataChange) string {
+		return fmt.Sprintf("~ %s %s %q -> %q", c.URL, c.Field, c.Before, c.After)
+	})
+	printSection(w, "Added links", d.AddedEdges, func(e snapshot.Edge) string {
+		return fmt.Sprintf("+ %s -> %s", e.Source, e.Target)
+	})
+	printSection(w, "Removed links", d.RemovedEdges, func(e snapshot.Edge) string {
+		return fmt.Sprintf("- %s -> %s", e.Source, e.Target)
+	})
+	printSection(w, "New findings", d.NewFindings, func(f audit.Finding) string {
+		return fmt.Sprintf("+ [%s] %s %s", f.Check, f.URL, f.Detail)
+	})
+	printSection(w, "Resolved findings", d.ResolvedFindings, func(f audit.Finding) string {
+		return fmt.Sprintf("- [%s] %s %s", f.Check, f.URL, f.Detail)
+	})
+}
+
+func printSection[T any](w io.Writer, title string, items []T, format func(T) string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s (%d):\n", title, len(items))
+	for _, item := range items {
+		fmt.Fprintf(w, "  %s\n", format(item))
+	}
+}
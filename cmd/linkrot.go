@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/fetcher"
+	"salsgithub.com/site-audit/internal/linkrot"
+)
+
+func runLinkRot(args []string) int {
+	o, err := parseOptions("site-audit linkrot", args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if o.config.LinkRotFile == "" {
+		fmt.Fprintln(os.Stderr, "AUDIT_LINK_ROT_FILE is required")
+		return exitError
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+	store, err := linkrot.Load(o.config.LinkRotFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	dead, err := checkLinkRot(ctx, o.config, store)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dead); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if len(dead) > 0 {
+		return exitThresholdExceeded
+	}
+	return exitOK
+}
+
+// recordLinkRot adds the external links found by auditor to the store and checks them
+func recordLinkRot(ctx context.Context, config audit.Config, auditor *audit.Audit) error {
+	store, err := linkrot.Load(config.LinkRotFile)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, link := range auditor.ExternalLinks() {
+		store.Add(link.URL, link.Source, now)
+	}
+	dead, err := checkLinkRot(ctx, config, store)
+	if err != nil {
+		return err
+	}
+	for _, link := range dead {
+		slog.Warn("External link is newly dead", "url", link.URL, "source", link.Source, "code", link.StatusCode, "err", link.Error, "archived", link.Archived)
+	}
+	return nil
+}
+
+// checkLinkRot uses a fetcher without policies or login so site credentials never reach other hosts.
+// Only the policies' status code rules apply.
+func checkLinkRot(ctx context.Context, config audit.Config, store *linkrot.Store) ([]linkrot.Link, error) {
+	policies, err := loadPolicies(config)
+	if err != nil {
+		return nil, err
+	}
+	store.Treatment = policies.Treatment
+	httpFetcher := fetcher.NewHTTPFetcher(config.Agent, fetcher.WithMaxConnsPerHost(config.MaxWorkers))
+	if config.LinkRotWayback {
+		store.Archive = linkrot.NewWayback(httpFetcher).Snapshot
+	}
+	slog.Info("Checking external links", "links", len(store.Links))
+	dead := store.Check(ctx, httpFetcher, config.MaxWorkers, time.Now().UTC())
+	if err := store.Save(config.LinkRotFile); err != nil {
+		return nil, fmt.Errorf("error saving link rot store: %w", err)
+	}
+	return dead, nil
+}
@@ -2,60 +2,127 @@ package main
 
 import (
 	"context"
-	"flag"
+	"fmt"
 	"log/slog"
+	"maps"
+	"net/http"
+	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
-	"github.com/joeshaw/envdecode"
-	"github.com/joho/godotenv"
+	"github.com/salsgithub/godst/graph"
 	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/compression"
+	"salsgithub.com/site-audit/internal/dnscache"
+	"salsgithub.com/site-audit/internal/embed"
 	"salsgithub.com/site-audit/internal/exporter"
 	"salsgithub.com/site-audit/internal/extractor"
 	"salsgithub.com/site-audit/internal/fetcher"
+	"salsgithub.com/site-audit/internal/notify"
+	"salsgithub.com/site-audit/internal/plugin"
+	"salsgithub.com/site-audit/internal/policy"
+	"salsgithub.com/site-audit/internal/rewrite"
+	"salsgithub.com/site-audit/internal/script"
+	"salsgithub.com/site-audit/internal/slogx"
+	"salsgithub.com/site-audit/internal/snapshot"
+	"salsgithub.com/site-audit/internal/webhook"
+)
+
+const (
+	exitOK = iota
+	exitError
+	exitThresholdExceeded
 )
 
 func main() {
-	var (
-		auditConfig audit.Config
-		local       bool
-	)
-	fs := flag.NewFlagSet("site-audit", flag.ContinueOnError)
-	fs.BoolVar(&local, "local", false, "Running locally using .env in root")
-	audit.AddFlags(auditConfig, fs)
-	if err := fs.Parse(os.Args[1:]); err != nil {
-		slog.Error("Error parsing flags", "err", err)
-		os.Exit(1)
-	}
-	if local {
-		if err := godotenv.Load(); err != nil && local {
-			slog.Error("Error loading .env", "err", err)
-			os.Exit(1)
-		}
-	}
-	if err := envdecode.Decode(&auditConfig); err != nil {
-		slog.Error("Error loading .env", "err", err)
-		os.Exit(1)
-	}
-	httpFetcher := fetcher.NewHTTPFetcher(auditConfig.Agent)
-	linkExtractor := extractor.NewLinkExtractor(extractor.WithDefaultIgnores())
-	auditor, err := audit.New(auditConfig, httpFetcher, linkExtractor)
+	os.Exit(run())
+}
+
+func run() int {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			return runValidate(os.Args[2:])
+		case "query":
+			return runQuery(os.Args[2:])
+		case "compare":
+			return runCompare(os.Args[2:])
+		case "shell":
+			return runShell(os.Args[2:])
+		case "serve":
+			return runServe(os.Args[2:])
+		case "recover":
+			return runRecover(os.Args[2:])
+		case "resume":
+			return runResume(os.Args[2:])
+		case "linkrot":
+			return runLinkRot(os.Args[2:])
+		case "trends":
+			return runTrends(os.Args[2:])
+		case "generate-sitemap":
+			return runGenerateSitemap(os.Args[2:])
+		}
+	}
+	return runAudit(os.Args[1:])
+}
+
+func runAudit(args []string) int {
+	o, err := parseOptions("site-audit", args)
+	if err != nil {
+		slog.Error("Error loading configuration", "err", err)
+		return exitError
+	}
+	if o.pprofPort > 0 {
+		go startProfiler(o.pprofPort)
+	}
+	auditor, siteFetcher, err := newSiteAudit(o.config)
 	if err != nil {
 		slog.Error("Auditor creation error", "err", err)
-		os.Exit(1)
+		return exitError
+	}
+	return runAuditor(o.config, auditor, siteFetcher)
+}
+
+func runAuditor(auditConfig audit.Config, auditor *audit.Audit, siteFetcher *fetcher.HTTPFetcher) int {
+	checks, exporters, err := loadPlugins(auditConfig, siteFetcher)
+	if err != nil {
+		slog.Error("Plugin loading error", "err", err)
+		return exitError
+	}
+	notifier, err := newAuditNotifier(auditConfig)
+	if err != nil {
+		slog.Error("Notification rules loading error", "err", err)
+		return exitError
+	}
+	sendWebhook := func(event webhook.Event, err error) {
+		var summary *audit.Summary
+		if event != webhook.EventStarted {
+			s := auditor.Summary()
+			summary = &s
+		}
+		notifier.send(event, auditConfig.StartURL, summary, err)
 	}
 	// Guarantee export of graph regardless of how auditor exits
 	defer func() {
-		graphVizExporter := exporter.NewGraphVizExporter("./out")
-		auditor.ExportGraph(graphVizExporter.Export)
+		for _, e := range exporters {
+			auditor.ExportGraph(e.Export)
+		}
+		if auditConfig.SnapshotFile != "" {
+			auditor.ExportGraph(func(g *graph.Graph[string], nodes map[string]audit.Node) error {
+				startURL, _ := audit.CanonicalURL(auditConfig.StartURL)
+				return snapshot.Write(auditConfig.SnapshotFile, startURL, g, nodes, auditor.Findings())
+			})
+		}
 	}()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
 	done := make(chan error, 1)
+	sendWebhook(webhook.EventStarted, nil)
 	go func() {
 		done <- auditor.Start(ctx)
 	}()
@@ -63,10 +130,41 @@ func main() {
 	case err := <-done:
 		if err != nil {
 			slog.Error("Auditing completed with error", "err", err)
+			sendWebhook(webhook.EventFailed, err)
+			return exitError
+		}
+		slog.Info("Auditing complete successfully")
+		code, err := finishAudit(ctx, auditor, checks)
+		if auditConfig.HistoryFile != "" {
+			if err := recordHistory(auditConfig, auditor); err != nil {
+				slog.Error("History recording failed", "err", err)
+			}
+		}
+		if auditConfig.BrokenLinksFile != "" {
+			links := auditor.BrokenLinks()
+			if err := audit.WriteBrokenLinks(auditConfig.BrokenLinksFile, links); err != nil {
+				slog.Error("Broken links report failed", "err", err)
+			} else {
+				slog.InfoContext(slogx.Summary(ctx), "Broken links report written", "path", auditConfig.BrokenLinksFile, "broken_links", len(links))
+			}
+		}
+		if auditConfig.LinkRotFile != "" {
+			if err := recordLinkRot(ctx, auditConfig, auditor); err != nil {
+				slog.Error("Link rot check failed", "err", err)
+			}
+		}
+		if auditConfig.ScreenshotCommand != "" {
+			if err := captureScreenshots(ctx, auditConfig, auditor); err != nil {
+				slog.Error("Screenshot capture failed", "err", err)
+			}
+		}
+		notifier.evaluate(auditConfig.StartURL, auditor.Summary())
+		if code == exitError {
+			sendWebhook(webhook.EventFailed, err)
 		} else {
-			slog.Info("Auditing complete successfully")
+			sendWebhook(webhook.EventFinished, err)
 		}
-		return
+		return code
 	case s := <-sig:
 		slog.Info("Signal received, shutting down", "signal", s)
 		cancel()
@@ -78,5 +176,206 @@ func main() {
 		case <-shutdownCtx.Done():
 			slog.Info("Graceful shutdown timed out, force quitting")
 		}
+		if auditConfig.CheckpointFile != "" {
+			if err := audit.SaveCheckpoint(auditConfig.CheckpointFile, auditor.Checkpoint()); err != nil {
+				slog.Error("Checkpoint save failed", "err", err)
+			} else {
+				slog.Info("Checkpoint saved", "path", auditConfig.CheckpointFile)
+			}
+		}
+		sendWebhook(webhook.EventFailed, fmt.Errorf("interrupted by signal %s", s))
+		return exitError
+	}
+}
+
+func newAudit(config audit.Config) (*audit.Audit, error) {
+	auditor, _, err := newSiteAudit(config)
+	return auditor, err
+}
+
+// newSiteAudit also returns the fetcher crawling the site, so checks that fetch the site's own
+// pages go through the same connections, policies and session
+func newSiteAudit(config audit.Config) (*audit.Audit, *fetcher.HTTPFetcher, error) {
+	httpFetcher, linkExtractor, options, err := auditComponents(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	auditor, err := audit.New(config, httpFetcher, linkExtractor, options...)
+	return auditor, httpFetcher, err
+}
+
+func resumeAudit(checkpoint *audit.Checkpoint) (*audit.Audit, *fetcher.HTTPFetcher, error) {
+	httpFetcher, linkExtractor, options, err := auditComponents(checkpoint.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	auditor, err := audit.Resume(checkpoint, httpFetcher, linkExtractor, options...)
+	return auditor, httpFetcher, err
+}
+
+func auditComponents(config audit.Config) (*fetcher.HTTPFetcher, *extractor.LinkExtractor, []audit.Option, error) {
+	policies, err := loadPolicies(config)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	fetcherOptions := []fetcher.Option{
+		fetcher.WithPolicies(policies),
+		fetcher.WithMaxConnsPerHost(config.MaxWorkers),
+		fetcher.WithRedirectPolicy(fetcher.RedirectPolicy{Follow: config.FollowRedirects, MaxHops: config.MaxRedirects, CrossHost: config.CrossHostRedirects}),
+	}
+	if config.ConsentCookies != "" {
+		cookies, err := http.ParseCookie(config.ConsentCookies)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fetcherOptions = append(fetcherOptions, fetcher.WithCookies(cookies))
+	}
+	auditOptions := []audit.Option{audit.WithPolicies(policies), audit.WithLogger(slog.Default())}
+	rewrites, err := loadRewrites(config)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if rewrites != nil {
+		auditOptions = append(auditOptions, audit.WithRewriter(rewrites))
+	}
+	login, err := loadLogin(config)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if login != nil {
+		fetcherOptions = append(fetcherOptions, fetcher.WithLogin(login))
+	}
+	if config.DNSCacheTTL > 0 {
+		cache := dnscache.New(config.DNSCacheTTL)
+		fetcherOptions = append(fetcherOptions, fetcher.WithDNSCache(cache))
+		if config.DNSPrefetch {
+			auditOptions = append(auditOptions, audit.WithPrefetcher(cache))
+		}
+	}
+	connectTo, err := fetcher.ParseConnectTo(config.ConnectTo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Connecting elsewhere wraps the dialer, so comes after the DNS cache
+	fetcherOptions = append(fetcherOptions, fetcher.WithConnectTo(connectTo))
+	httpFetcher := fetcher.NewHTTPFetcher(config.Agent, fetcherOptions...)
+	if login != nil {
+		auditOptions = append(auditOptions, audit.WithAuthenticator(httpFetcher))
+	}
+	linkExtractor := extractor.NewLinkExtractor()
+	if !config.IncludeFiles {
+		linkExtractor = extractor.NewLinkExtractor(extractor.WithDefaultIgnores())
+	}
+	return httpFetcher, linkExtractor, auditOptions, nil
+}
+
+func finishAudit(ctx context.Context, auditor *audit.Audit, checks []audit.Check) (int, error) {
+	if err := auditor.RunChecks(ctx, checks...); err != nil {
+		slog.Error("Running checks failed", "err", err)
+		return exitError, err
+	}
+	for _, group := range auditor.FindingGroups() {
+		slog.InfoContext(slogx.Summary(ctx), "Findings", "check", group.Check, "template", group.Template, "count", group.Count, "duplicates", group.Duplicates, "examples", group.Examples)
+	}
+	failures := auditor.Failures()
+	for _, failure := range slices.Sorted(maps.Keys(failures)) {
+		slog.InfoContext(slogx.Summary(ctx), "Failures", "failure", failure, "count", failures[failure])
+	}
+	if err := auditor.UpdateBaseline(); err != nil {
+		slog.Error("Baseline update failed", "err", err)
+		return exitError, err
+	}
+	if err := auditor.UpdateRedirects(); err != nil {
+		slog.Error("Redirects update failed", "err", err)
+	}
+	if err := auditor.CheckThresholds(); err != nil {
+		slog.Error("Audit failed thresholds", "err", err)
+		return exitThresholdExceeded, err
+	}
+	return exitOK, nil
+}
+
+// loadPlugins builds the checks and exporters config selects. Checks fetching the site's own pages
+// use siteFetcher, the fetcher the crawl went through.
+func loadPlugins(config audit.Config, siteFetcher *fetcher.HTTPFetcher) ([]audit.Check, []exporter.Exporter, error) {
+	checks := []audit.Check{}
+	if config.CheckCaching {
+		checks = append(checks, audit.CachingCheck{})
+	}
+	if config.CheckCSP {
+		checks = append(checks, audit.CSPCheck{})
+	}
+	if config.CheckCompression {
+		checks = append(checks, compression.NewCheck(siteFetcher, config.MaxWorkers, config.MinifyThreshold))
+	}
+	if config.CheckEmbeds {
+		// Embeds are third party content, so they are fetched without the site's policies or login
+		checks = append(checks, embed.NewCheck(fetcher.NewHTTPFetcher(config.Agent), config.MaxWorkers))
+	}
+	if config.CheckNotFound {
+		notFoundFetcher := siteFetcher.Derive(fetcher.WithRedirectPolicy(fetcher.RedirectPolicy{}))
+		checks = append(checks, audit.NewNotFoundCheck(notFoundFetcher, extractor.NewLinkExtractor(), config.StartURL))
+	}
+	for _, commandLine := range plugin.Split(config.PluginChecks) {
+		check, err := plugin.NewExecCheck(commandLine)
+		if err != nil {
+			return nil, nil, err
+		}
+		checks = append(checks, check)
+	}
+	for _, path := range plugin.Split(config.ScriptChecks) {
+		check, err := script.NewCheck(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		checks = append(checks, check)
+	}
+	exporters, err := exporter.New(plugin.Split(config.Exporters), config.OutputDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, commandLine := range plugin.Split(config.PluginExporters) {
+		e, err := plugin.NewExecExporter(commandLine)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporters = append(exporters, e)
+	}
+	return checks, exporters, nil
+}
+
+func loadNotifyRules(config audit.Config) (*notify.Engine, error) {
+	if config.NotifyRulesFile == "" {
+		return nil, nil
+	}
+	return notify.LoadRules(config.NotifyRulesFile)
+}
+
+func loadPolicies(config audit.Config) (*policy.Set, error) {
+	if config.PoliciesFile == "" {
+		return nil, nil
+	}
+	return policy.Load(config.PoliciesFile)
+}
+
+func loadRewrites(config audit.Config) (*rewrite.Rules, error) {
+	if config.RewritesFile == "" {
+		return nil, nil
+	}
+	return rewrite.Load(config.RewritesFile)
+}
+
+func loadLogin(config audit.Config) (*fetcher.Login, error) {
+	if config.LoginFile == "" {
+		return nil, nil
+	}
+	return fetcher.LoadLogin(config.LoginFile)
+}
+
+func startProfiler(port int) {
+	address := fmt.Sprintf("localhost:%d", port)
+	slog.Info("Starting pprof server", "address", address)
+	if err := http.ListenAndServe(address, nil); err != nil {
+		slog.Error("pprof server stopped", "err", err)
 	}
 }
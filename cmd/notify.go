@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/notify"
+	"salsgithub.com/site-audit/internal/plugin"
+	"salsgithub.com/site-audit/internal/server"
+	"salsgithub.com/site-audit/internal/webhook"
+)
+
+var errJobCancelled = errors.New("cancelled")
+
+// auditNotifier sends the webhooks of an audit's lifecycle and evaluates the notification rules once
+// it finishes, for audits run from the command line and jobs run by the server alike
+type auditNotifier struct {
+	webhooks *webhook.Notifier
+	rules    *notify.Engine
+}
+
+func newAuditNotifier(config audit.Config) (*auditNotifier, error) {
+	rules, err := loadNotifyRules(config)
+	if err != nil {
+		return nil, err
+	}
+	return &auditNotifier{
+		webhooks: webhook.NewNotifier(plugin.Split(config.WebhookURLs), config.WebhookSecret, webhook.WithMaxRetries(config.WebhookMaxRetries)),
+		rules:    rules,
+	}, nil
+}
+
+// evaluate sends the notifications of the rules summary triggers
+func (n *auditNotifier) evaluate(startURL string, summary audit.Summary) {
+	if n.rules == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	triggered, err := n.rules.Evaluate(ctx, startURL, summary)
+	if err != nil {
+		slog.Error("Notification delivery failed", "err", err)
+	}
+	slog.Info("Notification rules evaluated", "start_url", startURL, "triggered", triggered)
+}
+
+// send delivers an event, giving up after a minute so a dead endpoint cannot hold up shutdown
+func (n *auditNotifier) send(event webhook.Event, startURL string, summary *audit.Summary, err error) {
+	payload := webhook.Payload{Event: event, StartURL: startURL, Summary: summary}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := n.webhooks.Notify(ctx, payload); err != nil {
+		slog.Error("Webhook notification failed", "event", event, "err", err)
+	}
+}
+
+func (n *auditNotifier) JobStarted(job server.Job) {
+	n.send(webhook.EventStarted, job.StartURL, nil, nil)
+}
+
+func (n *auditNotifier) JobEnded(job server.Job) {
+	switch job.Status {
+	case server.JobFinished:
+		n.evaluate(job.StartURL, *job.Summary)
+		n.send(webhook.EventFinished, job.StartURL, job.Summary, nil)
+	case server.JobCancelled:
+		n.send(webhook.EventFailed, job.StartURL, job.Summary, errJobCancelled)
+	default:
+		n.send(webhook.EventFailed, job.StartURL, job.Summary, errors.New(job.Error))
+	}
+}
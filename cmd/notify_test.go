@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/notify"
+	"salsgithub.com/site-audit/internal/server"
+	"salsgithub.com/site-audit/internal/webhook"
+)
+
+func TestAuditNotifier_ServerJobs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body></body></html>`))
+	}))
+	defer site.Close()
+	var mu sync.Mutex
+	payloads := []webhook.Payload{}
+	messages := []notify.Message{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/rules" {
+			var message notify.Message
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+			messages = append(messages, message)
+			return
+		}
+		var payload webhook.Payload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+	}))
+	defer receiver.Close()
+	rules := `{"rules":[{"name":"crawled","when":"visited > 0","channel":{"type":"webhook","url":"` + receiver.URL + `/rules"}}]}`
+	require.NoError(t, os.WriteFile("rules.json", []byte(rules), 0o644))
+	o, err := parseOptions("site-audit serve", []string{"-AUDIT_START_URL", site.URL, "-AUDIT_WEBHOOK_URLS", receiver.URL, "-AUDIT_NOTIFY_RULES_FILE", "rules.json"})
+	require.NoError(t, err)
+	notifier, err := newAuditNotifier(o.config)
+	require.NoError(t, err)
+	s, err := server.New(context.Background(), o.config, newAudit, server.WithHooks(notifier))
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/audits", strings.NewReader(`{"start_url":"`+site.URL+`"}`)))
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	s.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, payloads, 2)
+	require.Equal(t, webhook.EventStarted, payloads[0].Event)
+	require.Equal(t, site.URL, payloads[0].StartURL)
+	require.Equal(t, webhook.EventFinished, payloads[1].Event)
+	require.NotZero(t, payloads[1].Summary.Visited)
+	require.Len(t, messages, 1)
+	require.Equal(t, "crawled", messages[0].Rule)
+	require.Equal(t, site.URL, messages[0].StartURL)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/joeshaw/envdecode"
+	"github.com/joho/godotenv"
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/slogx"
+)
+
+type options struct {
+	config    audit.Config
+	local     bool
+	pprofPort int
+	preset    string
+}
+
+// parseOptions resolves configuration with the precedence flags > environment > .env > preset > defaults
+func parseOptions(name string, args []string, register ...func(fs *flag.FlagSet)) (options, error) {
+	var o options
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.BoolVar(&o.local, "local", false, "Running locally using .env in root")
+	fs.StringVar(&o.preset, "preset", "", fmt.Sprintf("Named bundle of configuration, one of %v", audit.Presets()))
+	fs.IntVar(&o.pprofPort, "pprof-port", 0, "Expose net/http/pprof on localhost at the given port (disabled when 0)")
+	audit.AddFlags(&o.config, fs)
+	for _, r := range register {
+		r(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return o, fmt.Errorf("error parsing flags: %w", err)
+	}
+	if o.local {
+		if err := godotenv.Load(); err != nil {
+			return o, fmt.Errorf("error loading .env: %w", err)
+		}
+	}
+	if err := envdecode.Decode(&o.config); err != nil {
+		return o, fmt.Errorf("error decoding environment: %w", err)
+	}
+	// Parsing again re-applies explicitly set flags over the environment
+	if err := fs.Parse(args); err != nil {
+		return o, fmt.Errorf("error parsing flags: %w", err)
+	}
+	if o.preset != "" {
+		if err := applyPreset(fs, o.preset); err != nil {
+			return o, err
+		}
+	}
+	if err := setupLogging(o.config); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// setupLogging sends the logs of the command, and of the audits it runs, where AUDIT_LOG_FILE,
+// AUDIT_LOG_FORMAT and AUDIT_LOG_QUIET ask. The log file is left open until the process exits.
+func setupLogging(config audit.Config) error {
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		level = slog.LevelInfo
+	}
+	logOptions := []slogx.Option{slogx.WithFormat(config.LogFormat), slogx.WithQuiet(config.LogQuiet)}
+	if config.LogFile != "" {
+		f, err := slogx.OpenRotatingFile(config.LogFile, config.LogMaxBytes, config.LogMaxFiles)
+		if err != nil {
+			return err
+		}
+		logOptions = append(logOptions, slogx.WithWriter(f))
+	}
+	slog.SetDefault(slogx.New(level, logOptions...))
+	return nil
+}
+
+// applyPreset sets preset values for settings not given as a flag or in the environment
+func applyPreset(fs *flag.FlagSet, name string) error {
+	preset, err := audit.Preset(name)
+	if err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		delete(preset, f.Name)
+	})
+	for key, value := range preset {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("error applying preset %q: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/query"
+	"salsgithub.com/site-audit/internal/snapshot"
+)
+
+const queryUsage = `usage: site-audit query <snapshot> <command> [args]
+
+commands:
+  path <from> <to>     shortest path of links between two urls
+  linking-to <url>     pages linking to the url
+  orphans              pages no other page links to
+  subtree <path>       pages under the url path, e.g. /blog`
+
+func runQuery(args []string) int {
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, queryUsage)
+		return exitError
+	}
+	s, err := snapshot.Load(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	results, err := runQueryCommand(s, args[1], args[2:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	for _, result := range results {
+		fmt.Println(result)
+	}
+	return exitOK
+}
+
+func runQueryCommand(s *snapshot.Snapshot, command string, args []string) ([]string, error) {
+	g := s.Graph()
+	switch command {
+	case "path":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("path requires <from> <to>\n%s", queryUsage)
+		}
+		from, err := audit.CanonicalURL(args[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := audit.CanonicalURL(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return query.ShortestPath(g, from, to)
+	case "linking-to":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("linking-to requires <url>\n%s", queryUsage)
+		}
+		target, err := audit.CanonicalURL(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return query.LinkingTo(g, target)
+	case "orphans":
+		return query.Orphans(g), nil
+	case "subtree":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("subtree requires <path>\n%s", queryUsage)
+		}
+		return query.Subtree(g, args[0]), nil
+	default:
+		return nil, fmt.Errorf("unknown query command %q\n%s", command, queryUsage)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/snapshot"
+)
+
+const recoverUsage = `usage: site-audit recover <graph-log> <snapshot>`
+
+func runRecover(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, recoverUsage)
+		return exitError
+	}
+	startURL, g, pages, err := audit.LoadGraphLog(args[0])
+	if err != nil {
+		slog.Error("Graph log loading error", "err", err)
+		return exitError
+	}
+	// The graph log only records statuses, so recovered nodes carry no other metadata
+	nodes := make(map[string]audit.Node, len(pages))
+	for _, page := range pages {
+		nodes[page.URL] = audit.Node{URL: page.URL, StatusCode: page.StatusCode}
+	}
+	if err := snapshot.Write(args[1], startURL, g, nodes, audit.BrokenLinkFindings(pages)); err != nil {
+		slog.Error("Snapshot save error", "err", err)
+		return exitError
+	}
+	slog.Info("Graph recovered", "nodes", g.Len(), "pages", len(pages), "snapshot", args[1])
+	return exitOK
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+const resumeUsage = `usage: site-audit resume <checkpoint>`
+
+func runResume(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, resumeUsage)
+		return exitError
+	}
+	checkpoint, err := audit.LoadCheckpoint(args[0])
+	if err != nil {
+		slog.Error("Checkpoint loading error", "err", err)
+		return exitError
+	}
+	auditor, siteFetcher, err := resumeAudit(checkpoint)
+	if err != nil {
+		slog.Error("Auditor creation error", "err", err)
+		return exitError
+	}
+	slog.Info("Resuming crawl from checkpoint",
+		"created_at", checkpoint.CreatedAt,
+		"fetched", len(checkpoint.Statuses)+checkpoint.FetchErrors,
+		"visited", len(checkpoint.Visited),
+		"frontier", len(checkpoint.Frontier),
+		"edges", len(checkpoint.Edges),
+	)
+	return runAuditor(checkpoint.Config, auditor, siteFetcher)
+}
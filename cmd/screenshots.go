@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/screenshot"
+	"salsgithub.com/site-audit/internal/slogx"
+)
+
+func captureScreenshots(ctx context.Context, config audit.Config, auditor *audit.Audit) error {
+	renderer, err := screenshot.NewCommandRenderer(config.ScreenshotCommand)
+	if err != nil {
+		return err
+	}
+	urls := []string{}
+	for _, page := range auditor.Pages() {
+		if page.StatusCode == http.StatusOK {
+			urls = append(urls, page.URL)
+		}
+	}
+	dir := filepath.Join(config.OutputDir, "screenshots")
+	shots, err := screenshot.Capture(ctx, renderer, screenshot.Sample(urls, config.ScreenshotLimit), dir)
+	failed := 0
+	for _, shot := range shots {
+		if shot.Error != "" {
+			failed++
+			slog.Warn("Screenshot failed", "url", shot.URL, "err", shot.Error)
+		}
+	}
+	slog.InfoContext(slogx.Summary(ctx), "Screenshots captured", "dir", dir, "screenshots", len(shots)-failed, "failed", failed)
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"salsgithub.com/site-audit/internal/server"
+)
+
+func runServe(args []string) int {
+	o, err := parseOptions("site-audit serve", args)
+	if err != nil {
+		slog.Error("Error loading configuration", "err", err)
+		return exitError
+	}
+	credentials, err := server.ParseTokens(o.config.ServerTokens)
+	if err != nil {
+		slog.Error("Error loading server tokens", "err", err)
+		return exitError
+	}
+	if o.pprofPort > 0 {
+		go startProfiler(o.pprofPort)
+	}
+	notifier, err := newAuditNotifier(o.config)
+	if err != nil {
+		slog.Error("Notification rules loading error", "err", err)
+		return exitError
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+	s, err := server.New(ctx, o.config, newAudit,
+		server.WithCredentials(credentials),
+		server.WithRateLimit(o.config.ServerRateLimit),
+		server.WithMaxJobs(o.config.ServerMaxJobs, o.config.ServerTenantMaxJobs),
+		server.WithHistoryFile(o.config.ServerHistoryFile),
+		server.WithJobRetention(o.config.ServerJobRetention),
+		server.WithHooks(notifier),
+	)
+	if err != nil {
+		slog.Error("Server creation error", "err", err)
+		return exitError
+	}
+	if err := s.CheckAddress(o.config.ServerAddress); err != nil {
+		slog.Error("Server address rejected", "err", err)
+		return exitError
+	}
+	httpServer := &http.Server{
+		Addr:              o.config.ServerAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("Server listening", "address", o.config.ServerAddress, "authenticated", len(credentials) > 0)
+		errs <- httpServer.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		slog.Error("Server stopped", "err", err)
+		return exitError
+	case <-ctx.Done():
+		slog.Info("Signal received, shutting down server")
+	}
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Server shutdown error", "err", err)
+	}
+	s.Wait()
+	return exitOK
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"salsgithub.com/site-audit/internal/shell"
+	"salsgithub.com/site-audit/internal/snapshot"
+)
+
+const shellUsage = `usage: site-audit shell <snapshot>`
+
+func runShell(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, shellUsage)
+		return exitError
+	}
+	s, err := snapshot.Load(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if err := shell.New(s, os.Stdout).Run(context.Background(), os.Stdin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
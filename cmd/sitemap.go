@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os/signal"
+	"syscall"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/sitemap"
+	"salsgithub.com/site-audit/internal/snapshot"
+)
+
+func runGenerateSitemap(args []string) int {
+	var from, out, baseURL string
+	var hreflang bool
+	o, err := parseOptions("site-audit generate-sitemap", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&from, "from", "", "Snapshot file to build the sitemap from instead of crawling")
+		fs.StringVar(&out, "out", "./out", "Directory the sitemap files are written to")
+		fs.StringVar(&baseURL, "base-url", "", "Url the sitemap files are served from (defaults to the site root)")
+		fs.BoolVar(&hreflang, "hreflang", false, "Annotate each url with the hreflang alternates of its language cluster")
+	})
+	if err != nil {
+		slog.Error("Error loading configuration", "err", err)
+		return exitError
+	}
+	var startURL string
+	var pages []audit.Page
+	if from != "" {
+		s, err := snapshot.Load(from)
+		if err != nil {
+			slog.Error("Snapshot loading error", "err", err)
+			return exitError
+		}
+		startURL, pages = s.StartURL, s.Pages()
+	} else {
+		auditor, err := newAudit(o.config)
+		if err != nil {
+			slog.Error("Auditor creation error", "err", err)
+			return exitError
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer cancel()
+		if err := auditor.Start(ctx); err != nil {
+			slog.Error("Auditing completed with error", "err", err)
+			return exitError
+		}
+		startURL, pages = o.config.StartURL, auditor.Pages()
+	}
+	if baseURL == "" {
+		u, err := url.Parse(startURL)
+		if err != nil {
+			slog.Error("Invalid start url", "err", err)
+			return exitError
+		}
+		baseURL = u.Scheme + "://" + u.Host
+	}
+	options := []sitemap.Option{}
+	if hreflang {
+		options = append(options, sitemap.WithAlternates(sitemapAlternates(pages)))
+	}
+	written, err := sitemap.Write(out, baseURL, sitemapURLs(pages), options...)
+	if err != nil {
+		slog.Error("Sitemap generation error", "err", err)
+		return exitError
+	}
+	slog.Info("Sitemap generated", "files", written)
+	return exitOK
+}
+
+// sitemapURLs keeps pages that returned a successful status
+func sitemapURLs(pages []audit.Page) []string {
+	urls := []string{}
+	for _, page := range pages {
+		if page.StatusCode >= http.StatusOK && page.StatusCode < http.StatusMultipleChoices {
+			urls = append(urls, page.URL)
+		}
+	}
+	return urls
+}
+
+// sitemapAlternates collects the hreflang alternates each page declared
+func sitemapAlternates(pages []audit.Page) map[string][]sitemap.Alternate {
+	declared := map[string][]sitemap.Alternate{}
+	for _, page := range pages {
+		for _, alternate := range page.Alternates {
+			if alternate.Lang == "" {
+				continue
+			}
+			declared[page.URL] = append(declared[page.URL], sitemap.Alternate{Lang: alternate.Lang, URL: alternate.URL})
+		}
+	}
+	return declared
+}
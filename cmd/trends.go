@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/history"
+)
+
+const trendsUsage = `usage: site-audit trends [-format json|csv] [-flipped from:to] [-since duration] <history> [site]`
+
+func runTrends(args []string) int {
+	fs := flag.NewFlagSet("site-audit trends", flag.ContinueOnError)
+	format := fs.String("format", "json", "Output format, json or csv")
+	flipped := fs.String("flipped", "", "Print urls whose status changed, as from:to with * matching any status, e.g. 200:404")
+	since := fs.Duration("since", 30*24*time.Hour, "How far back to compare url statuses with -flipped")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fmt.Fprintln(os.Stderr, trendsUsage)
+		return exitError
+	}
+	runs, err := history.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	site := fs.Arg(1)
+	if site == "" {
+		sites := history.Sites(runs)
+		if len(sites) != 1 {
+			fmt.Fprintf(os.Stderr, "history has %d sites, choose one of %v\n%s\n", len(sites), sites, trendsUsage)
+			return exitError
+		}
+		site = sites[0]
+	} else if site, err = audit.CanonicalURL(site); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if *flipped != "" {
+		from, to, err := parseFlip(*flipped)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n%s\n", err, trendsUsage)
+			return exitError
+		}
+		flips := history.Flips(runs, site, time.Now().Add(-*since), from, to)
+		switch *format {
+		case "json":
+			err = history.WriteFlipsJSON(os.Stdout, flips)
+		case "csv":
+			err = history.WriteFlipsCSV(os.Stdout, flips)
+		default:
+			err = fmt.Errorf("unknown format %q\n%s", *format, trendsUsage)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return exitOK
+	}
+	points := history.Trend(runs, site)
+	switch *format {
+	case "json":
+		err = history.WriteJSON(os.Stdout, points)
+	case "csv":
+		err = history.WriteCSV(os.Stdout, points)
+	default:
+		err = fmt.Errorf("unknown format %q\n%s", *format, trendsUsage)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// parseFlip parses a from:to status pair, where * or an empty side matches any status
+func parseFlip(s string) (int, int, error) {
+	from, to, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid flip %q, expected from:to", s)
+	}
+	status := func(v string) (int, error) {
+		if v == "" || v == "*" {
+			return 0, nil
+		}
+		code, err := strconv.Atoi(v)
+		if err != nil || code < 100 || code > 599 {
+			return 0, fmt.Errorf("invalid status %q in flip %q", v, s)
+		}
+		return code, nil
+	}
+	f, err := status(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	t, err := status(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	return f, t, nil
+}
+
+func recordHistory(config audit.Config, auditor *audit.Audit) error {
+	site, err := audit.CanonicalURL(config.StartURL)
+	if err != nil {
+		return err
+	}
+	run := history.Run{Site: site, CreatedAt: time.Now().UTC(), Summary: auditor.Summary()}
+	if config.HistoryURLs {
+		for _, node := range auditor.Nodes() {
+			if node.StatusCode != 0 {
+				run.Pages = append(run.Pages, history.Page{URL: node.URL, StatusCode: node.StatusCode, Title: node.Title, Hash: node.ContentHash})
+			}
+		}
+		slices.SortFunc(run.Pages, func(x, y history.Page) int {
+			return strings.Compare(x.URL, y.URL)
+		})
+	}
+	return history.Append(config.HistoryFile, run, config.HistoryRetention, config.HistoryURLRetention)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/history"
+)
+
+func TestRunAudit_AppendsHistory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/missing">missing</a></body></html>`))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "history.json")
+	args := []string{"-AUDIT_START_URL", server.URL, "-AUDIT_HISTORY_FILE", path}
+	require.Equal(t, exitOK, runAudit(args))
+	require.Equal(t, exitOK, runAudit(args))
+	runs, err := history.Load(path)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, server.URL+"/", runs[0].Site)
+	require.Equal(t, 2, runs[1].Summary.Visited)
+
+	t.Run("urls", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.json")
+		args := []string{"-AUDIT_START_URL", server.URL, "-AUDIT_HISTORY_FILE", path, "-AUDIT_HISTORY_URLS", "-AUDIT_HISTORY_URL_RETENTION", "1"}
+		require.Equal(t, exitOK, runAudit(args))
+		require.Equal(t, exitOK, runAudit(args))
+		runs, err := history.Load(path)
+		require.NoError(t, err)
+		require.Len(t, runs, 2)
+		require.Empty(t, runs[0].Pages)
+		require.NotEmpty(t, runs[1].Pages)
+		home := runs[1].Pages[0]
+		require.Equal(t, server.URL+"/", home.URL)
+		require.Len(t, home.Hash, 64)
+	})
+}
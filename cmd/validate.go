@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/fetcher"
+)
+
+func runValidate(args []string) int {
+	o, err := parseOptions("site-audit validate", args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	printConfig(os.Stdout, o.config)
+	problems := validate(o.config)
+	if len(problems) == 0 {
+		fmt.Println("\nConfiguration is valid")
+		return exitOK
+	}
+	fmt.Println("\nConfiguration is invalid:")
+	for _, problem := range problems {
+		fmt.Printf("  - %v\n", problem)
+	}
+	return exitError
+}
+
+func validate(config audit.Config) []error {
+	var problems []error
+	if err := config.Validate(); err != nil {
+		problems = append(problems, unwrapJoined(err)...)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		problems = append(problems, fmt.Errorf("invalid log level %q, use one of DEBUG, INFO, WARN or ERROR", config.LogLevel))
+	}
+	for _, scheme := range strings.Split(config.ValidSchemes, ",") {
+		if strings.TrimSpace(scheme) == "" {
+			problems = append(problems, fmt.Errorf("empty scheme in AUDIT_VALID_SCHEMES %q", config.ValidSchemes))
+		}
+	}
+	if _, _, err := loadPlugins(config, fetcher.NewHTTPFetcher(config.Agent)); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := loadNotifyRules(config); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := loadPolicies(config); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := loadRewrites(config); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := loadLogin(config); err != nil {
+		problems = append(problems, err)
+	}
+	if _, err := fetcher.ParseConnectTo(config.ConnectTo); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
+
+func unwrapJoined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func printConfig(w io.Writer, config audit.Config) {
+	fmt.Fprintln(w, "Effective configuration:")
+	v := reflect.ValueOf(config)
+	t := v.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+		value := v.Field(i).Interface()
+		if (strings.Contains(name, "SECRET") || strings.Contains(name, "TOKENS")) && value != "" {
+			value = "********"
+		}
+		fmt.Fprintf(w, "  %s=%v\n", name, value)
+	}
+}
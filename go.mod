@@ -8,6 +8,7 @@ require (
 	github.com/salsgithub/godst v0.0.1
 	github.com/stretchr/testify v1.3.0
 	github.com/temoto/robotstxt v1.1.2
+	go.starlark.net v0.0.0-20250417143717-f57e51f710eb
 	golang.org/x/net v0.44.0
 )
 
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const CheckMediaAlternate = "media-alternate"
+
+// mediaAlternateFindings must be called with a.mu held. Sites serving a separate mobile version
+// pair the pages both ways: the desktop page declares the mobile one as a rel=alternate with a
+// media query, and the mobile page names the desktop one as its rel=canonical. Alternates that were
+// not crawled, such as those on hosts outside the site, cannot be checked.
+func (a *Audit) mediaAlternateFindings() []Finding {
+	declared := map[string]map[string]bool{}
+	findings := []Finding{}
+	for u, info := range a.nodes {
+		reasons := []string{}
+		for _, alternate := range info.alternates {
+			if alternate.Media == "" {
+				continue
+			}
+			if declared[alternate.URL] == nil {
+				declared[alternate.URL] = map[string]bool{}
+			}
+			declared[alternate.URL][u] = true
+			code := a.statuses[alternate.URL]
+			switch {
+			case code == 0:
+			case code < http.StatusOK || code >= http.StatusMultipleChoices:
+				reasons = append(reasons, fmt.Sprintf("alternate %s returned status %d", alternate.URL, code))
+			case a.nodes[alternate.URL] == nil || a.nodes[alternate.URL].canonical == "":
+				reasons = append(reasons, fmt.Sprintf("alternate %s has no canonical", alternate.URL))
+			case a.nodes[alternate.URL].canonical != u:
+				reasons = append(reasons, fmt.Sprintf("alternate %s has canonical %s", alternate.URL, a.nodes[alternate.URL].canonical))
+			}
+		}
+		if len(reasons) > 0 {
+			findings = append(findings, Finding{Check: CheckMediaAlternate, URL: u, Detail: strings.Join(reasons, ", ")})
+		}
+	}
+	// A mobile page is recognised by a canonical on another host, which must declare it back
+	for u, info := range a.nodes {
+		if info.canonical == "" || info.canonical == u || urlHost(u) == urlHost(info.canonical) {
+			continue
+		}
+		if code := a.statuses[info.canonical]; code < http.StatusOK || code >= http.StatusMultipleChoices {
+			continue
+		}
+		if !declared[u][info.canonical] {
+			findings = append(findings, Finding{Check: CheckMediaAlternate, URL: u, Detail: fmt.Sprintf("canonical %s does not declare it as an alternate", info.canonical)})
+		}
+	}
+	return findings
+}
+
+func urlHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return normaliseHost(u.Host)
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_MediaAlternates(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxDepth = 3
+	c.InternalHosts = "m.example.com"
+	mobile := `<link rel="alternate" media="only screen and (max-width: 640px)" href="https://m.example.com%s">`
+	a, err := New(c, pagesFetcher{
+		"https://example.com":     fmt.Sprintf(mobile, "/") + `<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="https://m.example.com/c">c</a>`,
+		"https://m.example.com/":  `<link rel="canonical" href="https://example.com/">`,
+		"https://example.com/a":   fmt.Sprintf(mobile, "/a"),
+		"https://m.example.com/a": `<link rel="canonical" href="https://example.com/other">`,
+		"https://example.com/b":   fmt.Sprintf(mobile, "/b"),
+		"https://example.com/c":   "c",
+		"https://m.example.com/c": `<link rel="canonical" href="https://example.com/c">`,
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	findings := []Finding{}
+	for _, f := range a.Findings() {
+		if f.Check == CheckMediaAlternate {
+			findings = append(findings, f)
+		}
+	}
+	require.Equal(t, []Finding{
+		{Check: CheckMediaAlternate, URL: "https://example.com/a", Detail: "alternate https://m.example.com/a has canonical https://example.com/other"},
+		{Check: CheckMediaAlternate, URL: "https://example.com/b", Detail: "alternate https://m.example.com/b returned status 404"},
+		{Check: CheckMediaAlternate, URL: "https://m.example.com/c", Detail: "canonical https://example.com/c does not declare it as an alternate"},
+	}, findings)
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+const CheckUnreferencedAsset = "unreferenced-asset"
+
+// LoadAssets reads the deployed assets in path: a directory whose files are served from the site
+// root, a JSON manifest mapping names to built files as bundlers write them, or a list of urls or
+// paths one per line. Paths are returned as given, relative ones being from the site root.
+func LoadAssets(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAssetManifest, err)
+	}
+	assets := []string{}
+	if info.IsDir() {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(path, p)
+			assets = append(assets, filepath.ToSlash(rel))
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidAssetManifest, err)
+		}
+		return assets, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAssetManifest, err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var manifest map[string]json.RawMessage
+		if err := json.Unmarshal(b, &manifest); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidAssetManifest, err)
+		}
+		for _, entry := range manifest {
+			// Entries are either the built file or, as Vite writes them, an object naming it
+			var file string
+			if json.Unmarshal(entry, &file) != nil {
+				var chunk struct {
+					File string `json:"file"`
+				}
+				json.Unmarshal(entry, &chunk)
+				file = chunk.File
+			}
+			if file != "" {
+				assets = append(assets, file)
+			}
+		}
+		slices.Sort(assets)
+		return assets, nil
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			assets = append(assets, line)
+		}
+	}
+	return assets, nil
+}
+
+// deployedAssets resolves assets against the start url, as canonical urls
+func deployedAssets(startURL *url.URL, assets []string) []string {
+	urls := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		u, err := url.Parse(asset)
+		if err != nil {
+			continue
+		}
+		if !u.IsAbs() && !strings.HasPrefix(u.Path, "/") {
+			u.Path = "/" + u.Path
+		}
+		urls = append(urls, normaliseURL(startURL.ResolveReference(u)))
+	}
+	slices.Sort(urls)
+	return slices.Compact(urls)
+}
+
+// siteAssets returns the scripts and stylesheets a page loads from its own host
+func siteAssets(u *url.URL, resources []extractor.Resource) []string {
+	found := []string{}
+	for _, r := range resources {
+		if r.Kind != extractor.ResourceScript && r.Kind != extractor.ResourceStyle {
+			continue
+		}
+		ru, err := url.Parse(r.URL)
+		if err != nil || normaliseHost(ru.Host) != normaliseHost(u.Host) {
+			continue
+		}
+		ru.Fragment = ""
+		if asset := ru.String(); !slices.Contains(found, asset) {
+			found = append(found, intern(asset))
+		}
+	}
+	return found
+}
+
+// recordAssets must be called with a.mu held
+func (a *Audit) recordAssets(resources []string) {
+	if a.assets == nil {
+		return
+	}
+	for _, resource := range resources {
+		if u, err := url.Parse(resource); err == nil {
+			a.referencedAssets[intern(normaliseURL(u))] = struct{}{}
+		}
+	}
+}
+
+// UnreferencedAssets returns the deployed assets no page crawled loaded or linked to
+func (a *Audit) UnreferencedAssets() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.unreferencedAssets()
+}
+
+// unreferencedAssets must be called with a.mu held
+func (a *Audit) unreferencedAssets() []string {
+	unreferenced := []string{}
+	for _, asset := range a.assets {
+		if _, ok := a.referencedAssets[asset]; !ok && !a.visited.Contains(asset) {
+			unreferenced = append(unreferenced, asset)
+		}
+	}
+	return unreferenced
+}
+
+// unreferencedAssetFindings must be called with a.mu held
+func (a *Audit) unreferencedAssetFindings() []Finding {
+	findings := []Finding{}
+	for _, asset := range a.unreferencedAssets() {
+		findings = append(findings, Finding{Check: CheckUnreferencedAsset, URL: asset, Detail: "deployed but not referenced by any page crawled"})
+	}
+	return findings
+}
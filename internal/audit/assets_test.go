@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestLoadAssets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "static", "css"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "static", "css", "site.css"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo.png"), nil, 0644))
+	assets, err := LoadAssets(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"logo.png", "static/css/site.css"}, assets)
+
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, os.WriteFile(manifest, []byte(`{"main.js": "/static/main.1a2b.js", "index.html": {"file": "assets/index.3c4d.js", "css": ["assets/index.css"]}}`), 0644))
+	assets, err = LoadAssets(manifest)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/static/main.1a2b.js", "assets/index.3c4d.js"}, assets)
+
+	list := filepath.Join(t.TempDir(), "assets.txt")
+	require.NoError(t, os.WriteFile(list, []byte("# deployed\n/app.js\n\nhttps://cdn.example.com/lib.js\n"), 0644))
+	assets, err = LoadAssets(list)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/app.js", "https://cdn.example.com/lib.js"}, assets)
+
+	_, err = LoadAssets(filepath.Join(t.TempDir(), "missing.txt"))
+	require.True(t, errors.Is(err, ErrInvalidAssetManifest))
+	require.NoError(t, os.WriteFile(manifest, []byte("["), 0644))
+	_, err = LoadAssets(manifest)
+	require.True(t, errors.Is(err, ErrInvalidAssetManifest))
+}
+
+func TestAudit_UnreferencedAssets(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "assets.txt")
+	require.NoError(t, os.WriteFile(list, []byte("/app.js\n/old.js\nimg/logo.png\nimg/unused.png\n/docs/guide.pdf\n"), 0644))
+	c := testConfig
+	c.RespectRobots = false
+	c.AssetManifest = list
+	a, err := New(c, pagesFetcher{
+		"https://example.com": `<script src="/app.js?v=3"></script><img src="/img/logo.png"><a href="/docs/guide.pdf">guide</a>`,
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []string{"https://example.com/img/unused.png", "https://example.com/old.js"}, a.UnreferencedAssets())
+	findings := []Finding{}
+	for _, f := range a.Findings() {
+		if f.Check == CheckUnreferencedAsset {
+			findings = append(findings, f)
+		}
+	}
+	require.Len(t, findings, 2)
+}
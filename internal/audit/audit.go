@@ -2,19 +2,28 @@ package audit
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/salsgithub/godst/graph"
-	"github.com/salsgithub/godst/queue"
 	"github.com/salsgithub/godst/set"
 	"github.com/temoto/robotstxt"
+	"golang.org/x/net/idna"
+	"salsgithub.com/site-audit/internal/bloom"
+	"salsgithub.com/site-audit/internal/extractor"
+	"salsgithub.com/site-audit/internal/fetcher"
+	"salsgithub.com/site-audit/internal/policy"
 	"salsgithub.com/site-audit/internal/slogx"
 )
 
@@ -23,14 +32,36 @@ type Fetcher interface {
 }
 
 type Extractor interface {
-	Extract(u *url.URL, body io.Reader) ([]string, error)
+	Extract(ctx context.Context, u *url.URL, body io.Reader) ([]string, error)
 }
 
+// DetailExtractor is implemented by extractors that can also return a page's title and the
+// sources of its embedded content, such as iframes, in the same pass
+type DetailExtractor interface {
+	ExtractDetails(ctx context.Context, u *url.URL, body io.Reader) (extractor.Details, error)
+}
+
+// visitedSet is satisfied by an exact set, and by a bloom filter when memory must stay bounded
+type visitedSet interface {
+	Add(values ...string)
+	Contains(value string) bool
+	Len() int
+	IsEmpty() bool
+}
+
+// task holds its url as an interned string rather than a parsed url to keep the queue small
 type task struct {
-	u     *url.URL
-	depth int
+	rawURL string
+	depth  int
+	// attempts counts fetches answered with 429
+	attempts int
 }
 
+// loggerKey holds the logger of the task a context is processing
+type loggerKey struct{}
+
+type Option func(*Audit)
+
 type Audit struct {
 	config     Config
 	logger     *slog.Logger
@@ -39,78 +70,361 @@ type Audit struct {
 	startURL   *url.URL
 	schemes    *set.Set[string]
 	robotsData *robotstxt.RobotsData
-	tasks      *queue.Queue[*task]
-	visited    *set.Set[string]
-	siteGraph  *graph.Graph[string]
-	wg         sync.WaitGroup
-	mu         sync.Mutex
+	// robotsText is the robots.txt read, kept when AUDIT_CHECK_ROBOTS is set
+	robotsText     []byte
+	robotsAllow    []string
+	robotsIgnore   *set.Set[string]
+	internalHosts  *set.Set[string]
+	fragmentRoutes []string
+	captureHeaders []string
+	tasks          *frontier
+	visited        visitedSet
+	siteGraph      *graph.Graph[string]
+	statuses       map[string]int
+	failures       map[string]Failure
+	headers        map[string]map[string]string
+	embeds         map[string][]string
+	thirdParty     map[string][]Resource
+	nodes          map[string]*nodeInfo
+	baseline       *Baseline
+	checkConfig    *CheckConfig
+	policies       *policy.Set
+	prefetcher     Prefetcher
+	authenticator  Authenticator
+	rewriter       Rewriter
+	hostPages      map[string]int
+	trapPatterns   map[string]int
+	externalLinks  map[string]string
+	outbound       map[string]*outboundDomain
+	redirects      map[string]*Redirect
+	// redirectHistory is when temporary redirects were first seen, nil without AUDIT_REDIRECTS_FILE
+	redirectHistory map[string]time.Time
+	// seeds are the urls read from AUDIT_SEEDS_FILE
+	seeds []string
+	// languages are the AUDIT_ACCEPT_LANGUAGES, the first sent with every request
+	languages []string
+	// quotas are the AUDIT_DEPTH_QUOTAS and AUDIT_SECTION_QUOTAS, nil when neither is set
+	quotas *crawlQuotas
+	// indexSamples are the search and faceted urls fetched for AUDIT_CHECK_INDEXABILITY, one per
+	// path and kind
+	indexSamples []indexSample
+	// pageAssets are the scripts and stylesheets each page loads from its own host, kept for
+	// AUDIT_CHECK_COMPRESSION
+	pageAssets map[string][]string
+	// contacts holds the pages linking to each mailto: and tel: link
+	contacts     map[string]map[string]struct{}
+	emailDomains []string
+	disallowed   map[string]map[string]struct{}
+	backoffs     map[string]*hostBackoff
+	throttles    []ThrottleEvent
+	subscribers  map[chan PageEvent]struct{}
+	started      time.Time
+	throughput   []int
+	paramSamples map[string][]string
+	paramImpacts []ParamImpact
+	// assets are the deployed assets from AUDIT_ASSET_MANIFEST, nil when it is not set
+	assets           []string
+	referencedAssets map[string]struct{}
+	checkFindings    []Finding
+	fetchErrs        int
+	failed           int
+	enqueued         int
+	cancel           context.CancelFunc
+	cancelled        bool
+	resumed          bool
+	inFlight         int
+	busy             int
+	paused           int
+	queueSkipped     int
+	concurrency      *concurrencyController
+	graphLog         *graphLog
+	idle             *sync.Cond
+	done             bool
+	// drained is set once the dispatcher has run out of work and seeds can no longer be added
+	drained bool
+	wg      sync.WaitGroup
+	mu      sync.Mutex
 }
 
-func New(config Config, fetcher Fetcher, extractor Extractor) (*Audit, error) {
+func New(config Config, fetcher Fetcher, extractor Extractor, options ...Option) (*Audit, error) {
 	if fetcher == nil {
 		return nil, ErrNoFetcher
 	}
 	if extractor == nil {
 		return nil, ErrNoExtractor
 	}
-	startURL, err := url.Parse(config.StartURL)
-	if config.StartURL == "" || err != nil {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidStartURL, config.StartURL)
-	}
-	if startURL.Scheme == "" {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidStartScheme, config.StartURL)
-	}
-	if config.MaxWorkers < 0 {
-		return nil, ErrInvalidMaxWorkers
-	}
-	if config.MaxDepth < 0 {
-		return nil, ErrInvalidMaxDepth
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
+	startURL, _ := url.Parse(config.StartURL)
 	logLevel := slog.LevelInfo
 	if err := logLevel.UnmarshalText([]byte(config.LogLevel)); err != nil {
 		fmt.Printf("Invalid log level %s, using info\n", config.LogLevel)
 	}
+	files := config.files
+	quotas, err := parseQuotas(config.DepthQuotas, config.SectionQuotas)
+	if err != nil {
+		return nil, err
+	}
+	seeds := files.seeds
+	// Seeds read from stdin are left by Validate, as stdin can only be read once
+	if config.SeedsFile == stdinSeeds {
+		if seeds, err = loadSeeds(config.SeedsFile); err != nil {
+			return nil, err
+		}
+	}
+	var assets []string
+	if config.AssetManifest != "" {
+		assets = deployedAssets(startURL, files.assets)
+	}
 	schemes := set.New("https")
 	if config.ValidSchemes != "" {
 		split := strings.Split(config.ValidSchemes, ",")
 		schemes.Add(split...)
 	}
-	return &Audit{
-		config:    config,
-		logger:    slogx.New(logLevel),
-		fetcher:   fetcher,
-		extractor: extractor,
-		startURL:  startURL,
-		tasks:     queue.New[*task](),
-		visited:   set.New[string](),
-		siteGraph: graph.New[string](),
-		schemes:   schemes,
-	}, nil
+	robotsIgnore := set.New[string]()
+	for _, host := range splitList(config.RobotsIgnore) {
+		robotsIgnore.Add(normaliseHost(host))
+	}
+	emailDomains := []string{}
+	for _, domain := range splitList(config.EmailDomains) {
+		emailDomains = append(emailDomains, normaliseHost(domain))
+	}
+	internalHosts := set.New(normaliseHost(startURL.Host))
+	for _, host := range splitList(config.InternalHosts) {
+		internalHosts.Add(normaliseHost(host))
+	}
+	logger := slogx.New(logLevel, slogx.WithFormat(config.LogFormat), slogx.WithQuiet(config.LogQuiet))
+	a := &Audit{
+		config:           config,
+		logger:           logger,
+		fetcher:          fetcher,
+		extractor:        extractor,
+		startURL:         startURL,
+		tasks:            newFrontier(config.FrontierMemory, config.FrontierDir, logger),
+		visited:          newVisitedSet(config),
+		siteGraph:        graph.New[string](),
+		statuses:         make(map[string]int),
+		failures:         make(map[string]Failure),
+		headers:          make(map[string]map[string]string),
+		embeds:           make(map[string][]string),
+		thirdParty:       make(map[string][]Resource),
+		nodes:            make(map[string]*nodeInfo),
+		hostPages:        make(map[string]int),
+		trapPatterns:     make(map[string]int),
+		externalLinks:    make(map[string]string),
+		outbound:         make(map[string]*outboundDomain),
+		redirects:        make(map[string]*Redirect),
+		redirectHistory:  files.redirects,
+		seeds:            seeds,
+		contacts:         make(map[string]map[string]struct{}),
+		emailDomains:     emailDomains,
+		disallowed:       make(map[string]map[string]struct{}),
+		backoffs:         make(map[string]*hostBackoff),
+		subscribers:      make(map[chan PageEvent]struct{}),
+		paramSamples:     make(map[string][]string),
+		assets:           assets,
+		referencedAssets: make(map[string]struct{}),
+		baseline:         files.baseline,
+		checkConfig:      files.checks,
+		schemes:          schemes,
+
+		robotsAllow:  splitList(config.RobotsAllow),
+		robotsIgnore: robotsIgnore,
+
+		internalHosts:  internalHosts,
+		fragmentRoutes: splitList(config.FragmentRoutes),
+		languages:      splitList(config.AcceptLanguages),
+		quotas:         quotas,
+		pageAssets:     make(map[string][]string),
+		captureHeaders: capturedHeaders(config),
+	}
+	a.idle = sync.NewCond(&a.mu)
+	if config.AdaptiveConcurrency {
+		a.concurrency = newConcurrencyController(config.MaxWorkers, config.AdaptiveLatency)
+	}
+	for _, option := range options {
+		option(a)
+	}
+	a.tasks.logger = a.logger
+	return a, nil
+}
+
+// Authenticator signs in to the site before anything is crawled
+type Authenticator interface {
+	Login(ctx context.Context) error
+}
+
+func WithAuthenticator(auth Authenticator) Option {
+	return func(a *Audit) {
+		a.authenticator = auth
+	}
+}
+
+// Prefetcher warms name resolution for hosts found in links before they are fetched
+type Prefetcher interface {
+	Prefetch(host string)
+}
+
+func WithPrefetcher(p Prefetcher) Option {
+	return func(a *Audit) {
+		a.prefetcher = p
+	}
+}
+
+// Rewriter maps discovered urls to the url that should be crawled instead, such as forcing https
+// or folding mirrors into one host
+type Rewriter interface {
+	Rewrite(u string) string
+}
+
+func WithRewriter(r Rewriter) Option {
+	return func(a *Audit) {
+		a.rewriter = r
+	}
+}
+
+// WithLogger replaces the logger built from AUDIT_LOG_LEVEL, AUDIT_LOG_FORMAT and AUDIT_LOG_QUIET,
+// such as with one writing to AUDIT_LOG_FILE shared by several audits
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *Audit) {
+		a.logger = logger
+	}
+}
+
+func WithPolicies(policies *policy.Set) Option {
+	return func(a *Audit) {
+		a.policies = policies
+	}
 }
 
 func (a *Audit) Start(ctx context.Context) error {
 	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	a.mu.Lock()
+	a.cancel = cancel
+	a.started = start
+	if a.cancelled {
+		cancel()
+	}
+	a.mu.Unlock()
+	defer a.markDone()
+	if a.authenticator != nil {
+		if err := a.authenticator.Login(ctx); err != nil {
+			return fmt.Errorf("error logging in: %w", err)
+		}
+		a.logger.Info("Logged in")
+	}
 	if a.config.RespectRobots {
-		if err := a.respectRobots(ctx); err != nil {
+		if !a.respectsRobots(a.startURL) {
+			a.logger.Warn("Ignoring robots.txt for host, only do this for sites you own", "host", a.startURL.Host)
+		} else if err := a.respectRobots(ctx); err != nil {
 			return fmt.Errorf("failed to respect robots: %w", err)
 		}
+		if len(a.robotsAllow) > 0 {
+			a.logger.Warn("Crawling paths regardless of robots.txt, only do this for sites you own", "paths", a.robotsAllow)
+		}
 	}
-	a.tasks.Enqueue(&task{
-		u:     a.startURL,
-		depth: 0,
-	})
-	a.visited.Add(a.startURL.String())
-	for range a.config.MaxWorkers {
+	if a.config.GraphLogFile != "" {
+		graphLog, err := openGraphLog(a.config.GraphLogFile, a.resumed)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			a.mu.Lock()
+			a.graphLog = nil
+			a.mu.Unlock()
+			if err := graphLog.close(); err != nil {
+				a.logger.Error("Error closing graph log", "err", err)
+			}
+		}()
+		startURL, _ := CanonicalURL(a.config.StartURL)
+		if err := graphLog.write(graphLogRecord{StartURL: startURL}); err != nil {
+			return fmt.Errorf("error writing graph log: %w", err)
+		}
+		a.mu.Lock()
+		a.graphLog = graphLog
+		a.mu.Unlock()
+	}
+	var seeds []sitemapSeed
+	if a.config.SitemapOnly && !a.resumed {
+		var err error
+		if seeds, err = a.sitemapSeeds(ctx); err != nil {
+			return fmt.Errorf("failed to read sitemap: %w", err)
+		}
+	}
+	a.mu.Lock()
+	if a.config.SitemapOnly && !a.resumed {
+		a.enqueueSitemap(seeds)
+	} else if !a.config.SeedsOnly && !a.resumed {
+		a.withinPageLimit(a.startURL)
+		a.enqueue(&task{
+			rawURL: intern(a.startURL.String()),
+			depth:  0,
+		})
+		startURL := intern(a.canonicalURL(a.startURL))
+		a.visited.Add(startURL)
+		a.node(startURL).depth = 0
+	}
+	if !a.resumed {
+		a.enqueueSeeds(a.seeds)
+	}
+	a.mu.Unlock()
+	if a.config.StatsInterval > 0 {
+		statsCtx, stopStats := context.WithCancel(ctx)
+		statsDone := make(chan struct{})
+		go func() {
+			defer close(statsDone)
+			a.logStats(statsCtx, a.config.StatsInterval)
+		}()
+		defer func() {
+			stopStats()
+			<-statsDone
+		}()
+	}
+	work := make(chan *task)
+	go a.dispatch(ctx, work)
+	for id := range a.config.MaxWorkers {
 		a.wg.Add(1)
-		go a.startWorker(ctx)
+		go a.startWorker(ctx, id, work)
 	}
 	a.wg.Wait()
-	a.logger.Info("Auditing finished", "duration_s", time.Since(start).Seconds(), "visited", a.visited.Len())
+	if a.config.QueryParamSamples > 0 && ctx.Err() == nil {
+		a.analyseQueryParams(ctx)
+	}
+	if a.config.CheckIndexability && ctx.Err() == nil {
+		a.analyseIndexability(ctx)
+	}
+	a.logger.InfoContext(slogx.Summary(ctx), "Auditing finished", "duration_s", time.Since(start).Seconds(), "visited", a.visited.Len(), "skipped_queue_full", a.queueSkipped)
+	if p := a.Politeness(); p.Throttled > 0 {
+		a.logger.InfoContext(slogx.Summary(ctx), "Throttled by hosts", "responses_429", p.Throttled, "retry_after_honored", p.Honored, "hosts", len(p.Hosts))
+	}
 	return nil
 }
 
-func (a *Audit) ExportGraph(export func(g *graph.Graph[string]) error) {
-	if err := export(a.siteGraph); err != nil {
+func (a *Audit) UpdateBaseline() error {
+	if !a.config.UpdateBaseline || a.config.BaselineFile == "" {
+		return nil
+	}
+	findings := a.Findings()
+	if err := WriteBaseline(a.config.BaselineFile, findings); err != nil {
+		return fmt.Errorf("error writing baseline: %w", err)
+	}
+	a.logger.Info("Baseline updated", "path", a.config.BaselineFile, "findings", len(findings))
+	return nil
+}
+
+// ExportGraph passes export a copy of the site graph along with the metadata of its nodes
+func (a *Audit) ExportGraph(export func(g *graph.Graph[string], nodes map[string]Node) error) {
+	if err := export(a.graphSnapshot(), a.Nodes()); err != nil {
+		a.logger.Error("Error exporting site graph", "err", err)
+	}
+}
+
+// ExportFilteredGraph passes export the part of the site graph the filter keeps
+func (a *Audit) ExportFilteredGraph(filter GraphFilter, export func(g *graph.Graph[string], nodes map[string]Node) error) {
+	if err := export(a.FilteredGraph(filter)); err != nil {
 		a.logger.Error("Error exporting site graph", "err", err)
 	}
 }
@@ -145,89 +459,613 @@ func (a *Audit) respectRobots(ctx context.Context) error {
 	}
 	a.logger.Debug("robots.txt configured")
 	a.robotsData = robotsData
+	if a.config.CheckRobots {
+		a.robotsText = b
+	}
 	a.visited.Add(robotsURL)
 	return nil
 }
 
-func (a *Audit) startWorker(ctx context.Context) {
-	defer a.wg.Done()
+// dispatch feeds queued tasks to the workers, closing work once the queue is empty with no task
+// in flight that could enqueue more, or when ctx is cancelled
+func (a *Audit) dispatch(ctx context.Context, work chan<- *task) {
+	defer close(work)
+	stop := context.AfterFunc(ctx, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.idle.Broadcast()
+	})
+	defer stop()
 	for {
+		a.mu.Lock()
+		for (a.tasks.IsEmpty() || a.inFlight >= a.concurrency.Limit()) && a.inFlight > 0 && ctx.Err() == nil {
+			a.idle.Wait()
+		}
+		if ctx.Err() != nil || a.tasks.IsEmpty() {
+			a.drained = true
+			a.mu.Unlock()
+			return
+		}
+		t, _ := a.tasks.Dequeue()
+		a.inFlight++
+		// Wakes workers held back by a full queue
+		a.idle.Broadcast()
+		a.mu.Unlock()
 		select {
+		case work <- t:
 		case <-ctx.Done():
+			a.mu.Lock()
+			a.tasks.Enqueue(t)
+			a.inFlight--
+			a.mu.Unlock()
 			return
-		default:
-
 		}
+	}
+}
+
+// startWorker processes tasks with a logger carrying the worker, url, depth and attempt, so the
+// logs of workers running at once can be told apart
+func (a *Audit) startWorker(ctx context.Context, id int, work <-chan *task) {
+	defer a.wg.Done()
+	for t := range work {
 		a.mu.Lock()
-		if a.tasks.IsEmpty() {
-			a.mu.Unlock()
+		a.busy++
+		a.mu.Unlock()
+		logger := a.logger.With("worker", id, "url", t.rawURL, "depth", t.depth, "attempt", t.attempts+1)
+		a.process(context.WithValue(ctx, loggerKey{}, logger), t)
+		a.mu.Lock()
+		a.inFlight--
+		a.busy--
+		a.idle.Broadcast()
+		a.mu.Unlock()
+	}
+}
+
+func (a *Audit) process(ctx context.Context, t *task) {
+	if ctx.Err() != nil {
+		a.requeue(t)
+		return
+	}
+	logger := a.taskLogger(ctx)
+	u, err := url.Parse(t.rawURL)
+	if err != nil {
+		logger.Error("Invalid queued url", "err", err)
+		a.recordFetchError()
+		return
+	}
+	if err := a.waitForHost(ctx, u.Hostname()); err != nil {
+		a.requeue(t)
+		return
+	}
+	logger.Debug("Fetching")
+	mark := a.findingsMark()
+	fetchCtx := ctx
+	if lang := a.acceptLanguage(u); lang != "" {
+		fetchCtx = fetcher.WithHeader(ctx, "Accept-Language", lang)
+	}
+	start := time.Now()
+	response, err := a.fetcher.Fetch(fetchCtx, u)
+	if err != nil && ctx.Err() != nil {
+		a.requeue(t)
+		return
+	}
+	if err != nil {
+		a.concurrency.Observe(time.Since(start), true)
+		failure := classifyError(err)
+		logger.Error("Failed to fetch url", "err", err, "failure", failure)
+		a.recordFetchError()
+		a.recordFailure(intern(a.canonicalURL(u)), failure)
+		a.publishPage(u, t.depth, 0, err, mark)
+		return
+	}
+	elapsed := time.Since(start)
+	throttled := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError
+	a.concurrency.Observe(elapsed, throttled)
+	defer closeBody(response.Body)
+	if response.StatusCode != http.StatusTooManyRequests {
+		a.recordHostOK(u.Hostname())
+	} else if a.recordThrottle(logger, t, u, response.Header) {
+		a.requeue(&task{rawURL: t.rawURL, depth: t.depth, attempts: t.attempts + 1})
+		return
+	}
+	defer a.publishPage(u, t.depth, response.StatusCode, nil, mark)
+	a.recordStatus(u, response.StatusCode)
+	a.recordRedirect(intern(a.canonicalURL(u)), response)
+	a.recordHeaders(u, response.Header)
+	a.recordFetch(u, elapsed, response.ContentLength)
+	if failure := classifyStatus(response.StatusCode); failure != "" {
+		logger.Warn("Received non successful status code", "code", response.StatusCode)
+		a.recordFailure(intern(a.canonicalURL(u)), failure)
+		return
+	}
+	if variesByLanguage(response.Header) {
+		a.enqueueLanguageVariants(u, t.depth)
+	}
+	directives := parseRobotsTag(response.Header, a.config.Agent)
+	if directives.noIndex {
+		a.recordFinding(Finding{Check: CheckNoIndex, URL: a.canonicalURL(u), Detail: "X-Robots-Tag: noindex"})
+	}
+	if directives.noFollow {
+		a.recordFinding(Finding{Check: CheckNoFollow, URL: a.canonicalURL(u), Detail: "X-Robots-Tag: nofollow"})
+		if a.respectsRobots(u) {
+			logger.Info("Not following links on page marked nofollow")
 			return
 		}
-		task, _ := a.tasks.Dequeue()
-		a.mu.Unlock()
-		a.logger.Debug("Fetching", "url", task.u.String())
-		response, err := a.fetcher.Fetch(ctx, task.u)
-		if err != nil {
-			a.logger.Error("Failed to fetch url", "url", task.u.String(), "err", err)
+	}
+	body := &countingReader{r: response.Body, hash: sha256.New()}
+	details, err := a.extract(ctx, u, newBoundedReader(body, a.config.MaxBodyBytes))
+	if err != nil && ctx.Err() != nil {
+		a.requeue(t)
+		return
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		logger.Warn("Abandoning page past body limit", "limit", a.config.MaxBodyBytes)
+		a.recordFinding(Finding{Check: CheckBodyTooLarge, URL: a.canonicalURL(u), Detail: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Error extracting links", "err", err)
+		a.recordFailure(intern(a.canonicalURL(u)), FailureParse)
+		return
+	}
+	details.MergeHeaderLinks(extractor.ParseLinkHeader(u, response.Header.Values("Link")))
+	if location, ok := redirectLocation(response); ok {
+		details.AddLink(location)
+	}
+	logger.Debug("Links found", "links", details.Links)
+	a.recordDetails(u, details, body)
+	var meta robotsDirectives
+	meta.add(details.Robots)
+	if meta.noIndex && !directives.noIndex {
+		a.recordFinding(Finding{Check: CheckNoIndex, URL: a.canonicalURL(u), Detail: `<meta name="robots"> noindex`})
+	}
+	if details.Refresh != "" {
+		a.recordFinding(Finding{Check: CheckMetaRefresh, URL: a.canonicalURL(u), Detail: fmt.Sprintf("redirects to %s after %d seconds", details.Refresh, details.RefreshDelay)})
+	}
+	if a.config.SitemapOnly || a.config.SeedsOnly {
+		return
+	}
+	a.waitForQueue(ctx)
+	if err := a.processLinks(ctx, u, t.depth, details.Links, details.LinkCounts); err != nil {
+		a.requeue(t)
+	}
+}
+
+// taskLogger is the logger of the task ctx is processing, or the audit's logger outside of one
+func (a *Audit) taskLogger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return a.logger
+}
+
+func (a *Audit) extract(ctx context.Context, u *url.URL, body io.Reader) (extractor.Details, error) {
+	if e, ok := a.extractor.(DetailExtractor); ok {
+		return e.ExtractDetails(ctx, u, body)
+	}
+	links, err := a.extractor.Extract(ctx, u, body)
+	return extractor.Details{Links: links}, err
+}
+
+// waitForQueue holds a worker back from enqueueing while the queue is at AUDIT_MAX_QUEUE, as
+// long as another worker is still fetching and so draining it. Links that still do not fit are
+// skipped by processLinks.
+func (a *Audit) waitForQueue(ctx context.Context) {
+	if a.config.MaxQueue <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.tasks.Len() < a.config.MaxQueue {
+		return
+	}
+	a.taskLogger(ctx).Debug("Queue full, pausing before enqueueing links", "queued", a.tasks.Len())
+	a.paused++
+	for a.tasks.Len() >= a.config.MaxQueue && a.paused < a.busy && ctx.Err() == nil {
+		a.idle.Wait()
+	}
+	a.paused--
+}
+
+// requeue puts back a task cut short by cancellation so it is kept in a checkpoint
+func (a *Audit) requeue(t *task) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tasks.Enqueue(t)
+}
+
+type candidate struct {
+	u         *url.URL
+	canonical string
+	external  bool
+	// disallowed marks internal links robots.txt keeps the crawl from following
+	disallowed bool
+	// contact marks mailto: and tel: links, which are collected rather than crawled
+	contact bool
+	count   int
+}
+
+// processLinks filters links without holding the lock, which is then only taken to update the
+// visited set, graph and frontier. It returns the context's error if cancelled before the update,
+// which is applied all at once so a requeued page is never left half recorded. Each edge is
+// weighted by how many times the page links to its target, counting a link once when counts is nil.
+func (a *Audit) processLinks(ctx context.Context, base *url.URL, depth int, links []string, counts map[string]int) error {
+	logger := a.taskLogger(ctx)
+	candidates, err := a.filterLinks(ctx, base, links, counts)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	source := intern(a.canonicalURL(base))
+	// Links differing only in ways the canonical url drops, such as /a and /a/, are one target
+	weights := make(map[string]int, len(candidates))
+	targets := make([]candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if _, ok := weights[c.canonical]; !ok {
+			targets = append(targets, c)
+		}
+		weights[c.canonical] += c.count
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	edges := make([]graphLogRecord, 0, len(candidates))
+	defer func() {
+		if err := a.graphLog.write(edges...); err != nil {
+			logger.Error("Error writing graph log", "err", err)
+		}
+	}()
+	for _, c := range targets {
+		if c.external {
+			a.recordOutbound(c.u, source, weights[c.canonical])
+			if a.config.LinkRotFile == "" {
+				continue
+			}
+			if _, ok := a.externalLinks[c.canonical]; !ok {
+				a.externalLinks[c.canonical] = source
+			}
 			continue
 		}
-		defer response.Body.Close()
-		if response.StatusCode >= http.StatusBadRequest {
-			a.logger.Warn("Received non successful status code", "url", task.u.String(), "code", response.StatusCode)
+		if c.disallowed {
+			a.recordDisallowed(c.canonical, source)
 			continue
 		}
-		links, err := a.extractor.Extract(task.u, response.Body)
-		if err != nil {
-			a.logger.Error("Error extracting links", "url", task.u.String(), "err", err)
+		if c.contact {
+			a.recordContact(c.u, source)
 			continue
 		}
-		a.logger.Debug("Links found", "links", links)
-		a.processLinks(task, links)
+		a.recordQueryParams(c.u)
+		a.recordIndexSample(c.u)
+		if c.canonical != source {
+			a.siteGraph.AddEdge(source, c.canonical, weights[c.canonical])
+			edges = append(edges, graphLogRecord{Source: source, Target: c.canonical, Weight: weights[c.canonical]})
+		}
+		if a.visited.Contains(c.canonical) {
+			continue
+		}
+		a.visited.Add(c.canonical)
+		a.node(c.canonical).depth = depth + 1
+		if depth+1 >= a.config.MaxDepth {
+			continue
+		}
+		if a.config.MaxQueue > 0 && a.tasks.Len() >= a.config.MaxQueue {
+			if a.queueSkipped == 0 {
+				logger.Warn("Queue full, links found from now on are recorded but not crawled", "max_queue", a.config.MaxQueue)
+			}
+			a.queueSkipped++
+			continue
+		}
+		if a.trapped(c.u) {
+			logger.Debug("Skipping url in crawler trap", "link", c.u.String())
+			continue
+		}
+		if !a.withinPageLimit(c.u) {
+			logger.Debug("Skipping url as host page limit reached", "link", c.u.String())
+			continue
+		}
+		if !a.withinQuotas(c.u, depth+1) {
+			logger.Debug("Skipping url as crawl quota reached", "link", c.u.String())
+			continue
+		}
+		a.enqueue(&task{
+			rawURL: intern(c.u.String()),
+			depth:  depth + 1,
+		})
 	}
+	return nil
 }
 
-func (a *Audit) processLinks(t *task, links []string) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	baseURL := t.u
+// filterLinks resolves links against baseURL and keeps those the crawl may follow. It only reads
+// state that is fixed once the crawl has started.
+func (a *Audit) filterLinks(ctx context.Context, baseURL *url.URL, links []string, counts map[string]int) ([]candidate, error) {
+	logger := a.taskLogger(ctx)
 	baseHost := normaliseHost(baseURL.Host)
+	candidates := make([]candidate, 0, len(links))
 	for _, linkString := range links {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		parsedLink, err := url.Parse(linkString)
 		if err != nil {
-			a.logger.Debug("Malformed link", "link", linkString)
+			logger.Debug("Malformed link", "link", linkString)
+			continue
+		}
+		resolvedLink, ok := a.rewrite(baseURL.ResolveReference(parsedLink))
+		if !ok {
+			continue
+		}
+		if isContactScheme(resolvedLink.Scheme) {
+			candidates = append(candidates, candidate{u: resolvedLink, canonical: intern(resolvedLink.String()), contact: true, count: 1})
 			continue
 		}
-		resolvedLink := baseURL.ResolveReference(parsedLink)
 		resolvedHost := normaliseHost(resolvedLink.Host)
 		if !a.schemes.Contains(resolvedLink.Scheme) {
-			a.logger.Debug("Skipping link as scheme not permitted", "link", linkString, "scheme", resolvedLink.Scheme)
+			logger.Debug("Skipping link as scheme not permitted", "link", linkString, "scheme", resolvedLink.Scheme)
 			continue
 		}
-		if baseHost != resolvedHost {
-			a.logger.Debug("Skipping external link", "link", resolvedLink.String())
+		if baseHost != resolvedHost && !a.internalHosts.Contains(resolvedHost) {
+			logger.Debug("Skipping external link", "link", resolvedLink.String())
+			external := *resolvedLink
+			external.Fragment = ""
+			candidates = append(candidates, candidate{u: resolvedLink, canonical: intern(external.String()), external: true, count: max(counts[linkString], 1)})
 			continue
 		}
-		if a.robotsData != nil && !a.robotsData.TestAgent(resolvedLink.Path, a.config.Agent) {
-			a.logger.Info("Skipping url disallowed by robots.txt", "url", resolvedLink.String())
-			continue
+		if a.prefetcher != nil {
+			a.prefetcher.Prefetch(resolvedLink.Hostname())
 		}
-		canonicalURL := normaliseURL(resolvedLink)
-		if a.visited.Contains(canonicalURL) {
+		if a.disallowedByRobots(resolvedLink) {
+			logger.Info("Skipping url disallowed by robots.txt", "link", resolvedLink.String())
+			candidates = append(candidates, candidate{u: resolvedLink, canonical: intern(a.canonicalURL(resolvedLink)), disallowed: true, count: 1})
 			continue
 		}
-		a.visited.Add(canonicalURL)
-		a.siteGraph.AddEdge(normaliseURL(baseURL), canonicalURL, 1)
-		if t.depth+1 < a.config.MaxDepth {
-			a.tasks.Enqueue(&task{
-				u:     resolvedLink,
-				depth: t.depth + 1,
-			})
+		candidates = append(candidates, candidate{u: resolvedLink, canonical: intern(a.canonicalURL(resolvedLink)), count: max(counts[linkString], 1)})
+	}
+	return candidates, nil
+}
+
+// rewrite applies the rewrite rules to u before it is checked, deduplicated or queued
+func (a *Audit) rewrite(u *url.URL) (*url.URL, bool) {
+	if a.rewriter == nil {
+		return u, true
+	}
+	rewritten, err := url.Parse(a.rewriter.Rewrite(u.String()))
+	if err != nil {
+		a.logger.Debug("Malformed rewritten link", "link", u.String(), "err", err)
+		return nil, false
+	}
+	return rewritten, true
+}
+
+// withinPageLimit must be called with a.mu held
+func (a *Audit) withinPageLimit(u *url.URL) bool {
+	p := a.policies.Match(u.Hostname())
+	if p == nil || p.MaxPages == 0 {
+		return true
+	}
+	host := u.Hostname()
+	if a.hostPages[host] >= p.MaxPages {
+		return false
+	}
+	a.hostPages[host]++
+	return true
+}
+
+// respectsRobots reports whether robots directives apply to u
+func (a *Audit) respectsRobots(u *url.URL) bool {
+	return a.config.RespectRobots && !a.robotsIgnore.Contains(normaliseHost(u.Host))
+}
+
+// disallowedByRobots reports whether robots.txt keeps the crawl from fetching u, warning about urls
+// crawled anyway because of AUDIT_ROBOTS_ALLOW
+func (a *Audit) disallowedByRobots(u *url.URL) bool {
+	if a.robotsData == nil || normaliseHost(a.startURL.Host) != normaliseHost(u.Host) || a.robotsData.TestAgent(u.Path, a.config.Agent) {
+		return false
+	}
+	if a.robotsAllowed(u.Path) {
+		a.logger.Warn("Crawling url disallowed by robots.txt due to override", "url", u.String())
+		return false
+	}
+	return true
+}
+
+func (a *Audit) robotsAllowed(path string) bool {
+	for _, prefix := range a.robotsAllow {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Audit) recordStatus(u *url.URL, code int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.statuses[intern(a.canonicalURL(u))] = code
+	if a.broken(u.String(), code) {
+		a.failed++
+	}
+	if err := a.graphLog.write(graphLogRecord{URL: a.canonicalURL(u), StatusCode: code}); err != nil {
+		a.logger.Error("Error writing graph log", "err", err)
+	}
+}
+
+func (a *Audit) recordHeaders(u *url.URL, header http.Header) {
+	recorded := map[string]string{}
+	for _, key := range a.captureHeaders {
+		if values := header.Values(key); len(values) > 0 {
+			recorded[key] = strings.Join(values, ", ")
+		}
+	}
+	if len(recorded) == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.headers[intern(a.canonicalURL(u))] = recorded
+}
+
+func (a *Audit) recordFetch(u *url.URL, elapsed time.Duration, contentLength int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	info := a.node(intern(a.canonicalURL(u)))
+	info.fetchTime = elapsed
+	info.contentLength = max(contentLength, 0)
+	a.recordThroughput()
+}
+
+// recordDetails falls back to the bytes read for the content length when none was sent
+func (a *Audit) recordDetails(u *url.URL, details extractor.Details, body *countingReader) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	canonical := intern(a.canonicalURL(u))
+	info := a.node(canonical)
+	info.title = details.Title
+	info.lang = details.Lang
+	info.canonical = ""
+	if cu, err := url.Parse(details.Canonical); err == nil && details.Canonical != "" {
+		info.canonical = intern(a.canonicalURL(cu))
+	}
+	if info.contentLength == 0 {
+		info.contentLength = body.read
+	}
+	info.contentHash = body.sum()
+	info.alternates = nil
+	for _, alternate := range details.Alternates {
+		if au, err := url.Parse(alternate.URL); err == nil {
+			info.alternates = append(info.alternates, Alternate{Lang: alternate.Lang, Media: alternate.Media, URL: intern(a.canonicalURL(au))})
+		}
+	}
+	if a.config.CheckEmbeds && len(details.Embeds) > 0 {
+		a.embeds[canonical] = details.Embeds
+	}
+	resources := make([]string, 0, len(details.Resources))
+	for _, resource := range details.Resources {
+		resources = append(resources, resource.URL)
+	}
+	a.recordAssets(resources)
+	if a.config.CheckCompression {
+		if assets := siteAssets(u, details.Resources); len(assets) > 0 {
+			a.pageAssets[canonical] = assets
 		}
 	}
+	if a.config.CheckCSP {
+		if resources := thirdPartyResources(u, details.Resources); len(resources) > 0 {
+			a.thirdParty[canonical] = resources
+		}
+	}
+}
+
+func (a *Audit) recordFinding(f Finding) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.checkFindings = append(a.checkFindings, f)
+}
+
+func (a *Audit) recordFetchError() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fetchErrs++
+	a.failed++
+	a.recordThroughput()
+}
+
+// ExternalLink is a link off the site, collected when AUDIT_LINK_ROT_FILE is set
+type ExternalLink struct {
+	URL    string `json:"url"`
+	Source string `json:"source"`
+}
+
+// ExternalLinks returns the external links found, with the first page each was found on, sorted by url
+func (a *Audit) ExternalLinks() []ExternalLink {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	links := make([]ExternalLink, 0, len(a.externalLinks))
+	for u, source := range a.externalLinks {
+		links = append(links, ExternalLink{URL: u, Source: source})
+	}
+	slices.SortFunc(links, func(x, y ExternalLink) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	return links
+}
+
+func CanonicalURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return normaliseURL(u), nil
+}
+
+// canonicalURL is normaliseURL keeping any fragment matching AUDIT_FRAGMENT_ROUTES, so hash
+// routes such as /#/about are recorded as pages of their own
+func (a *Audit) canonicalURL(u *url.URL) string {
+	canonical := normaliseURL(u)
+	if u.Fragment == "" {
+		return canonical
+	}
+	fragment := "#" + u.EscapedFragment()
+	if len(a.languages) > 1 && strings.HasPrefix(u.Fragment, languageFragment) {
+		return canonical + fragment
+	}
+	for _, prefix := range a.fragmentRoutes {
+		if strings.HasPrefix(fragment, prefix) {
+			return canonical + fragment
+		}
+	}
+	return canonical
+}
+
+func newVisitedSet(config Config) visitedSet {
+	if config.VisitedMode == VisitedBloom {
+		return bloom.New(config.BloomCapacity, config.BloomFalsePositive)
+	}
+	return newHandleSet()
+}
+
+func splitList(s string) []string {
+	values := []string{}
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
 }
 
 func normaliseHost(host string) string {
-	return strings.TrimPrefix(host, "www.")
+	return strings.TrimPrefix(canonicalHost(host), "www.")
+}
+
+// canonicalHost lower cases host and converts an internationalised name to its punycode form,
+// so bücher.example and xn--bcher-kva.example are the same host
+func canonicalHost(host string) string {
+	ascii := true
+	for i := range len(host) {
+		if host[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return strings.ToLower(host)
+	}
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, ""
+	}
+	converted, err := idna.Lookup.ToASCII(hostname)
+	if err != nil {
+		return strings.ToLower(host)
+	}
+	if port != "" {
+		return net.JoinHostPort(converted, port)
+	}
+	return converted
 }
 
 func normaliseURL(u *url.URL) string {
@@ -238,5 +1076,5 @@ func normaliseURL(u *url.URL) string {
 	if path == "" {
 		path = "/"
 	}
-	return u.Scheme + "://" + u.Host + path
+	return u.Scheme + "://" + canonicalHost(u.Host) + path
 }
@@ -1,20 +1,29 @@
 package audit
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/salsgithub/godst/graph"
 	"github.com/stretchr/testify/require"
 	"github.com/temoto/robotstxt"
 	"salsgithub.com/site-audit/internal/extractor"
+	"salsgithub.com/site-audit/internal/fetcher"
+	"salsgithub.com/site-audit/internal/policy"
+	"salsgithub.com/site-audit/internal/sitegen"
 )
 
 var (
@@ -68,10 +77,49 @@ type mockExtractor struct {
 	err    error
 }
 
-func (m *mockExtractor) Extract(u *url.URL, body io.Reader) ([]string, error) {
+func (m *mockExtractor) Extract(ctx context.Context, u *url.URL, body io.Reader) ([]string, error) {
 	return m.values, m.err
 }
 
+type pagesFetcher map[string]string
+
+func (f pagesFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	if body, ok := f[u.String()]; ok {
+		return successResponse(body), nil
+	}
+	return notFoundResponse(""), nil
+}
+
+type headerFetcher map[string]http.Header
+
+func (f headerFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	header, ok := f[u.String()]
+	if !ok {
+		return notFoundResponse(""), nil
+	}
+	response := successResponse("page")
+	response.Header = header
+	return response, nil
+}
+
+// newTestAudit builds an audit that ignores robots.txt and discards its logs
+func newTestAudit(t testing.TB, c Config, f Fetcher, e Extractor, options ...Option) *Audit {
+	t.Helper()
+	c.RespectRobots = false
+	a, err := New(c, f, e, options...)
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	return a
+}
+
+// crawlTestAudit runs a test audit over the links of f's pages to completion
+func crawlTestAudit(t testing.TB, c Config, f Fetcher, options ...Option) *Audit {
+	t.Helper()
+	a := newTestAudit(t, c, f, extractor.NewLinkExtractor(extractor.WithDefaultIgnores()), options...)
+	require.NoError(t, a.Start(context.Background()))
+	return a
+}
+
 func TestAudit_New(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -140,6 +188,18 @@ func TestAudit_New(t *testing.T) {
 			extractor: &mockExtractor{},
 			wantErr:   nil,
 		},
+		{
+			name: "Missing baseline file",
+			config: Config{
+				StartURL:     "https://example.com",
+				MaxWorkers:   5,
+				MaxDepth:     2,
+				BaselineFile: "does-not-exist.json",
+			},
+			fetcher:   &mockFetcher{},
+			extractor: &mockExtractor{},
+			wantErr:   ErrInvalidBaseline,
+		},
 		{
 			name: "With valid schemes",
 			config: Config{
@@ -197,6 +257,20 @@ func TestAudit_Start(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to respect robots")
 	})
+	t.Run("robots.txt is not fetched for ignored hosts", func(t *testing.T) {
+		mockFetcher := &mockFetcher{
+			responses: map[string]*http.Response{
+				"https://example.com/robots.txt": forbiddenResponse("FORBIDDEN!"),
+			},
+		}
+		c := testConfig
+		c.RobotsIgnore = "www.example.com, other.com"
+		a, err := New(c, mockFetcher, &mockExtractor{})
+		require.NoError(t, err)
+		a.logger = slog.New(slog.DiscardHandler)
+		require.NoError(t, a.Start(context.Background()))
+		require.Nil(t, a.robotsData)
+	})
 	t.Run("audit starts without respecting robots.txt", func(t *testing.T) {
 		mockFetcher := &mockFetcher{
 			responses: map[string]*http.Response{
@@ -317,48 +391,36 @@ func TestAudit_WorkerErrorDoesNotStopAuditing(t *testing.T) {
 
 func TestAudit_ProcessLinks(t *testing.T) {
 	newAudit := func() *Audit {
-		mockFetcher := &mockFetcher{}
-		mockExtractor := &mockExtractor{}
-		c := testConfig
-		c.RespectRobots = false
-		a, err := New(c, mockFetcher, mockExtractor)
-		require.NoError(t, err)
-		require.NotNil(t, a)
-		a.logger = slog.New(slog.DiscardHandler)
-		return a
+		return newTestAudit(t, testConfig, &mockFetcher{}, &mockExtractor{})
 	}
 	t.Run("skips already visited links", func(t *testing.T) {
 		a := newAudit()
 		a.logger = slog.New(slog.DiscardHandler)
 		startURL, _ := url.Parse(testConfig.StartURL)
-		startTask := &task{u: startURL, depth: 0}
 		a.visited.Add(normaliseURL(startURL))
 		initialLen := a.visited.Len()
-		a.processLinks(startTask, []string{testConfig.StartURL})
+		a.processLinks(context.Background(), startURL, 0, []string{testConfig.StartURL}, nil)
 		require.Equal(t, initialLen, a.visited.Len())
 		require.True(t, a.tasks.IsEmpty())
 	})
 	t.Run("skips external links", func(t *testing.T) {
 		a := newAudit()
 		startURL, _ := url.Parse(testConfig.StartURL)
-		startTask := &task{u: startURL, depth: 0}
-		a.processLinks(startTask, []string{"http://somethingelse.com"})
+		a.processLinks(context.Background(), startURL, 0, []string{"http://somethingelse.com"}, nil)
 		require.True(t, a.visited.IsEmpty())
 		require.True(t, a.tasks.IsEmpty())
 	})
 	t.Run("skip links with disallowed scheme", func(t *testing.T) {
 		a := newAudit()
 		startURL, _ := url.Parse(testConfig.StartURL)
-		startTask := &task{u: startURL, depth: 0}
-		a.processLinks(startTask, []string{"mailto:test@example.com"})
+		a.processLinks(context.Background(), startURL, 0, []string{"mailto:test@example.com"}, nil)
 		require.True(t, a.visited.IsEmpty())
 		require.True(t, a.tasks.IsEmpty())
 	})
 	t.Run("skips links with url parse error", func(t *testing.T) {
 		a := newAudit()
 		startURL, _ := url.Parse(testConfig.StartURL)
-		startTask := &task{u: startURL, depth: 0}
-		a.processLinks(startTask, []string{"https://a b.com"})
+		a.processLinks(context.Background(), startURL, 0, []string{"https://a b.com"}, nil)
 		require.True(t, a.visited.IsEmpty())
 		require.True(t, a.tasks.IsEmpty())
 	})
@@ -369,10 +431,30 @@ func TestAudit_ProcessLinks(t *testing.T) {
 		require.NoError(t, err)
 		a.robotsData = robotsData
 		startURL, _ := url.Parse(testConfig.StartURL)
-		startTask := &task{u: startURL, depth: 0}
-		a.processLinks(startTask, []string{fmt.Sprintf("%v/forbidden", testConfig.StartURL)})
+		a.processLinks(context.Background(), startURL, 0, []string{fmt.Sprintf("%v/forbidden", testConfig.StartURL)}, nil)
 		require.True(t, a.visited.IsEmpty())
 		require.True(t, a.tasks.IsEmpty())
+		otherPage, _ := url.Parse("https://example.com/other")
+		a.processLinks(context.Background(), otherPage, 1, []string{"/forbidden"}, nil)
+		require.Equal(t, []DisallowedLink{
+			{URL: "https://example.com/forbidden", Referrers: []string{"https://example.com/", "https://example.com/other"}},
+		}, a.Disallowed())
+		require.Contains(t, a.Findings(), Finding{
+			Check:  CheckLinkedDisallowed,
+			URL:    "https://example.com/forbidden",
+			Detail: "disallowed by robots.txt, linked from https://example.com/, https://example.com/other",
+		})
+	})
+	t.Run("follows robots.txt disallowed links on allowlisted paths", func(t *testing.T) {
+		a := newAudit()
+		a.robotsAllow = []string{"/forbidden/owned"}
+		robotsData, err := robotstxt.FromBytes([]byte("User-Agent: *\nDisallow: /forbidden"))
+		require.NoError(t, err)
+		a.robotsData = robotsData
+		startURL, _ := url.Parse(testConfig.StartURL)
+		a.processLinks(context.Background(), startURL, 0, []string{"/forbidden/owned/page", "/forbidden/other"}, nil)
+		require.True(t, a.visited.Contains("https://example.com/forbidden/owned/page"))
+		require.False(t, a.visited.Contains("https://example.com/forbidden/other"))
 	})
 }
 
@@ -389,7 +471,7 @@ func TestAudit_ExportGraph(t *testing.T) {
 	a.siteGraph.AddEdge("https://example.com", "https://example.com/something", 1)
 	t.Run("export without error", func(t *testing.T) {
 		exported := false
-		exportFunction := func(g *graph.Graph[string]) error {
+		exportFunction := func(g *graph.Graph[string], nodes map[string]Node) error {
 			exported = true
 			return nil
 		}
@@ -398,7 +480,7 @@ func TestAudit_ExportGraph(t *testing.T) {
 	})
 	t.Run("export with error", func(t *testing.T) {
 		var exportErr error
-		exportFunction := func(g *graph.Graph[string]) error {
+		exportFunction := func(g *graph.Graph[string], nodes map[string]Node) error {
 			exportErr = errors.New("export error")
 			return exportErr
 		}
@@ -406,3 +488,487 @@ func TestAudit_ExportGraph(t *testing.T) {
 		require.Error(t, exportErr)
 	})
 }
+
+func TestAudit_TaskLogging(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, pagesFetcher{"https://example.com": `<a href="/a">a</a>`}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	var logs bytes.Buffer
+	a.logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	require.NoError(t, a.Start(context.Background()))
+	fetching := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		record := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if record["msg"] == "Fetching" {
+			fetching[record["url"].(string)] = record
+		}
+	}
+	require.Len(t, fetching, 2)
+	record := fetching["https://example.com/a"]
+	require.Contains(t, record, "worker")
+	require.Equal(t, float64(1), record["depth"])
+	require.Equal(t, float64(1), record["attempt"])
+}
+
+func TestAudit_PolicyMaxPages(t *testing.T) {
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com": successResponse(""),
+		},
+	}
+	mockExtractor := &mockExtractor{values: []string{"/a", "/b", "/c"}}
+	policies, err := policy.New([]*policy.Policy{{Host: "example.com", MaxPages: 2}})
+	require.NoError(t, err)
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, mockFetcher, mockExtractor, WithPolicies(policies))
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Len(t, a.Pages(), 2)
+	require.Equal(t, 4, a.siteGraph.Len())
+}
+
+func TestAudit_BloomVisited(t *testing.T) {
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com":   successResponse(`<a href="/a">A</a><a href="/b">B</a>`),
+			"https://example.com/a": successResponse(`<a href="/b">B</a><a href="/">Home</a>`),
+		},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxDepth = 3
+	c.VisitedMode = VisitedBloom
+	c.BloomCapacity = 100
+	c.BloomFalsePositive = 0.001
+	a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	require.Len(t, a.Pages(), 3)
+	require.Equal(t, 3, a.Summary().Visited)
+	require.Equal(t, []string{"https://example.com/", "https://example.com/a", "https://example.com/b"}, a.Checkpoint().Visited)
+}
+
+func BenchmarkAudit_ProcessLinks(b *testing.B) {
+	c := testConfig
+	c.MaxDepth = 1
+	a, err := New(c, &mockFetcher{}, &mockExtractor{})
+	require.NoError(b, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	links := make([]string, 100)
+	for i := range links {
+		links[i] = fmt.Sprintf("/section/%d/page?ref=%d", i%10, i)
+	}
+	startURL, _ := url.Parse(testConfig.StartURL)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			a.processLinks(context.Background(), startURL, 0, links, nil)
+		}
+	})
+}
+
+func BenchmarkAudit_Crawl(b *testing.B) {
+	benchmarks := []struct {
+		name    string
+		workers int
+		site    *sitegen.Site
+	}{
+		{name: "fast site 1 worker", workers: 1, site: sitegen.New(sitegen.WithPages(500))},
+		{name: "fast site 10 workers", workers: 10, site: sitegen.New(sitegen.WithPages(500))},
+		{name: "slow site 10 workers", workers: 10, site: sitegen.New(sitegen.WithPages(200), sitegen.WithLatency(5*time.Millisecond))},
+		{name: "slow site 50 workers", workers: 50, site: sitegen.New(sitegen.WithPages(200), sitegen.WithLatency(5*time.Millisecond))},
+		{name: "failing site 10 workers", workers: 10, site: sitegen.New(sitegen.WithPages(500), sitegen.WithErrorRate(0.2))},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			server := httptest.NewServer(bm.site)
+			defer server.Close()
+			c := testConfig
+			c.StartURL = server.URL
+			c.RespectRobots = false
+			c.MaxWorkers = bm.workers
+			c.MaxDepth = 10
+			for b.Loop() {
+				httpFetcher := fetcher.NewHTTPFetcher("agent", fetcher.WithMaxConnsPerHost(bm.workers))
+				a, err := New(c, httpFetcher, extractor.NewLinkExtractor())
+				require.NoError(b, err)
+				a.logger = slog.New(slog.DiscardHandler)
+				require.NoError(b, a.Start(context.Background()))
+				// Pages below a failing page are unreachable
+				if bm.site.Errors() == 0 {
+					require.Len(b, a.Pages(), bm.site.Pages())
+				}
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "crawls/s")
+		})
+	}
+}
+
+type recordingPrefetcher struct {
+	hosts []string
+	mu    sync.Mutex
+}
+
+func (r *recordingPrefetcher) Prefetch(host string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts = append(r.hosts, host)
+}
+
+func TestAudit_Prefetcher(t *testing.T) {
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com": successResponse(""),
+		},
+	}
+	mockExtractor := &mockExtractor{values: []string{"/a", "https://www.example.com/b", "https://other.com/c"}}
+	prefetcher := &recordingPrefetcher{}
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxDepth = 1
+	a, err := New(c, mockFetcher, mockExtractor, WithPrefetcher(prefetcher))
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []string{"example.com", "www.example.com"}, prefetcher.hosts)
+}
+
+type cancellingExtractor struct {
+	cancel func()
+}
+
+func (c *cancellingExtractor) Extract(ctx context.Context, u *url.URL, body io.Reader) ([]string, error) {
+	c.cancel()
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestAudit_CancelMidPage(t *testing.T) {
+	t.Run("page being extracted is requeued", func(t *testing.T) {
+		mockFetcher := &mockFetcher{
+			responses: map[string]*http.Response{
+				"https://example.com": successResponse(""),
+			},
+		}
+		extractor := &cancellingExtractor{}
+		c := testConfig
+		c.RespectRobots = false
+		a, err := New(c, mockFetcher, extractor)
+		require.NoError(t, err)
+		extractor.cancel = a.Cancel
+		require.NoError(t, a.Start(context.Background()))
+		require.Equal(t, []CheckpointTask{{URL: "https://example.com", Depth: 0}}, a.Checkpoint().Frontier)
+	})
+	t.Run("links are not processed once cancelled", func(t *testing.T) {
+		a, err := New(testConfig, &mockFetcher{}, &mockExtractor{})
+		require.NoError(t, err)
+		startURL, _ := url.Parse(testConfig.StartURL)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = a.processLinks(ctx, startURL, 0, []string{"/a", "/b"}, nil)
+		require.True(t, errors.Is(err, context.Canceled))
+		require.True(t, a.visited.IsEmpty())
+		require.True(t, a.tasks.IsEmpty())
+	})
+}
+
+// meshFetcher serves pages that all link to many of the others through several spellings of
+// the same url, and counts how often each canonical url is fetched
+type meshFetcher struct {
+	pages   int
+	fetches map[string]int
+	mu      sync.Mutex
+}
+
+func (m *meshFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	m.mu.Lock()
+	m.fetches[normaliseURL(u)]++
+	m.mu.Unlock()
+	var body strings.Builder
+	i, _ := strconv.Atoi(strings.TrimPrefix(u.Path, "/page/"))
+	for k := range 10 {
+		target := (i*7 + k) % m.pages
+		fmt.Fprintf(&body, `<a href="/page/%d">a</a><a href="/page/%d/">b</a><a href="/page/%d?ref=%d#top">c</a>`, target, target, target, i)
+	}
+	body.WriteString(`<a href="/">home</a><a href="https://example.com">home</a>`)
+	return successResponse(body.String()), nil
+}
+
+func TestAudit_ConcurrentDeduplication(t *testing.T) {
+	fetcher := &meshFetcher{pages: 200, fetches: map[string]int{}}
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxWorkers = 50
+	c.MaxDepth = 100
+	c.StatsInterval = 0
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	for u, count := range fetcher.fetches {
+		require.Equal(t, 1, count, "%s fetched %d times", u, count)
+	}
+	require.Len(t, fetcher.fetches, fetcher.pages+1)
+	require.Len(t, a.Pages(), fetcher.pages+1)
+}
+
+type stubAuthenticator struct {
+	err   error
+	calls int
+}
+
+func (s *stubAuthenticator) Login(ctx context.Context) error {
+	s.calls++
+	return s.err
+}
+
+func TestAudit_Authenticator(t *testing.T) {
+	newFetcher := func() *mockFetcher {
+		return &mockFetcher{responses: map[string]*http.Response{"https://example.com": successResponse("")}}
+	}
+	c := testConfig
+	c.RespectRobots = false
+	t.Run("logs in before crawling", func(t *testing.T) {
+		auth := &stubAuthenticator{}
+		a, err := New(c, newFetcher(), &mockExtractor{}, WithAuthenticator(auth))
+		require.NoError(t, err)
+		require.NoError(t, a.Start(context.Background()))
+		require.Equal(t, 1, auth.calls)
+		require.Len(t, a.Pages(), 1)
+	})
+	t.Run("failed login stops the audit", func(t *testing.T) {
+		auth := &stubAuthenticator{err: errors.New("bad credentials")}
+		a, err := New(c, newFetcher(), &mockExtractor{}, WithAuthenticator(auth))
+		require.NoError(t, err)
+		require.Error(t, a.Start(context.Background()))
+		require.Empty(t, a.Pages())
+	})
+}
+
+func TestAudit_InternalHosts(t *testing.T) {
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com":                successResponse(`<a href="https://cdn.example.net/app.css">css</a><a href="https://other.com/">other</a>`),
+			"https://cdn.example.net/app.css":    successResponse(`<a href="https://example.com/from-cdn">back</a>`),
+			"https://example.com/from-cdn":       successResponse(""),
+			"https://other.com/":                 successResponse(""),
+			"https://cdn.example.net/robots.txt": successResponse(""),
+		},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxDepth = 3
+	c.InternalHosts = "cdn.example.net"
+	a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	urls := []string{}
+	for _, page := range a.Pages() {
+		urls = append(urls, page.URL)
+	}
+	require.Equal(t, []string{"https://cdn.example.net/app.css", "https://example.com/", "https://example.com/from-cdn"}, urls)
+}
+
+func TestNormaliseInternationalHosts(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "example.com", want: "example.com"},
+		{host: "Example.COM:8443", want: "example.com:8443"},
+		{host: "bücher.example", want: "xn--bcher-kva.example"},
+		{host: "BÜCHER.example:8080", want: "xn--bcher-kva.example:8080"},
+		{host: "xn--bcher-kva.example", want: "xn--bcher-kva.example"},
+		{host: "[::1]:80", want: "[::1]:80"},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			require.Equal(t, test.want, canonicalHost(test.host))
+		})
+	}
+	require.Equal(t, "xn--bcher-kva.example", normaliseHost("www.bücher.example"))
+	unicode, _ := CanonicalURL("https://bücher.example/a/")
+	punycode, _ := CanonicalURL("https://xn--bcher-kva.example/a")
+	require.Equal(t, punycode, unicode)
+}
+
+func TestAudit_InternationalHosts(t *testing.T) {
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://b%C3%BCcher.example":         successResponse(`<a href="https://xn--bcher-kva.example/">home</a><a href="https://www.xn--bcher-kva.example/a">a</a>`),
+			"https://www.xn--bcher-kva.example/a": successResponse(""),
+		},
+	}
+	c := testConfig
+	c.StartURL = "https://bücher.example"
+	c.RespectRobots = false
+	a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	urls := []string{}
+	for _, page := range a.Pages() {
+		urls = append(urls, page.URL)
+	}
+	require.Equal(t, []string{"https://www.xn--bcher-kva.example/a", "https://xn--bcher-kva.example/"}, urls)
+}
+
+func TestAudit_FragmentRoutes(t *testing.T) {
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com":        successResponse(`<a href="#/about">about</a><a href="#!/shop">shop</a><a href="#top">top</a>`),
+			"https://example.com#/about": successResponse(""),
+			"https://example.com#!/shop": successResponse(""),
+		},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.FragmentRoutes = "#/, #!"
+	a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	urls := []string{}
+	for _, page := range a.Pages() {
+		urls = append(urls, page.URL)
+	}
+	require.Equal(t, []string{"https://example.com/", "https://example.com/#!/shop", "https://example.com/#/about"}, urls)
+}
+
+func TestAudit_ExternalLinks(t *testing.T) {
+	newFetcher := func() *mockFetcher {
+		return &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com":      successResponse(`<a href="https://other.com/a?x=1#top">a</a><a href="/page">page</a>`),
+			"https://example.com/page": successResponse(`<a href="https://other.com/a?x=1">again</a><a href="http://third.net/">third</a>`),
+		}}
+	}
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Empty(t, a.ExternalLinks())
+
+	c.LinkRotFile = "links.json"
+	a, err = New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []ExternalLink{
+		{URL: "http://third.net/", Source: "https://example.com/page"},
+		{URL: "https://other.com/a?x=1", Source: "https://example.com/"},
+	}, a.ExternalLinks())
+}
+
+func TestAudit_RecordsEmbeds(t *testing.T) {
+	newFetcher := func() *mockFetcher {
+		return &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com": successResponse(`<a href="/a">a</a><iframe src="https://www.youtube.com/embed/abc"></iframe>`),
+		}}
+	}
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Nil(t, a.Pages()[0].Embeds)
+
+	c.CheckEmbeds = true
+	a, err = New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	pages := a.Pages()
+	require.Equal(t, "https://example.com/", pages[0].URL)
+	require.Equal(t, []string{"https://www.youtube.com/embed/abc"}, pages[0].Embeds)
+}
+
+type rewriterFunc func(u string) string
+
+func (f rewriterFunc) Rewrite(u string) string {
+	return f(u)
+}
+
+func TestAudit_Rewriter(t *testing.T) {
+	fetcher := &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":   successResponse(`<a href="http://example.com/a">a</a><a href="https://m.example.com/a">a</a><a href="https://m.example.com/b">b</a>`),
+		"https://example.com/a": successResponse(""),
+		"https://example.com/b": successResponse(""),
+	}}
+	c := testConfig
+	c.RespectRobots = false
+	rewrite := rewriterFunc(func(u string) string {
+		u = strings.Replace(u, "http://", "https://", 1)
+		return strings.Replace(u, "https://m.example.com", "https://example.com", 1)
+	})
+	a, err := New(c, fetcher, extractor.NewLinkExtractor(), WithRewriter(rewrite))
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	pages := []string{}
+	for _, page := range a.Pages() {
+		pages = append(pages, page.URL)
+	}
+	require.Equal(t, []string{"https://example.com/", "https://example.com/a", "https://example.com/b"}, pages)
+	neighbours, _ := a.siteGraph.Neighbours("https://example.com/")
+	require.Len(t, neighbours, 2)
+	require.Equal(t, "https://example.com/a", neighbours[0].Link)
+	require.Equal(t, 2, neighbours[0].Weight)
+}
+
+// pagesFetcher serves a fresh response for each request, so pages can be fetched again
+func TestAudit_MetaRefresh(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":     successResponse(`<meta http-equiv="refresh" content="0; url=/new">`),
+		"https://example.com/new": successResponse(""),
+	}}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Contains(t, a.Findings(), Finding{Check: CheckMetaRefresh, URL: "https://example.com/", Detail: "redirects to https://example.com/new after 0 seconds"})
+	pages := map[string]Page{}
+	for _, page := range a.Pages() {
+		pages[page.URL] = page
+	}
+	require.Equal(t, []string{"https://example.com/new"}, pages["https://example.com/"].Links)
+	require.Equal(t, http.StatusOK, pages["https://example.com/new"].StatusCode)
+}
+
+func TestAudit_LinkHeader(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, headerFetcher{
+		"https://example.com":   {"Link": {`</a>; rel="next"`}},
+		"https://example.com/a": {"Link": {`<https://example.com/>; rel="canonical", </>; rel="prev"`}},
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	pages := a.Pages()
+	require.Len(t, pages, 2)
+	require.Equal(t, "https://example.com/a", pages[1].URL)
+	require.Equal(t, "https://example.com/", a.nodes["https://example.com/a"].canonical)
+}
+
+func TestAudit_StatusCodeRules(t *testing.T) {
+	policies, err := policy.New([]*policy.Policy{{Host: "example.com", StatusCodes: []policy.StatusRule{
+		{Code: http.StatusUnauthorized, Path: "/account/*", Treat: policy.TreatExpected},
+		{Code: http.StatusNoContent, Treat: policy.TreatBroken},
+	}}})
+	require.NoError(t, err)
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":                successResponse(`<a href="/account/orders">orders</a><a href="/admin">admin</a><a href="/empty">empty</a>`),
+		"https://example.com/account/orders": buildResponse("", http.StatusUnauthorized),
+		"https://example.com/admin":          buildResponse("", http.StatusUnauthorized),
+		"https://example.com/empty":          buildResponse("", http.StatusNoContent),
+	}}, extractor.NewLinkExtractor(), WithPolicies(policies))
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []Finding{
+		{Check: CheckBrokenLink, URL: "https://example.com/admin", Detail: "status 401"},
+		{Check: CheckBrokenLink, URL: "https://example.com/empty", Detail: "status 204"},
+	}, a.Findings())
+	summary := a.Summary()
+	require.Equal(t, 2, summary.BrokenLinks)
+	require.Equal(t, 50, summary.Score)
+}
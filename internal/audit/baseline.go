@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/salsgithub/godst/set"
+)
+
+type baselineFile struct {
+	Findings []Finding `json:"findings"`
+}
+
+type Baseline struct {
+	keys *set.Set[string]
+}
+
+func LoadBaseline(path string) (*Baseline, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBaseline, err)
+	}
+	var file baselineFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBaseline, err)
+	}
+	baseline := &Baseline{keys: set.New[string]()}
+	for _, finding := range file.Findings {
+		baseline.keys.Add(finding.Key())
+	}
+	return baseline, nil
+}
+
+func WriteBaseline(path string, findings []Finding) error {
+	b, err := json.MarshalIndent(baselineFile{Findings: findings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func (b *Baseline) Contains(f Finding) bool {
+	return b.keys.Contains(f.Key())
+}
+
+func (b *Baseline) Len() int {
+	return b.keys.Len()
+}
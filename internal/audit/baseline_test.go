@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit_Baseline(t *testing.T) {
+	newAudit := func(c Config) *Audit {
+		return crawlTestAudit(t, c, &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com": successResponse(`<html><body><a href="/page-a">A</a></body></html>`),
+		}})
+	}
+	t.Run("findings report broken links", func(t *testing.T) {
+		a := newAudit(testConfig)
+		require.Equal(t, []Finding{{Check: CheckBrokenLink, URL: "https://example.com/page-a", Detail: "status 404"}}, a.Findings())
+	})
+	t.Run("update writes baseline and is then suppressed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "baseline.json")
+		c := testConfig
+		c.MaxBrokenLinks = 0
+		c.BaselineFile = path
+		c.UpdateBaseline = true
+		a := newAudit(c)
+		require.Error(t, a.CheckThresholds())
+		require.NoError(t, a.UpdateBaseline())
+		baseline, err := LoadBaseline(path)
+		require.NoError(t, err)
+		require.Equal(t, 1, baseline.Len())
+		c.UpdateBaseline = false
+		a = newAudit(c)
+		require.Empty(t, a.NewFindings())
+		require.NoError(t, a.CheckThresholds())
+	})
+	t.Run("update is a no-op when not configured", func(t *testing.T) {
+		a := newAudit(testConfig)
+		require.NoError(t, a.UpdateBaseline())
+	})
+	t.Run("invalid baseline contents", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "baseline.json")
+		require.NoError(t, os.WriteFile(path, []byte("not json"), 0644))
+		_, err := LoadBaseline(path)
+		require.True(t, errors.Is(err, ErrInvalidBaseline))
+	})
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"fmt"
+	"io"
+)
+
+// boundedReader fails with ErrBodyTooLarge once more than limit bytes have been read
+type boundedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func newBoundedReader(r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+	// One byte past the limit distinguishes an oversized body from one of exactly limit bytes
+	return &boundedReader{r: io.LimitReader(r, limit+1), limit: limit}
+}
+
+func (b *boundedReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n - int(b.read-b.limit), fmt.Errorf("%w: over %d bytes", ErrBodyTooLarge, b.limit)
+	}
+	return n, err
+}
+
+// maxDrainBytes bounds how much of an unread body is discarded to let its connection be reused
+const maxDrainBytes = 64 << 10
+
+func closeBody(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+	body.Close()
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_MaxBodyBytes(t *testing.T) {
+	page := `<a href="/a">A</a>` + strings.Repeat("x", 100) + `<a href="/b">B</a>`
+	t.Run("bounded reader", func(t *testing.T) {
+		b, err := io.ReadAll(newBoundedReader(strings.NewReader("12345"), 5))
+		require.NoError(t, err)
+		require.Equal(t, "12345", string(b))
+		b, err = io.ReadAll(newBoundedReader(strings.NewReader("123456"), 5))
+		require.True(t, errors.Is(err, ErrBodyTooLarge))
+		require.Equal(t, "12345", string(b))
+	})
+	t.Run("oversized pages are abandoned", func(t *testing.T) {
+		mockFetcher := &mockFetcher{
+			responses: map[string]*http.Response{"https://example.com": successResponse(page)},
+		}
+		c := testConfig
+		c.RespectRobots = false
+		c.MaxBodyBytes = 50
+		a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		a.logger = slog.New(slog.DiscardHandler)
+		require.NoError(t, a.Start(context.Background()))
+		require.Len(t, a.Pages(), 1)
+		require.Equal(t, []Finding{{Check: CheckBodyTooLarge, URL: "https://example.com/", Detail: "response body too large: over 50 bytes"}}, a.Findings())
+	})
+	t.Run("unlimited", func(t *testing.T) {
+		mockFetcher := &mockFetcher{
+			responses: map[string]*http.Response{"https://example.com": successResponse(page)},
+		}
+		c := testConfig
+		c.RespectRobots = false
+		a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		a.logger = slog.New(slog.DiscardHandler)
+		require.NoError(t, a.Start(context.Background()))
+		require.Len(t, a.Pages(), 3)
+	})
+}
+
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestAudit_ClosesBodies(t *testing.T) {
+	bodies := map[string]*trackedBody{
+		"https://example.com":   {Reader: strings.NewReader(`<a href="/a">A</a>`)},
+		"https://example.com/a": {Reader: strings.NewReader("not found")},
+	}
+	responses := map[string]*http.Response{
+		"https://example.com":   {StatusCode: http.StatusOK, Body: bodies["https://example.com"]},
+		"https://example.com/a": {StatusCode: http.StatusNotFound, Body: bodies["https://example.com/a"]},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, &mockFetcher{responses: responses}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	for u, body := range bodies {
+		require.True(t, body.closed, u)
+		rest, _ := io.ReadAll(body.Reader)
+		require.Empty(t, rest, "body of %s was not drained", u)
+	}
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// BrokenLink is a url that returned a 4xx or 5xx status, as treated by the crawl policies, along
+// with the pages linking to it
+type BrokenLink struct {
+	URL        string   `json:"url"`
+	StatusCode int      `json:"status_code"`
+	Referrers  []string `json:"referrers"`
+}
+
+// BrokenLinks returns every broken url crawled with its referrers, sorted by url. Seeds and the
+// start url may have no referrers.
+func (a *Audit) BrokenLinks() []BrokenLink {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	links := []BrokenLink{}
+	index := map[string]int{}
+	for u, code := range a.statuses {
+		if a.broken(u, code) {
+			index[u] = len(links)
+			links = append(links, BrokenLink{URL: u, StatusCode: code, Referrers: []string{}})
+		}
+	}
+	if len(links) == 0 {
+		return links
+	}
+	for _, node := range a.siteGraph.Nodes() {
+		neighbours, _ := a.siteGraph.Neighbours(node)
+		for _, neighbour := range neighbours {
+			if i, ok := index[neighbour.Link]; ok {
+				links[i].Referrers = append(links[i].Referrers, node)
+			}
+		}
+	}
+	slices.SortFunc(links, func(x, y BrokenLink) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	return links
+}
+
+// WriteBrokenLinks writes the report to path, as CSV with a row for each referrer when path ends in
+// .csv and as JSON otherwise
+func WriteBrokenLinks(path string, links []BrokenLink) error {
+	if !strings.EqualFold(filepath.Ext(path), ".csv") {
+		b, err := json.MarshalIndent(links, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, b, 0644)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"url", "status_code", "referrer"})
+	for _, link := range links {
+		code := strconv.Itoa(link.StatusCode)
+		if len(link.Referrers) == 0 {
+			w.Write([]string{link.URL, code, ""})
+		}
+		for _, referrer := range link.Referrers {
+			w.Write([]string{link.URL, code, referrer})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_BrokenLinks(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxDepth = 3
+	fetcher := &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":   successResponse(`<a href="/a">a</a><a href="/b">b</a>`),
+		"https://example.com/b": successResponse(`<a href="/a">a</a><a href="/c">c</a>`),
+		"https://example.com/c": buildResponse("", http.StatusInternalServerError),
+	}}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	links := a.BrokenLinks()
+	require.Equal(t, []BrokenLink{
+		{URL: "https://example.com/a", StatusCode: http.StatusNotFound, Referrers: []string{"https://example.com/", "https://example.com/b"}},
+		{URL: "https://example.com/c", StatusCode: http.StatusInternalServerError, Referrers: []string{"https://example.com/b"}},
+	}, links)
+	t.Run("writes json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "broken.json")
+		require.NoError(t, WriteBrokenLinks(path, links))
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var got []BrokenLink
+		require.NoError(t, json.Unmarshal(b, &got))
+		require.Equal(t, links, got)
+	})
+	t.Run("writes csv with a row per referrer", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "broken.csv")
+		require.NoError(t, WriteBrokenLinks(path, append(links, BrokenLink{URL: "https://example.com/seed", StatusCode: 410, Referrers: []string{}})))
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, `url,status_code,referrer
+https://example.com/a,404,https://example.com/
+https://example.com/a,404,https://example.com/b
+https://example.com/c,500,https://example.com/b
+https://example.com/seed,410,
+`, string(b))
+	})
+}
@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CheckUncacheable     = "uncacheable"
+	CheckCacheConflict   = "cache-conflict"
+	CheckShortAssetCache = "short-asset-cache"
+)
+
+// minAssetMaxAge is the shortest lifetime expected of assets, which are usually fingerprinted
+const minAssetMaxAge = 7 * 24 * time.Hour
+
+// recordedHeaders are kept for every response so checks can inspect them
+var recordedHeaders = []string{"Content-Type", "Cache-Control", "Expires", "ETag", "Last-Modified", "Date"}
+
+// capturedHeaders adds the headers listed in AUDIT_CAPTURE_HEADERS, and those needed by enabled
+// checks, to recordedHeaders
+func capturedHeaders(config Config) []string {
+	headers := slices.Clone(recordedHeaders)
+	extra := splitList(config.CaptureHeaders)
+	if config.CheckCSP {
+		extra = append(extra, cspHeader)
+	}
+	if config.Environment != "" {
+		extra = append(extra, authenticateHeader)
+	}
+	for _, key := range extra {
+		if key = http.CanonicalHeaderKey(key); !slices.Contains(headers, key) {
+			headers = append(headers, key)
+		}
+	}
+	return headers
+}
+
+// CachingCheck reports pages that cannot be cached, caching directives that contradict each
+// other, and assets that are not cached for long
+type CachingCheck struct{}
+
+func (CachingCheck) Name() string {
+	return "caching"
+}
+
+func (CachingCheck) Run(ctx context.Context, pages []Page) ([]Finding, error) {
+	findings := []Finding{}
+	for _, page := range pages {
+		if page.StatusCode < 200 || page.StatusCode > 299 {
+			continue
+		}
+		findings = append(findings, cachingFindings(page)...)
+	}
+	return findings, nil
+}
+
+func cachingFindings(page Page) []Finding {
+	header := http.Header{}
+	for key, value := range page.Headers {
+		header.Set(key, value)
+	}
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	findings := []Finding{}
+	if conflicts := cacheConflicts(directives, header); len(conflicts) > 0 {
+		findings = append(findings, Finding{Check: CheckCacheConflict, URL: page.URL, Detail: strings.Join(conflicts, ", ")})
+	}
+	_, noStore := directives["no-store"]
+	validators := header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+	switch {
+	case noStore:
+		findings = append(findings, Finding{Check: CheckUncacheable, URL: page.URL, Detail: "Cache-Control: no-store"})
+		return findings
+	case header.Get("Cache-Control") == "" && header.Get("Expires") == "" && !validators:
+		findings = append(findings, Finding{Check: CheckUncacheable, URL: page.URL, Detail: "no Cache-Control, Expires, ETag or Last-Modified headers"})
+		return findings
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "text/html" {
+		return findings
+	}
+	if _, immutable := directives["immutable"]; immutable {
+		return findings
+	}
+	if lifetime, ok := freshnessLifetime(directives, header); !ok || lifetime < minAssetMaxAge {
+		findings = append(findings, Finding{
+			Check:  CheckShortAssetCache,
+			URL:    page.URL,
+			Detail: fmt.Sprintf("%s cached for %s, expected at least %s", mediaType, lifetime, minAssetMaxAge),
+		})
+	}
+	return findings
+}
+
+// parseCacheControl maps lower case directive names to their values, which are empty for flags
+func parseCacheControl(value string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(value, ",") {
+		name, argument, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		directives[strings.ToLower(name)] = strings.Trim(argument, `"`)
+	}
+	return directives
+}
+
+func cacheConflicts(directives map[string]string, header http.Header) []string {
+	conflicts := []string{}
+	_, noStore := directives["no-store"]
+	_, noCache := directives["no-cache"]
+	_, public := directives["public"]
+	_, private := directives["private"]
+	_, immutable := directives["immutable"]
+	maxAge, hasMaxAge := directives["max-age"]
+	if noStore && hasMaxAge && maxAge != "0" {
+		conflicts = append(conflicts, "no-store with max-age="+maxAge)
+	}
+	if noStore && public {
+		conflicts = append(conflicts, "no-store with public")
+	}
+	if public && private {
+		conflicts = append(conflicts, "public with private")
+	}
+	if immutable && (noCache || noStore) {
+		conflicts = append(conflicts, "immutable with no-cache or no-store")
+	}
+	if hasMaxAge {
+		if _, err := strconv.Atoi(maxAge); err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("invalid max-age %q", maxAge))
+		}
+	}
+	if expires := header.Get("Expires"); expires != "" && expires != "0" && expires != "-1" {
+		if _, err := http.ParseTime(expires); err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("invalid Expires %q", expires))
+		}
+	}
+	return conflicts
+}
+
+// freshnessLifetime uses max-age when set, as caches do, and otherwise Expires relative to Date
+func freshnessLifetime(directives map[string]string, header http.Header) (time.Duration, bool) {
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		return time.Duration(seconds) * time.Second, err == nil
+	}
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		return 0, false
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+	return expires.Sub(date), true
+}
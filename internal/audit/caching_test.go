@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    []string
+	}{
+		{name: "cacheable html", headers: map[string]string{"Content-Type": "text/html", "Cache-Control": "max-age=60"}, want: nil},
+		{name: "html with only validators", headers: map[string]string{"Content-Type": "text/html", "ETag": `"abc"`}, want: nil},
+		{name: "no headers", headers: map[string]string{"Content-Type": "text/html"}, want: []string{CheckUncacheable}},
+		{name: "no-store", headers: map[string]string{"Content-Type": "image/png", "Cache-Control": "no-store"}, want: []string{CheckUncacheable}},
+		{name: "conflicting directives", headers: map[string]string{"Content-Type": "text/html", "Cache-Control": "public, private, max-age=60"}, want: []string{CheckCacheConflict}},
+		{name: "no-store with max-age", headers: map[string]string{"Content-Type": "text/html", "Cache-Control": "no-store, max-age=600"}, want: []string{CheckCacheConflict, CheckUncacheable}},
+		{name: "invalid expires", headers: map[string]string{"Content-Type": "text/html", "Expires": "tomorrow"}, want: []string{CheckCacheConflict}},
+		{name: "long-lived asset", headers: map[string]string{"Content-Type": "text/css", "Cache-Control": "public, max-age=31536000"}, want: nil},
+		{name: "immutable asset", headers: map[string]string{"Content-Type": "text/css", "Cache-Control": "max-age=60, immutable"}, want: nil},
+		{name: "short-lived asset", headers: map[string]string{"Content-Type": "application/javascript", "Cache-Control": "max-age=3600"}, want: []string{CheckShortAssetCache}},
+		{name: "asset with only validators", headers: map[string]string{"Content-Type": "image/png", "ETag": `"abc"`}, want: []string{CheckShortAssetCache}},
+		{
+			name:    "asset expiring in a year",
+			headers: map[string]string{"Content-Type": "image/png", "Date": "Mon, 06 Jan 2025 00:00:00 GMT", "Expires": "Tue, 06 Jan 2026 00:00:00 GMT"},
+			want:    nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			findings, err := CachingCheck{}.Run(context.Background(), []Page{
+				{URL: "https://example.com/", StatusCode: http.StatusOK, Headers: test.headers},
+				{URL: "https://example.com/missing", StatusCode: http.StatusNotFound},
+			})
+			require.NoError(t, err)
+			var checks []string
+			for _, f := range findings {
+				require.Equal(t, "https://example.com/", f.URL)
+				checks = append(checks, f.Check)
+			}
+			require.Equal(t, test.want, checks)
+		})
+	}
+}
+
+func TestAudit_RecordsHeaders(t *testing.T) {
+	response := successResponse("")
+	response.Header = http.Header{"Cache-Control": []string{"no-store"}, "Set-Cookie": []string{"a=b"}}
+	mockFetcher := &mockFetcher{responses: map[string]*http.Response{"https://example.com": response}}
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, mockFetcher, &mockExtractor{})
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, map[string]string{"Cache-Control": "no-store"}, a.Pages()[0].Headers)
+}
+
+func TestAudit_CaptureHeaders(t *testing.T) {
+	response := successResponse("")
+	response.Header = http.Header{"Cache-Control": []string{"no-store"}, "Server": []string{"nginx"}, "X-Cache": []string{"HIT"}, "X-Other": []string{"1"}}
+	fetcher := &mockFetcher{responses: map[string]*http.Response{"https://example.com": response}}
+	c := testConfig
+	c.RespectRobots = false
+	c.CaptureHeaders = "server, x-cache"
+	a, err := New(c, fetcher, &mockExtractor{})
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	want := map[string]string{"Cache-Control": "no-store", "Server": "nginx", "X-Cache": "HIT"}
+	require.Equal(t, want, a.Pages()[0].Headers)
+	require.Equal(t, want, a.Nodes()["https://example.com/"].Headers)
+	resumed, err := Resume(a.Checkpoint(), &mockFetcher{}, &mockExtractor{})
+	require.NoError(t, err)
+	require.Equal(t, want, resumed.Pages()[0].Headers)
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+)
+
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// CheckSettings tunes one check. Scope and Exclude are url path patterns written as in robots.txt,
+// where * matches anything and a trailing $ anchors the end, so /blog/ covers the whole blog.
+type CheckSettings struct {
+	// Enabled is true when omitted
+	Enabled  *bool    `json:"enabled,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Scope    []string `json:"scope,omitempty"`
+	Exclude  []string `json:"exclude,omitempty"`
+	// Profiles limits the check to audits run with one of these AUDIT_PROFILE values
+	Profiles []string `json:"profiles,omitempty"`
+	// MaxFindings fails the thresholds when more new findings of the check are reported
+	MaxFindings *int `json:"max_findings,omitempty"`
+
+	scope   []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// CheckConfig holds the settings of each check, keyed by the check reported in its findings or the
+// name of a plugin or built in check
+type CheckConfig struct {
+	Checks map[string]*CheckSettings `json:"checks"`
+
+	profile string
+}
+
+func LoadCheckConfig(path string) (*CheckConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidChecksFile, err)
+	}
+	var c CheckConfig
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidChecksFile, err)
+	}
+	for name, settings := range c.Checks {
+		if settings == nil {
+			return nil, fmt.Errorf("%w: check %q has no settings", ErrInvalidChecksFile, name)
+		}
+		switch settings.Severity {
+		case "", SeverityInfo, SeverityWarning, SeverityError:
+		default:
+			return nil, fmt.Errorf("%w: check %q severity %q must be info, warning or error", ErrInvalidChecksFile, name, settings.Severity)
+		}
+		if settings.MaxFindings != nil && *settings.MaxFindings < 0 {
+			return nil, fmt.Errorf("%w: check %q max_findings must be zero or more", ErrInvalidChecksFile, name)
+		}
+		for _, pattern := range settings.Scope {
+			settings.scope = append(settings.scope, robotsPattern(pattern))
+		}
+		for _, pattern := range settings.Exclude {
+			settings.exclude = append(settings.exclude, robotsPattern(pattern))
+		}
+	}
+	return &c, nil
+}
+
+// Enabled reports whether a check, by the name of its findings or of the check itself, is on for
+// the profile of the audit
+func (c *CheckConfig) Enabled(check string) bool {
+	if c == nil {
+		return true
+	}
+	settings, ok := c.Checks[check]
+	if !ok {
+		return true
+	}
+	if len(settings.Profiles) > 0 && !slices.Contains(settings.Profiles, c.profile) {
+		return false
+	}
+	return settings.Enabled == nil || *settings.Enabled
+}
+
+// Apply drops the findings of disabled checks and those outside a check's scope, and sets the
+// severities configured
+func (c *CheckConfig) Apply(findings []Finding) []Finding {
+	if c == nil {
+		return findings
+	}
+	kept := findings[:0]
+	for _, f := range findings {
+		settings, ok := c.Checks[f.Check]
+		if !ok {
+			kept = append(kept, f)
+			continue
+		}
+		if !c.Enabled(f.Check) || !settings.inScope(f.URL) {
+			continue
+		}
+		if settings.Severity != "" {
+			f.Severity = settings.Severity
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// exceeded returns an error for each check reporting more findings than its max_findings
+func (c *CheckConfig) exceeded(findings []Finding) []error {
+	if c == nil {
+		return nil
+	}
+	counts := map[string]int{}
+	for _, f := range findings {
+		counts[f.Check]++
+	}
+	errs := []error{}
+	for _, check := range slices.Sorted(maps.Keys(c.Checks)) {
+		settings := c.Checks[check]
+		if settings.MaxFindings != nil && counts[check] > *settings.MaxFindings {
+			errs = append(errs, fmt.Errorf("%w: %d %s findings exceeds maximum of %d", ErrThresholdExceeded, counts[check], check, *settings.MaxFindings))
+		}
+	}
+	return errs
+}
+
+// inScope matches the path and query of u, or u itself when it has no path such as a mailto: link
+func (s *CheckSettings) inScope(u string) bool {
+	target := u
+	if parsed, err := url.Parse(u); err == nil && parsed.Opaque == "" {
+		target = parsed.RequestURI()
+	}
+	matches := func(patterns []*regexp.Regexp) bool {
+		return slices.ContainsFunc(patterns, func(p *regexp.Regexp) bool { return p.MatchString(target) })
+	}
+	if len(s.scope) > 0 && !matches(s.scope) {
+		return false
+	}
+	return !matches(s.exclude)
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_CheckConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"checks": {
+		"broken-link": {"severity": "error", "exclude": ["/legacy/"], "max_findings": 0},
+		"meta-refresh": {"enabled": false},
+		"mock": {"enabled": false}
+	}}`), 0644))
+	c := testConfig
+	c.RespectRobots = false
+	c.ChecksFile = path
+	a, err := New(c, pagesFetcher{
+		"https://example.com": `<meta http-equiv="refresh" content="5; url=/b"><a href="/legacy/a">a</a>`,
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.NoError(t, a.RunChecks(context.Background(), &mockCheck{findings: []Finding{{Check: "mock", URL: "https://example.com/"}}}))
+	require.Equal(t, []Finding{
+		{Check: CheckBrokenLink, URL: "https://example.com/b", Detail: "status 404", Severity: SeverityError},
+	}, a.Findings())
+	err = a.CheckThresholds()
+	require.True(t, errors.Is(err, ErrThresholdExceeded))
+	require.Contains(t, err.Error(), "1 broken-link findings exceeds maximum of 0")
+
+	for _, invalid := range []string{`{"checks": {"x": {"severity": "fatal"}}}`, `{"checks": {"x": {"max_findings": -1}}}`, `{"checks": {"x": null}}`} {
+		require.NoError(t, os.WriteFile(path, []byte(invalid), 0644))
+		_, err := LoadCheckConfig(path)
+		require.True(t, errors.Is(err, ErrInvalidChecksFile), invalid)
+	}
+}
+
+func TestAudit_CheckConfigProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"checks": {
+		"mock": {"profiles": ["nightly"]},
+		"meta-refresh": {"profiles": ["nightly", "release"], "enabled": false}
+	}}`), 0644))
+	for _, test := range []struct {
+		profile string
+		want    []string
+	}{
+		{profile: "", want: []string{CheckBrokenLink}},
+		{profile: "release", want: []string{CheckBrokenLink}},
+		{profile: "nightly", want: []string{CheckBrokenLink, "mock"}},
+	} {
+		t.Run("profile "+test.profile, func(t *testing.T) {
+			c := testConfig
+			c.RespectRobots = false
+			c.ChecksFile = path
+			c.Profile = test.profile
+			a, err := New(c, pagesFetcher{
+				"https://example.com": `<meta http-equiv="refresh" content="5; url=/b">`,
+			}, extractor.NewLinkExtractor())
+			require.NoError(t, err)
+			require.NoError(t, a.Start(context.Background()))
+			check := &mockCheck{findings: []Finding{{Check: "mock", URL: "https://example.com/"}}}
+			require.NoError(t, a.RunChecks(context.Background(), check))
+			checks := []string{}
+			for _, f := range a.Findings() {
+				checks = append(checks, f.Check)
+			}
+			slices.Sort(checks)
+			require.Equal(t, test.want, checks)
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+type CheckpointTask struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Attempts int    `json:"attempts,omitempty"`
+}
+
+type CheckpointEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// CheckpointOutbound is an external domain with every url and page linking to it, which
+// OutboundDomain only counts
+type CheckpointOutbound struct {
+	Domain   string   `json:"domain"`
+	Links    int      `json:"links"`
+	URLs     []string `json:"urls"`
+	Pages    []string `json:"pages"`
+	Examples []string `json:"examples"`
+}
+
+// CheckpointSample is a search or faceted url fetched for AUDIT_CHECK_INDEXABILITY
+type CheckpointSample struct {
+	Check  string `json:"check"`
+	URL    string `json:"url"`
+	Detail string `json:"detail"`
+}
+
+// Checkpoint holds the state needed to continue an interrupted crawl
+type Checkpoint struct {
+	Config      Config           `json:"config"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Frontier    []CheckpointTask `json:"frontier"`
+	Visited     []string         `json:"visited"`
+	Edges       []CheckpointEdge `json:"edges"`
+	Statuses    map[string]int   `json:"statuses"`
+	Nodes       []Node           `json:"nodes,omitempty"`
+	FetchErrors int              `json:"fetch_errors"`
+	Findings    []Finding        `json:"findings,omitempty"`
+	Disallowed  []DisallowedLink `json:"disallowed,omitempty"`
+	Throttles   []ThrottleEvent  `json:"throttles,omitempty"`
+
+	// HostPages counts the pages crawled on each host towards the policies' max_pages
+	HostPages    map[string]int `json:"host_pages,omitempty"`
+	TrapPatterns map[string]int `json:"trap_patterns,omitempty"`
+	// DepthQuotas and SectionQuotas count the urls crawled towards each quota
+	DepthQuotas   map[int]int          `json:"depth_quotas,omitempty"`
+	SectionQuotas map[string]int       `json:"section_quotas,omitempty"`
+	ExternalLinks []ExternalLink       `json:"external_links,omitempty"`
+	Contacts      []ContactLink        `json:"contacts,omitempty"`
+	Outbound      []CheckpointOutbound `json:"outbound,omitempty"`
+	Redirects     []Redirect           `json:"redirects,omitempty"`
+
+	// Embeds, ThirdParty and PageAssets are what each page loads, kept for the checks run once the
+	// crawl finishes
+	Embeds           map[string][]string   `json:"embeds,omitempty"`
+	ThirdParty       map[string][]Resource `json:"third_party,omitempty"`
+	PageAssets       map[string][]string   `json:"page_assets,omitempty"`
+	ReferencedAssets []string              `json:"referenced_assets,omitempty"`
+	ParamSamples     map[string][]string   `json:"param_samples,omitempty"`
+	IndexSamples     []CheckpointSample    `json:"index_samples,omitempty"`
+}
+
+// cloneValues copies m along with its slices, which the crawl may still append to
+func cloneValues[V any](m map[string][]V) map[string][]V {
+	if len(m) == 0 {
+		return nil
+	}
+	c := make(map[string][]V, len(m))
+	for k, v := range m {
+		c[k] = slices.Clone(v)
+	}
+	return c
+}
+
+// visitedValues must be called with a.mu held. A bloom filter cannot be enumerated, so the urls
+// discovered through links stand in for it along with the start url.
+func (a *Audit) visitedValues() []string {
+	if exact, ok := a.visited.(interface{ Values() []string }); ok {
+		return exact.Values()
+	}
+	values := append(a.siteGraph.Nodes(), a.canonicalURL(a.startURL))
+	return slices.Compact(slices.Sorted(slices.Values(values)))
+}
+
+func (a *Audit) Checkpoint() *Checkpoint {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := &Checkpoint{
+		Config:      a.config,
+		CreatedAt:   time.Now().UTC(),
+		Frontier:    []CheckpointTask{},
+		Visited:     a.visitedValues(),
+		Edges:       []CheckpointEdge{},
+		Statuses:    make(map[string]int, len(a.statuses)),
+		FetchErrors: a.fetchErrs,
+		Findings:    slices.Clone(a.checkFindings),
+		Disallowed:  a.disallowedLinks(),
+		Throttles:   slices.Clone(a.throttles),
+
+		HostPages:    maps.Clone(a.hostPages),
+		TrapPatterns: maps.Clone(a.trapPatterns),
+		Contacts:     a.contactLinks(),
+
+		Embeds:           cloneValues(a.embeds),
+		ThirdParty:       cloneValues(a.thirdParty),
+		PageAssets:       cloneValues(a.pageAssets),
+		ReferencedAssets: slices.Sorted(maps.Keys(a.referencedAssets)),
+		ParamSamples:     cloneValues(a.paramSamples),
+	}
+	for _, sample := range a.indexSamples {
+		c.IndexSamples = append(c.IndexSamples, CheckpointSample{Check: sample.check, URL: sample.url, Detail: sample.detail})
+	}
+	if a.quotas != nil {
+		c.DepthQuotas = maps.Clone(a.quotas.depthCounts)
+		c.SectionQuotas = maps.Clone(a.quotas.sectionCounts)
+	}
+	for u, source := range a.externalLinks {
+		c.ExternalLinks = append(c.ExternalLinks, ExternalLink{URL: u, Source: source})
+	}
+	slices.SortFunc(c.ExternalLinks, func(x, y ExternalLink) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	for _, domain := range slices.Sorted(maps.Keys(a.outbound)) {
+		d := a.outbound[domain]
+		c.Outbound = append(c.Outbound, CheckpointOutbound{
+			Domain:   domain,
+			Links:    d.links,
+			URLs:     slices.Sorted(maps.Keys(d.urls)),
+			Pages:    slices.Sorted(maps.Keys(d.pages)),
+			Examples: slices.Clone(d.examples),
+		})
+	}
+	for _, u := range slices.Sorted(maps.Keys(a.redirects)) {
+		c.Redirects = append(c.Redirects, *a.redirects[u])
+	}
+	// The queue has no iterator so it is drained and refilled in order
+	for range a.tasks.Len() {
+		t, _ := a.tasks.Dequeue()
+		c.Frontier = append(c.Frontier, CheckpointTask{URL: t.rawURL, Depth: t.depth, Attempts: t.attempts})
+		a.tasks.Enqueue(t)
+	}
+	slices.Sort(c.Visited)
+	for _, node := range a.siteGraph.Nodes() {
+		neighbours, _ := a.siteGraph.Neighbours(node)
+		for _, neighbour := range neighbours {
+			c.Edges = append(c.Edges, CheckpointEdge{Source: node, Target: neighbour.Link, Weight: neighbour.Weight})
+		}
+	}
+	for u, code := range a.statuses {
+		c.Statuses[u] = code
+	}
+	for _, n := range a.nodeSnapshot() {
+		c.Nodes = append(c.Nodes, n)
+	}
+	slices.SortFunc(c.Nodes, func(x, y Node) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	return c
+}
+
+// Resume creates an audit that continues crawling from the checkpoint with its original config
+func Resume(c *Checkpoint, fetcher Fetcher, extractor Extractor, options ...Option) (*Audit, error) {
+	a, err := New(c.Config, fetcher, extractor, options...)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range c.Frontier {
+		u, err := url.Parse(t.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: frontier url %q: %w", ErrInvalidCheckpoint, t.URL, err)
+		}
+		a.tasks.Enqueue(&task{rawURL: intern(u.String()), depth: t.Depth, attempts: t.Attempts})
+	}
+	a.visited.Add(c.Visited...)
+	for _, e := range c.Edges {
+		a.siteGraph.AddEdge(intern(e.Source), intern(e.Target), e.Weight)
+	}
+	for u, code := range c.Statuses {
+		a.statuses[intern(u)] = code
+	}
+	for _, n := range c.Nodes {
+		a.nodes[intern(n.URL)] = &nodeInfo{
+			depth:         n.Depth,
+			title:         n.Title,
+			lang:          n.Lang,
+			canonical:     n.Canonical,
+			inSitemap:     n.InSitemap,
+			fetchTime:     time.Duration(n.FetchMillis) * time.Millisecond,
+			contentLength: n.ContentLength,
+			contentHash:   n.ContentHash,
+			alternates:    n.Alternates,
+		}
+		if n.Failure != "" {
+			a.failures[intern(n.URL)] = n.Failure
+		}
+		if len(n.Headers) > 0 {
+			a.headers[intern(n.URL)] = n.Headers
+		}
+	}
+	a.fetchErrs = c.FetchErrors
+	a.checkFindings = slices.Clone(c.Findings)
+	a.throttles = slices.Clone(c.Throttles)
+	for _, link := range c.Disallowed {
+		for _, referrer := range link.Referrers {
+			a.recordDisallowed(intern(link.URL), intern(referrer))
+		}
+	}
+	maps.Copy(a.hostPages, c.HostPages)
+	maps.Copy(a.trapPatterns, c.TrapPatterns)
+	if a.quotas != nil {
+		maps.Copy(a.quotas.depthCounts, c.DepthQuotas)
+		maps.Copy(a.quotas.sectionCounts, c.SectionQuotas)
+	}
+	for _, link := range c.ExternalLinks {
+		a.externalLinks[intern(link.URL)] = intern(link.Source)
+	}
+	for _, contact := range c.Contacts {
+		u, err := url.Parse(contact.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: contact %q: %w", ErrInvalidCheckpoint, contact.URL, err)
+		}
+		for _, page := range contact.Pages {
+			a.recordContact(u, intern(page))
+		}
+	}
+	for _, o := range c.Outbound {
+		d := &outboundDomain{links: o.Links, urls: map[string]struct{}{}, pages: map[string]struct{}{}, examples: o.Examples}
+		for _, u := range o.URLs {
+			d.urls[u] = struct{}{}
+		}
+		for _, page := range o.Pages {
+			d.pages[intern(page)] = struct{}{}
+		}
+		a.outbound[o.Domain] = d
+	}
+	for _, r := range c.Redirects {
+		a.redirects[intern(r.URL)] = &r
+	}
+	for u, embeds := range c.Embeds {
+		a.embeds[intern(u)] = embeds
+	}
+	for u, resources := range c.ThirdParty {
+		a.thirdParty[intern(u)] = resources
+	}
+	for u, assets := range c.PageAssets {
+		a.pageAssets[intern(u)] = assets
+	}
+	for _, asset := range c.ReferencedAssets {
+		a.referencedAssets[intern(asset)] = struct{}{}
+	}
+	for param, samples := range c.ParamSamples {
+		a.paramSamples[param] = samples
+	}
+	for _, sample := range c.IndexSamples {
+		a.indexSamples = append(a.indexSamples, indexSample{check: sample.Check, url: intern(sample.URL), detail: sample.Detail})
+	}
+	a.enqueued = len(a.statuses) + a.fetchErrs + a.tasks.Len()
+	a.resumed = true
+	return a, nil
+}
+
+func SaveCheckpoint(path string, c *Checkpoint) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Checkpoints carry the full config, secrets included
+	return os.WriteFile(path, b, 0o600)
+}
+
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCheckpoint, err)
+	}
+	var c Checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCheckpoint, err)
+	}
+	return &c, nil
+}
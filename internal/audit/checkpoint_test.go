@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_CheckpointResume(t *testing.T) {
+	responses := func() map[string]*http.Response {
+		return map[string]*http.Response{
+			"https://example.com":        successResponse(`<html><body><a href="/page-a">A</a></body></html>`),
+			"https://example.com/page-a": successResponse(`<html><body></body></html>`),
+		}
+	}
+	fetcher := &blockingFetcher{
+		mockFetcher: mockFetcher{responses: responses()},
+		started:     make(chan struct{}, 10),
+		release:     make(chan struct{}),
+	}
+	c := testConfig
+	c.MaxWorkers = 1
+	a := newTestAudit(t, c, fetcher, extractor.NewLinkExtractor())
+	done := make(chan error, 1)
+	go func() { done <- a.Start(context.Background()) }()
+	<-fetcher.started
+	fetcher.release <- struct{}{}
+	<-fetcher.started
+	a.Cancel()
+	require.NoError(t, <-done)
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	require.NoError(t, SaveCheckpoint(path, a.Checkpoint()))
+	checkpoint, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	require.Equal(t, []CheckpointTask{{URL: "https://example.com/page-a", Depth: 1}}, checkpoint.Frontier)
+	require.Equal(t, map[string]int{"https://example.com/": http.StatusOK}, checkpoint.Statuses)
+
+	resumed, err := Resume(checkpoint, &mockFetcher{responses: responses()}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	resumed.logger = slog.New(slog.DiscardHandler)
+	require.Equal(t, Progress{Fetched: 1, Queued: 1, Total: 2, Percent: 50}, resumed.Progress())
+	require.NoError(t, resumed.Start(context.Background()))
+	require.Equal(t, []Page{
+		{URL: "https://example.com/", StatusCode: http.StatusOK, ContentLength: 49, Links: []string{"https://example.com/page-a"}},
+		{URL: "https://example.com/page-a", StatusCode: http.StatusOK, Depth: 1, ContentLength: 26, Links: []string{}},
+	}, resumed.Pages())
+
+	t.Run("invalid checkpoint", func(t *testing.T) {
+		_, err := LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json"))
+		require.True(t, errors.Is(err, ErrInvalidCheckpoint))
+	})
+}
+
+func TestAudit_ResumeKeepsCrawlState(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.SectionQuotas = "/product/=2"
+	c.LinkRotFile = filepath.Join(t.TempDir(), "linkrot.json")
+	c.AssetManifest = filepath.Join(t.TempDir(), "assets.txt")
+	require.NoError(t, os.WriteFile(c.AssetManifest, []byte("/app.js\n/unused.js\n"), 0644))
+	c.CheckCSP = true
+	c.CheckCompression = true
+	fetcher := pagesFetcher{
+		"https://example.com": `<a href="/product/1">1</a><a href="/product/2">2</a><a href="mailto:hi@example.com">mail</a>
+			<a href="https://other.com/a">other</a><script src="/app.js"></script><script src="https://cdn.other.com/lib.js"></script>`,
+		"https://example.com/more": `<a href="/product/3">3</a><a href="https://other.com/b">other</a>`,
+	}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	checkpoint := a.Checkpoint()
+	require.Equal(t, map[string]int{"/product/": 2}, checkpoint.SectionQuotas)
+	// A page left in the frontier links to a product past the quota
+	checkpoint.Frontier = append(checkpoint.Frontier, CheckpointTask{URL: "https://example.com/more", Depth: 0})
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	require.NoError(t, SaveCheckpoint(path, checkpoint))
+	checkpoint, err = LoadCheckpoint(path)
+	require.NoError(t, err)
+	resumed, err := Resume(checkpoint, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	resumed.logger = slog.New(slog.DiscardHandler)
+	require.Equal(t, a.Contacts(), resumed.Contacts())
+	require.Equal(t, []string{"https://example.com/unused.js"}, resumed.UnreferencedAssets())
+	require.NoError(t, resumed.Start(context.Background()))
+	require.Equal(t, []string{"https://example.com/unused.js"}, resumed.UnreferencedAssets())
+	pages := map[string]Page{}
+	for _, page := range resumed.Pages() {
+		pages[page.URL] = page
+	}
+	home := pages["https://example.com/"]
+	require.Equal(t, []string{"https://example.com/app.js"}, home.Assets)
+	require.Equal(t, []Resource{{Kind: "script", Origin: "https://cdn.other.com"}}, home.ThirdParty)
+	for _, page := range resumed.Pages() {
+		require.NotEqual(t, "https://example.com/product/3", page.URL)
+	}
+	require.Equal(t, []ExternalLink{
+		{URL: "https://other.com/a", Source: "https://example.com/"},
+		{URL: "https://other.com/b", Source: "https://example.com/more"},
+	}, resumed.ExternalLinks())
+	require.Equal(t, []OutboundDomain{
+		{Domain: "other.com", Links: 2, URLs: 2, Pages: 2, Examples: []string{"https://example.com/", "https://example.com/more"}},
+	}, resumed.OutboundDomains())
+}
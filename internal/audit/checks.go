@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type Page struct {
+	URL           string            `json:"url"`
+	StatusCode    int               `json:"status_code"`
+	Depth         int               `json:"depth"`
+	Title         string            `json:"title,omitempty"`
+	Lang          string            `json:"lang,omitempty"`
+	Canonical     string            `json:"canonical,omitempty"`
+	ContentLength int64             `json:"content_length,omitempty"`
+	Links         []string          `json:"links"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Embeds        []string          `json:"embeds,omitempty"`
+	Assets        []string          `json:"assets,omitempty"`
+	ThirdParty    []Resource        `json:"third_party,omitempty"`
+	Alternates    []Alternate       `json:"alternates,omitempty"`
+}
+
+type Check interface {
+	Name() string
+	Run(ctx context.Context, pages []Page) ([]Finding, error)
+}
+
+func (a *Audit) Pages() []Page {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pages := make([]Page, 0, len(a.statuses))
+	for u, code := range a.statuses {
+		page := Page{URL: u, StatusCode: code, Links: []string{}, Headers: maps.Clone(a.headers[u]), Embeds: slices.Clone(a.embeds[u]), Assets: slices.Clone(a.pageAssets[u]), ThirdParty: slices.Clone(a.thirdParty[u])}
+		if info, ok := a.nodes[u]; ok {
+			page.Depth = info.depth
+			page.Title = info.title
+			page.Lang = info.lang
+			page.Canonical = info.canonical
+			page.ContentLength = info.contentLength
+			page.Alternates = slices.Clone(info.alternates)
+		}
+		neighbours, _ := a.siteGraph.Neighbours(u)
+		for _, neighbour := range neighbours {
+			page.Links = append(page.Links, neighbour.Link)
+		}
+		pages = append(pages, page)
+	}
+	slices.SortFunc(pages, func(x, y Page) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	return pages
+}
+
+func (a *Audit) RunChecks(ctx context.Context, checks ...Check) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	pages := a.Pages()
+	for _, check := range checks {
+		if !a.checkConfig.Enabled(check.Name()) {
+			a.logger.Debug("Skipping disabled check", "check", check.Name())
+			continue
+		}
+		findings, err := check.Run(ctx, pages)
+		if err != nil {
+			return fmt.Errorf("check %s failed: %w", check.Name(), err)
+		}
+		a.logger.Debug("Check complete", "check", check.Name(), "findings", len(findings))
+		a.mu.Lock()
+		a.checkFindings = append(a.checkFindings, findings...)
+		a.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockCheck struct {
+	findings []Finding
+	err      error
+}
+
+func (m *mockCheck) Name() string {
+	return "mock"
+}
+
+func (m *mockCheck) Run(ctx context.Context, pages []Page) ([]Finding, error) {
+	return m.findings, m.err
+}
+
+func TestAudit_RunChecks(t *testing.T) {
+	newAudit := func() *Audit {
+		return crawlTestAudit(t, testConfig, &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com":        successResponse(`<html><body><a href="/page-a">A</a></body></html>`),
+			"https://example.com/page-a": successResponse(`<html><body></body></html>`),
+		}})
+	}
+	t.Run("pages include links", func(t *testing.T) {
+		a := newAudit()
+		require.Equal(t, []Page{
+			{URL: "https://example.com/", StatusCode: http.StatusOK, ContentLength: 49, Links: []string{"https://example.com/page-a"}},
+			{URL: "https://example.com/page-a", StatusCode: http.StatusOK, Depth: 1, ContentLength: 26, Links: []string{}},
+		}, a.Pages())
+	})
+	t.Run("check findings are recorded", func(t *testing.T) {
+		a := newAudit()
+		finding := Finding{Check: "mock", URL: "https://example.com/", Detail: "custom"}
+		err := a.RunChecks(context.Background(), &mockCheck{findings: []Finding{finding}})
+		require.NoError(t, err)
+		require.Equal(t, []Finding{finding}, a.Findings())
+	})
+	t.Run("check error is returned", func(t *testing.T) {
+		a := newAudit()
+		err := a.RunChecks(context.Background(), &mockCheck{err: errors.New("boom")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "check mock failed")
+	})
+}
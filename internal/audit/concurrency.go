@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// concurrencyController adapts how many fetches run at once with additive increase on healthy
+// responses and multiplicative decrease on errors, throttling or slow responses
+type concurrencyController struct {
+	mu           sync.Mutex
+	limit        float64
+	max          float64
+	target       time.Duration
+	lastDecrease time.Time
+	now          func() time.Time
+}
+
+func newConcurrencyController(maxWorkers int, target time.Duration) *concurrencyController {
+	return &concurrencyController{
+		limit:  math.Max(1, float64(maxWorkers)/2),
+		max:    math.Max(1, float64(maxWorkers)),
+		target: target,
+		now:    time.Now,
+	}
+}
+
+// Limit is the number of fetches allowed in flight, unbounded when adaptive concurrency is off
+func (c *concurrencyController) Limit() int {
+	if c == nil {
+		return math.MaxInt
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int(c.limit)
+}
+
+func (c *concurrencyController) Observe(latency time.Duration, failed bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if failed || latency > c.target {
+		// Fetches already in flight report the same trouble, so back off once per target window
+		if now := c.now(); now.Sub(c.lastDecrease) >= c.target {
+			c.limit = math.Max(1, c.limit/2)
+			c.lastDecrease = now
+		}
+		return
+	}
+	c.limit = math.Min(c.max, c.limit+1/c.limit)
+}
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+type concurrencyFetcher struct {
+	mockFetcher
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (c *concurrencyFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	c.mu.Lock()
+	c.current++
+	c.max = max(c.max, c.current)
+	c.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	c.mu.Lock()
+	c.current--
+	c.mu.Unlock()
+	return c.mockFetcher.Fetch(ctx, u)
+}
+
+func TestAudit_WorkersWaitForWork(t *testing.T) {
+	fetcher := &concurrencyFetcher{mockFetcher: mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com": successResponse(`<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a>`),
+		},
+	}}
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxWorkers = 3
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	require.Len(t, a.Pages(), 4)
+	// Idle workers must not exit while the start page is in flight
+	require.Equal(t, 3, fetcher.max)
+}
+
+func TestConcurrencyController(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newConcurrencyController(8, time.Second)
+	c.now = func() time.Time { return now }
+	require.Equal(t, 4, c.Limit())
+	for range 5 {
+		c.Observe(10*time.Millisecond, false)
+	}
+	require.Equal(t, 5, c.Limit())
+	c.Observe(10*time.Millisecond, true)
+	require.Equal(t, 2, c.Limit())
+	// Further trouble inside the same window does not compound the back off
+	c.Observe(2*time.Second, false)
+	require.Equal(t, 2, c.Limit())
+	now = now.Add(time.Second)
+	c.Observe(2*time.Second, false)
+	require.Equal(t, 1, c.Limit())
+	now = now.Add(time.Second)
+	c.Observe(0, true)
+	require.Equal(t, 1, c.Limit())
+	for range 200 {
+		c.Observe(0, false)
+	}
+	require.Equal(t, 8, c.Limit())
+	var disabled *concurrencyController
+	disabled.Observe(0, true)
+	require.Equal(t, math.MaxInt, disabled.Limit())
+
+	t.Run("crawl with adaptive concurrency", func(t *testing.T) {
+		fetcher := &concurrencyFetcher{mockFetcher: mockFetcher{
+			responses: map[string]*http.Response{
+				"https://example.com": successResponse(`<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a><a href="/d">D</a>`),
+			},
+		}}
+		c := testConfig
+		c.RespectRobots = false
+		c.MaxWorkers = 4
+		c.AdaptiveConcurrency = true
+		c.AdaptiveLatency = time.Millisecond
+		a, err := New(c, fetcher, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		a.logger = slog.New(slog.DiscardHandler)
+		require.NoError(t, a.Start(context.Background()))
+		require.Len(t, a.Pages(), 5)
+		// Every fetch is slower than the target so the crawl backs off to one at a time
+		require.Equal(t, 1, fetcher.max)
+	})
+}
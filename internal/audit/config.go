@@ -1,22 +1,373 @@
 package audit
 
-import "flag"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"salsgithub.com/site-audit/internal/slogx"
+)
+
+const (
+	VisitedExact = "exact"
+	VisitedBloom = "bloom"
+)
 
 type Config struct {
-	LogLevel      string `env:"AUDIT_LOG_LEVEL,default=INFO"`
-	StartURL      string `env:"AUDIT_START_URL,default="`
-	Agent         string `env:"AUDIT_AGENT,default=agent"`
-	ValidSchemes  string `env:"AUDIT_VALID_SCHEMES,default=https"`
-	RespectRobots bool   `env:"AUDIT_RESPECT_ROBOTS,default=TRUE"`
-	MaxWorkers    int    `env:"AUDIT_MAX_WORKERS,default=10"`
-	MaxDepth      int    `env:"AUDIT_MAX_DEPTH,default=2"`
+	LogLevel       string        `env:"AUDIT_LOG_LEVEL,default=INFO"`
+	LogFormat      string        `env:"AUDIT_LOG_FORMAT,default=json"`
+	LogFile        string        `env:"AUDIT_LOG_FILE,default="`
+	LogMaxBytes    int64         `env:"AUDIT_LOG_MAX_BYTES,default=104857600"`
+	LogMaxFiles    int           `env:"AUDIT_LOG_MAX_FILES,default=5"`
+	LogQuiet       bool          `env:"AUDIT_LOG_QUIET,default=FALSE"`
+	StatsInterval  time.Duration `env:"AUDIT_STATS_INTERVAL,default=30s"`
+	StartURL       string        `env:"AUDIT_START_URL,default="`
+	Agent          string        `env:"AUDIT_AGENT,default=agent"`
+	ValidSchemes   string        `env:"AUDIT_VALID_SCHEMES,default=https"`
+	RespectRobots  bool          `env:"AUDIT_RESPECT_ROBOTS,default=TRUE"`
+	RobotsAllow    string        `env:"AUDIT_ROBOTS_ALLOW,default="`
+	RobotsIgnore   string        `env:"AUDIT_ROBOTS_IGNORE_HOSTS,default="`
+	InternalHosts  string        `env:"AUDIT_INTERNAL_HOSTS,default="`
+	FragmentRoutes string        `env:"AUDIT_FRAGMENT_ROUTES,default="`
+	SitemapOnly    bool          `env:"AUDIT_SITEMAP_ONLY,default=FALSE"`
+	SitemapURL     string        `env:"AUDIT_SITEMAP_URL,default="`
+	SeedsFile      string        `env:"AUDIT_SEEDS_FILE,default="`
+	SeedsOnly      bool          `env:"AUDIT_SEEDS_ONLY,default=FALSE"`
+	MaxWorkers     int           `env:"AUDIT_MAX_WORKERS,default=10"`
+	MaxDepth       int           `env:"AUDIT_MAX_DEPTH,default=2"`
+
+	AdaptiveConcurrency bool          `env:"AUDIT_ADAPTIVE_CONCURRENCY,default=FALSE"`
+	AdaptiveLatency     time.Duration `env:"AUDIT_ADAPTIVE_LATENCY,default=2s"`
+
+	DNSCacheTTL time.Duration `env:"AUDIT_DNS_CACHE_TTL,default=0s"`
+	DNSPrefetch bool          `env:"AUDIT_DNS_PREFETCH,default=FALSE"`
+	ConnectTo   string        `env:"AUDIT_CONNECT_TO,default="`
+
+	MaxRedirects       int  `env:"AUDIT_MAX_REDIRECTS,default=10"`
+	FollowRedirects    bool `env:"AUDIT_FOLLOW_REDIRECTS,default=TRUE"`
+	CrossHostRedirects bool `env:"AUDIT_CROSS_HOST_REDIRECTS,default=TRUE"`
+
+	IncludeFiles bool  `env:"AUDIT_INCLUDE_FILES,default=FALSE"`
+	MaxBodyBytes int64 `env:"AUDIT_MAX_BODY_BYTES,default=10485760"`
+
+	CaptureHeaders  string `env:"AUDIT_CAPTURE_HEADERS,default="`
+	AcceptLanguages string `env:"AUDIT_ACCEPT_LANGUAGES,default="`
+	ConsentCookies  string `env:"AUDIT_CONSENT_COOKIES,default="`
+
+	VisitedMode        string  `env:"AUDIT_VISITED_MODE,default=exact"`
+	BloomCapacity      int     `env:"AUDIT_BLOOM_CAPACITY,default=1000000"`
+	BloomFalsePositive float64 `env:"AUDIT_BLOOM_FALSE_POSITIVE,default=0.001"`
+	FrontierMemory     int     `env:"AUDIT_FRONTIER_MEMORY,default=0"`
+	MaxQueue           int     `env:"AUDIT_MAX_QUEUE,default=0"`
+	TrapLimit          int     `env:"AUDIT_TRAP_LIMIT,default=0"`
+	DepthQuotas        string  `env:"AUDIT_DEPTH_QUOTAS,default="`
+	SectionQuotas      string  `env:"AUDIT_SECTION_QUOTAS,default="`
+	FrontierDir        string  `env:"AUDIT_FRONTIER_DIR,default="`
+
+	CheckCaching      bool `env:"AUDIT_CHECK_CACHING,default=FALSE"`
+	CheckEmbeds       bool `env:"AUDIT_CHECK_EMBEDS,default=FALSE"`
+	CheckCSP          bool `env:"AUDIT_CHECK_CSP,default=FALSE"`
+	CheckRobots       bool `env:"AUDIT_CHECK_ROBOTS,default=FALSE"`
+	CheckNotFound     bool `env:"AUDIT_CHECK_NOT_FOUND,default=FALSE"`
+	CheckIndexability bool `env:"AUDIT_CHECK_INDEXABILITY,default=FALSE"`
+	CheckCompression  bool `env:"AUDIT_CHECK_COMPRESSION,default=FALSE"`
+	MinifyThreshold   int  `env:"AUDIT_MINIFY_THRESHOLD,default=10240"`
+
+	SegmentBy string `env:"AUDIT_SEGMENT_BY,default="`
+
+	Environment string `env:"AUDIT_ENVIRONMENT,default="`
+
+	QueryParamSamples int    `env:"AUDIT_QUERY_PARAM_SAMPLES,default=0"`
+	AssetManifest     string `env:"AUDIT_ASSET_MANIFEST,default="`
+	EmailDomains      string `env:"AUDIT_EMAIL_DOMAINS,default="`
+
+	FailOnServerError bool `env:"AUDIT_FAIL_ON_SERVER_ERROR,default=FALSE"`
+	MaxBrokenLinks    int  `env:"AUDIT_MAX_BROKEN_LINKS,default=-1"`
+	MinScore          int  `env:"AUDIT_MIN_SCORE,default=0"`
+
+	ChecksFile     string `env:"AUDIT_CHECKS_FILE,default="`
+	Profile        string `env:"AUDIT_PROFILE,default="`
+	BaselineFile   string `env:"AUDIT_BASELINE_FILE,default="`
+	UpdateBaseline bool   `env:"AUDIT_UPDATE_BASELINE,default=FALSE"`
+
+	PluginChecks    string `env:"AUDIT_PLUGIN_CHECKS,default="`
+	PluginExporters string `env:"AUDIT_PLUGIN_EXPORTERS,default="`
+	ScriptChecks    string `env:"AUDIT_SCRIPT_CHECKS,default="`
+
+	SnapshotFile string `env:"AUDIT_SNAPSHOT_FILE,default="`
+	Exporters    string `env:"AUDIT_EXPORTERS,default=graphviz"`
+	OutputDir    string `env:"AUDIT_OUTPUT_DIR,default=./out"`
+
+	ScreenshotCommand string `env:"AUDIT_SCREENSHOT_COMMAND,default="`
+	ScreenshotLimit   int    `env:"AUDIT_SCREENSHOT_LIMIT,default=0"`
+	CheckpointFile    string `env:"AUDIT_CHECKPOINT_FILE,default="`
+	GraphLogFile      string `env:"AUDIT_GRAPH_LOG_FILE,default="`
+	PoliciesFile      string `env:"AUDIT_POLICIES_FILE,default="`
+	RewritesFile      string `env:"AUDIT_REWRITES_FILE,default="`
+	LoginFile         string `env:"AUDIT_LOGIN_FILE,default="`
+
+	HistoryFile         string `env:"AUDIT_HISTORY_FILE,default="`
+	HistoryRetention    int    `env:"AUDIT_HISTORY_RETENTION,default=0"`
+	HistoryURLs         bool   `env:"AUDIT_HISTORY_URLS,default=FALSE"`
+	HistoryURLRetention int    `env:"AUDIT_HISTORY_URL_RETENTION,default=10"`
+	LinkRotFile         string `env:"AUDIT_LINK_ROT_FILE,default="`
+	BrokenLinksFile     string `env:"AUDIT_BROKEN_LINKS_FILE,default="`
+	LinkRotWayback      bool   `env:"AUDIT_LINK_ROT_WAYBACK,default=FALSE"`
+	RedirectsFile       string `env:"AUDIT_REDIRECTS_FILE,default="`
+
+	WebhookURLs       string `env:"AUDIT_WEBHOOK_URLS,default="`
+	WebhookSecret     string `env:"AUDIT_WEBHOOK_SECRET,default="`
+	WebhookMaxRetries int    `env:"AUDIT_WEBHOOK_MAX_RETRIES,default=3"`
+	NotifyRulesFile   string `env:"AUDIT_NOTIFY_RULES_FILE,default="`
+
+	ServerAddress   string `env:"AUDIT_SERVER_ADDRESS,default=localhost:8080"`
+	ServerTokens    string `env:"AUDIT_SERVER_TOKENS,default="`
+	ServerRateLimit int    `env:"AUDIT_SERVER_RATE_LIMIT,default=60"`
+
+	ServerMaxJobs       int           `env:"AUDIT_SERVER_MAX_JOBS,default=4"`
+	ServerTenantMaxJobs int           `env:"AUDIT_SERVER_TENANT_MAX_JOBS,default=1"`
+	ServerHistoryFile   string        `env:"AUDIT_SERVER_HISTORY_FILE,default="`
+	ServerJobRetention  time.Duration `env:"AUDIT_SERVER_JOB_RETENTION,default=168h"`
+
+	// files holds what Validate loaded from the files the config names, so New reads each only once
+	files *configFiles
+}
+
+type configFiles struct {
+	redirects map[string]time.Time
+	seeds     []string
+	checks    *CheckConfig
+	baseline  *Baseline
+	assets    []string
 }
 
-func AddFlags(config Config, fs *flag.FlagSet) {
+func AddFlags(config *Config, fs *flag.FlagSet) {
 	fs.StringVar(&config.LogLevel, "AUDIT_LOG_LEVEL", "INFO", "The log level")
+	fs.StringVar(&config.LogFormat, "AUDIT_LOG_FORMAT", "json", "Log format, json or text")
+	fs.StringVar(&config.LogFile, "AUDIT_LOG_FILE", "", "Path of a file logs are written to instead of stdout")
+	fs.Int64Var(&config.LogMaxBytes, "AUDIT_LOG_MAX_BYTES", 100<<20, "Size at which the log file is rotated (never when 0)")
+	fs.IntVar(&config.LogMaxFiles, "AUDIT_LOG_MAX_FILES", 5, "How many rotated log files are kept")
+	fs.BoolVar(&config.LogQuiet, "AUDIT_LOG_QUIET", false, "Only log errors and the final summary")
+	fs.DurationVar(&config.StatsInterval, "AUDIT_STATS_INTERVAL", 30*time.Second, "How often crawl statistics are logged (disabled when 0)")
 	fs.StringVar(&config.StartURL, "AUDIT_START_URL", "", "The start URL")
+	fs.StringVar(&config.Agent, "AUDIT_AGENT", "agent", "The user-agent name")
 	fs.StringVar(&config.ValidSchemes, "AUDIT_VALID_SCHEMES", "https", "Comma-separated list of values for valid schemes")
-	fs.BoolVar(&config.RespectRobots, "AUDIT_RESPECT_ROBOTS", true, "Whether to respsect the robots.txt file")
+	fs.BoolVar(&config.RespectRobots, "AUDIT_RESPECT_ROBOTS", true, "Whether to respect the robots.txt file")
+	fs.StringVar(&config.RobotsAllow, "AUDIT_ROBOTS_ALLOW", "", "Comma-separated list of path prefixes crawled even when robots.txt disallows them")
+	fs.StringVar(&config.RobotsIgnore, "AUDIT_ROBOTS_IGNORE_HOSTS", "", "Comma-separated list of hosts whose robots.txt is ignored")
+	fs.StringVar(&config.InternalHosts, "AUDIT_INTERNAL_HOSTS", "", "Comma-separated list of other hosts, such as asset or CDN hosts, crawled as part of the site")
+	fs.StringVar(&config.FragmentRoutes, "AUDIT_FRAGMENT_ROUTES", "", "Comma-separated list of fragment prefixes, such as #/ or #!, treated as distinct pages")
+	fs.BoolVar(&config.SitemapOnly, "AUDIT_SITEMAP_ONLY", false, "Fetch exactly the urls in the sitemap without following links, reporting those that would not be indexed")
+	fs.StringVar(&config.SitemapURL, "AUDIT_SITEMAP_URL", "", "Sitemap read when AUDIT_SITEMAP_ONLY is set (defaults to those listed in robots.txt, then /sitemap.xml)")
+	fs.StringVar(&config.SeedsFile, "AUDIT_SEEDS_FILE", "", "Path to a file of urls, one per line, crawled from depth 0 along with the start url, or - to read them from stdin")
+	fs.BoolVar(&config.SeedsOnly, "AUDIT_SEEDS_ONLY", false, "Fetch exactly the urls in AUDIT_SEEDS_FILE without the start url or following links")
 	fs.IntVar(&config.MaxWorkers, "AUDIT_MAX_WORKERS", 10, "Maximum number of worker routines")
 	fs.IntVar(&config.MaxDepth, "AUDIT_MAX_DEPTH", 2, "The maximum depth to traverse through links")
+	fs.BoolVar(&config.AdaptiveConcurrency, "AUDIT_ADAPTIVE_CONCURRENCY", false, "Scale concurrent fetches between 1 and AUDIT_MAX_WORKERS based on latency and errors")
+	fs.DurationVar(&config.AdaptiveLatency, "AUDIT_ADAPTIVE_LATENCY", 2*time.Second, "Response time above which adaptive concurrency backs off")
+	fs.DurationVar(&config.DNSCacheTTL, "AUDIT_DNS_CACHE_TTL", 0, "How long resolved hostnames are cached (disabled when 0)")
+	fs.BoolVar(&config.DNSPrefetch, "AUDIT_DNS_PREFETCH", false, "Resolve hostnames found in links in the background before they are fetched")
+	fs.StringVar(&config.ConnectTo, "AUDIT_CONNECT_TO", "", "Comma separated host=address pairs sending requests for a host to another IP or internal hostname, keeping its Host header and SNI")
+	fs.IntVar(&config.MaxRedirects, "AUDIT_MAX_REDIRECTS", 10, "Maximum redirects followed for one fetch, the last response is recorded past it")
+	fs.BoolVar(&config.FollowRedirects, "AUDIT_FOLLOW_REDIRECTS", true, "Whether redirects are followed, rather than recorded with their target crawled as a link")
+	fs.BoolVar(&config.CrossHostRedirects, "AUDIT_CROSS_HOST_REDIRECTS", true, "Whether redirects to another host are followed")
+	fs.BoolVar(&config.IncludeFiles, "AUDIT_INCLUDE_FILES", false, "Crawl linked files such as images and documents instead of ignoring them")
+	fs.Int64Var(&config.MaxBodyBytes, "AUDIT_MAX_BODY_BYTES", 10485760, "Maximum bytes of a page parsed for links, larger pages are abandoned (unlimited when 0)")
+	fs.StringVar(&config.CaptureHeaders, "AUDIT_CAPTURE_HEADERS", "", "Comma-separated list of response headers, such as Server or X-Cache, recorded for every page")
+	fs.StringVar(&config.ConsentCookies, "AUDIT_CONSENT_COOKIES", "", "Cookies sent with every request, such as consent=accepted; analytics=no, so pages are crawled as seen after accepting a consent banner")
+	fs.StringVar(&config.AcceptLanguages, "AUDIT_ACCEPT_LANGUAGES", "", "Comma-separated list of Accept-Language values, the first sent with every request and the others crawled as variants of pages that vary by it")
+	fs.StringVar(&config.VisitedMode, "AUDIT_VISITED_MODE", VisitedExact, "How visited urls are tracked, exact or bloom for bounded memory on huge crawls")
+	fs.IntVar(&config.BloomCapacity, "AUDIT_BLOOM_CAPACITY", 1000000, "Number of urls the bloom filter is sized for")
+	fs.Float64Var(&config.BloomFalsePositive, "AUDIT_BLOOM_FALSE_POSITIVE", 0.001, "Chance the bloom filter wrongly reports an unseen url as visited")
+	fs.IntVar(&config.FrontierMemory, "AUDIT_FRONTIER_MEMORY", 0, "Maximum queued urls held in memory before spilling to disk (unlimited when 0)")
+	fs.IntVar(&config.MaxQueue, "AUDIT_MAX_QUEUE", 0, "Queue length at which workers pause before enqueueing more links (unbounded when 0)")
+	fs.IntVar(&config.TrapLimit, "AUDIT_TRAP_LIMIT", 0, "Number of urls sharing a pattern after which the rest are treated as a crawler trap (disabled when 0)")
+	fs.StringVar(&config.DepthQuotas, "AUDIT_DEPTH_QUOTAS", "", "Comma-separated depth=pages limits on how many urls are crawled at each depth, such as 1=50,2=500")
+	fs.StringVar(&config.SectionQuotas, "AUDIT_SECTION_QUOTAS", "", "Comma-separated prefix=pages limits on how many urls are crawled under each path prefix, such as /product/=500")
+	fs.StringVar(&config.FrontierDir, "AUDIT_FRONTIER_DIR", "", "Directory queued urls spill to (defaults to the system temp directory)")
+	fs.BoolVar(&config.CheckCaching, "AUDIT_CHECK_CACHING", false, "Report uncacheable pages, conflicting caching directives and short-lived asset caching")
+	fs.BoolVar(&config.CheckEmbeds, "AUDIT_CHECK_EMBEDS", false, "Report iframes, embeds, video and audio whose content fails to load, including removed or private YouTube and Vimeo videos")
+	fs.BoolVar(&config.CheckCSP, "AUDIT_CHECK_CSP", false, "Report pages without a Content-Security-Policy, unsafe-inline or unsafe-eval sources, and third party origins loaded but not allowed by the policy")
+	fs.BoolVar(&config.CheckRobots, "AUDIT_CHECK_ROBOTS", false, "Report robots.txt disallowing the whole site, conflicting rules, a missing Sitemap and rules matching no crawled url")
+	fs.BoolVar(&config.CheckNotFound, "AUDIT_CHECK_NOT_FOUND", false, "Request a nonexistent page and report the site when it does not answer with a 404 page linking back into the site")
+	fs.BoolVar(&config.CheckCompression, "AUDIT_CHECK_COMPRESSION", false, "Report HTML, CSS and JavaScript served without gzip or brotli, and scripts and stylesheets that look unminified")
+	fs.IntVar(&config.MinifyThreshold, "AUDIT_MINIFY_THRESHOLD", 10240, "Size in bytes above which scripts and stylesheets that look unminified are reported")
+	fs.BoolVar(&config.CheckIndexability, "AUDIT_CHECK_INDEXABILITY", false, "Report internal search results and faceted urls with many query parameters that search engines could index")
+	fs.BoolVar(&config.FailOnServerError, "AUDIT_FAIL_ON_SERVER_ERROR", false, "Fail the audit if any page returns a 5xx status")
+	fs.StringVar(&config.SegmentBy, "AUDIT_SEGMENT_BY", "", "Break the summary down by section of the site: language (from the html lang attribute), path (first path segment) or template (url pattern such as /product/{slug})")
+	fs.StringVar(&config.AssetManifest, "AUDIT_ASSET_MANIFEST", "", "Directory, JSON manifest or list of deployed assets to report those no page references")
+	fs.StringVar(&config.EmailDomains, "AUDIT_EMAIL_DOMAINS", "", "Comma-separated list of other domains email links are expected to use")
+	fs.StringVar(&config.Environment, "AUDIT_ENVIRONMENT", "", "Check for staging leaks: staging expects every page noindexed or behind authentication, production expects neither")
+	fs.IntVar(&config.QueryParamSamples, "AUDIT_QUERY_PARAM_SAMPLES", 0, "Number of urls per query parameter fetched with and without it after the crawl to tell whether it changes content (disabled when 0)")
+	fs.IntVar(&config.MaxBrokenLinks, "AUDIT_MAX_BROKEN_LINKS", -1, "Fail the audit if more than this many pages return a 4xx/5xx status (disabled when negative)")
+	fs.IntVar(&config.MinScore, "AUDIT_MIN_SCORE", 0, "Fail the audit if its health score is below this percentage (disabled when 0)")
+	fs.StringVar(&config.ChecksFile, "AUDIT_CHECKS_FILE", "", "Path to a JSON file enabling, scoping and setting severities and thresholds of individual checks")
+	fs.StringVar(&config.Profile, "AUDIT_PROFILE", "", "Name of the profile the audit runs as, turning on the checks configured for it in the checks file")
+	fs.StringVar(&config.BaselineFile, "AUDIT_BASELINE_FILE", "", "Path to a baseline of accepted findings ignored by thresholds")
+	fs.BoolVar(&config.UpdateBaseline, "AUDIT_UPDATE_BASELINE", false, "Write the findings of this run to the baseline file")
+	fs.StringVar(&config.PluginChecks, "AUDIT_PLUGIN_CHECKS", "", "Comma-separated list of external check commands")
+	fs.StringVar(&config.PluginExporters, "AUDIT_PLUGIN_EXPORTERS", "", "Comma-separated list of external exporter commands")
+	fs.StringVar(&config.ScriptChecks, "AUDIT_SCRIPT_CHECKS", "", "Comma-separated list of Starlark check scripts")
+	fs.StringVar(&config.SnapshotFile, "AUDIT_SNAPSHOT_FILE", "", "Path to save a JSON snapshot of the crawl for later querying")
+	fs.StringVar(&config.Exporters, "AUDIT_EXPORTERS", "graphviz", "Comma-separated list of formats the graph is exported in: graphviz, csv, graphml, gexf or json")
+	fs.StringVar(&config.OutputDir, "AUDIT_OUTPUT_DIR", "./out", "Directory exported graphs are written to")
+	fs.StringVar(&config.ScreenshotCommand, "AUDIT_SCREENSHOT_COMMAND", "", "Headless browser command run for each page to screenshot, with {url} and {output} replaced by the page and the PNG to write")
+	fs.IntVar(&config.ScreenshotLimit, "AUDIT_SCREENSHOT_LIMIT", 0, "Maximum number of pages screenshot, sampled evenly across the site (every page when 0)")
+	fs.StringVar(&config.CheckpointFile, "AUDIT_CHECKPOINT_FILE", "", "Path to save the crawl state to when interrupted, for use with resume")
+	fs.StringVar(&config.GraphLogFile, "AUDIT_GRAPH_LOG_FILE", "", "Path to append edges and statuses to as they are discovered, for use with recover")
+	fs.StringVar(&config.PoliciesFile, "AUDIT_POLICIES_FILE", "", "Path to a JSON file of per-host crawl policies")
+	fs.StringVar(&config.RewritesFile, "AUDIT_REWRITES_FILE", "", "Path to a JSON file of regex rewrite rules applied to discovered urls before they are queued")
+	fs.StringVar(&config.LoginFile, "AUDIT_LOGIN_FILE", "", "Path to a JSON file describing a login form submitted before crawling")
+	fs.StringVar(&config.HistoryFile, "AUDIT_HISTORY_FILE", "", "Path to a JSON file recording the summary of each run for trend reports")
+	fs.IntVar(&config.HistoryRetention, "AUDIT_HISTORY_RETENTION", 0, "Number of runs kept per site in the history file (unlimited when 0)")
+	fs.BoolVar(&config.HistoryURLs, "AUDIT_HISTORY_URLS", false, "Record the status, title and content hash of every fetched url in the history file")
+	fs.IntVar(&config.HistoryURLRetention, "AUDIT_HISTORY_URL_RETENTION", 10, "Number of runs per site whose urls are kept in the history file (unlimited when 0)")
+	fs.StringVar(&config.LinkRotFile, "AUDIT_LINK_ROT_FILE", "", "Path to a JSON file tracking the status of every external link found")
+	fs.StringVar(&config.BrokenLinksFile, "AUDIT_BROKEN_LINKS_FILE", "", "Path to write the broken links found with the pages linking to them, as CSV when it ends in .csv and JSON otherwise")
+	fs.BoolVar(&config.LinkRotWayback, "AUDIT_LINK_ROT_WAYBACK", false, "Look up an Internet Archive snapshot of each external link that dies")
+	fs.StringVar(&config.RedirectsFile, "AUDIT_REDIRECTS_FILE", "", "Path to a JSON file tracking when each temporary redirect was first seen")
+	fs.StringVar(&config.WebhookURLs, "AUDIT_WEBHOOK_URLS", "", "Comma-separated list of urls notified when the audit starts, finishes or fails")
+	fs.StringVar(&config.WebhookSecret, "AUDIT_WEBHOOK_SECRET", "", "Secret used to sign webhook payloads")
+	fs.IntVar(&config.WebhookMaxRetries, "AUDIT_WEBHOOK_MAX_RETRIES", 3, "Maximum retries for a failed webhook delivery")
+	fs.StringVar(&config.NotifyRulesFile, "AUDIT_NOTIFY_RULES_FILE", "", "Path to a JSON file of notification rules evaluated after the audit")
+	fs.StringVar(&config.ServerAddress, "AUDIT_SERVER_ADDRESS", "localhost:8080", "The address the server listens on")
+	fs.StringVar(&config.ServerTokens, "AUDIT_SERVER_TOKENS", "", "Comma-separated list of tenant:token pairs accepted by the server")
+	fs.IntVar(&config.ServerRateLimit, "AUDIT_SERVER_RATE_LIMIT", 60, "Maximum server requests per minute for each token (disabled when 0)")
+	fs.IntVar(&config.ServerMaxJobs, "AUDIT_SERVER_MAX_JOBS", 4, "Maximum audit jobs running at once on the server (unlimited when 0)")
+	fs.IntVar(&config.ServerTenantMaxJobs, "AUDIT_SERVER_TENANT_MAX_JOBS", 1, "Maximum audit jobs running at once for each tenant (unlimited when 0)")
+	fs.StringVar(&config.ServerHistoryFile, "AUDIT_SERVER_HISTORY_FILE", "", "Path to persist the server job history")
+	fs.DurationVar(&config.ServerJobRetention, "AUDIT_SERVER_JOB_RETENTION", 168*time.Hour, "How long the server keeps the records of finished jobs (forever when 0)")
+}
+
+// Validate reports every problem with the config, loading the files it names and keeping what
+// they hold for New
+func (c *Config) Validate() error {
+	var errs []error
+	files := &configFiles{}
+	c.files = files
+	startURL, err := url.Parse(c.StartURL)
+	if c.StartURL == "" || err != nil {
+		errs = append(errs, fmt.Errorf("%w: %q, set AUDIT_START_URL to an absolute url such as https://example.com", ErrInvalidStartURL, c.StartURL))
+	} else if startURL.Scheme == "" {
+		errs = append(errs, fmt.Errorf("%w: %q, include a scheme such as https://", ErrInvalidStartScheme, c.StartURL))
+	}
+	if c.LogFormat != "" && c.LogFormat != slogx.FormatJSON && c.LogFormat != slogx.FormatText {
+		errs = append(errs, fmt.Errorf("%w: %q, AUDIT_LOG_FORMAT must be json or text", ErrInvalidLogFormat, c.LogFormat))
+	}
+	if c.SitemapURL != "" {
+		if u, err := url.Parse(c.SitemapURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("%w: %q, expected an http or https url", ErrInvalidSitemapURL, c.SitemapURL))
+		}
+	}
+	if c.MaxWorkers < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_MAX_WORKERS must be zero or more", ErrInvalidMaxWorkers, c.MaxWorkers))
+	}
+	if c.MaxDepth < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_MAX_DEPTH must be zero or more", ErrInvalidMaxDepth, c.MaxDepth))
+	}
+	if c.ConsentCookies != "" {
+		if _, err := http.ParseCookie(c.ConsentCookies); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w, AUDIT_CONSENT_COOKIES must look like name=value; other=value", ErrInvalidConsentCookies, err))
+		}
+	}
+	if c.MaxRedirects < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_MAX_REDIRECTS must be zero or more", ErrInvalidMaxRedirects, c.MaxRedirects))
+	}
+	if c.AdaptiveConcurrency && c.AdaptiveLatency <= 0 {
+		errs = append(errs, fmt.Errorf("%w: %s, AUDIT_ADAPTIVE_LATENCY must be more than zero", ErrInvalidLatency, c.AdaptiveLatency))
+	}
+	if c.DNSCacheTTL < 0 || (c.DNSPrefetch && c.DNSCacheTTL == 0) {
+		errs = append(errs, fmt.Errorf("%w: %s, AUDIT_DNS_CACHE_TTL must be more than zero to prefetch", ErrInvalidDNSCache, c.DNSCacheTTL))
+	}
+	switch c.VisitedMode {
+	case "", VisitedExact:
+	case VisitedBloom:
+		if c.BloomCapacity <= 0 {
+			errs = append(errs, fmt.Errorf("%w: %d, AUDIT_BLOOM_CAPACITY must be more than zero", ErrInvalidVisitedMode, c.BloomCapacity))
+		}
+		if c.BloomFalsePositive <= 0 || c.BloomFalsePositive >= 1 {
+			errs = append(errs, fmt.Errorf("%w: %v, AUDIT_BLOOM_FALSE_POSITIVE must be between 0 and 1", ErrInvalidVisitedMode, c.BloomFalsePositive))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q, AUDIT_VISITED_MODE must be exact or bloom", ErrInvalidVisitedMode, c.VisitedMode))
+	}
+	if c.MaxQueue < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_MAX_QUEUE must be zero or more", ErrInvalidFrontier, c.MaxQueue))
+	}
+	if c.TrapLimit < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_TRAP_LIMIT must be zero or more", ErrInvalidTrapLimit, c.TrapLimit))
+	}
+	if c.MinifyThreshold < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_MINIFY_THRESHOLD must be zero or more", ErrInvalidMinifyThreshold, c.MinifyThreshold))
+	}
+	if c.ScreenshotLimit < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_SCREENSHOT_LIMIT must be zero or more", ErrInvalidScreenshotLimit, c.ScreenshotLimit))
+	}
+	if c.MinScore < 0 || c.MinScore > 100 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_MIN_SCORE must be between 0 and 100", ErrInvalidMinScore, c.MinScore))
+	}
+	if _, err := parseQuotas(c.DepthQuotas, c.SectionQuotas); err != nil {
+		errs = append(errs, err)
+	}
+	if c.FrontierMemory < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_FRONTIER_MEMORY must be zero or more", ErrInvalidFrontier, c.FrontierMemory))
+	}
+	if c.QueryParamSamples < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_QUERY_PARAM_SAMPLES must be zero or more", ErrInvalidQueryParamSamples, c.QueryParamSamples))
+	}
+	switch c.Environment {
+	case "", EnvironmentStaging, EnvironmentProduction:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q, AUDIT_ENVIRONMENT must be staging or production", ErrInvalidEnvironment, c.Environment))
+	}
+	switch c.SegmentBy {
+	case "", SegmentLanguage, SegmentPath, SegmentTemplate:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q, AUDIT_SEGMENT_BY must be language, path or template", ErrInvalidSegmentBy, c.SegmentBy))
+	}
+	if c.AssetManifest != "" {
+		if files.assets, err = LoadAssets(c.AssetManifest); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.RedirectsFile != "" {
+		if files.redirects, err = loadRedirectHistory(c.RedirectsFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.ChecksFile != "" {
+		if files.checks, err = LoadCheckConfig(c.ChecksFile); err != nil {
+			errs = append(errs, err)
+		} else {
+			files.checks.profile = c.Profile
+		}
+	}
+	if c.SeedsFile != "" && c.SeedsFile != stdinSeeds {
+		if files.seeds, err = loadSeeds(c.SeedsFile); err != nil {
+			errs = append(errs, err)
+		}
+	} else if c.SeedsOnly && c.SeedsFile == "" {
+		errs = append(errs, fmt.Errorf("%w: AUDIT_SEEDS_ONLY needs AUDIT_SEEDS_FILE", ErrInvalidSeedsFile))
+	}
+	if c.BaselineFile != "" && !c.UpdateBaseline {
+		if files.baseline, err = LoadBaseline(c.BaselineFile); err != nil {
+			errs = append(errs, fmt.Errorf("%w, set AUDIT_UPDATE_BASELINE to create it", err))
+		}
+	}
+	for _, webhookURL := range strings.Split(c.WebhookURLs, ",") {
+		if webhookURL = strings.TrimSpace(webhookURL); webhookURL == "" {
+			continue
+		}
+		if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("%w: %q, expected an http or https url", ErrInvalidWebhookURL, webhookURL))
+		}
+	}
+	if c.WebhookMaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d, AUDIT_WEBHOOK_MAX_RETRIES must be zero or more", ErrInvalidWebhookRetries, c.WebhookMaxRetries))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("valid config", func(t *testing.T) {
+		require.NoError(t, testConfig.Validate())
+	})
+	t.Run("keeps the files it loads", func(t *testing.T) {
+		c := testConfig
+		c.SeedsFile = filepath.Join(t.TempDir(), "seeds.txt")
+		require.NoError(t, os.WriteFile(c.SeedsFile, []byte("https://example.com/a\n"), 0644))
+		require.NoError(t, c.Validate())
+		require.NoError(t, os.Remove(c.SeedsFile))
+		require.Equal(t, []string{"https://example.com/a"}, c.files.seeds)
+	})
+	t.Run("reports every problem", func(t *testing.T) {
+		c := Config{
+			StartURL:   "example.com",
+			MaxWorkers: -1,
+			MaxDepth:   -1,
+
+			MaxRedirects:      -1,
+			ConsentCookies:    "no-equals-sign",
+			SectionQuotas:     "product=5",
+			MinifyThreshold:   -1,
+			MinScore:          101,
+			ScreenshotLimit:   -1,
+			FrontierMemory:    -1,
+			TrapLimit:         -1,
+			DNSPrefetch:       true,
+			SegmentBy:         "country",
+			QueryParamSamples: -1,
+			AssetManifest:     "missing-assets.txt",
+			Environment:       "qa",
+			ChecksFile:        "missing-checks.json",
+			SeedsFile:         "missing-seeds.txt",
+			LogFormat:         "xml",
+			SitemapURL:        "sitemap.xml",
+
+			WebhookURLs:       "https://hooks.example.com, ftp://example.com",
+			WebhookMaxRetries: -1,
+		}
+		err := c.Validate()
+		require.Error(t, err)
+		require.True(t, errors.Is(err, ErrInvalidStartScheme))
+		require.True(t, errors.Is(err, ErrInvalidMaxWorkers))
+		require.True(t, errors.Is(err, ErrInvalidMaxDepth))
+		require.True(t, errors.Is(err, ErrInvalidMaxRedirects))
+		require.True(t, errors.Is(err, ErrInvalidConsentCookies))
+		require.True(t, errors.Is(err, ErrInvalidQuotas))
+		require.True(t, errors.Is(err, ErrInvalidMinifyThreshold))
+		require.True(t, errors.Is(err, ErrInvalidMinScore))
+		require.True(t, errors.Is(err, ErrInvalidScreenshotLimit))
+		require.True(t, errors.Is(err, ErrInvalidFrontier))
+		require.True(t, errors.Is(err, ErrInvalidTrapLimit))
+		require.True(t, errors.Is(err, ErrInvalidSegmentBy))
+		require.True(t, errors.Is(err, ErrInvalidQueryParamSamples))
+		require.True(t, errors.Is(err, ErrInvalidAssetManifest))
+		require.True(t, errors.Is(err, ErrInvalidEnvironment))
+		require.True(t, errors.Is(err, ErrInvalidChecksFile))
+		require.True(t, errors.Is(err, ErrInvalidSeedsFile))
+		require.True(t, errors.Is(err, ErrInvalidLogFormat))
+		require.True(t, errors.Is(err, ErrInvalidSitemapURL))
+		require.True(t, errors.Is(err, ErrInvalidDNSCache))
+		require.True(t, errors.Is(err, ErrInvalidWebhookURL))
+		require.True(t, errors.Is(err, ErrInvalidWebhookRetries))
+		require.NotContains(t, err.Error(), "hooks.example.com")
+	})
+	t.Run("visited mode", func(t *testing.T) {
+		c := testConfig
+		c.VisitedMode = "disk"
+		require.True(t, errors.Is(c.Validate(), ErrInvalidVisitedMode))
+		c.VisitedMode = VisitedBloom
+		c.BloomCapacity = 0
+		c.BloomFalsePositive = 1
+		err := c.Validate()
+		require.Contains(t, err.Error(), "AUDIT_BLOOM_CAPACITY")
+		require.Contains(t, err.Error(), "AUDIT_BLOOM_FALSE_POSITIVE")
+	})
+}
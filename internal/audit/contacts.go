@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"fmt"
+	"maps"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	CheckMalformedContact     = "malformed-contact-link"
+	CheckExternalEmailDomain  = "external-email-domain"
+	contactMail, contactPhone = "mailto", "tel"
+)
+
+// ContactLink is a mailto: or tel: link and the pages linking to it. Problem says what is wrong
+// with the address, if anything.
+type ContactLink struct {
+	URL     string   `json:"url"`
+	Scheme  string   `json:"scheme"`
+	Address string   `json:"address"`
+	Pages   []string `json:"pages"`
+	Problem string   `json:"problem,omitempty"`
+	// External is set on email addresses outside the site's domains and AUDIT_EMAIL_DOMAINS
+	External bool `json:"external,omitempty"`
+}
+
+func isContactScheme(scheme string) bool {
+	return scheme == contactMail || scheme == contactPhone
+}
+
+// recordContact must be called with a.mu held
+func (a *Audit) recordContact(u *url.URL, source string) {
+	link := intern(u.String())
+	pages, ok := a.contacts[link]
+	if !ok {
+		pages = map[string]struct{}{}
+		a.contacts[link] = pages
+	}
+	pages[source] = struct{}{}
+}
+
+// Contacts returns every mailto: and tel: link found, sorted by url
+func (a *Audit) Contacts() []ContactLink {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.contactLinks()
+}
+
+// contactLinks must be called with a.mu held
+func (a *Audit) contactLinks() []ContactLink {
+	contacts := make([]ContactLink, 0, len(a.contacts))
+	for _, link := range slices.Sorted(maps.Keys(a.contacts)) {
+		u, _ := url.Parse(link)
+		contact := ContactLink{URL: link, Scheme: u.Scheme, Pages: slices.Sorted(maps.Keys(a.contacts[link]))}
+		switch u.Scheme {
+		case contactMail:
+			contact.Address, contact.Problem, contact.External = a.checkMailto(u)
+		case contactPhone:
+			contact.Address, contact.Problem = checkTel(u)
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts
+}
+
+// checkMailto reads the addresses before any ?subject= and reports whether any is outside the site
+func (a *Audit) checkMailto(u *url.URL) (string, string, bool) {
+	opaque, _, _ := strings.Cut(u.Opaque, "?")
+	address, err := url.PathUnescape(opaque)
+	if err != nil {
+		address = opaque
+	}
+	if strings.TrimSpace(address) == "" {
+		return address, "no email address", false
+	}
+	external := false
+	for _, part := range strings.Split(address, ",") {
+		parsed, err := mail.ParseAddress(strings.TrimSpace(part))
+		if err != nil {
+			return address, fmt.Sprintf("invalid email address %q", strings.TrimSpace(part)), false
+		}
+		_, domain, _ := strings.Cut(parsed.Address, "@")
+		if !a.emailDomainExpected(domain) {
+			external = true
+		}
+	}
+	return address, "", external
+}
+
+// emailDomainExpected reports whether domain belongs to the site, counting the start url's domain,
+// its subdomains and parents, the internal hosts and AUDIT_EMAIL_DOMAINS
+func (a *Audit) emailDomainExpected(domain string) bool {
+	domain = normaliseHost(domain)
+	site := normaliseHost(a.startURL.Hostname())
+	if domain == site || strings.HasSuffix(domain, "."+site) || strings.HasSuffix(site, "."+domain) {
+		return true
+	}
+	return a.internalHosts.Contains(domain) || slices.Contains(a.emailDomains, domain)
+}
+
+// checkTel accepts numbers of 3 to 15 digits, optionally starting with +, once visual separators
+// and parameters such as ;ext= are dropped
+func checkTel(u *url.URL) (string, string) {
+	number, err := url.PathUnescape(u.Opaque)
+	if err != nil {
+		number = u.Opaque
+	}
+	digits, _, _ := strings.Cut(number, ";")
+	digits = strings.TrimPrefix(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -.()", r) {
+			return -1
+		}
+		return r
+	}, digits), "+")
+	if len(digits) < 3 || len(digits) > 15 || strings.Trim(digits, "0123456789") != "" {
+		return number, fmt.Sprintf("invalid phone number %q", number)
+	}
+	return number, ""
+}
+
+// contactFindings must be called with a.mu held. Each link is reported once however many pages
+// link to it.
+func (a *Audit) contactFindings() []Finding {
+	findings := []Finding{}
+	for _, contact := range a.contactLinks() {
+		linked := fmt.Sprintf("linked from %d pages", len(contact.Pages))
+		if len(contact.Pages) == 1 {
+			linked = "linked from " + contact.Pages[0]
+		}
+		if contact.Problem != "" {
+			findings = append(findings, Finding{Check: CheckMalformedContact, URL: contact.URL, Detail: contact.Problem + ", " + linked})
+		}
+		if contact.External {
+			findings = append(findings, Finding{Check: CheckExternalEmailDomain, URL: contact.URL, Detail: "email address outside the site's domains, " + linked})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_Contacts(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.EmailDomains = "support.example.net"
+	a, err := New(c, pagesFetcher{
+		"https://example.com":   `<a href="mailto:info@example.com?subject=Hi">mail</a><a href="tel:+44 20 7946 0000">call</a><a href="/a">a</a>`,
+		"https://example.com/a": `<a href="mailto:info@example.com?subject=Hi">mail</a><a href="mailto:help@support.example.net,sales@gmail.com">mail</a><a href="mailto:info-at-example.com">mail</a><a href="tel:call-us">call</a>`,
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []ContactLink{
+		{URL: "mailto:help@support.example.net,sales@gmail.com", Scheme: "mailto", Address: "help@support.example.net,sales@gmail.com", Pages: []string{"https://example.com/a"}, External: true},
+		{URL: "mailto:info-at-example.com", Scheme: "mailto", Address: "info-at-example.com", Pages: []string{"https://example.com/a"}, Problem: `invalid email address "info-at-example.com"`},
+		{URL: "mailto:info@example.com?subject=Hi", Scheme: "mailto", Address: "info@example.com", Pages: []string{"https://example.com/", "https://example.com/a"}},
+		{URL: "tel:+44 20 7946 0000", Scheme: "tel", Address: "+44 20 7946 0000", Pages: []string{"https://example.com/"}},
+		{URL: "tel:call-us", Scheme: "tel", Address: "call-us", Pages: []string{"https://example.com/a"}, Problem: `invalid phone number "call-us"`},
+	}, a.Contacts())
+	findings := []Finding{}
+	for _, f := range a.Findings() {
+		if f.Check == CheckMalformedContact || f.Check == CheckExternalEmailDomain {
+			findings = append(findings, f)
+		}
+	}
+	require.Equal(t, []Finding{
+		{Check: CheckExternalEmailDomain, URL: "mailto:help@support.example.net,sales@gmail.com", Detail: "email address outside the site's domains, linked from https://example.com/a"},
+		{Check: CheckMalformedContact, URL: "mailto:info-at-example.com", Detail: `invalid email address "info-at-example.com", linked from https://example.com/a`},
+		{Check: CheckMalformedContact, URL: "tel:call-us", Detail: `invalid phone number "call-us", linked from https://example.com/a`},
+	}, findings)
+}
@@ -0,0 +1,257 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+const (
+	CheckMissingCSP     = "missing-csp"
+	CheckUnsafeCSP      = "unsafe-csp"
+	CheckUnlistedSource = "csp-unlisted-source"
+)
+
+const cspHeader = "Content-Security-Policy"
+
+// cspFallbacks lists the directives governing each kind of resource, most specific first
+var cspFallbacks = map[string][]string{
+	extractor.ResourceScript: {"script-src", "default-src"},
+	extractor.ResourceStyle:  {"style-src", "default-src"},
+	extractor.ResourceImage:  {"img-src", "default-src"},
+	extractor.ResourceFrame:  {"frame-src", "child-src", "default-src"},
+	extractor.ResourceMedia:  {"media-src", "default-src"},
+	extractor.ResourceObject: {"object-src", "default-src"},
+	extractor.ResourceFont:   {"font-src", "default-src"},
+}
+
+// Resource is a third party origin a page loads content from, kept when AUDIT_CHECK_CSP is set
+type Resource struct {
+	Kind   string `json:"kind"`
+	Origin string `json:"origin"`
+}
+
+// CSPCheck reports HTML pages without a Content-Security-Policy, policies allowing inline or
+// eval'd script, and third party origins a page loads from that its policy does not allow
+type CSPCheck struct{}
+
+func (CSPCheck) Name() string {
+	return "csp"
+}
+
+func (CSPCheck) Run(ctx context.Context, pages []Page) ([]Finding, error) {
+	findings := []Finding{}
+	for _, page := range pages {
+		if page.StatusCode < 200 || page.StatusCode > 299 {
+			continue
+		}
+		if mediaType, _, _ := mime.ParseMediaType(page.Headers["Content-Type"]); mediaType != "" && mediaType != "text/html" {
+			continue
+		}
+		findings = append(findings, cspFindings(page)...)
+	}
+	return findings, nil
+}
+
+func cspFindings(page Page) []Finding {
+	value := page.Headers[cspHeader]
+	if strings.TrimSpace(value) == "" {
+		return []Finding{{Check: CheckMissingCSP, URL: page.URL, Detail: "no Content-Security-Policy header"}}
+	}
+	policies := parseCSP(value)
+	findings := []Finding{}
+	for _, detail := range unsafeSources(policies) {
+		findings = append(findings, Finding{Check: CheckUnsafeCSP, URL: page.URL, Detail: detail})
+	}
+	reported := map[string]bool{}
+	for _, resource := range page.ThirdParty {
+		for _, policy := range policies {
+			directive, sources, ok := policy.governing(resource.Kind)
+			if !ok || allowsOrigin(sources, resource.Origin) {
+				continue
+			}
+			// Host allowlists are ignored for scripts trusted through a nonce or hash
+			if resource.Kind == extractor.ResourceScript && containsFold(sources, "'strict-dynamic'") {
+				continue
+			}
+			detail := fmt.Sprintf("%s does not allow %s from %s", directive, resource.Kind, resource.Origin)
+			if !reported[detail] {
+				reported[detail] = true
+				findings = append(findings, Finding{Check: CheckUnlistedSource, URL: page.URL, Detail: detail})
+			}
+		}
+	}
+	return findings
+}
+
+// cspPolicy maps lower case directive names to their source lists
+type cspPolicy map[string][]string
+
+// parseCSP splits a header into its policies, which are separated by commas when a response
+// sends more than one and must all be satisfied
+func parseCSP(value string) []cspPolicy {
+	policies := []cspPolicy{}
+	for _, serialised := range strings.Split(value, ",") {
+		policy := cspPolicy{}
+		for _, directive := range strings.Split(serialised, ";") {
+			fields := strings.Fields(directive)
+			if len(fields) == 0 {
+				continue
+			}
+			name := strings.ToLower(fields[0])
+			// Browsers ignore repeated directives
+			if _, ok := policy[name]; !ok {
+				policy[name] = fields[1:]
+			}
+		}
+		if len(policy) > 0 {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+func (p cspPolicy) governing(kind string) (string, []string, bool) {
+	for _, directive := range cspFallbacks[kind] {
+		if sources, ok := p[directive]; ok {
+			return directive, sources, true
+		}
+	}
+	return "", nil, false
+}
+
+// unsafeSources reports 'unsafe-inline' for scripts and styles and 'unsafe-eval' for scripts.
+// Browsers ignore 'unsafe-inline' when a nonce or hash is listed alongside it.
+func unsafeSources(policies []cspPolicy) []string {
+	details := []string{}
+	for _, policy := range policies {
+		for _, kind := range []string{extractor.ResourceScript, extractor.ResourceStyle} {
+			directive, sources, ok := policy.governing(kind)
+			if !ok {
+				continue
+			}
+			keywords := []string{"'unsafe-inline'"}
+			if kind == extractor.ResourceScript {
+				keywords = append(keywords, "'unsafe-eval'")
+			}
+			for _, keyword := range keywords {
+				if !containsFold(sources, keyword) || (keyword == "'unsafe-inline'" && hasNonceOrHash(sources)) {
+					continue
+				}
+				if detail := directive + " allows " + keyword; !slices.Contains(details, detail) {
+					details = append(details, detail)
+				}
+			}
+		}
+	}
+	return details
+}
+
+// allowsOrigin matches origin against wildcard, scheme and host sources. Paths in host sources
+// are not compared as only the origin of each resource is kept.
+func allowsOrigin(sources []string, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	for _, source := range sources {
+		source = strings.ToLower(source)
+		switch {
+		case source == "*":
+			return true
+		case strings.HasPrefix(source, "'"):
+			// Keywords, nonces and hashes never list a third party origin
+		case strings.HasSuffix(source, ":") && !strings.Contains(source, "/"):
+			if schemeAllowed(strings.TrimSuffix(source, ":"), u.Scheme) {
+				return true
+			}
+		default:
+			if hostSourceMatches(source, u) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hostSourceMatches(source string, u *url.URL) bool {
+	scheme, rest, ok := strings.Cut(source, "://")
+	if !ok {
+		scheme, rest = "", source
+	}
+	if scheme != "" && !schemeAllowed(scheme, u.Scheme) {
+		return false
+	}
+	hostPort, _, _ := strings.Cut(rest, "/")
+	host, port := hostPort, ""
+	if i := strings.LastIndex(hostPort, ":"); i >= 0 && !strings.HasSuffix(hostPort, "]") {
+		host, port = hostPort[:i], hostPort[i+1:]
+	}
+	// Without a port only the default port of the scheme matches
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+	if port != "*" && port != effectivePort(u) {
+		return false
+	}
+	hostname := strings.ToLower(u.Hostname())
+	if wildcard, ok := strings.CutPrefix(host, "*."); ok {
+		return strings.HasSuffix(hostname, "."+wildcard)
+	}
+	return host == hostname
+}
+
+// schemeAllowed lets a source for http also match https, as browsers upgrade it
+func schemeAllowed(source, scheme string) bool {
+	return source == scheme || (source == "http" && scheme == "https")
+}
+
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	return defaultPort(u.Scheme)
+}
+
+func defaultPort(scheme string) string {
+	if scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
+
+func hasNonceOrHash(sources []string) bool {
+	return slices.ContainsFunc(sources, func(source string) bool {
+		source = strings.ToLower(source)
+		return strings.HasPrefix(source, "'nonce-") || strings.HasPrefix(source, "'sha256-") ||
+			strings.HasPrefix(source, "'sha384-") || strings.HasPrefix(source, "'sha512-")
+	})
+}
+
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
+
+// thirdPartyResources keeps the distinct origins of resources loaded from other origins than u
+func thirdPartyResources(u *url.URL, resources []extractor.Resource) []Resource {
+	pageOrigin := u.Scheme + "://" + strings.ToLower(u.Host)
+	found := []Resource{}
+	for _, r := range resources {
+		ru, err := url.Parse(r.URL)
+		if err != nil || (ru.Scheme != "http" && ru.Scheme != "https") {
+			continue
+		}
+		resource := Resource{Kind: r.Kind, Origin: ru.Scheme + "://" + strings.ToLower(ru.Host)}
+		if resource.Origin != pageOrigin && !slices.Contains(found, resource) {
+			found = append(found, resource)
+		}
+	}
+	return found
+}
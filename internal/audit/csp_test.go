@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestCSPCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		thirdParty []Resource
+		want       []string
+	}{
+		{name: "no policy", headers: map[string]string{"Content-Type": "text/html"}, want: []string{CheckMissingCSP}},
+		{name: "assets need no policy", headers: map[string]string{"Content-Type": "image/png"}, want: nil},
+		{name: "strict policy", headers: map[string]string{cspHeader: "default-src 'self'"}, want: nil},
+		{name: "unsafe inline and eval", headers: map[string]string{cspHeader: "script-src 'self' 'unsafe-inline' 'unsafe-eval'"}, want: []string{CheckUnsafeCSP, CheckUnsafeCSP}},
+		{name: "unsafe inline with a nonce", headers: map[string]string{cspHeader: "script-src 'nonce-abc' 'unsafe-inline'"}, want: nil},
+		{name: "unsafe inline styles", headers: map[string]string{cspHeader: "default-src 'self'; style-src 'self' 'unsafe-inline'"}, want: []string{CheckUnsafeCSP}},
+		{
+			name:       "listed sources",
+			headers:    map[string]string{cspHeader: "default-src 'self'; script-src https://cdn.example.net:443/js/; img-src *.images.example.org; frame-src https:"},
+			thirdParty: []Resource{{Kind: "script", Origin: "https://cdn.example.net"}, {Kind: "image", Origin: "https://a.images.example.org"}, {Kind: "frame", Origin: "https://www.youtube.com"}},
+			want:       nil,
+		},
+		{
+			name:       "unlisted sources",
+			headers:    map[string]string{cspHeader: "default-src 'self'; img-src images.example.org"},
+			thirdParty: []Resource{{Kind: "script", Origin: "https://cdn.example.net"}, {Kind: "image", Origin: "https://images.example.org:8443"}, {Kind: "style", Origin: "https://fonts.example.com"}},
+			want:       []string{CheckUnlistedSource, CheckUnlistedSource, CheckUnlistedSource},
+		},
+		{
+			name:       "every policy must allow a source",
+			headers:    map[string]string{cspHeader: "script-src *, script-src 'self'"},
+			thirdParty: []Resource{{Kind: "script", Origin: "https://cdn.example.net"}},
+			want:       []string{CheckUnlistedSource},
+		},
+		{
+			name:       "strict-dynamic ignores host lists",
+			headers:    map[string]string{cspHeader: "script-src 'nonce-abc' 'strict-dynamic'"},
+			thirdParty: []Resource{{Kind: "script", Origin: "https://cdn.example.net"}},
+			want:       nil,
+		},
+		{
+			name:       "none allows nothing",
+			headers:    map[string]string{cspHeader: "object-src 'none'"},
+			thirdParty: []Resource{{Kind: "object", Origin: "https://plugins.example.net"}},
+			want:       []string{CheckUnlistedSource},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			findings, err := CSPCheck{}.Run(context.Background(), []Page{
+				{URL: "https://example.com/", StatusCode: http.StatusOK, Headers: test.headers, ThirdParty: test.thirdParty},
+				{URL: "https://example.com/missing", StatusCode: http.StatusNotFound},
+			})
+			require.NoError(t, err)
+			var checks []string
+			for _, f := range findings {
+				require.Equal(t, "https://example.com/", f.URL)
+				checks = append(checks, f.Check)
+			}
+			require.Equal(t, test.want, checks)
+		})
+	}
+}
+
+func TestAudit_RecordsThirdParty(t *testing.T) {
+	response := successResponse(`<script src="https://cdn.example.net/app.js"></script><script src="/local.js"></script>` +
+		`<link rel="stylesheet" href="https://cdn.example.net/site.css"><img src="https://cdn.example.net/logo.png">`)
+	response.Header = http.Header{cspHeader: []string{"default-src 'self'", "img-src *"}}
+	fetcher := &mockFetcher{responses: map[string]*http.Response{"https://example.com": response}}
+	c := testConfig
+	c.RespectRobots = false
+	c.CheckCSP = true
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	page := a.Pages()[0]
+	require.Equal(t, "default-src 'self', img-src *", page.Headers[cspHeader])
+	require.Equal(t, []Resource{
+		{Kind: "script", Origin: "https://cdn.example.net"},
+		{Kind: "style", Origin: "https://cdn.example.net"},
+		{Kind: "image", Origin: "https://cdn.example.net"},
+	}, page.ThirdParty)
+	findings, err := CSPCheck{}.Run(context.Background(), a.Pages())
+	require.NoError(t, err)
+	require.Len(t, findings, 3)
+}
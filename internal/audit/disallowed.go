@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const CheckLinkedDisallowed = "linked-but-disallowed"
+
+// maxDetailReferrers caps how many referrers a finding lists, as navigation links can come from
+// every page
+const maxDetailReferrers = 5
+
+// DisallowedLink is an internal url that was not crawled because robots.txt disallows it, along
+// with the pages linking to it
+type DisallowedLink struct {
+	URL       string   `json:"url"`
+	Referrers []string `json:"referrers"`
+}
+
+// Disallowed returns the internal urls skipped because of robots.txt, sorted by url
+func (a *Audit) Disallowed() []DisallowedLink {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.disallowedLinks()
+}
+
+// disallowedLinks must be called with a.mu held
+func (a *Audit) disallowedLinks() []DisallowedLink {
+	links := make([]DisallowedLink, 0, len(a.disallowed))
+	for u, referrers := range a.disallowed {
+		links = append(links, DisallowedLink{URL: u, Referrers: slices.Sorted(maps.Keys(referrers))})
+	}
+	slices.SortFunc(links, func(x, y DisallowedLink) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	return links
+}
+
+// recordDisallowed must be called with a.mu held
+func (a *Audit) recordDisallowed(u, referrer string) {
+	referrers, ok := a.disallowed[u]
+	if !ok {
+		referrers = map[string]struct{}{}
+		a.disallowed[u] = referrers
+	}
+	referrers[referrer] = struct{}{}
+}
+
+func disallowedFindings(links []DisallowedLink) []Finding {
+	findings := make([]Finding, 0, len(links))
+	for _, link := range links {
+		shown := link.Referrers[:min(len(link.Referrers), maxDetailReferrers)]
+		detail := "disallowed by robots.txt, linked from " + strings.Join(shown, ", ")
+		if more := len(link.Referrers) - len(shown); more > 0 {
+			detail += fmt.Sprintf(" and %d more", more)
+		}
+		findings = append(findings, Finding{Check: CheckLinkedDisallowed, URL: link.URL, Detail: detail})
+	}
+	return findings
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	EnvironmentStaging    = "staging"
+	EnvironmentProduction = "production"
+)
+
+const (
+	CheckStagingIndexable    = "staging-indexable"
+	CheckLeftoverNoIndex     = "leftover-noindex"
+	CheckLeftoverAuth        = "leftover-auth"
+	CheckLeftoverDisallowAll = "leftover-disallow-all"
+)
+
+const authenticateHeader = "WWW-Authenticate"
+
+// environmentFindings must be called with a.mu held. Staging should keep every page out of search
+// engines with noindex or authentication, while production should have no such leftovers.
+func (a *Audit) environmentFindings() []Finding {
+	noIndex := map[string]string{}
+	for _, f := range a.checkFindings {
+		if f.Check == CheckNoIndex {
+			noIndex[f.URL] = f.Detail
+		}
+	}
+	findings := []Finding{}
+	for u, code := range a.statuses {
+		challenge := a.headers[u][http.CanonicalHeaderKey(authenticateHeader)]
+		switch a.config.Environment {
+		case EnvironmentStaging:
+			if code >= http.StatusOK && code < http.StatusMultipleChoices && noIndex[u] == "" {
+				findings = append(findings, Finding{Check: CheckStagingIndexable, URL: u, Detail: "served without noindex or authentication"})
+			}
+		case EnvironmentProduction:
+			if detail := noIndex[u]; detail != "" {
+				findings = append(findings, Finding{Check: CheckLeftoverNoIndex, URL: u, Detail: detail})
+			}
+			if code == http.StatusUnauthorized && challenge != "" {
+				findings = append(findings, Finding{Check: CheckLeftoverAuth, URL: u, Detail: fmt.Sprintf("requires authentication (%s: %s)", authenticateHeader, challenge)})
+			}
+		}
+	}
+	if a.config.Environment == EnvironmentProduction && a.robotsData != nil && !a.robotsData.TestAgent("/", a.config.Agent) {
+		robotsURL := a.startURL.Scheme + "://" + a.startURL.Host + "/robots.txt"
+		findings = append(findings, Finding{Check: CheckLeftoverDisallowAll, URL: robotsURL, Detail: "robots.txt disallows the whole site"})
+	}
+	return findings
+}
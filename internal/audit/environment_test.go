@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_EnvironmentChecks(t *testing.T) {
+	environmentFindings := func(a *Audit) []Finding {
+		findings := []Finding{}
+		for _, f := range a.Findings() {
+			switch f.Check {
+			case CheckStagingIndexable, CheckLeftoverNoIndex, CheckLeftoverAuth, CheckLeftoverDisallowAll:
+				findings = append(findings, f)
+			}
+		}
+		return findings
+	}
+	newFetcher := func() *mockFetcher {
+		hidden := successResponse("")
+		hidden.Header = http.Header{"X-Robots-Tag": []string{"noindex"}}
+		admin := buildResponse("", http.StatusUnauthorized)
+		admin.Header = http.Header{"Www-Authenticate": []string{`Basic realm="staging"`}}
+		return &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com":        successResponse(`<meta name="robots" content="noindex"><a href="/open">open</a><a href="/hidden">hidden</a><a href="/admin">admin</a>`),
+			"https://example.com/open":   successResponse(""),
+			"https://example.com/hidden": hidden,
+			"https://example.com/admin":  admin,
+		}}
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.Environment = EnvironmentStaging
+	a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []Finding{{Check: CheckStagingIndexable, URL: "https://example.com/open", Detail: "served without noindex or authentication"}}, environmentFindings(a))
+
+	c.Environment = EnvironmentProduction
+	a, err = New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []Finding{
+		{Check: CheckLeftoverAuth, URL: "https://example.com/admin", Detail: `requires authentication (WWW-Authenticate: Basic realm="staging")`},
+		{Check: CheckLeftoverNoIndex, URL: "https://example.com/", Detail: `<meta name="robots"> noindex`},
+		{Check: CheckLeftoverNoIndex, URL: "https://example.com/hidden", Detail: "X-Robots-Tag: noindex"},
+	}, environmentFindings(a))
+
+	t.Run("robots.txt disallowing everything", func(t *testing.T) {
+		c := testConfig
+		c.Environment = EnvironmentProduction
+		a, err := New(c, &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com/robots.txt": successResponse("User-agent: *\nDisallow: /"),
+		}}, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		require.NoError(t, a.Start(context.Background()))
+		require.Contains(t, environmentFindings(a), Finding{Check: CheckLeftoverDisallowAll, URL: "https://example.com/robots.txt", Detail: "robots.txt disallows the whole site"})
+	})
+}
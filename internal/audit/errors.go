@@ -5,14 +5,61 @@ import "errors"
 var (
 	ErrInvalidStartURL    = errors.New("invalid start url")
 	ErrInvalidStartScheme = errors.New("invalid start url scheme")
+	ErrInvalidSitemapURL  = errors.New("invalid sitemap url")
+	ErrInvalidLogFormat   = errors.New("invalid log format")
 )
 
 var (
-	ErrInvalidMaxWorkers = errors.New("invaild max workers")
-	ErrInvalidMaxDepth   = errors.New("invalid max depth")
+	ErrInvalidMaxWorkers        = errors.New("invaild max workers")
+	ErrInvalidMaxDepth          = errors.New("invalid max depth")
+	ErrInvalidMaxRedirects      = errors.New("invalid max redirects")
+	ErrInvalidConsentCookies    = errors.New("invalid consent cookies")
+	ErrInvalidLatency           = errors.New("invalid adaptive latency")
+	ErrInvalidVisitedMode       = errors.New("invalid visited mode")
+	ErrInvalidFrontier          = errors.New("invalid frontier")
+	ErrInvalidTrapLimit         = errors.New("invalid trap limit")
+	ErrInvalidSegmentBy         = errors.New("invalid segment by")
+	ErrInvalidQueryParamSamples = errors.New("invalid query param samples")
+	ErrInvalidEnvironment       = errors.New("invalid environment")
+	ErrInvalidMinifyThreshold   = errors.New("invalid minify threshold")
+	ErrInvalidMinScore          = errors.New("invalid min score")
+	ErrInvalidScreenshotLimit   = errors.New("invalid screenshot limit")
 )
 
 var (
 	ErrNoFetcher   = errors.New("no fetcher provided")
 	ErrNoExtractor = errors.New("no extractor provided")
 )
+
+var (
+	ErrThresholdExceeded = errors.New("threshold exceeded")
+	ErrInvalidBaseline   = errors.New("invalid baseline")
+	ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+	ErrInvalidGraphLog   = errors.New("invalid graph log")
+)
+
+var (
+	ErrInvalidWebhookURL     = errors.New("invalid webhook url")
+	ErrInvalidWebhookRetries = errors.New("invalid webhook retries")
+)
+
+var ErrUnknownPreset = errors.New("unknown preset")
+
+var ErrBodyTooLarge = errors.New("response body too large")
+
+var ErrInvalidDNSCache = errors.New("invalid dns cache")
+
+var ErrInvalidAssetManifest = errors.New("invalid asset manifest")
+
+var ErrInvalidChecksFile = errors.New("invalid checks file")
+
+var ErrInvalidRedirectsFile = errors.New("invalid redirects file")
+
+var ErrInvalidSeedsFile = errors.New("invalid seeds file")
+
+var ErrInvalidQuotas = errors.New("invalid crawl quotas")
+
+var (
+	ErrInvalidSeed   = errors.New("invalid seed url")
+	ErrCrawlFinished = errors.New("crawl has finished")
+)
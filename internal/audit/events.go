@@ -0,0 +1,78 @@
+package audit
+
+import "net/url"
+
+// eventBuffer is how far a subscriber can fall behind before events are dropped for it
+const eventBuffer = 256
+
+// PageEvent is published for each page as it is crawled
+type PageEvent struct {
+	URL      string    `json:"url"`
+	Status   int       `json:"status,omitempty"`
+	Depth    int       `json:"depth"`
+	Error    string    `json:"error,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Subscribe returns a channel receiving an event for every page crawled from now on, closed once
+// the audit is done, and a function to stop receiving them. A subscriber that falls behind misses
+// events rather than slowing the crawl down.
+func (a *Audit) Subscribe() (<-chan PageEvent, func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	events := make(chan PageEvent, eventBuffer)
+	if a.done || (a.cancelled && a.cancel == nil) {
+		close(events)
+		return events, func() {}
+	}
+	a.subscribers[events] = struct{}{}
+	return events, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if _, ok := a.subscribers[events]; ok {
+			delete(a.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// findingsMark is where the findings recorded from now on start, for publishPage to pick out those
+// of the page being crawled
+func (a *Audit) findingsMark() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.checkFindings)
+}
+
+// publishPage sends subscribers the outcome of crawling u along with the findings recorded for it
+// since mark
+func (a *Audit) publishPage(u *url.URL, depth, status int, fetchErr error, mark int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.subscribers) == 0 {
+		return
+	}
+	event := PageEvent{URL: a.canonicalURL(u), Status: status, Depth: depth}
+	if fetchErr != nil {
+		event.Error = fetchErr.Error()
+	}
+	for _, f := range a.checkFindings[mark:] {
+		if f.URL == event.URL {
+			event.Findings = append(event.Findings, f)
+		}
+	}
+	for events := range a.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// closeSubscribers must be called with a.mu held
+func (a *Audit) closeSubscribers() {
+	for events := range a.subscribers {
+		close(events)
+	}
+	clear(a.subscribers)
+}
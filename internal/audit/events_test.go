@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_Subscribe(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	noIndex := successResponse("")
+	noIndex.Header = http.Header{"X-Robots-Tag": []string{"noindex"}}
+	fetcher := &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":        successResponse(`<a href="/hidden">hidden</a><a href="/missing">missing</a>`),
+		"https://example.com/hidden": noIndex,
+	}}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	events, unsubscribe := a.Subscribe()
+	defer unsubscribe()
+	require.NoError(t, a.Start(context.Background()))
+	got := map[string]PageEvent{}
+	for event := range events {
+		got[event.URL] = event
+	}
+	require.Len(t, got, 3)
+	require.Equal(t, PageEvent{URL: "https://example.com/", Status: http.StatusOK}, got["https://example.com/"])
+	require.Equal(t, PageEvent{URL: "https://example.com/missing", Status: http.StatusNotFound, Depth: 1}, got["https://example.com/missing"])
+	require.Equal(t, []Finding{{Check: CheckNoIndex, URL: "https://example.com/hidden", Detail: "X-Robots-Tag: noindex"}}, got["https://example.com/hidden"].Findings)
+	late, _ := a.Subscribe()
+	_, open := <-late
+	require.False(t, open)
+	t.Run("cancelled before starting", func(t *testing.T) {
+		a, err := New(c, &mockFetcher{}, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		events, _ := a.Subscribe()
+		a.Cancel()
+		_, open := <-events
+		require.False(t, open)
+	})
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// Failure is the cause a url could not be crawled, recorded so failures can be broken down by it
+type Failure string
+
+const (
+	FailureDNS             Failure = "dns"
+	FailureTLS             Failure = "tls"
+	FailureTimeout         Failure = "timeout"
+	FailureConnectionReset Failure = "connection-reset"
+	FailureClientError     Failure = "4xx"
+	FailureServerError     Failure = "5xx"
+	FailureParse           Failure = "parse"
+	FailureOther           Failure = "other"
+)
+
+// classifyError works out the cause of a failed fetch from the errors it wraps
+func classifyError(err error) Failure {
+	var (
+		dnsErr       *net.DNSError
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		certificate  x509.CertificateInvalidError
+		netErr       net.Error
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return FailureDNS
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr), errors.As(err, &certificate):
+		return FailureTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return FailureTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+		return FailureConnectionReset
+	}
+	return FailureOther
+}
+
+// classifyStatus is the failure a status code stands for, or empty when it is not an error
+func classifyStatus(code int) Failure {
+	switch {
+	case code >= http.StatusInternalServerError:
+		return FailureServerError
+	case code >= http.StatusBadRequest:
+		return FailureClientError
+	}
+	return ""
+}
+
+func (a *Audit) recordFailure(u string, failure Failure) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failures[u] = failure
+}
+
+// Failures returns how many urls failed with each cause
+func (a *Audit) Failures() map[Failure]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.failureCounts()
+}
+
+// failureCounts must be called with a.mu held
+func (a *Audit) failureCounts() map[Failure]int {
+	counts := make(map[Failure]int)
+	for _, failure := range a.failures {
+		counts[failure]++
+	}
+	return counts
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+type failingFetcher struct {
+	mockFetcher
+	errs map[string]error
+}
+
+func (f *failingFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	if err, ok := f.errs[u.String()]; ok {
+		return nil, err
+	}
+	return f.mockFetcher.Fetch(ctx, u)
+}
+
+func TestAudit_Failures(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	fetcher := &failingFetcher{
+		mockFetcher: mockFetcher{responses: map[string]*http.Response{
+			"https://example.com":        successResponse(`<a href="/dns">a</a><a href="/reset">b</a><a href="/slow">c</a><a href="/missing">d</a><a href="/down">e</a><a href="/broken">f</a>`),
+			"https://example.com/down":   buildResponse("", http.StatusServiceUnavailable),
+			"https://example.com/broken": {StatusCode: http.StatusOK, Body: io.NopCloser(iotest.ErrReader(errors.New("unexpected end of body")))},
+		}},
+		errs: map[string]error{
+			"https://example.com/dns":   &url.Error{Op: "Get", URL: "https://example.com/dns", Err: &net.DNSError{Err: "no such host", Name: "example.com"}},
+			"https://example.com/reset": &url.Error{Op: "Get", URL: "https://example.com/reset", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}},
+			"https://example.com/slow":  &url.Error{Op: "Get", URL: "https://example.com/slow", Err: context.DeadlineExceeded},
+		},
+	}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	want := map[Failure]int{FailureDNS: 1, FailureConnectionReset: 1, FailureTimeout: 1, FailureClientError: 1, FailureServerError: 1, FailureParse: 1}
+	require.Equal(t, want, a.Failures())
+	require.Equal(t, want, a.Summary().Failures)
+	nodes := a.Nodes()
+	require.Equal(t, FailureDNS, nodes["https://example.com/dns"].Failure)
+	require.Equal(t, FailureClientError, nodes["https://example.com/missing"].Failure)
+	require.Equal(t, FailureParse, nodes["https://example.com/broken"].Failure)
+	require.Empty(t, nodes["https://example.com/"].Failure)
+	t.Run("kept in checkpoints", func(t *testing.T) {
+		resumed, err := Resume(a.Checkpoint(), fetcher, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		require.Equal(t, want, resumed.Failures())
+	})
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Failure
+	}{
+		{err: &net.DNSError{Err: "no such host"}, want: FailureDNS},
+		{err: fmt.Errorf("get: %w", x509.UnknownAuthorityError{}), want: FailureTLS},
+		{err: &tls.CertificateVerificationError{Err: errors.New("expired")}, want: FailureTLS},
+		{err: os.ErrDeadlineExceeded, want: FailureTimeout},
+		{err: syscall.ECONNRESET, want: FailureConnectionReset},
+		{err: syscall.ECONNREFUSED, want: FailureOther},
+		{err: errors.New("stopped after 10 redirects"), want: FailureOther},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, classifyError(tt.err), tt.err.Error())
+	}
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// maxGroupExamples caps how many urls a finding group lists
+const maxGroupExamples = 5
+
+// FindingGroup is the findings of one check on pages built from the same template
+type FindingGroup struct {
+	Check    string `json:"check"`
+	Template string `json:"template"`
+	Severity string `json:"severity,omitempty"`
+	// Count is how many findings the group holds once url variants are merged
+	Count int `json:"count"`
+	// Duplicates is how many findings were merged into others as variants of their url
+	Duplicates int      `json:"duplicates"`
+	Examples   []string `json:"examples"`
+}
+
+func (a *Audit) FindingGroups() []FindingGroup {
+	return GroupFindings(a.Findings())
+}
+
+// GroupFindings merges findings of the same check and detail on variants of a url, differing only
+// by query, fragment or trailing slash, then groups what is left by check and url template. Groups
+// are ordered by count, largest first.
+func GroupFindings(findings []Finding) []FindingGroup {
+	type variantKey struct{ check, detail, variant string }
+	unique := []Finding{}
+	duplicates := map[int]int{}
+	seen := map[variantKey]int{}
+	for _, finding := range findings {
+		key := variantKey{finding.Check, finding.Detail, urlVariant(finding.URL)}
+		if i, ok := seen[key]; ok {
+			duplicates[i]++
+			continue
+		}
+		seen[key] = len(unique)
+		unique = append(unique, finding)
+	}
+	urls := make([]string, len(unique))
+	for i, finding := range unique {
+		urls[i] = finding.URL
+	}
+	templates := templatePatterns(urls)
+	type groupKey struct{ check, template string }
+	groups := map[groupKey]*FindingGroup{}
+	for i, finding := range unique {
+		template, ok := templates[finding.URL]
+		if !ok {
+			template = finding.URL
+		}
+		key := groupKey{finding.Check, template}
+		g, ok := groups[key]
+		if !ok {
+			g = &FindingGroup{Check: finding.Check, Template: template, Severity: finding.Severity}
+			groups[key] = g
+		}
+		g.Count++
+		g.Duplicates += duplicates[i]
+		if len(g.Examples) < maxGroupExamples {
+			g.Examples = append(g.Examples, finding.URL)
+		}
+	}
+	grouped := make([]FindingGroup, 0, len(groups))
+	for _, g := range groups {
+		grouped = append(grouped, *g)
+	}
+	slices.SortFunc(grouped, func(x, y FindingGroup) int {
+		return cmp.Or(cmp.Compare(y.Count, x.Count), strings.Compare(x.Check, y.Check), strings.Compare(x.Template, y.Template))
+	})
+	return grouped
+}
+
+// urlVariant drops the parts of u that usually reach the same page
+func urlVariant(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	parsed.RawQuery, parsed.Fragment, parsed.RawFragment = "", "", ""
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	return parsed.String()
+}
@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupFindings(t *testing.T) {
+	findings := []Finding{
+		{Check: CheckBrokenLink, URL: "https://example.com/product/a", Detail: "status 404"},
+		{Check: CheckBrokenLink, URL: "https://example.com/product/a/", Detail: "status 404"},
+		{Check: CheckBrokenLink, URL: "https://example.com/product/a?ref=nav", Detail: "status 404"},
+		{Check: CheckBrokenLink, URL: "https://example.com/product/b", Detail: "status 404"},
+		{Check: CheckBrokenLink, URL: "https://example.com/product/c", Detail: "status 500"},
+		{Check: CheckBrokenLink, URL: "https://example.com/about", Detail: "status 404"},
+		{Check: CheckMetaRefresh, URL: "https://example.com/product/a", Detail: "refreshes to /", Severity: "warning"},
+	}
+	require.Equal(t, []FindingGroup{
+		{Check: CheckBrokenLink, Template: "/product/{slug}", Count: 3, Duplicates: 2, Examples: []string{"https://example.com/product/a", "https://example.com/product/b", "https://example.com/product/c"}},
+		{Check: CheckBrokenLink, Template: "/about", Count: 1, Examples: []string{"https://example.com/about"}},
+		{Check: CheckMetaRefresh, Template: "/product/{slug}", Severity: "warning", Count: 1, Examples: []string{"https://example.com/product/a"}},
+	}, GroupFindings(findings))
+}
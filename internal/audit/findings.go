@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/policy"
+)
+
+const (
+	CheckBrokenLink   = "broken-link"
+	CheckBodyTooLarge = "body-too-large"
+	CheckMetaRefresh  = "meta-refresh"
+)
+
+type Finding struct {
+	Check  string `json:"check"`
+	URL    string `json:"url"`
+	Detail string `json:"detail"`
+	// Severity is only set when AUDIT_CHECKS_FILE gives one for the check
+	Severity string `json:"severity,omitempty"`
+}
+
+func (f Finding) Key() string {
+	return f.Check + " " + f.URL
+}
+
+func (a *Audit) Findings() []Finding {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	findings := append([]Finding{}, a.checkFindings...)
+	findings = append(findings, brokenLinkFindings(a.statuses, a.broken)...)
+	findings = append(findings, disallowedFindings(a.disallowedLinks())...)
+	if a.config.SitemapOnly {
+		findings = append(findings, a.sitemapFindings()...)
+	}
+	if a.config.Environment != "" {
+		findings = append(findings, a.environmentFindings()...)
+	}
+	findings = append(findings, a.mediaAlternateFindings()...)
+	findings = append(findings, a.contactFindings()...)
+	findings = append(findings, a.robotsFindings()...)
+	findings = append(findings, a.redirectFindings()...)
+	if a.assets != nil {
+		findings = append(findings, a.unreferencedAssetFindings()...)
+	}
+	findings = a.checkConfig.Apply(findings)
+	sortFindings(findings)
+	return findings
+}
+
+// BrokenLinkFindings reports pages that returned a 4xx or 5xx status
+func BrokenLinkFindings(pages []Page) []Finding {
+	statuses := make(map[string]int, len(pages))
+	for _, page := range pages {
+		statuses[page.URL] = page.StatusCode
+	}
+	findings := brokenLinkFindings(statuses, func(_ string, code int) bool {
+		return isFailure(code)
+	})
+	sortFindings(findings)
+	return findings
+}
+
+func brokenLinkFindings(statuses map[string]int, broken func(u string, code int) bool) []Finding {
+	findings := []Finding{}
+	for u, code := range statuses {
+		if !broken(u, code) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Check:  CheckBrokenLink,
+			URL:    u,
+			Detail: fmt.Sprintf("status %d", code),
+		})
+	}
+	return findings
+}
+
+// broken applies the status code rules of the crawl policies matching u to isFailure
+func (a *Audit) broken(u string, code int) bool {
+	if parsed, err := url.Parse(u); err == nil {
+		switch a.policies.Treatment(parsed, code) {
+		case policy.TreatOK, policy.TreatExpected:
+			return false
+		case policy.TreatBroken:
+			return true
+		}
+	}
+	return isFailure(code)
+}
+
+func sortFindings(findings []Finding) {
+	slices.SortFunc(findings, func(x, y Finding) int {
+		return strings.Compare(x.Key(), y.Key())
+	})
+}
+
+func (a *Audit) NewFindings() []Finding {
+	findings := a.Findings()
+	if a.baseline == nil {
+		return findings
+	}
+	return slices.DeleteFunc(findings, a.baseline.Contains)
+}
@@ -0,0 +1,203 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/salsgithub/godst/queue"
+)
+
+// frontier is a task queue holding up to limit tasks in memory. Once full, later tasks are
+// appended to segment files on disk and read back a segment at a time as memory drains.
+type frontier struct {
+	memory   *hostQueues
+	limit    int
+	dir      string
+	spillDir string
+	segments []*segment
+	sequence int
+	writer   *os.File
+	encoder  *json.Encoder
+	onDisk   int
+	logger   *slog.Logger
+}
+
+type segment struct {
+	path  string
+	count int
+}
+
+func newFrontier(limit int, dir string, logger *slog.Logger) *frontier {
+	return &frontier{
+		memory: newHostQueues(),
+		limit:  limit,
+		dir:    dir,
+		logger: logger,
+	}
+}
+
+func (f *frontier) Enqueue(t *task) {
+	if f.limit <= 0 || (f.onDisk == 0 && f.memory.Len() < f.limit) {
+		f.memory.Enqueue(t)
+		return
+	}
+	if err := f.spill(t); err != nil {
+		f.logger.Error("Frontier spill failed, keeping task in memory", "url", t.rawURL, "err", err)
+		f.memory.Enqueue(t)
+	}
+}
+
+func (f *frontier) Dequeue() (*task, bool) {
+	if f.memory.IsEmpty() && f.onDisk > 0 {
+		f.load()
+	}
+	return f.memory.Dequeue()
+}
+
+func (f *frontier) Len() int {
+	return f.memory.Len() + f.onDisk
+}
+
+func (f *frontier) IsEmpty() bool {
+	return f.Len() == 0
+}
+
+func (f *frontier) spill(t *task) error {
+	if f.writer == nil {
+		if f.spillDir == "" {
+			spillDir, err := os.MkdirTemp(f.dir, "site-audit-frontier-*")
+			if err != nil {
+				return err
+			}
+			f.spillDir = spillDir
+		}
+		f.sequence++
+		path := filepath.Join(f.spillDir, fmt.Sprintf("%08d.jsonl", f.sequence))
+		writer, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		f.segments = append(f.segments, &segment{path: path})
+		f.writer, f.encoder = writer, json.NewEncoder(writer)
+	}
+	if err := f.encoder.Encode(CheckpointTask{URL: t.rawURL, Depth: t.depth, Attempts: t.attempts}); err != nil {
+		return err
+	}
+	current := f.segments[len(f.segments)-1]
+	current.count++
+	f.onDisk++
+	if current.count >= f.limit {
+		f.closeWriter()
+	}
+	return nil
+}
+
+// load moves the oldest segment into memory, dropping any tasks that cannot be read
+func (f *frontier) load() {
+	oldest := f.segments[0]
+	f.segments = f.segments[1:]
+	if len(f.segments) == 0 {
+		f.closeWriter()
+	}
+	if err := f.readSegment(oldest.path); err != nil {
+		f.logger.Error("Frontier segment unreadable, tasks lost", "path", oldest.path, "err", err)
+	}
+	f.onDisk -= oldest.count
+	if len(f.segments) == 0 {
+		os.RemoveAll(f.spillDir)
+		f.spillDir = ""
+	}
+}
+
+func (f *frontier) readSegment(path string) error {
+	defer os.Remove(path)
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var ct CheckpointTask
+		if err := json.Unmarshal(scanner.Bytes(), &ct); err != nil {
+			return err
+		}
+		f.memory.Enqueue(&task{rawURL: intern(ct.URL), depth: ct.Depth, attempts: ct.Attempts})
+	}
+	return scanner.Err()
+}
+
+func (f *frontier) closeWriter() {
+	if f.writer == nil {
+		return
+	}
+	if err := f.writer.Close(); err != nil {
+		f.logger.Error("Frontier segment close failed", "err", err)
+	}
+	f.writer, f.encoder = nil, nil
+}
+
+// hostQueues takes a task from each host in turn, so a host with far more pages queued cannot
+// starve the others of their share of the page budget. Tasks for one host stay in FIFO order.
+type hostQueues struct {
+	queues map[string]*queue.Queue[*task]
+	turns  *queue.Queue[string]
+	len    int
+}
+
+func newHostQueues() *hostQueues {
+	return &hostQueues{
+		queues: make(map[string]*queue.Queue[*task]),
+		turns:  queue.New[string](),
+	}
+}
+
+func (h *hostQueues) Enqueue(t *task) {
+	host := taskHost(t.rawURL)
+	q, ok := h.queues[host]
+	if !ok {
+		q = queue.New[*task]()
+		h.queues[host] = q
+		h.turns.Enqueue(host)
+	}
+	q.Enqueue(t)
+	h.len++
+}
+
+func (h *hostQueues) Dequeue() (*task, bool) {
+	host, ok := h.turns.Dequeue()
+	if !ok {
+		return nil, false
+	}
+	q := h.queues[host]
+	t, _ := q.Dequeue()
+	if q.IsEmpty() {
+		delete(h.queues, host)
+	} else {
+		h.turns.Enqueue(host)
+	}
+	h.len--
+	return t, true
+}
+
+func (h *hostQueues) Len() int {
+	return h.len
+}
+
+func (h *hostQueues) IsEmpty() bool {
+	return h.len == 0
+}
+
+// taskHost returns the host of an absolute url without the cost of parsing it
+func taskHost(rawURL string) string {
+	_, rest, _ := strings.Cut(rawURL, "://")
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
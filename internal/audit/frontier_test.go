@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+	"salsgithub.com/site-audit/internal/fetcher"
+	"salsgithub.com/site-audit/internal/sitegen"
+)
+
+func TestFrontier(t *testing.T) {
+	dir := t.TempDir()
+	f := newFrontier(2, dir, slog.New(slog.DiscardHandler))
+	for i := range 7 {
+		f.Enqueue(&task{rawURL: fmt.Sprintf("https://example.com/%d", i), depth: i})
+	}
+	require.Equal(t, 7, f.Len())
+	spilled, err := filepath.Glob(filepath.Join(dir, "site-audit-frontier-*", "*.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, spilled, 3)
+	for i := range 7 {
+		next, ok := f.Dequeue()
+		require.True(t, ok)
+		require.Equal(t, fmt.Sprintf("https://example.com/%d", i), next.rawURL)
+		require.Equal(t, i, next.depth)
+		require.Equal(t, 6-i, f.Len())
+	}
+	require.True(t, f.IsEmpty())
+	remaining, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+
+	t.Run("crawl spilling to disk", func(t *testing.T) {
+		mockFetcher := &mockFetcher{
+			responses: map[string]*http.Response{
+				"https://example.com": successResponse(`<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a>`),
+			},
+		}
+		c := testConfig
+		c.RespectRobots = false
+		c.FrontierMemory = 1
+		c.FrontierDir = t.TempDir()
+		a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		a.logger = slog.New(slog.DiscardHandler)
+		require.NoError(t, a.Start(context.Background()))
+		require.Len(t, a.Pages(), 4)
+	})
+}
+
+func TestFrontier_FairAcrossHosts(t *testing.T) {
+	f := newFrontier(0, "", slog.New(slog.DiscardHandler))
+	for _, u := range []string{
+		"https://big.example.com/1",
+		"https://big.example.com/2",
+		"https://big.example.com/3",
+		"https://small.example.com/1",
+		"https://other.example.com:8443?q=1",
+		"https://small.example.com/2",
+	} {
+		f.Enqueue(&task{rawURL: u})
+	}
+	got := []string{}
+	for !f.IsEmpty() {
+		next, _ := f.Dequeue()
+		got = append(got, next.rawURL)
+	}
+	require.Equal(t, []string{
+		"https://big.example.com/1",
+		"https://small.example.com/1",
+		"https://other.example.com:8443?q=1",
+		"https://big.example.com/2",
+		"https://small.example.com/2",
+		"https://big.example.com/3",
+	}, got)
+	_, ok := f.Dequeue()
+	require.False(t, ok)
+}
+
+type queueObservingFetcher struct {
+	Fetcher
+	audit     *Audit
+	mu        sync.Mutex
+	maxQueued int
+}
+
+func (q *queueObservingFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	queued := q.audit.Progress().Queued
+	q.mu.Lock()
+	q.maxQueued = max(q.maxQueued, queued)
+	q.mu.Unlock()
+	return q.Fetcher.Fetch(ctx, u)
+}
+
+func TestAudit_MaxQueue(t *testing.T) {
+	const pages, fanOut, workers = 300, 10, 4
+	crawl := func(maxQueue int) (*Audit, int) {
+		server := httptest.NewServer(sitegen.New(sitegen.WithPages(pages), sitegen.WithFanOut(fanOut), sitegen.WithLatency(time.Millisecond)))
+		defer server.Close()
+		c := testConfig
+		c.StartURL = server.URL
+		c.RespectRobots = false
+		c.MaxWorkers = workers
+		c.MaxDepth = 5
+		c.MaxQueue = maxQueue
+		f := &queueObservingFetcher{Fetcher: fetcher.NewHTTPFetcher("agent")}
+		a, err := New(c, f, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		f.audit = a
+		a.logger = slog.New(slog.DiscardHandler)
+		require.NoError(t, a.Start(context.Background()))
+		return a, f.maxQueued
+	}
+	unbounded, unboundedQueued := crawl(0)
+	require.Len(t, unbounded.Pages(), pages)
+	require.True(t, unboundedQueued > 10)
+	bounded, boundedQueued := crawl(10)
+	require.True(t, boundedQueued <= 10, "max queued %d", boundedQueued)
+	require.True(t, len(bounded.Pages()) > 10)
+	require.True(t, bounded.queueSkipped > 0)
+	// Skipped links are still discovered
+	require.True(t, bounded.Summary().Visited > len(bounded.Pages()))
+}
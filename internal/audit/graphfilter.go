@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/salsgithub/godst/graph"
+)
+
+// GraphFilter selects the part of the site graph to keep. Unset fields keep every url, and a url
+// must pass every field that is set. Links are kept when both of their ends are.
+type GraphFilter struct {
+	// Statuses keeps urls that returned one of the codes, with 0 for urls found but not fetched
+	Statuses []int
+	// Pattern keeps urls it matches
+	Pattern *regexp.Regexp
+	// MaxDepth keeps urls found within that many links of the start url
+	MaxDepth *int
+	// Component keeps urls connected to it by links in either direction, across the whole graph
+	Component string
+}
+
+// FilteredGraph returns a copy of the part of the site graph the filter keeps, along with the
+// metadata of its nodes
+func (a *Audit) FilteredGraph(filter GraphFilter) (*graph.Graph[string], map[string]Node) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	nodes := a.nodeSnapshot()
+	var component map[string]bool
+	if filter.Component != "" {
+		component = a.component(filter.Component)
+	}
+	keep := func(u string) bool {
+		node := nodes[u]
+		switch {
+		case len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, node.StatusCode):
+			return false
+		case filter.Pattern != nil && !filter.Pattern.MatchString(u):
+			return false
+		case filter.MaxDepth != nil && node.Depth > *filter.MaxDepth:
+			return false
+		case component != nil && !component[u]:
+			return false
+		}
+		return true
+	}
+	g := graph.New[string]()
+	kept := map[string]Node{}
+	for _, node := range a.siteGraph.Nodes() {
+		if keep(node) {
+			g.AddNode(node)
+			kept[node] = nodes[node]
+		}
+	}
+	for _, node := range g.Nodes() {
+		neighbours, _ := a.siteGraph.Neighbours(node)
+		for _, neighbour := range neighbours {
+			if _, ok := kept[neighbour.Link]; ok {
+				g.AddEdge(node, neighbour.Link, neighbour.Weight)
+			}
+		}
+	}
+	return g, kept
+}
+
+// component must be called with a.mu held. It walks links both ways from u, so that pages only
+// linking into the component are part of it too.
+func (a *Audit) component(u string) map[string]bool {
+	incoming := map[string][]string{}
+	for _, node := range a.siteGraph.Nodes() {
+		neighbours, _ := a.siteGraph.Neighbours(node)
+		for _, neighbour := range neighbours {
+			incoming[neighbour.Link] = append(incoming[neighbour.Link], node)
+		}
+	}
+	seen := map[string]bool{}
+	if _, ok := a.siteGraph.Neighbours(u); !ok {
+		return seen
+	}
+	pending := []string{u}
+	seen[u] = true
+	for len(pending) > 0 {
+		node := pending[0]
+		pending = pending[1:]
+		linked := slices.Clone(incoming[node])
+		neighbours, _ := a.siteGraph.Neighbours(node)
+		for _, neighbour := range neighbours {
+			linked = append(linked, neighbour.Link)
+		}
+		for _, next := range linked {
+			if !seen[next] {
+				seen[next] = true
+				pending = append(pending, next)
+			}
+		}
+	}
+	return seen
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_FilteredGraph(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, pagesFetcher{
+		"https://example.com":      `<a href="/blog">blog</a><a href="/missing">missing</a>`,
+		"https://example.com/blog": `<a href="/blog/post">post</a>`,
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	a.mu.Lock()
+	a.siteGraph.AddEdge("https://example.com/island", "https://example.com/island/page", 1)
+	a.mu.Unlock()
+	urls := func(filter GraphFilter) []string {
+		g, nodes := a.FilteredGraph(filter)
+		require.Len(t, nodes, len(g.Nodes()))
+		return g.Nodes()
+	}
+	require.ElementsMatch(t, []string{"https://example.com/", "https://example.com/blog"}, urls(GraphFilter{Statuses: []int{http.StatusOK}}))
+	require.ElementsMatch(t, []string{"https://example.com/blog", "https://example.com/blog/post"}, urls(GraphFilter{Pattern: regexp.MustCompile(`/blog`)}))
+	depth := 0
+	require.ElementsMatch(t, []string{"https://example.com/", "https://example.com/island", "https://example.com/island/page"}, urls(GraphFilter{MaxDepth: &depth}))
+	require.ElementsMatch(t, []string{"https://example.com/island", "https://example.com/island/page"}, urls(GraphFilter{Component: "https://example.com/island/page"}))
+	g, _ := a.FilteredGraph(GraphFilter{Statuses: []int{http.StatusOK}})
+	neighbours, _ := g.Neighbours("https://example.com/")
+	require.Equal(t, []graph.Edge[string]{{Link: "https://example.com/blog", Weight: 1}}, neighbours)
+}
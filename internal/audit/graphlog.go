@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/salsgithub/godst/graph"
+)
+
+// graphLogRecord is one line of the graph log: the crawl's start url, an edge or a page status
+type graphLogRecord struct {
+	StartURL   string `json:"start_url,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Target     string `json:"target,omitempty"`
+	Weight     int    `json:"weight,omitempty"`
+	URL        string `json:"url,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+// graphLog appends discovered edges and statuses as JSON lines, one write per batch, so a crash
+// loses at most the batch being written
+type graphLog struct {
+	file *os.File
+}
+
+func openGraphLog(path string, appendTo bool) (*graphLog, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !appendTo {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening graph log: %w", err)
+	}
+	return &graphLog{file: file}, nil
+}
+
+func (g *graphLog) write(records ...graphLogRecord) error {
+	if g == nil || len(records) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	_, err := g.file.Write(buf.Bytes())
+	return err
+}
+
+func (g *graphLog) close() error {
+	if g == nil {
+		return nil
+	}
+	return g.file.Close()
+}
+
+// LoadGraphLog rebuilds the graph and page statuses from a graph log. A truncated final line,
+// as left by a crash mid-write, is ignored.
+func LoadGraphLog(path string) (string, *graph.Graph[string], []Page, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%w: %w", ErrInvalidGraphLog, err)
+	}
+	defer file.Close()
+	var startURL string
+	g := graph.New[string]()
+	statuses := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	var pending error
+	for line := 1; scanner.Scan(); line++ {
+		if pending != nil {
+			return "", nil, nil, pending
+		}
+		var record graphLogRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			pending = fmt.Errorf("%w: line %d: %w", ErrInvalidGraphLog, line, err)
+			continue
+		}
+		switch {
+		case record.StartURL != "":
+			startURL = record.StartURL
+		case record.Source != "":
+			g.AddEdge(record.Source, record.Target, record.Weight)
+		case record.URL != "":
+			statuses[record.URL] = record.StatusCode
+			g.AddNode(record.URL)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, nil, fmt.Errorf("%w: %w", ErrInvalidGraphLog, err)
+	}
+	pages := make([]Page, 0, len(statuses))
+	for u, code := range statuses {
+		page := Page{URL: u, StatusCode: code, Links: []string{}}
+		neighbours, _ := g.Neighbours(u)
+		for _, neighbour := range neighbours {
+			page.Links = append(page.Links, neighbour.Link)
+		}
+		pages = append(pages, page)
+	}
+	slices.SortFunc(pages, func(x, y Page) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	return startURL, g, pages, nil
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_GraphLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.log")
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com":   successResponse(`<a href="/a">A</a><a href="/b">B</a>`),
+			"https://example.com/a": successResponse(""),
+		},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.GraphLogFile = path
+	a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+
+	startURL, g, pages, err := LoadGraphLog(path)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/", startURL)
+	require.Equal(t, a.graphSnapshot().Nodes(), g.Nodes())
+	// The log records the graph and statuses, not what each page holds
+	crawled := a.Pages()
+	for i, page := range crawled {
+		crawled[i] = Page{URL: page.URL, StatusCode: page.StatusCode, Links: page.Links}
+	}
+	require.Equal(t, crawled, pages)
+	require.Equal(t, a.Findings(), BrokenLinkFindings(pages))
+
+	t.Run("truncated final line is ignored", func(t *testing.T) {
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, append(b, []byte(`{"source":"https://exa`)...), 0o644))
+		_, _, recovered, err := LoadGraphLog(path)
+		require.NoError(t, err)
+		require.Equal(t, pages, recovered)
+	})
+	t.Run("corruption before the end is an error", func(t *testing.T) {
+		corrupt := filepath.Join(t.TempDir(), "graph.log")
+		require.NoError(t, os.WriteFile(corrupt, []byte("{\n{\"url\":\"https://example.com/\",\"status_code\":200}\n"), 0o644))
+		_, _, _, err := LoadGraphLog(corrupt)
+		require.True(t, errors.Is(err, ErrInvalidGraphLog))
+	})
+}
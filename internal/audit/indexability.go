@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	CheckIndexableSearch = "indexable-search-page"
+	CheckIndexableParams = "indexable-param-page"
+)
+
+// searchParams are the query parameters site search forms commonly submit
+var searchParams = []string{"q", "query", "s", "search", "search_query", "keyword", "keywords", "term"}
+
+const (
+	// minFacetParams is how many query parameters a url must carry to be taken as one of the endless
+	// combinations of a faceted listing
+	minFacetParams = 3
+	// maxIndexSamples caps the urls fetched after the crawl to check they cannot be indexed
+	maxIndexSamples = 100
+)
+
+type indexSample struct {
+	check  string
+	url    string
+	detail string
+}
+
+// parameterSpace tells internal search results and faceted listings apart from other urls with a
+// query. Both have no end of variants, which search engines should not be left to index.
+func parameterSpace(u *url.URL) (indexSample, bool) {
+	query := u.Query()
+	for _, param := range searchParams {
+		if query.Has(param) {
+			return indexSample{check: CheckIndexableSearch, detail: fmt.Sprintf("search results for %q", param)}, true
+		}
+	}
+	if slices.Contains(strings.Split(strings.ToLower(u.Path), "/"), "search") {
+		return indexSample{check: CheckIndexableSearch, detail: "search results"}, true
+	}
+	if len(query) >= minFacetParams {
+		return indexSample{check: CheckIndexableParams, detail: fmt.Sprintf("url with %d query parameters", len(query))}, true
+	}
+	return indexSample{}, false
+}
+
+// recordIndexSample keeps the first url linked of each kind of parameter space on a path. The crawl
+// treats urls differing only by query as one page, so samples are fetched once it is over. It must
+// be called with a.mu held.
+func (a *Audit) recordIndexSample(u *url.URL) {
+	if !a.config.CheckIndexability || u.RawQuery == "" || len(a.indexSamples) >= maxIndexSamples {
+		return
+	}
+	sample, ok := parameterSpace(u)
+	if !ok {
+		return
+	}
+	for _, recorded := range a.indexSamples {
+		if recorded.check != sample.check {
+			continue
+		}
+		if parsed, err := url.Parse(recorded.url); err == nil && normaliseHost(parsed.Host) == normaliseHost(u.Host) && parsed.Path == u.Path {
+			return
+		}
+	}
+	sampled := *u
+	sampled.Fragment = ""
+	sample.url = intern(sampled.String())
+	a.indexSamples = append(a.indexSamples, sample)
+}
+
+// analyseIndexability fetches each sample and reports those search engines could index: ones not
+// marked noindex or pointing their canonical at another url. Links disallowed by robots.txt are
+// never sampled.
+func (a *Audit) analyseIndexability(ctx context.Context) {
+	a.mu.Lock()
+	samples := slices.Clone(a.indexSamples)
+	a.mu.Unlock()
+	indexable := 0
+	for _, sample := range samples {
+		if ctx.Err() != nil {
+			return
+		}
+		u, err := url.Parse(sample.url)
+		if err != nil || !a.indexable(ctx, u) {
+			continue
+		}
+		indexable++
+		a.recordFinding(Finding{
+			Check:  sample.check,
+			URL:    sample.url,
+			Detail: sample.detail + " can be indexed, add noindex, a canonical without the query or a robots.txt rule",
+		})
+	}
+	a.logger.Info("Parameter pages checked for indexability", "sampled", len(samples), "indexable", indexable)
+}
+
+func (a *Audit) indexable(ctx context.Context, u *url.URL) bool {
+	if err := a.waitForHost(ctx, u.Hostname()); err != nil {
+		return false
+	}
+	response, err := a.fetcher.Fetch(ctx, u)
+	if err != nil {
+		a.logger.Debug("Failed to fetch indexability sample", "url", u.String(), "err", err)
+		return false
+	}
+	defer closeBody(response.Body)
+	if response.StatusCode != http.StatusOK || parseRobotsTag(response.Header, a.config.Agent).noIndex {
+		return false
+	}
+	details, err := a.extract(ctx, u, newBoundedReader(response.Body, a.config.MaxBodyBytes))
+	if err != nil {
+		return false
+	}
+	var meta robotsDirectives
+	meta.add(details.Robots)
+	if meta.noIndex {
+		return false
+	}
+	if details.Canonical == "" {
+		return true
+	}
+	canonical, err := url.Parse(details.Canonical)
+	if err != nil {
+		return true
+	}
+	canonical.Fragment = ""
+	return canonical.String() == u.String()
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_Indexability(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.CheckIndexability = true
+	fetcher := pagesFetcher{
+		"https://example.com": `<a href="/?s=shoes">s</a><a href="/search?type=all">search</a><a href="/search">form</a>
+			<a href="/shoes?colour=red&size=9&sort=price">facets</a><a href="/shoes?colour=blue&size=8&sort=name">same path</a>
+			<a href="/shoes?colour=red">colour</a><a href="/missing?q=gone">missing</a><a href="/results?q=hidden">hidden</a>
+			<a href="/canonical?q=elsewhere">canonical</a><a href="/self?q=canonical">self</a>`,
+		"https://example.com/?s=shoes":                           "results",
+		"https://example.com/search?type=all":                    "results",
+		"https://example.com/search":                             "form",
+		"https://example.com/shoes?colour=red&size=9&sort=price": "listing",
+		"https://example.com/shoes?colour=blue&size=8&sort=name": "listing",
+		"https://example.com/shoes?colour=red":                   "listing",
+		"https://example.com/results?q=hidden":                   `<meta name="robots" content="noindex">`,
+		"https://example.com/canonical?q=elsewhere":              `<link rel="canonical" href="/canonical">`,
+		"https://example.com/self?q=canonical":                   `<link rel="canonical" href="/self?q=canonical#top">`,
+	}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	found := []Finding{}
+	for _, finding := range a.Findings() {
+		if finding.Check == CheckIndexableSearch || finding.Check == CheckIndexableParams {
+			found = append(found, finding)
+		}
+	}
+	require.Equal(t, []Finding{
+		{Check: CheckIndexableParams, URL: "https://example.com/shoes?colour=red&size=9&sort=price", Detail: "url with 3 query parameters can be indexed, add noindex, a canonical without the query or a robots.txt rule"},
+		{Check: CheckIndexableSearch, URL: "https://example.com/?s=shoes", Detail: `search results for "s" can be indexed, add noindex, a canonical without the query or a robots.txt rule`},
+		{Check: CheckIndexableSearch, URL: "https://example.com/search?type=all", Detail: "search results can be indexed, add noindex, a canonical without the query or a robots.txt rule"},
+		{Check: CheckIndexableSearch, URL: "https://example.com/self?q=canonical", Detail: `search results for "q" can be indexed, add noindex, a canonical without the query or a robots.txt rule`},
+	}, found)
+}
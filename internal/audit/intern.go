@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"slices"
+	"unique"
+)
+
+// intern returns s backed by the one copy shared by every equal interned string, so a url
+// referenced from the visited set, queue, graph and statuses is only stored once
+func intern(s string) string {
+	return unique.Make(s).Value()
+}
+
+// handleSet is the exact visited set. Holding the interned handle of each url instead of the
+// string keeps its shared copy alive for the whole crawl at the cost of one pointer per url.
+type handleSet map[unique.Handle[string]]struct{}
+
+func newHandleSet() handleSet {
+	return make(handleSet)
+}
+
+func (h handleSet) Add(values ...string) {
+	for _, value := range values {
+		h[unique.Make(value)] = struct{}{}
+	}
+}
+
+func (h handleSet) Contains(value string) bool {
+	_, ok := h[unique.Make(value)]
+	return ok
+}
+
+func (h handleSet) Len() int {
+	return len(h)
+}
+
+func (h handleSet) IsEmpty() bool {
+	return len(h) == 0
+}
+
+func (h handleSet) Values() []string {
+	values := make([]string, 0, len(h))
+	for handle := range h {
+		values = append(values, handle.Value())
+	}
+	slices.Sort(values)
+	return values
+}
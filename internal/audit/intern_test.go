@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntern(t *testing.T) {
+	a := intern(strings.Repeat("https://example.com/", 2))
+	b := intern("https://example.com/https://example.com/")
+	require.Equal(t, a, b)
+	require.True(t, unsafe.StringData(a) == unsafe.StringData(b))
+
+	visited := newHandleSet()
+	require.True(t, visited.IsEmpty())
+	visited.Add("https://example.com/b", "https://example.com/a", "https://example.com/a")
+	require.Equal(t, 2, visited.Len())
+	require.True(t, visited.Contains("https://example.com/a"))
+	require.False(t, visited.Contains("https://example.com/c"))
+	require.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, visited.Values())
+}
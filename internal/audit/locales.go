@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// languageFragment marks a url fetched with one of the other AUDIT_ACCEPT_LANGUAGES, so each
+// locale variant of a page negotiated by Accept-Language is a node of its own. Fragments are not
+// sent to the server, and links found on a variant resolve without it.
+const languageFragment = "accept-language="
+
+// acceptLanguage is the Accept-Language u is fetched with, "" when none is configured
+func (a *Audit) acceptLanguage(u *url.URL) string {
+	if lang, ok := strings.CutPrefix(u.Fragment, languageFragment); ok && len(a.languages) > 1 {
+		return lang
+	}
+	if len(a.languages) > 0 {
+		return a.languages[0]
+	}
+	return ""
+}
+
+// variesByLanguage reports whether a response says its content is negotiated by Accept-Language
+func variesByLanguage(header http.Header) bool {
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), "Accept-Language") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enqueueLanguageVariants queues u in each of the other languages at the same depth, linked from
+// u in the graph
+func (a *Audit) enqueueLanguageVariants(u *url.URL, depth int) {
+	if len(a.languages) < 2 || strings.HasPrefix(u.Fragment, languageFragment) {
+		return
+	}
+	source := intern(a.canonicalURL(u))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	edges := make([]graphLogRecord, 0, len(a.languages)-1)
+	for _, lang := range a.languages[1:] {
+		variant := *u
+		variant.Fragment = languageFragment + lang
+		canonical := intern(a.canonicalURL(&variant))
+		a.siteGraph.AddEdge(source, canonical, 1)
+		edges = append(edges, graphLogRecord{Source: source, Target: canonical, Weight: 1})
+		if a.visited.Contains(canonical) {
+			continue
+		}
+		a.visited.Add(canonical)
+		a.node(canonical).depth = depth
+		a.enqueue(&task{rawURL: intern(variant.String()), depth: depth})
+	}
+	if err := a.graphLog.write(edges...); err != nil {
+		a.logger.Error("Error writing graph log", "err", err)
+	}
+}
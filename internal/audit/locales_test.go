@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+	"salsgithub.com/site-audit/internal/fetcher"
+)
+
+func TestAudit_AcceptLanguages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := r.Header.Get("Accept-Language")
+		if r.URL.Path != "/" {
+			fmt.Fprintf(w, `<html lang="%s">page</html>`, lang)
+			return
+		}
+		w.Header().Set("Vary", "Accept-Encoding, accept-language")
+		links := map[string]string{"en": "/about", "de": "/ueber-uns"}
+		fmt.Fprintf(w, `<html lang="%s"><a href="%s">about</a></html>`, lang, links[lang])
+	}))
+	defer server.Close()
+	c := testConfig
+	c.StartURL = server.URL
+	c.RespectRobots = false
+	c.AcceptLanguages = "en, de"
+	a, err := New(c, fetcher.NewHTTPFetcher("agent"), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	langs := map[string]string{}
+	for u, node := range a.Nodes() {
+		langs[strings.TrimPrefix(u, server.URL)] = node.Lang
+	}
+	require.Equal(t, map[string]string{"/": "en", "/#accept-language=de": "de", "/about": "en", "/ueber-uns": "en"}, langs)
+	neighbours, _ := a.graphSnapshot().Neighbours(server.URL + "/")
+	require.ElementsMatch(t, []graph.Edge[string]{{Link: server.URL + "/about", Weight: 1}, {Link: server.URL + "/#accept-language=de", Weight: 1}}, neighbours)
+}
@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Node is what the crawl recorded about a url in the site graph. Urls found but not fetched only
+// have a depth.
+type Node struct {
+	URL           string  `json:"url"`
+	StatusCode    int     `json:"status_code,omitempty"`
+	Depth         int     `json:"depth"`
+	Title         string  `json:"title,omitempty"`
+	Lang          string  `json:"lang,omitempty"`
+	Canonical     string  `json:"canonical,omitempty"`
+	InSitemap     bool    `json:"in_sitemap,omitempty"`
+	FetchMillis   int64   `json:"fetch_ms,omitempty"`
+	ContentLength int64   `json:"content_length,omitempty"`
+	ContentHash   string  `json:"content_hash,omitempty"`
+	Failure       Failure `json:"failure,omitempty"`
+
+	Headers    map[string]string `json:"headers,omitempty"`
+	Alternates []Alternate       `json:"alternates,omitempty"`
+}
+
+// Alternate is a translation or regional version of a page it declares with hreflang, or a version
+// for other devices it declares with a media query
+type Alternate struct {
+	Lang  string `json:"hreflang,omitempty"`
+	Media string `json:"media,omitempty"`
+	URL   string `json:"url"`
+}
+
+type nodeInfo struct {
+	depth         int
+	title         string
+	lang          string
+	canonical     string
+	inSitemap     bool
+	fetchTime     time.Duration
+	contentLength int64
+	contentHash   string
+	alternates    []Alternate
+}
+
+// Nodes returns the metadata of every url in the site graph keyed by url
+func (a *Audit) Nodes() map[string]Node {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.nodeSnapshot()
+}
+
+// nodeSnapshot must be called with a.mu held
+func (a *Audit) nodeSnapshot() map[string]Node {
+	nodes := make(map[string]Node, len(a.nodes))
+	for u, info := range a.nodes {
+		nodes[u] = Node{
+			URL:           u,
+			StatusCode:    a.statuses[u],
+			Depth:         info.depth,
+			Title:         info.title,
+			Lang:          info.lang,
+			Canonical:     info.canonical,
+			InSitemap:     info.inSitemap,
+			FetchMillis:   info.fetchTime.Milliseconds(),
+			ContentLength: info.contentLength,
+			ContentHash:   info.contentHash,
+			Failure:       a.failures[u],
+			Headers:       maps.Clone(a.headers[u]),
+			Alternates:    slices.Clone(info.alternates),
+		}
+	}
+	for u, code := range a.statuses {
+		if _, ok := nodes[u]; !ok {
+			nodes[u] = Node{URL: u, StatusCode: code, Failure: a.failures[u], Headers: maps.Clone(a.headers[u])}
+		}
+	}
+	for u, failure := range a.failures {
+		if _, ok := nodes[u]; !ok {
+			nodes[u] = Node{URL: u, Failure: failure}
+		}
+	}
+	return nodes
+}
+
+// node must be called with a.mu held
+func (a *Audit) node(u string) *nodeInfo {
+	info, ok := a.nodes[u]
+	if !ok {
+		info = &nodeInfo{}
+		a.nodes[u] = info
+	}
+	return info
+}
+
+// countingReader counts the bytes of a body whose length was not sent, and hashes them so a
+// change to the page can be told apart from a change to its status
+type countingReader struct {
+	r    io.Reader
+	read int64
+	hash hash.Hash
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	c.hash.Write(p[:n])
+	return n, err
+}
+
+// sum is the hex encoded hash of the bytes read so far
+func (c *countingReader) sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_Nodes(t *testing.T) {
+	sum := func(body string) string {
+		hash := sha256.Sum256([]byte(body))
+		return hex.EncodeToString(hash[:])
+	}
+	home := successResponse(`<title>Home</title><a href="/a">a</a>`)
+	home.ContentLength = 1234
+	fetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com":   home,
+			"https://example.com/a": successResponse(`<title>Page A</title><a href="/b">b</a>`),
+		},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	nodes := a.Nodes()
+	for u, n := range nodes {
+		n.FetchMillis = 0
+		nodes[u] = n
+	}
+	require.Equal(t, map[string]Node{
+		"https://example.com/":  {URL: "https://example.com/", StatusCode: 200, Depth: 0, Title: "Home", ContentLength: 1234, ContentHash: sum(`<title>Home</title><a href="/a">a</a>`)},
+		"https://example.com/a": {URL: "https://example.com/a", StatusCode: 200, Depth: 1, Title: "Page A", ContentLength: 39, ContentHash: sum(`<title>Page A</title><a href="/b">b</a>`)},
+		"https://example.com/b": {URL: "https://example.com/b", Depth: 2},
+	}, nodes)
+
+	resumed, err := Resume(a.Checkpoint(), &mockFetcher{}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.Equal(t, a.Nodes()["https://example.com/a"].Title, resumed.Nodes()["https://example.com/a"].Title)
+	require.Equal(t, a.Nodes()["https://example.com/a"].ContentHash, resumed.Nodes()["https://example.com/a"].ContentHash)
+	require.Equal(t, 2, resumed.Nodes()["https://example.com/b"].Depth)
+}
+
+func TestAudit_EdgeWeights(t *testing.T) {
+	fetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com":   successResponse(`<a href="/a">a</a><a href="/a/">a again</a><a href="/a#more">more</a><a href="/b">b</a><a href="/">home</a>`),
+			"https://example.com/a": successResponse(`<a href="/">home</a><a href="/b">b</a>`),
+		},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	weights := map[string]int{}
+	for _, node := range a.siteGraph.Nodes() {
+		neighbours, _ := a.siteGraph.Neighbours(node)
+		for _, neighbour := range neighbours {
+			weights[node+" -> "+neighbour.Link] = neighbour.Weight
+		}
+	}
+	require.Equal(t, map[string]int{
+		"https://example.com/ -> https://example.com/a":  3,
+		"https://example.com/ -> https://example.com/b":  1,
+		"https://example.com/a -> https://example.com/":  1,
+		"https://example.com/a -> https://example.com/b": 1,
+	}, weights)
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const CheckNotFoundPage = "not-found-page"
+
+// notFoundPath is a url no site should serve. It is fixed so baselines recognise the finding
+// from one crawl to the next.
+const notFoundPath = "/site-audit-nonexistent-page"
+
+// NotFoundCheck requests a page that does not exist and reports the site when it answers with
+// anything but a 404 or 410, such as a 200 or a redirect to the home page, or with an error page
+// offering no links back into the site. The fetcher should not follow redirects.
+type NotFoundCheck struct {
+	fetcher   Fetcher
+	extractor Extractor
+	startURL  string
+}
+
+func NewNotFoundCheck(fetcher Fetcher, extractor Extractor, startURL string) *NotFoundCheck {
+	return &NotFoundCheck{fetcher: fetcher, extractor: extractor, startURL: startURL}
+}
+
+func (c *NotFoundCheck) Name() string {
+	return CheckNotFoundPage
+}
+
+func (c *NotFoundCheck) Run(ctx context.Context, pages []Page) ([]Finding, error) {
+	start, err := url.Parse(c.startURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing start url: %w", err)
+	}
+	u := &url.URL{Scheme: start.Scheme, Host: start.Host, Path: notFoundPath}
+	response, err := c.fetcher.Fetch(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", u, err)
+	}
+	defer closeBody(response.Body)
+	finding := func(detail string) []Finding {
+		return []Finding{{Check: CheckNotFoundPage, URL: u.String(), Detail: detail}}
+	}
+	switch code := response.StatusCode; {
+	case code >= http.StatusMultipleChoices && code < http.StatusBadRequest:
+		location, err := url.Parse(response.Header.Get("Location"))
+		if err != nil || location.String() == "" {
+			return finding(fmt.Sprintf("nonexistent page returned status %d without a location", code)), nil
+		}
+		location = u.ResolveReference(location)
+		if location.Host == u.Host && (location.Path == "" || location.Path == "/") {
+			return finding(fmt.Sprintf("nonexistent page redirects to the home page with status %d", code)), nil
+		}
+		return finding(fmt.Sprintf("nonexistent page redirects to %s with status %d", location, code)), nil
+	case code != http.StatusNotFound && code != http.StatusGone:
+		return finding(fmt.Sprintf("nonexistent page returned status %d instead of 404", code)), nil
+	}
+	links, err := c.extractor.Extract(ctx, u, response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting links from %s: %w", u, err)
+	}
+	if len(links) == 0 {
+		return finding("404 page has no links back into the site"), nil
+	}
+	return []Finding{}, nil
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotFoundCheck(t *testing.T) {
+	probe := "https://example.com" + notFoundPath
+	redirect := func(location string) *http.Response {
+		response := buildResponse("", http.StatusFound)
+		response.Header = http.Header{"Location": {location}}
+		return response
+	}
+	tests := []struct {
+		name     string
+		response *http.Response
+		links    []string
+		want     []Finding
+	}{
+		{name: "404 with links", response: notFoundResponse("not here"), links: []string{"https://example.com/"}, want: []Finding{}},
+		{name: "410 with links", response: buildResponse("gone", http.StatusGone), links: []string{"https://example.com/"}, want: []Finding{}},
+		{name: "404 without links", response: notFoundResponse(""), want: []Finding{{Check: CheckNotFoundPage, URL: probe, Detail: "404 page has no links back into the site"}}},
+		{name: "soft 404", response: successResponse("home"), want: []Finding{{Check: CheckNotFoundPage, URL: probe, Detail: "nonexistent page returned status 200 instead of 404"}}},
+		{name: "redirect to home", response: redirect("/"), want: []Finding{{Check: CheckNotFoundPage, URL: probe, Detail: "nonexistent page redirects to the home page with status 302"}}},
+		{name: "redirect elsewhere", response: redirect("https://example.com/search"), want: []Finding{{Check: CheckNotFoundPage, URL: probe, Detail: "nonexistent page redirects to https://example.com/search with status 302"}}},
+		{name: "server error", response: buildResponse("", http.StatusInternalServerError), want: []Finding{{Check: CheckNotFoundPage, URL: probe, Detail: "nonexistent page returned status 500 instead of 404"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fetcher := &mockFetcher{responses: map[string]*http.Response{probe: test.response}}
+			check := NewNotFoundCheck(fetcher, &mockExtractor{values: test.links}, "https://example.com/a/b")
+			findings, err := check.Run(context.Background(), nil)
+			require.NoError(t, err)
+			require.Equal(t, test.want, findings)
+		})
+	}
+	t.Run("errors when the fetch fails", func(t *testing.T) {
+		check := NewNotFoundCheck(&mockFetcher{err: errors.New("boom")}, &mockExtractor{}, "https://example.com/")
+		_, err := check.Run(context.Background(), nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"cmp"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// maxOutboundExamples bounds the pages kept as examples of where a domain is linked from
+const maxOutboundExamples = 3
+
+// OutboundDomain is a third party the site links to. Links counts every link to the domain, URLs
+// the distinct targets and Pages the pages linking to it, a few of which are kept as Examples.
+type OutboundDomain struct {
+	Domain   string   `json:"domain"`
+	Links    int      `json:"links"`
+	URLs     int      `json:"urls"`
+	Pages    int      `json:"pages"`
+	Examples []string `json:"examples"`
+}
+
+type outboundDomain struct {
+	links    int
+	urls     map[string]struct{}
+	pages    map[string]struct{}
+	examples []string
+}
+
+// recordOutbound must be called with a.mu held. Targets differing only by fragment or the case of
+// their host are one url.
+func (a *Audit) recordOutbound(u *url.URL, source string, count int) {
+	target := *u
+	target.Host, target.Fragment = strings.ToLower(target.Host), ""
+	domain := normaliseHost(u.Hostname())
+	d, ok := a.outbound[domain]
+	if !ok {
+		d = &outboundDomain{urls: map[string]struct{}{}, pages: map[string]struct{}{}}
+		a.outbound[domain] = d
+	}
+	d.links += count
+	d.urls[target.String()] = struct{}{}
+	if _, ok := d.pages[source]; !ok && len(d.examples) < maxOutboundExamples {
+		d.examples = append(d.examples, source)
+	}
+	d.pages[source] = struct{}{}
+}
+
+// OutboundDomains returns the domains the site links out to, most linked first
+func (a *Audit) OutboundDomains() []OutboundDomain {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	domains := make([]OutboundDomain, 0, len(a.outbound))
+	for _, domain := range slices.Sorted(maps.Keys(a.outbound)) {
+		d := a.outbound[domain]
+		domains = append(domains, OutboundDomain{
+			Domain:   domain,
+			Links:    d.links,
+			URLs:     len(d.urls),
+			Pages:    len(d.pages),
+			Examples: slices.Clone(d.examples),
+		})
+	}
+	slices.SortStableFunc(domains, func(x, y OutboundDomain) int {
+		return cmp.Compare(y.Links, x.Links)
+	})
+	return domains
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_OutboundDomains(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, pagesFetcher{
+		"https://example.com":      `<a href="https://other.com/a">a</a><a href="https://other.com/a">again</a><a href="/page">page</a><a href="https://www.third.net/">third</a>`,
+		"https://example.com/page": `<a href="https://other.com/b#top">b</a><a href="https://OTHER.com/a">a</a>`,
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []OutboundDomain{
+		{Domain: "other.com", Links: 4, URLs: 2, Pages: 2, Examples: []string{"https://example.com/", "https://example.com/page"}},
+		{Domain: "third.net", Links: 1, URLs: 1, Pages: 1, Examples: []string{"https://example.com/"}},
+	}, a.OutboundDomains())
+	require.Empty(t, a.ExternalLinks())
+}
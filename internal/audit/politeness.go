@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxThrottleRetries is how many times a page answered with 429 is fetched again before its
+	// status is recorded
+	maxThrottleRetries = 3
+	// maxBackoff caps how long a host is left alone, whatever its Retry-After asks for
+	maxBackoff = 5 * time.Minute
+	// initialBackoff is doubled for each 429 in a row from a host that sends no Retry-After
+	initialBackoff = time.Second
+)
+
+// ThrottleEvent is a 429 response and how the crawl backed off its host. Honored is whether the
+// next request to the host waited as long as Retry-After asked, which only fails when the wait
+// asked for is longer than the crawl allows.
+type ThrottleEvent struct {
+	URL           string    `json:"url"`
+	Host          string    `json:"host"`
+	At            time.Time `json:"at"`
+	RetryAfter    string    `json:"retry_after,omitempty"`
+	BackoffMillis int64     `json:"backoff_ms"`
+	Honored       bool      `json:"honored"`
+	// NextRequestMillis is how long after the 429 the host was next requested, when it was
+	NextRequestMillis int64 `json:"next_request_ms,omitempty"`
+}
+
+// HostPoliteness totals the 429s from one host and the time spent backing off it
+type HostPoliteness struct {
+	Throttled     int   `json:"throttled"`
+	BackoffMillis int64 `json:"backoff_ms"`
+}
+
+// Politeness shows how the crawl behaved when hosts asked it to slow down
+type Politeness struct {
+	Throttled int                       `json:"throttled"`
+	Honored   int                       `json:"honored"`
+	Hosts     map[string]HostPoliteness `json:"hosts"`
+	Events    []ThrottleEvent           `json:"events"`
+}
+
+// hostBackoff is the state kept for a host that has sent a 429
+type hostBackoff struct {
+	until time.Time
+	// requested is when the host asked to be contacted again, which can be later than until
+	requested time.Time
+	// streak counts 429s in a row, doubling the backoff when there is no Retry-After
+	streak int
+	// pending is the event waiting for the host's next request
+	pending int
+}
+
+func (a *Audit) Politeness() Politeness {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := Politeness{Throttled: len(a.throttles), Hosts: map[string]HostPoliteness{}, Events: slices.Clone(a.throttles)}
+	for _, event := range a.throttles {
+		if event.Honored {
+			p.Honored++
+		}
+		host := p.Hosts[event.Host]
+		host.Throttled++
+		host.BackoffMillis += event.BackoffMillis
+		p.Hosts[event.Host] = host
+	}
+	return p
+}
+
+// recordThrottle backs off the host of a 429 response, returning whether the page should be
+// fetched again
+func (a *Audit) recordThrottle(logger *slog.Logger, t *task, u *url.URL, header http.Header) bool {
+	host := u.Hostname()
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b, ok := a.backoffs[host]
+	if !ok {
+		b = &hostBackoff{pending: -1}
+		a.backoffs[host] = b
+	}
+	b.streak++
+	retryAfter := header.Get("Retry-After")
+	wait, ok := parseRetryAfter(retryAfter, now)
+	if !ok {
+		wait = initialBackoff << min(b.streak-1, 8)
+	}
+	b.requested = now.Add(wait)
+	wait = min(wait, maxBackoff)
+	if until := now.Add(wait); until.After(b.until) {
+		b.until = until
+	}
+	b.pending = len(a.throttles)
+	a.throttles = append(a.throttles, ThrottleEvent{
+		URL:           a.canonicalURL(u),
+		Host:          host,
+		At:            now.UTC(),
+		RetryAfter:    retryAfter,
+		BackoffMillis: wait.Milliseconds(),
+		Honored:       true,
+	})
+	logger.Warn("Backing off throttled host", "host", host, "retry_after", retryAfter, "backoff", wait)
+	return t.attempts < maxThrottleRetries
+}
+
+// waitForHost holds a request to a host until its backoff is over, then records how long after
+// the last 429 the host was contacted again
+func (a *Audit) waitForHost(ctx context.Context, host string) error {
+	a.mu.Lock()
+	b, ok := a.backoffs[host]
+	if !ok {
+		a.mu.Unlock()
+		return nil
+	}
+	until := b.until
+	a.mu.Unlock()
+	if delay := time.Until(until); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if b.pending >= 0 {
+		event := &a.throttles[b.pending]
+		event.NextRequestMillis = now.Sub(event.At).Milliseconds()
+		event.Honored = !now.Before(b.requested)
+		b.pending = -1
+	}
+	return nil
+}
+
+// recordHostOK ends a host's run of 429s
+func (a *Audit) recordHostOK(host string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if b, ok := a.backoffs[host]; ok {
+		b.streak = 0
+	}
+}
+
+// parseRetryAfter reads either form of the header, a number of seconds or an HTTP date, capping the
+// wait at maxBackoff so a huge number of seconds cannot overflow
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(min(max(seconds, 0), int(maxBackoff/time.Second))) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return min(max(at.Sub(now), 0), maxBackoff), true
+}
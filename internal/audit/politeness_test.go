@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+// throttlingFetcher answers the first throttled requests for each url with 429
+type throttlingFetcher struct {
+	throttled  map[string]int
+	retryAfter string
+	mu         sync.Mutex
+}
+
+func (f *throttlingFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.throttled[u.String()] > 0 {
+		f.throttled[u.String()]--
+		response := buildResponse("", http.StatusTooManyRequests)
+		response.Header = http.Header{"Retry-After": []string{f.retryAfter}}
+		return response, nil
+	}
+	return successResponse(`<a href="/a">a</a>`), nil
+}
+
+func TestAudit_Politeness(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	fetcher := &throttlingFetcher{throttled: map[string]int{"https://example.com": 1, "https://example.com/a": 10}, retryAfter: "0"}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	statuses := map[string]int{}
+	for _, page := range a.Pages() {
+		statuses[page.URL] = page.StatusCode
+	}
+	// The home page succeeds once retried, /a is given up on after its retries
+	require.Equal(t, map[string]int{"https://example.com/": http.StatusOK, "https://example.com/a": http.StatusTooManyRequests}, statuses)
+	p := a.Politeness()
+	require.Equal(t, 1+maxThrottleRetries+1, p.Throttled)
+	require.Equal(t, p.Throttled, p.Honored)
+	require.Equal(t, map[string]HostPoliteness{"example.com": {Throttled: p.Throttled}}, p.Hosts)
+	require.Equal(t, "https://example.com/", p.Events[0].URL)
+	require.Equal(t, "0", p.Events[0].RetryAfter)
+	require.Equal(t, p.Throttled, a.Summary().Throttled)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "120", want: 2 * time.Minute, ok: true},
+		{value: " 0 ", want: 0, ok: true},
+		{value: "99999999999", want: maxBackoff, ok: true},
+		{value: "-5", want: 0, ok: true},
+		{value: "Mon, 06 Jan 2025 01:00:00 GMT", want: maxBackoff, ok: true},
+		{value: "Mon, 06 Jan 2025 00:00:30 GMT", want: 30 * time.Second, ok: true},
+		{value: "Sun, 05 Jan 2025 00:00:00 GMT", want: 0, ok: true},
+		{value: "", ok: false},
+		{value: "soon", ok: false},
+	}
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value, now)
+		require.Equal(t, test.ok, ok, test.value)
+		require.Equal(t, test.want, got, test.value)
+	}
+}
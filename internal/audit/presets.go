@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// presets bundle configuration for common jobs, keyed by environment variable name
+var presets = map[string]map[string]string{
+	"links-only": {
+		"AUDIT_MAX_DEPTH":        "10",
+		"AUDIT_MAX_BROKEN_LINKS": "0",
+	},
+	"seo-full": {
+		"AUDIT_MAX_DEPTH":      "20",
+		"AUDIT_RESPECT_ROBOTS": "TRUE",
+		"AUDIT_CHECK_ROBOTS":   "TRUE",
+		"AUDIT_VALID_SCHEMES":  "https",
+	},
+	"assets": {
+		"AUDIT_MAX_DEPTH":     "5",
+		"AUDIT_INCLUDE_FILES": "TRUE",
+	},
+	"security": {
+		"AUDIT_MAX_DEPTH":            "5",
+		"AUDIT_VALID_SCHEMES":        "https,http",
+		"AUDIT_FAIL_ON_SERVER_ERROR": "TRUE",
+	},
+}
+
+func Presets() []string {
+	return slices.Sorted(maps.Keys(presets))
+}
+
+func Preset(name string) (map[string]string, error) {
+	preset, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q, use one of %v", ErrUnknownPreset, name, Presets())
+	}
+	return maps.Clone(preset), nil
+}
@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresets(t *testing.T) {
+	envNames := map[string]bool{}
+	configType := reflect.TypeOf(Config{})
+	for i := range configType.NumField() {
+		name, _, _ := strings.Cut(configType.Field(i).Tag.Get("env"), ",")
+		envNames[name] = true
+	}
+	for _, name := range Presets() {
+		preset, err := Preset(name)
+		require.NoError(t, err)
+		for key := range preset {
+			require.True(t, envNames[key], "preset %s sets unknown setting %s", name, key)
+		}
+	}
+	_, err := Preset("everything")
+	require.True(t, errors.Is(err, ErrUnknownPreset))
+}
@@ -0,0 +1,70 @@
+package audit
+
+import "github.com/salsgithub/godst/graph"
+
+type Progress struct {
+	Fetched int     `json:"fetched"`
+	Queued  int     `json:"queued"`
+	Total   int     `json:"total"`
+	Percent float64 `json:"percent"`
+	Done    bool    `json:"done"`
+}
+
+// Cancel stops a running audit gracefully, or prevents one that has not started from crawling
+func (a *Audit) Cancel() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cancelled = true
+	if a.cancel != nil {
+		a.cancel()
+		return
+	}
+	// Nothing will be crawled, so there is nothing to wait for
+	a.closeSubscribers()
+}
+
+// Progress estimates completion from the tasks fetched against every task enqueued so far
+func (a *Audit) Progress() Progress {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := Progress{
+		Fetched: len(a.statuses) + a.fetchErrs,
+		Queued:  a.tasks.Len(),
+		Total:   a.enqueued,
+		Done:    a.done,
+	}
+	switch {
+	case p.Done:
+		p.Percent = 100
+	case p.Total > 0:
+		p.Percent = float64(p.Fetched) / float64(p.Total) * 100
+	}
+	return p
+}
+
+func (a *Audit) markDone() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.done = true
+	a.closeSubscribers()
+}
+
+// enqueue must be called with a.mu held
+func (a *Audit) enqueue(t *task) {
+	a.tasks.Enqueue(t)
+	a.enqueued++
+}
+
+func (a *Audit) graphSnapshot() *graph.Graph[string] {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	g := graph.New[string]()
+	for _, node := range a.siteGraph.Nodes() {
+		g.AddNode(node)
+		neighbours, _ := a.siteGraph.Neighbours(node)
+		for _, neighbour := range neighbours {
+			g.AddEdge(node, neighbour.Link, neighbour.Weight)
+		}
+	}
+	return g
+}
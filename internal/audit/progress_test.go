@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+type blockingFetcher struct {
+	mockFetcher
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return b.mockFetcher.Fetch(ctx, u)
+}
+
+func TestAudit_CancelAndProgress(t *testing.T) {
+	newAudit := func() (*Audit, *blockingFetcher) {
+		fetcher := &blockingFetcher{
+			mockFetcher: mockFetcher{
+				responses: map[string]*http.Response{
+					"https://example.com":        successResponse(`<html><body><a href="/page-a">A</a></body></html>`),
+					"https://example.com/page-a": successResponse(`<html><body></body></html>`),
+				},
+			},
+			started: make(chan struct{}, 10),
+			release: make(chan struct{}),
+		}
+		c := testConfig
+		c.MaxWorkers = 1
+		return newTestAudit(t, c, fetcher, extractor.NewLinkExtractor()), fetcher
+	}
+	t.Run("progress while running and when done", func(t *testing.T) {
+		a, fetcher := newAudit()
+		require.Equal(t, Progress{}, a.Progress())
+		done := make(chan error, 1)
+		go func() { done <- a.Start(context.Background()) }()
+		<-fetcher.started
+		require.Equal(t, Progress{Fetched: 0, Queued: 0, Total: 1}, a.Progress())
+		fetcher.release <- struct{}{}
+		<-fetcher.started
+		require.Equal(t, Progress{Fetched: 1, Queued: 0, Total: 2, Percent: 50}, a.Progress())
+		require.Len(t, a.Pages(), 1)
+		fetcher.release <- struct{}{}
+		require.NoError(t, <-done)
+		require.Equal(t, Progress{Fetched: 2, Queued: 0, Total: 2, Percent: 100, Done: true}, a.Progress())
+	})
+	t.Run("cancel stops a running audit", func(t *testing.T) {
+		a, fetcher := newAudit()
+		done := make(chan error, 1)
+		go func() { done <- a.Start(context.Background()) }()
+		<-fetcher.started
+		a.Cancel()
+		require.NoError(t, <-done)
+		require.Empty(t, a.Pages())
+		require.True(t, a.Progress().Done)
+	})
+	t.Run("cancel before start prevents crawling", func(t *testing.T) {
+		a, fetcher := newAudit()
+		a.Cancel()
+		require.NoError(t, a.Start(context.Background()))
+		require.Empty(t, fetcher.started)
+	})
+}
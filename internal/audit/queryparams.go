@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+const CheckIgnorableParam = "ignorable-query-param"
+
+const (
+	ParamChangesContent = "changes-content"
+	ParamDuplicate      = "duplicate"
+	ParamMixed          = "mixed"
+)
+
+// ParamImpact is whether removing a query parameter from the sampled urls carrying it changed
+// what they served. Duplicate parameters, such as tracking ones, are safe to strip when
+// normalising urls.
+type ParamImpact struct {
+	Param     string   `json:"param"`
+	Sampled   int      `json:"sampled"`
+	Different int      `json:"different"`
+	Verdict   string   `json:"verdict"`
+	Samples   []string `json:"samples"`
+}
+
+// QueryParams returns the impact of each query parameter sampled once the crawl finished, sorted
+// by parameter
+func (a *Audit) QueryParams() []ParamImpact {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.paramImpacts)
+}
+
+// recordQueryParams keeps up to AUDIT_QUERY_PARAM_SAMPLES urls linked with each query parameter.
+// It must be called with a.mu held.
+func (a *Audit) recordQueryParams(u *url.URL) {
+	if a.config.QueryParamSamples <= 0 || u.RawQuery == "" {
+		return
+	}
+	sample := *u
+	sample.Fragment = ""
+	for param := range u.Query() {
+		samples := a.paramSamples[param]
+		if len(samples) < a.config.QueryParamSamples && !slices.Contains(samples, sample.String()) {
+			a.paramSamples[param] = append(samples, intern(sample.String()))
+		}
+	}
+}
+
+// analyseQueryParams fetches each sampled url with and without its parameter, comparing the bodies.
+// Samples where either fetch fails or is not a 200 are left out.
+func (a *Audit) analyseQueryParams(ctx context.Context) {
+	a.mu.Lock()
+	params := make([]string, 0, len(a.paramSamples))
+	for param := range a.paramSamples {
+		params = append(params, param)
+	}
+	a.mu.Unlock()
+	slices.Sort(params)
+	impacts := []ParamImpact{}
+	for _, param := range params {
+		a.mu.Lock()
+		samples := slices.Clone(a.paramSamples[param])
+		a.mu.Unlock()
+		impact := ParamImpact{Param: param, Samples: []string{}}
+		for _, sample := range samples {
+			if ctx.Err() != nil {
+				return
+			}
+			different, ok := a.paramChangesContent(ctx, sample, param)
+			if !ok {
+				continue
+			}
+			impact.Sampled++
+			impact.Samples = append(impact.Samples, sample)
+			if different {
+				impact.Different++
+			}
+		}
+		if impact.Sampled == 0 {
+			continue
+		}
+		switch impact.Different {
+		case 0:
+			impact.Verdict = ParamDuplicate
+		case impact.Sampled:
+			impact.Verdict = ParamChangesContent
+		default:
+			impact.Verdict = ParamMixed
+		}
+		impacts = append(impacts, impact)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.paramImpacts = impacts
+	for _, impact := range impacts {
+		if impact.Verdict == ParamDuplicate {
+			a.checkFindings = append(a.checkFindings, Finding{
+				Check:  CheckIgnorableParam,
+				URL:    impact.Samples[0],
+				Detail: fmt.Sprintf("query parameter %q did not change the content of %d sampled urls and can be stripped", impact.Param, impact.Sampled),
+			})
+		}
+	}
+	a.logger.Info("Query parameters analysed", "params", len(impacts))
+}
+
+func (a *Audit) paramChangesContent(ctx context.Context, sample, param string) (bool, bool) {
+	with, err := url.Parse(sample)
+	if err != nil {
+		return false, false
+	}
+	without := *with
+	query := without.Query()
+	query.Del(param)
+	without.RawQuery = query.Encode()
+	withHash, ok := a.bodyHash(ctx, with)
+	if !ok {
+		return false, false
+	}
+	withoutHash, ok := a.bodyHash(ctx, &without)
+	if !ok {
+		return false, false
+	}
+	return !bytes.Equal(withHash, withoutHash), true
+}
+
+func (a *Audit) bodyHash(ctx context.Context, u *url.URL) ([]byte, bool) {
+	if err := a.waitForHost(ctx, u.Hostname()); err != nil {
+		return nil, false
+	}
+	response, err := a.fetcher.Fetch(ctx, u)
+	if err != nil {
+		a.logger.Debug("Failed to fetch query parameter sample", "url", u.String(), "err", err)
+		return nil, false
+	}
+	defer closeBody(response.Body)
+	if response.StatusCode != http.StatusOK {
+		return nil, false
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, newBoundedReader(response.Body, a.config.MaxBodyBytes)); err != nil {
+		return nil, false
+	}
+	return hash.Sum(nil), true
+}
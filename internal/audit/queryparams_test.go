@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_QueryParams(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.QueryParamSamples = 2
+	fetcher := pagesFetcher{
+		"https://example.com":                       `<a href="/a?utm_source=x">a</a><a href="/b?utm_source=y&page=2">b</a><a href="/missing?page=3">missing</a>`,
+		"https://example.com/a?utm_source=x":        "a",
+		"https://example.com/a":                     "a",
+		"https://example.com/b?utm_source=y&page=2": "b page 2",
+		"https://example.com/b?utm_source=y":        "b page 1",
+		"https://example.com/b?page=2":              "b page 2",
+	}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []ParamImpact{
+		{Param: "page", Sampled: 1, Different: 1, Verdict: ParamChangesContent, Samples: []string{"https://example.com/b?utm_source=y&page=2"}},
+		{Param: "utm_source", Sampled: 2, Verdict: ParamDuplicate, Samples: []string{"https://example.com/a?utm_source=x", "https://example.com/b?utm_source=y&page=2"}},
+	}, a.QueryParams())
+	findings := []Finding{}
+	for _, f := range a.Findings() {
+		if f.Check == CheckIgnorableParam {
+			findings = append(findings, f)
+		}
+	}
+	require.Equal(t, []Finding{{Check: CheckIgnorableParam, URL: "https://example.com/a?utm_source=x", Detail: `query parameter "utm_source" did not change the content of 2 sampled urls and can be stripped`}}, findings)
+}
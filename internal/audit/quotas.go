@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// crawlQuotas caps how many urls are crawled at a depth or under a path prefix, so enormous
+// catalogs can be sampled rather than crawled in full
+type crawlQuotas struct {
+	depths map[int]int
+	// sections is ordered longest prefix first, as a url counts towards the most specific one
+	sections      []sectionQuota
+	depthCounts   map[int]int
+	sectionCounts map[string]int
+	// reached holds the quotas already warned about
+	reached map[string]bool
+}
+
+type sectionQuota struct {
+	prefix string
+	limit  int
+}
+
+// parseQuotas reads AUDIT_DEPTH_QUOTAS, such as 1=50,2=500, and AUDIT_SECTION_QUOTAS, such as
+// /product/=500,/blog/=100. It returns nil when neither is set.
+func parseQuotas(depths, sections string) (*crawlQuotas, error) {
+	if depths == "" && sections == "" {
+		return nil, nil
+	}
+	q := &crawlQuotas{depths: map[int]int{}, depthCounts: map[int]int{}, sectionCounts: map[string]int{}, reached: map[string]bool{}}
+	for _, entry := range splitList(depths) {
+		key, value, _ := strings.Cut(entry, "=")
+		depth, err := strconv.Atoi(strings.TrimSpace(key))
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("%w: %q, AUDIT_DEPTH_QUOTAS entries must look like 2=500 with a depth of 1 or more", ErrInvalidQuotas, entry)
+		}
+		limit, err := parseQuota(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q, %w", ErrInvalidQuotas, entry, err)
+		}
+		q.depths[depth] = limit
+	}
+	for _, entry := range splitList(sections) {
+		prefix, value, _ := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("%w: %q, AUDIT_SECTION_QUOTAS entries must look like /product/=500", ErrInvalidQuotas, entry)
+		}
+		limit, err := parseQuota(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q, %w", ErrInvalidQuotas, entry, err)
+		}
+		q.sections = append(q.sections, sectionQuota{prefix: prefix, limit: limit})
+	}
+	slices.SortStableFunc(q.sections, func(x, y sectionQuota) int {
+		return cmp.Compare(len(y.prefix), len(x.prefix))
+	})
+	return q, nil
+}
+
+func parseQuota(value string) (int, error) {
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("quota must be a number of pages, zero or more")
+	}
+	return limit, nil
+}
+
+// withinQuotas counts u towards the quotas of its depth and section when both have room left,
+// warning the first time a quota is reached. It must be called with a.mu held.
+func (a *Audit) withinQuotas(u *url.URL, depth int) bool {
+	q := a.quotas
+	if q == nil {
+		return true
+	}
+	depthLimit, depthQuota := q.depths[depth]
+	if depthQuota && q.depthCounts[depth] >= depthLimit {
+		if key := "depth " + strconv.Itoa(depth); !q.reached[key] {
+			q.reached[key] = true
+			a.logger.Warn("Depth quota reached, no longer crawling urls at this depth", "depth", depth, "quota", depthLimit)
+		}
+		return false
+	}
+	section, sectionQuota := q.section(u)
+	if sectionQuota && q.sectionCounts[section.prefix] >= section.limit {
+		if key := "section " + section.prefix; !q.reached[key] {
+			q.reached[key] = true
+			a.logger.Warn("Section quota reached, no longer crawling urls in this section", "section", section.prefix, "quota", section.limit)
+		}
+		return false
+	}
+	q.depthCounts[depth]++
+	if sectionQuota {
+		q.sectionCounts[section.prefix]++
+	}
+	return true
+}
+
+func (q *crawlQuotas) section(u *url.URL) (sectionQuota, bool) {
+	for _, section := range q.sections {
+		if strings.HasPrefix(u.Path, section.prefix) {
+			return section, true
+		}
+	}
+	return sectionQuota{}, false
+}
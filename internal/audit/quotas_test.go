@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_Quotas(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxDepth = 3
+	c.DepthQuotas = "2=0"
+	c.SectionQuotas = "/product/=2, /product/featured/=1"
+	fetcher := pagesFetcher{
+		"https://example.com": `<a href="/product/1">1</a><a href="/product/2">2</a><a href="/product/3">3</a>
+			<a href="/product/featured/a">a</a><a href="/product/featured/b">b</a><a href="/about">about</a>`,
+		"https://example.com/about": `<a href="/team">team</a>`,
+	}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	a.logger = slog.New(slog.DiscardHandler)
+	require.NoError(t, a.Start(context.Background()))
+	urls := []string{}
+	for _, page := range a.Pages() {
+		urls = append(urls, page.URL)
+	}
+	require.Equal(t, []string{"https://example.com/", "https://example.com/about", "https://example.com/product/1", "https://example.com/product/2", "https://example.com/product/featured/a"}, urls)
+	// Links beyond the quotas are still recorded in the graph
+	neighbours, ok := a.siteGraph.Neighbours("https://example.com/about")
+	require.True(t, ok)
+	require.Len(t, neighbours, 1)
+	c.DepthQuotas = "0=5"
+	require.True(t, errors.Is(c.Validate(), ErrInvalidQuotas))
+}
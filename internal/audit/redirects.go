@@ -0,0 +1,208 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CheckTemporaryRedirect  = "temporary-redirect"
+	CheckMixedRedirectChain = "mixed-redirect-chain"
+	CheckTooManyRedirects   = "too-many-redirects"
+)
+
+const (
+	RedirectPermanent = "permanent"
+	RedirectTemporary = "temporary"
+	RedirectMixed     = "mixed"
+)
+
+// longLivedRedirect is how long a temporary redirect is seen for before it should be permanent
+const longLivedRedirect = 30 * 24 * time.Hour
+
+// RedirectHop is one response in a chain of redirects
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// Redirect is a crawled url that redirected to Target through Hops. Kind is permanent when every hop
+// is a 301 or 308, temporary when none is, and mixed otherwise.
+type Redirect struct {
+	URL    string        `json:"url"`
+	Target string        `json:"target"`
+	Kind   string        `json:"kind"`
+	Hops   []RedirectHop `json:"hops"`
+	// Stopped is set when the fetcher did not follow the last hop, because of AUDIT_MAX_REDIRECTS,
+	// AUDIT_FOLLOW_REDIRECTS or AUDIT_CROSS_HOST_REDIRECTS, so Target is where it points
+	Stopped bool `json:"stopped,omitempty"`
+	// FirstSeen is when a redirect with a temporary hop was first crawled, when AUDIT_REDIRECTS_FILE
+	// is set
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+}
+
+// redirectHistory is the AUDIT_REDIRECTS_FILE, recording when each temporary redirect was first seen
+type redirectHistory struct {
+	Temporary map[string]time.Time `json:"temporary"`
+}
+
+// loadRedirectHistory returns an empty history when the file does not exist yet
+func loadRedirectHistory(path string) (map[string]time.Time, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]time.Time{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRedirectsFile, err)
+	}
+	var history redirectHistory
+	if err := json.Unmarshal(b, &history); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRedirectsFile, err)
+	}
+	if history.Temporary == nil {
+		history.Temporary = map[string]time.Time{}
+	}
+	return history.Temporary, nil
+}
+
+func isPermanentRedirect(code int) bool {
+	return code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect
+}
+
+// redirectKind classifies a chain by the types of its hops
+func redirectKind(hops []RedirectHop) string {
+	permanent := 0
+	for _, hop := range hops {
+		if isPermanentRedirect(hop.StatusCode) {
+			permanent++
+		}
+	}
+	switch permanent {
+	case len(hops):
+		return RedirectPermanent
+	case 0:
+		return RedirectTemporary
+	}
+	return RedirectMixed
+}
+
+// redirectHops follows a response back through the redirects the client followed to reach it
+func redirectHops(response *http.Response) []RedirectHop {
+	hops := []RedirectHop{}
+	for r := response.Request.Response; r != nil && r.Request != nil; r = r.Request.Response {
+		hops = append(hops, RedirectHop{URL: r.Request.URL.String(), StatusCode: r.StatusCode})
+	}
+	slices.Reverse(hops)
+	return hops
+}
+
+// redirectLocation is where a redirect the fetcher returned rather than followed points
+func redirectLocation(response *http.Response) (string, bool) {
+	if response.StatusCode < http.StatusMultipleChoices || response.StatusCode >= http.StatusBadRequest || response.Request == nil {
+		return "", false
+	}
+	location, err := response.Location()
+	if err != nil {
+		return "", false
+	}
+	return location.String(), true
+}
+
+func (a *Audit) recordRedirect(source string, response *http.Response) {
+	if response.Request == nil {
+		return
+	}
+	hops := redirectHops(response)
+	target := response.Request.URL.String()
+	location, stopped := redirectLocation(response)
+	if stopped {
+		hops = append(hops, RedirectHop{URL: target, StatusCode: response.StatusCode})
+		target = location
+	}
+	if len(hops) == 0 {
+		return
+	}
+	r := &Redirect{URL: source, Target: target, Kind: redirectKind(hops), Hops: hops, Stopped: stopped}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if r.Kind != RedirectPermanent && a.redirectHistory != nil {
+		r.FirstSeen = a.started.UTC()
+		if seen, ok := a.redirectHistory[source]; ok {
+			r.FirstSeen = seen
+		}
+	}
+	a.redirects[source] = r
+}
+
+// Redirects returns the redirects crawled, sorted by url
+func (a *Audit) Redirects() []Redirect {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	redirects := make([]Redirect, 0, len(a.redirects))
+	for _, u := range slices.Sorted(maps.Keys(a.redirects)) {
+		r := *a.redirects[u]
+		r.Hops = slices.Clone(r.Hops)
+		redirects = append(redirects, r)
+	}
+	return redirects
+}
+
+// UpdateRedirects writes when each temporary redirect crawled was first seen to AUDIT_REDIRECTS_FILE,
+// dropping those that are no longer temporary
+func (a *Audit) UpdateRedirects() error {
+	if a.config.RedirectsFile == "" {
+		return nil
+	}
+	history := redirectHistory{Temporary: map[string]time.Time{}}
+	for _, r := range a.Redirects() {
+		if r.Kind != RedirectPermanent {
+			history.Temporary[r.URL] = r.FirstSeen
+		}
+	}
+	b, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(a.config.RedirectsFile, b, 0644); err != nil {
+		return fmt.Errorf("error writing redirects: %w", err)
+	}
+	a.logger.Info("Redirects updated", "path", a.config.RedirectsFile, "temporary", len(history.Temporary))
+	return nil
+}
+
+// redirectFindings must be called with a.mu held. Without AUDIT_REDIRECTS_FILE there is no telling
+// how long a temporary redirect has been in place, so every one is reported.
+func (a *Audit) redirectFindings() []Finding {
+	findings := []Finding{}
+	for u, r := range a.redirects {
+		chain := make([]string, 0, len(r.Hops))
+		for _, hop := range r.Hops {
+			chain = append(chain, strconv.Itoa(hop.StatusCode))
+		}
+		if r.Stopped && a.config.FollowRedirects && len(r.Hops) > a.config.MaxRedirects {
+			findings = append(findings, Finding{Check: CheckTooManyRedirects, URL: u, Detail: fmt.Sprintf("stopped at %s, AUDIT_MAX_REDIRECTS is %d", r.Hops[len(r.Hops)-1].URL, a.config.MaxRedirects)})
+		}
+		if r.Kind == RedirectMixed {
+			findings = append(findings, Finding{Check: CheckMixedRedirectChain, URL: u, Detail: fmt.Sprintf("%s to %s", strings.Join(chain, ", "), r.Target)})
+		}
+		if r.Kind == RedirectPermanent {
+			continue
+		}
+		if a.redirectHistory == nil {
+			findings = append(findings, Finding{Check: CheckTemporaryRedirect, URL: u, Detail: fmt.Sprintf("%s to %s", strings.Join(chain, ", "), r.Target)})
+			continue
+		}
+		if age := a.started.Sub(r.FirstSeen); age >= longLivedRedirect {
+			findings = append(findings, Finding{Check: CheckTemporaryRedirect, URL: u, Detail: fmt.Sprintf("%s to %s for %d days", strings.Join(chain, ", "), r.Target, int(age.Hours()/24))})
+		}
+	}
+	return findings
+}
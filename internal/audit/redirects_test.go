@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+// redirectedResponse is a response reached by following hops, as the http client leaves it
+func redirectedResponse(target string, hops ...RedirectHop) *http.Response {
+	var previous *http.Response
+	for _, hop := range hops {
+		u, _ := url.Parse(hop.URL)
+		previous = &http.Response{StatusCode: hop.StatusCode, Request: &http.Request{URL: u, Response: previous}}
+	}
+	response := successResponse("")
+	u, _ := url.Parse(target)
+	response.Request = &http.Request{URL: u, Response: previous}
+	return response
+}
+
+func TestAudit_Redirects(t *testing.T) {
+	newFetcher := func() *mockFetcher {
+		return &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com":       successResponse(`<a href="/old">old</a><a href="/promo">promo</a><a href="/chain">chain</a>`),
+			"https://example.com/old":   redirectedResponse("https://example.com/new", RedirectHop{URL: "https://example.com/old", StatusCode: http.StatusMovedPermanently}),
+			"https://example.com/promo": redirectedResponse("https://example.com/sale", RedirectHop{URL: "https://example.com/promo", StatusCode: http.StatusFound}),
+			"https://example.com/chain": redirectedResponse("https://example.com/end",
+				RedirectHop{URL: "https://example.com/chain", StatusCode: http.StatusMovedPermanently},
+				RedirectHop{URL: "https://example.com/mid", StatusCode: http.StatusTemporaryRedirect}),
+		}}
+	}
+	redirectFindings := func(a *Audit) []Finding {
+		findings := []Finding{}
+		for _, f := range a.Findings() {
+			if f.Check == CheckTemporaryRedirect || f.Check == CheckMixedRedirectChain {
+				findings = append(findings, f)
+			}
+		}
+		return findings
+	}
+	c := testConfig
+	c.RespectRobots = false
+	a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	redirects := a.Redirects()
+	require.Len(t, redirects, 3)
+	require.Equal(t, Redirect{
+		URL:    "https://example.com/chain",
+		Target: "https://example.com/end",
+		Kind:   RedirectMixed,
+		Hops:   []RedirectHop{{URL: "https://example.com/chain", StatusCode: 301}, {URL: "https://example.com/mid", StatusCode: 307}},
+	}, redirects[0])
+	require.Equal(t, RedirectPermanent, redirects[1].Kind)
+	require.Equal(t, RedirectTemporary, redirects[2].Kind)
+	require.Equal(t, []Finding{
+		{Check: CheckMixedRedirectChain, URL: "https://example.com/chain", Detail: "301, 307 to https://example.com/end"},
+		{Check: CheckTemporaryRedirect, URL: "https://example.com/chain", Detail: "301, 307 to https://example.com/end"},
+		{Check: CheckTemporaryRedirect, URL: "https://example.com/promo", Detail: "302 to https://example.com/sale"},
+	}, redirectFindings(a))
+
+	t.Run("only long lived temporary redirects with a history", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redirects.json")
+		seen := time.Now().Add(-60 * 24 * time.Hour).UTC().Truncate(time.Second)
+		b, err := json.Marshal(map[string]any{"temporary": map[string]time.Time{"https://example.com/promo": seen, "https://example.com/gone": seen}})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, b, 0644))
+		c.RedirectsFile = path
+		a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		require.NoError(t, a.Start(context.Background()))
+		require.Equal(t, []Finding{
+			{Check: CheckMixedRedirectChain, URL: "https://example.com/chain", Detail: "301, 307 to https://example.com/end"},
+			{Check: CheckTemporaryRedirect, URL: "https://example.com/promo", Detail: "302 to https://example.com/sale for 60 days"},
+		}, redirectFindings(a))
+		require.NoError(t, a.UpdateRedirects())
+		history, err := loadRedirectHistory(path)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, seen, history["https://example.com/promo"])
+		require.Contains(t, history, "https://example.com/chain")
+	})
+}
+
+func TestAudit_StoppedRedirects(t *testing.T) {
+	stopped := func(rawURL, location string, hops ...RedirectHop) *http.Response {
+		response := redirectedResponse(rawURL, hops...)
+		response.StatusCode = http.StatusMovedPermanently
+		response.Header = http.Header{"Location": {location}}
+		return response
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.FollowRedirects = true
+	c.MaxRedirects = 1
+	a, err := New(c, &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":      successResponse(`<a href="/long">long</a><a href="/away">away</a>`),
+		"https://example.com/long": stopped("https://example.com/mid", "/end", RedirectHop{URL: "https://example.com/long", StatusCode: http.StatusMovedPermanently}),
+		"https://example.com/away": stopped("https://example.com/away", "https://other.com/"),
+		"https://example.com/end":  successResponse("end"),
+	}}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, []Redirect{
+		{URL: "https://example.com/away", Target: "https://other.com/", Kind: RedirectPermanent, Hops: []RedirectHop{{URL: "https://example.com/away", StatusCode: 301}}, Stopped: true},
+		{
+			URL:     "https://example.com/long",
+			Target:  "https://example.com/end",
+			Kind:    RedirectPermanent,
+			Hops:    []RedirectHop{{URL: "https://example.com/long", StatusCode: 301}, {URL: "https://example.com/mid", StatusCode: 301}},
+			Stopped: true,
+		},
+	}, a.Redirects())
+	findings := []Finding{}
+	for _, f := range a.Findings() {
+		if f.Check == CheckTooManyRedirects {
+			findings = append(findings, f)
+		}
+	}
+	require.Equal(t, []Finding{{Check: CheckTooManyRedirects, URL: "https://example.com/long", Detail: "stopped at https://example.com/mid, AUDIT_MAX_REDIRECTS is 1"}}, findings)
+	// The target of a redirect that was not followed is crawled as a link
+	neighbours, _ := a.graphSnapshot().Neighbours("https://example.com/long")
+	require.Equal(t, []graph.Edge[string]{{Link: "https://example.com/end", Weight: 1}}, neighbours)
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	CheckRobotsDisallowAll      = "robots-disallow-all"
+	CheckRobotsConflict         = "robots-conflicting-rules"
+	CheckRobotsMissingSitemap   = "robots-missing-sitemap"
+	CheckRobotsUnmatchedRule    = "robots-unmatched-rule"
+	robotsAllow, robotsDisallow = "allow", "disallow"
+)
+
+// robotsRule is an Allow or Disallow line of robots.txt and the user agents its group applies to
+type robotsRule struct {
+	line    int
+	field   string
+	pattern string
+	agents  []string
+}
+
+func (r robotsRule) String() string {
+	return fmt.Sprintf("line %d: %s: %s", r.line, strings.ToUpper(r.field[:1])+r.field[1:], r.pattern)
+}
+
+// parseRobotsRules reads the rules of every group, returning whether any Sitemap is listed.
+// Consecutive User-agent lines share the rules that follow them.
+func parseRobotsRules(b []byte) ([]robotsRule, bool) {
+	rules := []robotsRule{}
+	agents := []string{}
+	sitemap, inRules := false, false
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(text, ":")
+		if !ok {
+			continue
+		}
+		field, value = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(value)
+		switch field {
+		case "user-agent":
+			if inRules {
+				agents, inRules = []string{}, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case robotsAllow, robotsDisallow:
+			inRules = true
+			if value != "" {
+				rules = append(rules, robotsRule{line: line, field: field, pattern: value, agents: agents})
+			}
+		case "sitemap":
+			sitemap = true
+		}
+	}
+	return rules, sitemap
+}
+
+// robotsPattern turns a rule's path into a regular expression, where * matches anything and a
+// trailing $ anchors the end of the path
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expression := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expression += "$"
+	}
+	return regexp.MustCompile(expression)
+}
+
+// robotsFindings must be called with a.mu held. Disallowing the whole site is expected of staging,
+// and AUDIT_ENVIRONMENT reports it itself, so it is only flagged when no environment is set. Rules
+// are matched against the urls crawled or kept from the crawl, so a shallow crawl reports more of
+// them as unmatched.
+func (a *Audit) robotsFindings() []Finding {
+	if a.robotsText == nil {
+		return []Finding{}
+	}
+	robotsURL := a.startURL.Scheme + "://" + a.startURL.Host + "/robots.txt"
+	rules, sitemap := parseRobotsRules(a.robotsText)
+	findings := []Finding{}
+	if !sitemap {
+		findings = append(findings, Finding{Check: CheckRobotsMissingSitemap, URL: robotsURL, Detail: "no Sitemap directive"})
+	}
+	paths := []string{}
+	site := normaliseHost(a.startURL.Host)
+	for _, u := range a.crawledURLs() {
+		if parsed, err := url.Parse(u); err == nil && normaliseHost(parsed.Host) == site {
+			paths = append(paths, parsed.RequestURI())
+		}
+	}
+	for i, rule := range rules {
+		if rule.field == robotsDisallow && rule.pattern == "/" && a.config.Environment == "" {
+			findings = append(findings, Finding{Check: CheckRobotsDisallowAll, URL: robotsURL, Detail: fmt.Sprintf("%s blocks the whole site for %s", rule, strings.Join(rule.agents, ", "))})
+		}
+		for _, other := range rules[i+1:] {
+			if rule.field != other.field && sameAgents(rule.agents, other.agents) && strings.TrimRight(rule.pattern, "*") == strings.TrimRight(other.pattern, "*") {
+				findings = append(findings, Finding{Check: CheckRobotsConflict, URL: robotsURL, Detail: fmt.Sprintf("%s conflicts with %s", rule, other)})
+			}
+		}
+		pattern := robotsPattern(rule.pattern)
+		matched := false
+		for _, path := range paths {
+			if pattern.MatchString(path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			findings = append(findings, Finding{Check: CheckRobotsUnmatchedRule, URL: robotsURL, Detail: rule.String() + " matches no crawled url"})
+		}
+	}
+	return findings
+}
+
+// crawledURLs must be called with a.mu held. It includes the urls robots.txt kept from the crawl.
+func (a *Audit) crawledURLs() []string {
+	urls := make([]string, 0, len(a.statuses)+len(a.disallowed))
+	for u := range a.statuses {
+		urls = append(urls, u)
+	}
+	for u := range a.disallowed {
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+func sameAgents(x, y []string) bool {
+	return strings.Join(x, ",") == strings.Join(y, ",")
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_RobotsLint(t *testing.T) {
+	robots := "User-agent: *\nDisallow: /private\nAllow: /private*\nDisallow: /old/\nDisallow: /*.pdf$ # documents\n\nUser-agent: badbot\nDisallow: /\n"
+	c := testConfig
+	c.CheckRobots = true
+	a, err := New(c, pagesFetcher{
+		"https://example.com/robots.txt": robots,
+		"https://example.com":            `<a href="/private">private</a><a href="/guide.pdf">guide</a><a href="/a">a</a>`,
+	}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	findings := []Finding{}
+	for _, f := range a.Findings() {
+		if strings.HasPrefix(f.Check, "robots-") {
+			findings = append(findings, f)
+		}
+	}
+	robotsURL := "https://example.com/robots.txt"
+	require.Equal(t, []Finding{
+		{Check: CheckRobotsConflict, URL: robotsURL, Detail: "line 2: Disallow: /private conflicts with line 3: Allow: /private*"},
+		{Check: CheckRobotsDisallowAll, URL: robotsURL, Detail: "line 8: Disallow: / blocks the whole site for badbot"},
+		{Check: CheckRobotsMissingSitemap, URL: robotsURL, Detail: "no Sitemap directive"},
+		{Check: CheckRobotsUnmatchedRule, URL: robotsURL, Detail: "line 4: Disallow: /old/ matches no crawled url"},
+	}, findings)
+
+	c.Environment = EnvironmentStaging
+	c.CheckRobots = false
+	a, err = New(c, pagesFetcher{"https://example.com/robots.txt": robots}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	for _, f := range a.Findings() {
+		require.False(t, strings.HasPrefix(f.Check, "robots-"))
+	}
+}
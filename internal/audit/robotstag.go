@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	CheckNoIndex  = "noindex"
+	CheckNoFollow = "nofollow"
+)
+
+type robotsDirectives struct {
+	noIndex  bool
+	noFollow bool
+}
+
+// parseRobotsTag reads the X-Robots-Tag headers of a response. Directives scoped to a user agent,
+// such as "googlebot: noindex", only apply when the agent matches.
+func parseRobotsTag(header http.Header, agent string) robotsDirectives {
+	var d robotsDirectives
+	for _, value := range header.Values("X-Robots-Tag") {
+		if scope, rest, ok := strings.Cut(value, ":"); ok && !strings.Contains(scope, ",") {
+			if !strings.EqualFold(strings.TrimSpace(scope), agent) {
+				continue
+			}
+			value = rest
+		}
+		d.add(value)
+	}
+	return d
+}
+
+// add reads a comma-separated list of directives, as found in headers and robots meta tags
+func (d *robotsDirectives) add(value string) {
+	for _, directive := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex":
+			d.noIndex = true
+		case "nofollow":
+			d.noFollow = true
+		case "none":
+			d.noIndex, d.noFollow = true, true
+		}
+	}
+}
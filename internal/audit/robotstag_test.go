@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestParseRobotsTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    robotsDirectives
+	}{
+		{name: "absent", want: robotsDirectives{}},
+		{name: "noindex", headers: []string{"noindex"}, want: robotsDirectives{noIndex: true}},
+		{name: "both in one header", headers: []string{"NoIndex, nofollow"}, want: robotsDirectives{noIndex: true, noFollow: true}},
+		{name: "none", headers: []string{"none"}, want: robotsDirectives{noIndex: true, noFollow: true}},
+		{name: "several headers", headers: []string{"noarchive", "nofollow"}, want: robotsDirectives{noFollow: true}},
+		{name: "matching agent", headers: []string{"Agent: nofollow"}, want: robotsDirectives{noFollow: true}},
+		{name: "other agent", headers: []string{"googlebot: noindex, nofollow"}, want: robotsDirectives{}},
+		{name: "unavailable_after date", headers: []string{"unavailable_after: 25 Jun 2010 15:00:00 PST"}, want: robotsDirectives{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, value := range test.headers {
+				header.Add("X-Robots-Tag", value)
+			}
+			require.Equal(t, test.want, parseRobotsTag(header, "agent"))
+		})
+	}
+}
+
+func TestAudit_RobotsTag(t *testing.T) {
+	newFetcher := func() *mockFetcher {
+		home := successResponse(`<a href="/a">A</a>`)
+		home.Header = http.Header{"X-Robots-Tag": []string{"noindex, nofollow"}}
+		return &mockFetcher{
+			responses: map[string]*http.Response{
+				"https://example.com":   home,
+				"https://example.com/a": successResponse(""),
+			},
+		}
+	}
+	t.Run("nofollow stops links being followed", func(t *testing.T) {
+		c := testConfig
+		a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		require.NoError(t, a.Start(context.Background()))
+		require.Len(t, a.Pages(), 1)
+		require.Equal(t, []Finding{
+			{Check: CheckNoFollow, URL: "https://example.com/", Detail: "X-Robots-Tag: nofollow"},
+			{Check: CheckNoIndex, URL: "https://example.com/", Detail: "X-Robots-Tag: noindex"},
+		}, a.Findings())
+	})
+	t.Run("links followed when robots are not respected", func(t *testing.T) {
+		c := testConfig
+		c.RespectRobots = false
+		a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		require.NoError(t, a.Start(context.Background()))
+		require.Len(t, a.Pages(), 2)
+		require.Len(t, a.Findings(), 2)
+	})
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// stdinSeeds is the AUDIT_SEEDS_FILE reading seeds from stdin
+const stdinSeeds = "-"
+
+// ReadSeeds reads one url per line, skipping blank lines and # comments
+func ReadSeeds(r io.Reader) ([]string, error) {
+	seeds := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seeds = append(seeds, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSeedsFile, err)
+	}
+	return seeds, nil
+}
+
+// loadSeeds reads AUDIT_SEEDS_FILE, which is - for stdin
+func loadSeeds(path string) ([]string, error) {
+	if path == stdinSeeds {
+		return ReadSeeds(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSeedsFile, err)
+	}
+	defer f.Close()
+	return ReadSeeds(f)
+}
+
+// enqueueSeeds must be called with a.mu held. Seeds from AUDIT_SEEDS_FILE that are not urls on
+// the site are skipped rather than failing the audit, as lists exported from logs are rarely clean.
+func (a *Audit) enqueueSeeds(urls []string) {
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		ok := err == nil && u.IsAbs()
+		if ok {
+			u, ok = a.rewrite(u)
+		}
+		if !ok || !a.schemes.Contains(u.Scheme) || !a.internalHosts.Contains(normaliseHost(u.Host)) {
+			a.logger.Warn("Skipping seed outside the site", "url", raw)
+			continue
+		}
+		a.seed(u)
+	}
+}
+
+// seed must be called with a.mu held, returning whether u was queued
+func (a *Audit) seed(u *url.URL) bool {
+	canonical := intern(a.canonicalURL(u))
+	if a.visited.Contains(canonical) {
+		return false
+	}
+	a.visited.Add(canonical)
+	a.node(canonical).depth = 0
+	if a.disallowedByRobots(u) || !a.withinPageLimit(u) {
+		return false
+	}
+	a.enqueue(&task{rawURL: intern(u.String()), depth: 0})
+	return true
+}
+
+// AddSeeds queues more urls while the audit runs, such as pages found in server logs, returning
+// how many were new. Seeds are crawled from depth 0 like the start url and must be on the site's
+// hosts, or none are added. Once the crawl has run out of work no more can be added.
+func (a *Audit) AddSeeds(urls ...string) (int, error) {
+	parsed := make([]*url.URL, 0, len(urls))
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || !u.IsAbs() {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidSeed, raw)
+		}
+		parsed = append(parsed, u)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.drained || a.done || a.cancelled {
+		return 0, ErrCrawlFinished
+	}
+	for i, u := range parsed {
+		rewritten, ok := a.rewrite(u)
+		if !ok || !a.schemes.Contains(rewritten.Scheme) || !a.internalHosts.Contains(normaliseHost(rewritten.Host)) {
+			return 0, fmt.Errorf("%w: %q is not on the site", ErrInvalidSeed, urls[i])
+		}
+		parsed[i] = rewritten
+	}
+	added := 0
+	for _, u := range parsed {
+		if a.seed(u) {
+			added++
+		}
+	}
+	if added > 0 {
+		a.logger.Info("Seeds added", "added", added)
+		a.idle.Broadcast()
+	}
+	return added, nil
+}
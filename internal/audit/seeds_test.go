@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+// seedingFetcher adds seeds to the audit while the start page is being fetched
+type seedingFetcher struct {
+	audit *Audit
+	seeds []string
+	added int
+	err   error
+}
+
+func (f *seedingFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	if u.Path == "" {
+		f.added, f.err = f.audit.AddSeeds(f.seeds...)
+	}
+	return successResponse(""), nil
+}
+
+func TestAudit_SeedsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seeds.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# from the logs\nhttps://example.com/a\n\nhttps://other.com/\n/relative\nhttps://example.com/b\n"), 0644))
+	fetcher := pagesFetcher{
+		"https://example.com":   `<a href="/start-link">link</a>`,
+		"https://example.com/a": `<a href="/a-link">link</a>`,
+		"https://example.com/b": "b",
+	}
+	crawled := func(c Config) []string {
+		a, err := New(c, fetcher, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		require.NoError(t, a.Start(context.Background()))
+		urls := []string{}
+		for _, page := range a.Pages() {
+			urls = append(urls, page.URL)
+		}
+		return urls
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.SeedsFile = path
+	require.ElementsMatch(t, []string{"https://example.com/", "https://example.com/start-link", "https://example.com/a", "https://example.com/a-link", "https://example.com/b"}, crawled(c))
+	c.SeedsOnly = true
+	require.ElementsMatch(t, []string{"https://example.com/a", "https://example.com/b"}, crawled(c))
+	c.SeedsFile = ""
+	require.True(t, errors.Is(c.Validate(), ErrInvalidSeedsFile))
+}
+
+func TestAudit_AddSeeds(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	fetcher := &seedingFetcher{seeds: []string{"https://example.com/from-logs", "https://example.com/", "https://example.com/other#top"}}
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	fetcher.audit = a
+	require.NoError(t, a.Start(context.Background()))
+	require.NoError(t, fetcher.err)
+	// The start page has already been visited
+	require.Equal(t, 2, fetcher.added)
+	urls := []string{}
+	for _, page := range a.Pages() {
+		urls = append(urls, page.URL)
+	}
+	require.ElementsMatch(t, []string{"https://example.com/", "https://example.com/from-logs", "https://example.com/other"}, urls)
+	_, err = a.AddSeeds("https://example.com/late")
+	require.True(t, errors.Is(err, ErrCrawlFinished))
+	t.Run("invalid seeds", func(t *testing.T) {
+		a, err := New(c, &mockFetcher{}, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		for _, seed := range []string{"/relative", "https://other.com/", "ftp://example.com/file"} {
+			added, err := a.AddSeeds("https://example.com/ok", seed)
+			require.True(t, errors.Is(err, ErrInvalidSeed), seed)
+			require.Zero(t, added)
+		}
+		added, err := a.AddSeeds("https://example.com/ok")
+		require.NoError(t, err)
+		require.Equal(t, 1, added)
+	})
+}
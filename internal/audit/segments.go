@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	SegmentLanguage = "language"
+	SegmentPath     = "path"
+	SegmentTemplate = "template"
+)
+
+// unknownSegment holds pages whose section cannot be told, such as those without a lang attribute
+const unknownSegment = "unknown"
+
+// SegmentSummary is the part of a Summary for one section of the site. Pages counts the pages
+// fetched in the section and Findings its new findings by check.
+type SegmentSummary struct {
+	Pages        int            `json:"pages"`
+	StatusCodes  map[int]int    `json:"status_codes"`
+	BrokenLinks  int            `json:"broken_links"`
+	ServerErrors int            `json:"server_errors"`
+	NewFindings  int            `json:"new_findings"`
+	Findings     map[string]int `json:"findings,omitempty"`
+}
+
+// segmentSummaries must be called with a.mu held
+func (a *Audit) segmentSummaries(newFindings []Finding) map[string]SegmentSummary {
+	var templates map[string]string
+	if a.config.SegmentBy == SegmentTemplate {
+		urls := make([]string, 0, len(a.statuses)+len(newFindings))
+		for u := range a.statuses {
+			urls = append(urls, u)
+		}
+		for _, finding := range newFindings {
+			urls = append(urls, finding.URL)
+		}
+		templates = templatePatterns(urls)
+	}
+	segments := map[string]*SegmentSummary{}
+	segment := func(u string) *SegmentSummary {
+		name := a.segmentOf(u)
+		if templates != nil {
+			name = templates[u]
+		}
+		s, ok := segments[name]
+		if !ok {
+			s = &SegmentSummary{StatusCodes: make(map[int]int)}
+			segments[name] = s
+		}
+		return s
+	}
+	for u, code := range a.statuses {
+		s := segment(u)
+		s.Pages++
+		s.StatusCodes[code]++
+		if !a.broken(u, code) {
+			continue
+		}
+		s.BrokenLinks++
+		if code >= http.StatusInternalServerError {
+			s.ServerErrors++
+		}
+	}
+	for _, finding := range newFindings {
+		s := segment(finding.URL)
+		s.NewFindings++
+		if s.Findings == nil {
+			s.Findings = make(map[string]int)
+		}
+		s.Findings[finding.Check]++
+	}
+	summaries := make(map[string]SegmentSummary, len(segments))
+	for name, s := range segments {
+		summaries[name] = *s
+	}
+	return summaries
+}
+
+// segmentOf must be called with a.mu held. Languages are compared case insensitively, so en-GB
+// and en-gb are one segment.
+func (a *Audit) segmentOf(u string) string {
+	if a.config.SegmentBy == SegmentLanguage {
+		if info, ok := a.nodes[u]; ok && info.lang != "" {
+			return strings.ToLower(info.lang)
+		}
+		return unknownSegment
+	}
+	return pathSegment(u)
+}
+
+// pathSegment is the first segment of u's path, such as /blog/ for both /blog and /blog/post, or
+// / for the home page. Canonical urls drop trailing slashes, so /de/ and /about cannot be told
+// apart and every top level page is its own section.
+func pathSegment(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return unknownSegment
+	}
+	first, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if first == "" {
+		return "/"
+	}
+	return "/" + first + "/"
+}
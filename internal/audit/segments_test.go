@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_Segments(t *testing.T) {
+	newFetcher := func() *mockFetcher {
+		return &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com":            successResponse(`<html lang="en"><a href="/de/">de</a><a href="/blog/post">post</a><a href="/about">about</a>`),
+			"https://example.com/de/":        successResponse(`<html lang="de"><a href="/de/kontakt">kontakt</a>`),
+			"https://example.com/blog/post":  successResponse(`<html lang="EN">post`),
+			"https://example.com/about":      successResponse(`about`),
+			"https://example.com/de/kontakt": notFoundResponse(""),
+		}}
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.MaxDepth = 3
+	a, err := New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Nil(t, a.Summary().Segments)
+
+	c.SegmentBy = SegmentPath
+	a, err = New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, map[string]SegmentSummary{
+		"/":       {Pages: 1, StatusCodes: map[int]int{200: 1}},
+		"/about/": {Pages: 1, StatusCodes: map[int]int{200: 1}},
+		"/de/":    {Pages: 2, StatusCodes: map[int]int{200: 1, 404: 1}, BrokenLinks: 1, NewFindings: 1, Findings: map[string]int{CheckBrokenLink: 1}},
+		"/blog/":  {Pages: 1, StatusCodes: map[int]int{200: 1}},
+	}, a.Summary().Segments)
+
+	c.SegmentBy = SegmentLanguage
+	a, err = New(c, newFetcher(), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, map[string]SegmentSummary{
+		"en":           {Pages: 2, StatusCodes: map[int]int{200: 2}},
+		"de":           {Pages: 1, StatusCodes: map[int]int{200: 1}},
+		unknownSegment: {Pages: 2, StatusCodes: map[int]int{200: 1, 404: 1}, BrokenLinks: 1, NewFindings: 1, Findings: map[string]int{CheckBrokenLink: 1}},
+	}, a.Summary().Segments)
+}
+
+func TestAudit_TemplateSegments(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	c.SegmentBy = SegmentTemplate
+	a, err := New(c, &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":                  successResponse(`<a href="/product/hat">1</a><a href="/product/scarf">2</a><a href="/product/gloves">3</a><a href="/blog/2024/launch">4</a><a href="/about">5</a>`),
+		"https://example.com/product/hat":      successResponse(""),
+		"https://example.com/product/scarf":    successResponse(""),
+		"https://example.com/blog/2024/launch": successResponse(""),
+		"https://example.com/about":            successResponse(""),
+	}}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	require.Equal(t, map[string]SegmentSummary{
+		"/":                   {Pages: 1, StatusCodes: map[int]int{200: 1}},
+		"/about":              {Pages: 1, StatusCodes: map[int]int{200: 1}},
+		"/product/{slug}":     {Pages: 3, StatusCodes: map[int]int{200: 2, 404: 1}, BrokenLinks: 1, NewFindings: 1, Findings: map[string]int{CheckBrokenLink: 1}},
+		"/blog/{yyyy}/launch": {Pages: 1, StatusCodes: map[int]int{200: 1}},
+	}, a.Summary().Segments)
+}
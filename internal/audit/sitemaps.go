@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/sitemap"
+)
+
+const CheckSitemapNotIndexable = "sitemap-not-indexable"
+
+// maxSitemapFiles bounds how many sitemaps are read through sitemap indexes
+const maxSitemapFiles = 1000
+
+// sitemapSeed is a url listed in a sitemap and the sitemap listing it
+type sitemapSeed struct {
+	url     string
+	sitemap string
+}
+
+// sitemapLocations are AUDIT_SITEMAP_URL when set, otherwise the sitemaps robots.txt lists or
+// /sitemap.xml
+func (a *Audit) sitemapLocations() []string {
+	if a.config.SitemapURL != "" {
+		return []string{a.config.SitemapURL}
+	}
+	if a.robotsData != nil && len(a.robotsData.Sitemaps) > 0 {
+		return a.robotsData.Sitemaps
+	}
+	return []string{a.startURL.Scheme + "://" + a.startURL.Host + "/sitemap.xml"}
+}
+
+// sitemapSeeds reads every url from the sitemaps, following sitemap indexes. Only the first sitemap
+// failing is an error, as a broken sitemap referenced from an index still leaves the others.
+func (a *Audit) sitemapSeeds(ctx context.Context) ([]sitemapSeed, error) {
+	pending := a.sitemapLocations()
+	seen := map[string]bool{}
+	seeds := []sitemapSeed{}
+	for len(pending) > 0 && len(seen) < maxSitemapFiles {
+		location := pending[0]
+		pending = pending[1:]
+		if seen[location] {
+			continue
+		}
+		seen[location] = true
+		urls, nested, err := a.readSitemap(ctx, location)
+		if err != nil && len(seen) == 1 {
+			return nil, err
+		}
+		if err != nil {
+			a.logger.Error("Sitemap reading error", "sitemap", location, "err", err)
+			continue
+		}
+		for _, u := range urls {
+			seeds = append(seeds, sitemapSeed{url: u, sitemap: location})
+		}
+		pending = append(pending, nested...)
+	}
+	a.logger.Info("Sitemap read", "sitemaps", len(seen), "urls", len(seeds))
+	return seeds, nil
+}
+
+func (a *Audit) readSitemap(ctx context.Context, location string) ([]string, []string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %q", ErrInvalidSitemapURL, location)
+	}
+	response, err := a.fetcher.Fetch(ctx, u)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching sitemap %s: %w", location, err)
+	}
+	defer closeBody(response.Body)
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("sitemap %s returned status %d", location, response.StatusCode)
+	}
+	return sitemap.Parse(response.Body)
+}
+
+// enqueueSitemap must be called with a.mu held. Urls on other hosts cannot be listed in a site's
+// sitemap and are skipped, and those disallowed by robots.txt are recorded as linked from the
+// sitemap rather than fetched.
+func (a *Audit) enqueueSitemap(seeds []sitemapSeed) {
+	for _, seed := range seeds {
+		u, err := url.Parse(seed.url)
+		ok := err == nil
+		if ok {
+			u, ok = a.rewrite(u)
+		}
+		if !ok || !a.schemes.Contains(u.Scheme) || !a.internalHosts.Contains(normaliseHost(u.Host)) {
+			a.logger.Debug("Skipping sitemap url outside the site", "url", seed.url, "sitemap", seed.sitemap)
+			continue
+		}
+		canonical := intern(a.canonicalURL(u))
+		if a.visited.Contains(canonical) {
+			continue
+		}
+		a.visited.Add(canonical)
+		a.node(canonical).inSitemap = true
+		if a.disallowedByRobots(u) {
+			a.recordDisallowed(canonical, intern(seed.sitemap))
+			continue
+		}
+		if !a.withinPageLimit(u) {
+			continue
+		}
+		a.enqueue(&task{rawURL: intern(u.String()), depth: 0})
+	}
+}
+
+// sitemapFindings must be called with a.mu held. Urls still waiting to be fetched are not reported.
+func (a *Audit) sitemapFindings() []Finding {
+	noIndex := map[string]bool{}
+	for _, f := range a.checkFindings {
+		if f.Check == CheckNoIndex {
+			noIndex[f.URL] = true
+		}
+	}
+	findings := []Finding{}
+	for u, info := range a.nodes {
+		if !info.inSitemap {
+			continue
+		}
+		reasons := []string{}
+		if code := a.statuses[u]; code != 0 && (code < http.StatusOK || code >= http.StatusMultipleChoices) {
+			reasons = append(reasons, fmt.Sprintf("status %d", code))
+		}
+		if _, ok := a.disallowed[u]; ok {
+			reasons = append(reasons, "disallowed by robots.txt")
+		}
+		if noIndex[u] {
+			reasons = append(reasons, "noindex")
+		}
+		if info.canonical != "" && info.canonical != u {
+			reasons = append(reasons, "canonical is "+info.canonical)
+		}
+		if len(reasons) > 0 {
+			findings = append(findings, Finding{Check: CheckSitemapNotIndexable, URL: u, Detail: strings.Join(reasons, ", ")})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestAudit_SitemapOnly(t *testing.T) {
+	fetcher := &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com/robots.txt": successResponse("User-agent: *\nDisallow: /private\nSitemap: https://example.com/sitemap-index.xml"),
+		"https://example.com/sitemap-index.xml": successResponse(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+			<sitemap><loc>https://example.com/sitemap-1.xml</loc></sitemap>
+			<sitemap><loc>https://example.com/sitemap-missing.xml</loc></sitemap>
+		</sitemapindex>`),
+		"https://example.com/sitemap-1.xml": successResponse(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+			<url><loc>https://example.com/</loc></url>
+			<url><loc>https://example.com/a</loc></url>
+			<url><loc>https://example.com/b</loc></url>
+			<url><loc>https://example.com/missing</loc></url>
+			<url><loc>https://example.com/private</loc></url>
+			<url><loc>https://other.com/x</loc></url>
+		</urlset>`),
+		"https://example.com/":        successResponse(`<link rel="canonical" href="https://example.com/"><a href="/c">c</a>`),
+		"https://example.com/a":       successResponse(`<meta name="robots" content="noindex">`),
+		"https://example.com/b":       successResponse(`<link rel="canonical" href="/">`),
+		"https://example.com/missing": notFoundResponse(""),
+		"https://example.com/c":       successResponse(""),
+	}}
+	c := testConfig
+	c.SitemapOnly = true
+	a, err := New(c, fetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	pages := []string{}
+	for _, page := range a.Pages() {
+		pages = append(pages, page.URL)
+	}
+	require.Equal(t, []string{"https://example.com/", "https://example.com/a", "https://example.com/b", "https://example.com/missing"}, pages)
+	require.Equal(t, "https://example.com/", a.Nodes()["https://example.com/b"].Canonical)
+	require.True(t, a.Nodes()["https://example.com/b"].InSitemap)
+	details := map[string]string{}
+	for _, f := range a.Findings() {
+		if f.Check == CheckSitemapNotIndexable {
+			details[f.URL] = f.Detail
+		}
+	}
+	require.Equal(t, map[string]string{
+		"https://example.com/a":       "noindex",
+		"https://example.com/b":       "canonical is https://example.com/",
+		"https://example.com/missing": "status 404",
+		"https://example.com/private": "disallowed by robots.txt",
+	}, details)
+
+	t.Run("missing sitemap", func(t *testing.T) {
+		c.RespectRobots = false
+		a, err := New(c, &mockFetcher{}, extractor.NewLinkExtractor())
+		require.NoError(t, err)
+		require.Error(t, a.Start(context.Background()))
+	})
+}
@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// throughputInterval is the window pages per second is measured over
+const throughputInterval = 10 * time.Second
+
+// unknownContentType counts pages sent without a Content-Type
+const unknownContentType = "unknown"
+
+// Throughput is the pages fetched in one throughputInterval of the crawl
+type Throughput struct {
+	ElapsedSeconds float64 `json:"elapsed_s"`
+	Fetched        int     `json:"fetched"`
+	PagesPerSecond float64 `json:"pages_per_s"`
+}
+
+// Stats breaks down the pages fetched. Throughput covers the crawl since it was last started, so
+// a resumed crawl only reports its own run.
+type Stats struct {
+	PagesByDepth map[int]int    `json:"pages_by_depth"`
+	StatusCodes  map[int]int    `json:"status_codes"`
+	ContentTypes map[string]int `json:"content_types"`
+	Throughput   []Throughput   `json:"throughput"`
+}
+
+func (a *Audit) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats()
+}
+
+// stats must be called with a.mu held
+func (a *Audit) stats() Stats {
+	s := Stats{
+		PagesByDepth: make(map[int]int),
+		StatusCodes:  make(map[int]int),
+		ContentTypes: make(map[string]int),
+		Throughput:   make([]Throughput, 0, len(a.throughput)),
+	}
+	for u, code := range a.statuses {
+		s.StatusCodes[code]++
+		if info, ok := a.nodes[u]; ok {
+			s.PagesByDepth[info.depth]++
+		}
+		mediaType, _, _ := mime.ParseMediaType(a.headers[u]["Content-Type"])
+		if mediaType == "" {
+			mediaType = unknownContentType
+		}
+		s.ContentTypes[mediaType]++
+	}
+	for i, fetched := range a.throughput {
+		s.Throughput = append(s.Throughput, Throughput{
+			ElapsedSeconds: (time.Duration(i+1) * throughputInterval).Seconds(),
+			Fetched:        fetched,
+			PagesPerSecond: float64(fetched) / throughputInterval.Seconds(),
+		})
+	}
+	return s
+}
+
+// recordThroughput counts a fetch in the current throughputInterval. It must be called with a.mu held
+func (a *Audit) recordThroughput() {
+	if a.started.IsZero() {
+		return
+	}
+	i := int(time.Since(a.started) / throughputInterval)
+	for len(a.throughput) <= i {
+		a.throughput = append(a.throughput, 0)
+	}
+	a.throughput[i]++
+}
+
+// logStats logs crawl health every interval until ctx is done
+func (a *Audit) logStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastFetched, lastFailed int
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		a.mu.Lock()
+		fetched := len(a.statuses) + a.fetchErrs
+		failed := a.failed
+		queued := a.tasks.Len()
+		inFlight := a.inFlight
+		a.mu.Unlock()
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		errorRate := 0.0
+		if fetched > lastFetched {
+			errorRate = float64(failed-lastFailed) / float64(fetched-lastFetched)
+		}
+		a.logger.Info("Crawl stats",
+			"pages_per_s", float64(fetched-lastFetched)/interval.Seconds(),
+			"fetched", fetched,
+			"queued", queued,
+			"in_flight", inFlight,
+			"concurrency_limit", min(a.concurrency.Limit(), a.config.MaxWorkers),
+			"goroutines", runtime.NumGoroutine(),
+			"heap_in_use_mb", float64(memStats.HeapInuse)/(1<<20),
+			"error_rate", errorRate,
+		)
+		lastFetched, lastFailed = fetched, failed
+	}
+}
+
+func isFailure(code int) bool {
+	return code >= http.StatusBadRequest
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+	"salsgithub.com/site-audit/internal/fetcher"
+	"salsgithub.com/site-audit/internal/sitegen"
+)
+
+func TestAudit_StatsLogging(t *testing.T) {
+	server := httptest.NewServer(sitegen.New(sitegen.WithPages(20), sitegen.WithLatency(5*time.Millisecond), sitegen.WithErrorRate(0.2)))
+	defer server.Close()
+	c := testConfig
+	c.StartURL = server.URL
+	c.RespectRobots = false
+	c.MaxWorkers = 1
+	c.MaxDepth = 5
+	c.StatsInterval = 10 * time.Millisecond
+	a, err := New(c, fetcher.NewHTTPFetcher("agent"), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	a.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	require.NoError(t, a.Start(context.Background()))
+	var stats map[string]any
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, `"msg":"Crawl stats"`) {
+			require.NoError(t, json.Unmarshal([]byte(line), &stats))
+			break
+		}
+	}
+	require.NotNil(t, stats, "no stats logged")
+	for _, key := range []string{"pages_per_s", "fetched", "queued", "in_flight", "concurrency_limit", "goroutines", "heap_in_use_mb", "error_rate"} {
+		require.Contains(t, stats, key)
+	}
+	require.Equal(t, float64(1), stats["concurrency_limit"])
+}
+
+func TestAudit_Stats(t *testing.T) {
+	c := testConfig
+	c.RespectRobots = false
+	home := successResponse(`<a href="/a">a</a><a href="/missing">missing</a>`)
+	home.Header = http.Header{"Content-Type": []string{"text/html; charset=utf-8"}}
+	a, err := New(c, &mockFetcher{responses: map[string]*http.Response{
+		"https://example.com":   home,
+		"https://example.com/a": successResponse(`<a href="/b">b</a>`),
+	}}, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	stats := a.Stats()
+	require.Equal(t, map[int]int{0: 1, 1: 2}, stats.PagesByDepth)
+	require.Equal(t, map[int]int{http.StatusOK: 2, http.StatusNotFound: 1}, stats.StatusCodes)
+	require.Equal(t, map[string]int{"text/html": 1, unknownContentType: 2}, stats.ContentTypes)
+	require.Len(t, stats.Throughput, 1)
+	require.Equal(t, 3, stats.Throughput[0].Fetched)
+	require.Equal(t, 0.3, stats.Throughput[0].PagesPerSecond)
+	require.Equal(t, &stats, a.Summary().Stats)
+}
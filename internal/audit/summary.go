@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type Summary struct {
+	Score        int         `json:"score"`
+	Visited      int         `json:"visited"`
+	StatusCodes  map[int]int `json:"status_codes"`
+	BrokenLinks  int         `json:"broken_links"`
+	ServerErrors int         `json:"server_errors"`
+	FetchErrors  int         `json:"fetch_errors"`
+	NewFindings  int         `json:"new_findings"`
+	Throttled    int         `json:"throttled"`
+
+	Failures map[Failure]int           `json:"failures,omitempty"`
+	Segments map[string]SegmentSummary `json:"segments,omitempty"`
+	Stats    *Stats                    `json:"stats,omitempty"`
+}
+
+func (a *Audit) Summary() Summary {
+	newFindings := a.NewFindings()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	summary := Summary{
+		Visited:     a.visited.Len(),
+		StatusCodes: make(map[int]int),
+		FetchErrors: a.fetchErrs,
+		NewFindings: len(newFindings),
+		Throttled:   len(a.throttles),
+		Failures:    a.failureCounts(),
+	}
+	stats := a.stats()
+	summary.Stats = &stats
+	for u, code := range a.statuses {
+		summary.StatusCodes[code]++
+		if !a.broken(u, code) {
+			continue
+		}
+		summary.BrokenLinks++
+		if code >= http.StatusInternalServerError {
+			summary.ServerErrors++
+		}
+	}
+	summary.Score = summary.HealthScore()
+	if a.config.SegmentBy != "" {
+		summary.Segments = a.segmentSummaries(newFindings)
+	}
+	return summary
+}
+
+// HealthScore is the percentage of pages fetched that were neither broken nor a fetch error, or 100
+// when nothing was fetched. It is worked out from the counts so runs recorded before scores were
+// kept can be scored too.
+func (s Summary) HealthScore() int {
+	fetched := s.FetchErrors
+	for _, count := range s.StatusCodes {
+		fetched += count
+	}
+	if fetched == 0 {
+		return 100
+	}
+	return (fetched - s.FetchErrors - s.BrokenLinks) * 100 / fetched
+}
+
+func (a *Audit) CheckThresholds() error {
+	brokenLinks, serverErrors := 0, 0
+	score := a.Summary().Score
+	findings := a.NewFindings()
+	a.mu.Lock()
+	for _, finding := range findings {
+		if finding.Check != CheckBrokenLink {
+			continue
+		}
+		brokenLinks++
+		if a.statuses[finding.URL] >= http.StatusInternalServerError {
+			serverErrors++
+		}
+	}
+	a.mu.Unlock()
+	var errs []error
+	if a.config.FailOnServerError && serverErrors > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d pages returned a 5xx status", ErrThresholdExceeded, serverErrors))
+	}
+	if a.config.MaxBrokenLinks >= 0 && brokenLinks > a.config.MaxBrokenLinks {
+		errs = append(errs, fmt.Errorf("%w: %d broken links exceeds maximum of %d", ErrThresholdExceeded, brokenLinks, a.config.MaxBrokenLinks))
+	}
+	if a.config.MinScore > 0 && score < a.config.MinScore {
+		errs = append(errs, fmt.Errorf("%w: score %d is below minimum of %d", ErrThresholdExceeded, score, a.config.MinScore))
+	}
+	errs = append(errs, a.checkConfig.exceeded(findings)...)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit_CheckThresholds(t *testing.T) {
+	newAudit := func(c Config) *Audit {
+		return crawlTestAudit(t, c, &mockFetcher{responses: map[string]*http.Response{
+			"https://example.com":        successResponse(`<html><body><a href="/page-a">A</a><a href="/page-b">B</a></body></html>`),
+			"https://example.com/page-a": buildResponse("", http.StatusInternalServerError),
+		}})
+	}
+	t.Run("summary counts statuses", func(t *testing.T) {
+		a := newAudit(testConfig)
+		summary := a.Summary()
+		require.Equal(t, 3, summary.Visited)
+		require.Equal(t, 2, summary.BrokenLinks)
+		require.Equal(t, 1, summary.ServerErrors)
+		require.Equal(t, map[int]int{http.StatusOK: 1, http.StatusNotFound: 1, http.StatusInternalServerError: 1}, summary.StatusCodes)
+	})
+	t.Run("disabled thresholds pass", func(t *testing.T) {
+		c := testConfig
+		c.MaxBrokenLinks = -1
+		a := newAudit(c)
+		require.NoError(t, a.CheckThresholds())
+	})
+	t.Run("fails on server error", func(t *testing.T) {
+		c := testConfig
+		c.MaxBrokenLinks = -1
+		c.FailOnServerError = true
+		a := newAudit(c)
+		err := a.CheckThresholds()
+		require.True(t, errors.Is(err, ErrThresholdExceeded))
+		require.Contains(t, err.Error(), "5xx")
+	})
+	t.Run("fails on too many broken links", func(t *testing.T) {
+		c := testConfig
+		c.MaxBrokenLinks = 1
+		a := newAudit(c)
+		err := a.CheckThresholds()
+		require.True(t, errors.Is(err, ErrThresholdExceeded))
+		require.Contains(t, err.Error(), "broken links")
+	})
+	t.Run("broken links within maximum pass", func(t *testing.T) {
+		c := testConfig
+		c.MaxBrokenLinks = 2
+		a := newAudit(c)
+		require.NoError(t, a.CheckThresholds())
+	})
+	t.Run("fails below minimum score", func(t *testing.T) {
+		c := testConfig
+		c.MaxBrokenLinks = -1
+		c.MinScore = 80
+		a := newAudit(c)
+		err := a.CheckThresholds()
+		require.True(t, errors.Is(err, ErrThresholdExceeded))
+		require.Contains(t, err.Error(), "score 33 is below minimum of 80")
+	})
+	t.Run("score above minimum passes", func(t *testing.T) {
+		c := testConfig
+		c.MaxBrokenLinks = -1
+		c.MinScore = 30
+		a := newAudit(c)
+		require.NoError(t, a.CheckThresholds())
+	})
+}
+
+func TestSummary_HealthScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary Summary
+		want    int
+	}{
+		{name: "nothing fetched", summary: Summary{}, want: 100},
+		{name: "all ok", summary: Summary{StatusCodes: map[int]int{200: 4, 301: 1}}, want: 100},
+		{name: "broken pages and fetch errors", summary: Summary{StatusCodes: map[int]int{200: 6, 404: 1, 500: 1}, BrokenLinks: 2, FetchErrors: 2}, want: 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.summary.HealthScore())
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	templateSlug   = "{slug}"
+	templateNumber = "{n}"
+	templateYear   = "{yyyy}"
+)
+
+// minTemplateVariants is how many different values a path segment must take among urls sharing
+// everything before it for the segment to be taken as a template variable
+const minTemplateVariants = 3
+
+// templatePatterns groups urls by the page template they appear to be built from, such as
+// /product/{slug} or /blog/{yyyy}/{slug}. Numeric segments are always variables. Other segments
+// are when enough urls of the same length differ only from that segment on. The first segment is
+// kept as it usually names a section of the site rather than a page.
+func templatePatterns(urls []string) map[string]string {
+	paths := make(map[string][]string, len(urls))
+	longest := 0
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		segments := []string{}
+		if path := strings.Trim(u.Path, "/"); path != "" {
+			segments = strings.Split(path, "/")
+		}
+		for i, segment := range segments {
+			segments[i] = numberPlaceholder(segment)
+		}
+		paths[raw] = segments
+		longest = max(longest, len(segments))
+	}
+	for i := 1; i < longest; i++ {
+		parent := func(segments []string) string {
+			return strconv.Itoa(len(segments)) + " " + strings.Join(segments[:i], "/")
+		}
+		variants := map[string]map[string]bool{}
+		for _, segments := range paths {
+			if len(segments) <= i {
+				continue
+			}
+			key := parent(segments)
+			if variants[key] == nil {
+				variants[key] = map[string]bool{}
+			}
+			variants[key][segments[i]] = true
+		}
+		for _, segments := range paths {
+			if len(segments) > i && !isPlaceholder(segments[i]) && len(variants[parent(segments)]) >= minTemplateVariants {
+				segments[i] = templateSlug
+			}
+		}
+	}
+	patterns := make(map[string]string, len(paths))
+	for raw, segments := range paths {
+		patterns[raw] = "/" + strings.Join(segments, "/")
+	}
+	return patterns
+}
+
+// numberPlaceholder replaces a segment of digits, telling years apart so dated archives read as
+// /blog/{yyyy}/{n}
+func numberPlaceholder(segment string) string {
+	if segment == "" || strings.Trim(segment, "0123456789") != "" {
+		return segment
+	}
+	if len(segment) == 4 && (strings.HasPrefix(segment, "19") || strings.HasPrefix(segment, "20")) {
+		return templateYear
+	}
+	return templateNumber
+}
+
+func isPlaceholder(segment string) bool {
+	return segment == templateSlug || segment == templateNumber || segment == templateYear
+}
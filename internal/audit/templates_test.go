@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatePatterns(t *testing.T) {
+	urls := []string{
+		"https://example.com/",
+		"https://example.com/about",
+		"https://example.com/contact",
+		"https://example.com/blog/2023/first",
+		"https://example.com/blog/2024/second",
+		"https://example.com/blog/2024/third",
+		"https://example.com/blog/tags",
+		"https://example.com/product/a",
+		"https://example.com/product/b",
+		"https://example.com/product/c",
+		"https://example.com/product/c/reviews",
+		"https://example.com/order/42",
+	}
+	require.Equal(t, map[string]string{
+		"https://example.com/":                  "/",
+		"https://example.com/about":             "/about",
+		"https://example.com/contact":           "/contact",
+		"https://example.com/blog/2023/first":   "/blog/{yyyy}/{slug}",
+		"https://example.com/blog/2024/second":  "/blog/{yyyy}/{slug}",
+		"https://example.com/blog/2024/third":   "/blog/{yyyy}/{slug}",
+		"https://example.com/blog/tags":         "/blog/tags",
+		"https://example.com/product/a":         "/product/{slug}",
+		"https://example.com/product/b":         "/product/{slug}",
+		"https://example.com/product/c":         "/product/{slug}",
+		"https://example.com/product/c/reviews": "/product/c/reviews",
+		"https://example.com/order/42":          "/order/{n}",
+	}, templatePatterns(urls))
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const CheckCrawlerTrap = "crawler-trap"
+
+// maxSegmentRepeats is how often one path segment may appear before a url such as /a/b/a/b/a is
+// taken to be a relative link resolving against itself
+const maxSegmentRepeats = 3
+
+// trapped reports whether u looks like part of an infinite url space and should not be crawled,
+// recording a finding the first time each trap is found. It must be called with a.mu held.
+func (a *Audit) trapped(u *url.URL) bool {
+	if a.config.TrapLimit <= 0 {
+		return false
+	}
+	if segment, ok := repeatedSegment(u.Path); ok {
+		pattern := trapPattern(u)
+		if a.trapPatterns[pattern] == 0 {
+			a.checkFindings = append(a.checkFindings, Finding{
+				Check:  CheckCrawlerTrap,
+				URL:    a.canonicalURL(u),
+				Detail: fmt.Sprintf("path segment %q repeats %d or more times", segment, maxSegmentRepeats),
+			})
+		}
+		a.trapPatterns[pattern]++
+		return true
+	}
+	pattern := trapPattern(u)
+	a.trapPatterns[pattern]++
+	if a.trapPatterns[pattern] <= a.config.TrapLimit {
+		return false
+	}
+	if a.trapPatterns[pattern] == a.config.TrapLimit+1 {
+		a.logger.Warn("Crawler trap detected, no longer crawling urls matching pattern", "pattern", pattern, "limit", a.config.TrapLimit)
+		a.checkFindings = append(a.checkFindings, Finding{
+			Check:  CheckCrawlerTrap,
+			URL:    a.canonicalURL(u),
+			Detail: fmt.Sprintf("more than %d urls match %s", a.config.TrapLimit, pattern),
+		})
+	}
+	return true
+}
+
+// trapPattern groups urls that differ only in numeric path segments, so /calendar/2024/05 and
+// /calendar/2031/11 share the pattern example.com/calendar/{n}/{n}
+func trapPattern(u *url.URL) string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, segment := range segments {
+		if hasDigit(segment) {
+			segments[i] = "{n}"
+		}
+	}
+	return normaliseHost(u.Host) + "/" + strings.Join(segments, "/")
+}
+
+func repeatedSegment(path string) (string, bool) {
+	counts := map[string]int{}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+		counts[segment]++
+		if counts[segment] >= maxSegmentRepeats {
+			return segment, true
+		}
+	}
+	return "", false
+}
+
+func hasDigit(s string) bool {
+	return strings.ContainsAny(s, "0123456789")
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/extractor"
+)
+
+func TestTrapPattern(t *testing.T) {
+	tests := []struct {
+		raw      string
+		pattern  string
+		repeated bool
+	}{
+		{raw: "https://example.com/calendar/2024/05", pattern: "example.com/calendar/{n}/{n}"},
+		{raw: "https://www.example.com/item-42/", pattern: "example.com/{n}"},
+		{raw: "https://example.com/", pattern: "example.com/"},
+		{raw: "https://example.com/a/b/a/b/a", pattern: "example.com/a/b/a/b/a", repeated: true},
+		{raw: "https://example.com/a/a", pattern: "example.com/a/a"},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			u, err := url.Parse(test.raw)
+			require.NoError(t, err)
+			require.Equal(t, test.pattern, trapPattern(u))
+			_, repeated := repeatedSegment(u.Path)
+			require.Equal(t, test.repeated, repeated)
+		})
+	}
+}
+
+func TestAudit_CrawlerTraps(t *testing.T) {
+	mockFetcher := &mockFetcher{
+		responses: map[string]*http.Response{
+			"https://example.com": successResponse(`<a href="/calendar/2024/01">1</a><a href="/calendar/2024/02">2</a>` +
+				`<a href="/calendar/2024/03">3</a><a href="/calendar/2024/04">4</a><a href="/a/a/a">loop</a>`),
+		},
+	}
+	c := testConfig
+	c.RespectRobots = false
+	c.TrapLimit = 2
+	a, err := New(c, mockFetcher, extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	crawled := []string{}
+	for _, page := range a.Pages() {
+		crawled = append(crawled, page.URL)
+	}
+	require.Equal(t, []string{"https://example.com/", "https://example.com/calendar/2024/01", "https://example.com/calendar/2024/02"}, crawled)
+	traps := []string{}
+	for _, f := range a.Findings() {
+		if f.Check == CheckCrawlerTrap {
+			traps = append(traps, f.URL)
+		}
+	}
+	require.Equal(t, []string{"https://example.com/a/a/a", "https://example.com/calendar/2024/03"}, traps)
+}
@@ -0,0 +1,77 @@
+package bloom
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// Filter is an approximate set of strings. Contains may report false positives at the configured
+// rate but never false negatives, and memory stays fixed however many values are added.
+type Filter struct {
+	bits   []uint64
+	m      uint64
+	k      uint64
+	length int
+}
+
+// New sizes a filter for capacity values at the given false positive rate
+func New(capacity int, falsePositiveRate float64) *Filter {
+	n := float64(max(capacity, 1))
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+	return &Filter{
+		bits: make([]uint64, (uint64(m)+63)/64),
+		m:    uint64(m),
+		k:    uint64(k),
+	}
+}
+
+func (f *Filter) Add(values ...string) {
+	for _, value := range values {
+		h1, h2 := hashes(value)
+		added := false
+		for i := range f.k {
+			bit := (h1 + i*h2) % f.m
+			if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+				f.bits[bit/64] |= 1 << (bit % 64)
+				added = true
+			}
+		}
+		if added {
+			f.length++
+		}
+	}
+}
+
+func (f *Filter) Contains(value string) bool {
+	h1, h2 := hashes(value)
+	for i := range f.k {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len approximates the number of distinct values added
+func (f *Filter) Len() int {
+	return f.length
+}
+
+func (f *Filter) IsEmpty() bool {
+	return f.length == 0
+}
+
+// SizeBytes is the memory held by the filter's bits
+func (f *Filter) SizeBytes() int {
+	return len(f.bits) * 8
+}
+
+func hashes(value string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	sum := h.Sum64()
+	// An odd second hash keeps every probe distinct
+	return sum & math.MaxUint32, sum>>32 | 1
+}
@@ -0,0 +1,42 @@
+package bloom
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	t.Run("contains added values", func(t *testing.T) {
+		f := New(1000, 0.01)
+		require.True(t, f.IsEmpty())
+		f.Add("https://example.com/a", "https://example.com/b")
+		f.Add("https://example.com/a")
+		require.True(t, f.Contains("https://example.com/a"))
+		require.True(t, f.Contains("https://example.com/b"))
+		require.False(t, f.Contains("https://example.com/c"))
+		require.Equal(t, 2, f.Len())
+	})
+	t.Run("false positive rate near target", func(t *testing.T) {
+		f := New(10000, 0.01)
+		for i := range 10000 {
+			f.Add(fmt.Sprintf("https://example.com/page/%d", i))
+		}
+		for i := range 10000 {
+			require.True(t, f.Contains(fmt.Sprintf("https://example.com/page/%d", i)))
+		}
+		falsePositives := 0
+		for i := range 10000 {
+			if f.Contains(fmt.Sprintf("https://example.com/other/%d", i)) {
+				falsePositives++
+			}
+		}
+		require.True(t, falsePositives < 300, "false positives: %d", falsePositives)
+	})
+	t.Run("memory is fixed by capacity", func(t *testing.T) {
+		// ~9.6 bits per value at 1%
+		size := New(1_000_000, 0.01).SizeBytes()
+		require.True(t, size > 1_190_000 && size < 1_210_000, "size: %d", size)
+	})
+}
@@ -0,0 +1,211 @@
+package compression
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/fetcher"
+)
+
+const (
+	CheckUncompressed = "uncompressed-response"
+	CheckUnminified   = "unminified-asset"
+)
+
+const (
+	// minCompressBytes is the size below which compressing a response is not worth it
+	minCompressBytes = 1024
+	// maxReadBytes caps how much of a response is read to measure and inspect it
+	maxReadBytes = 8 << 20
+	// acceptEncoding is sent so servers can compress. Setting it stops the transport from asking
+	// for gzip itself and hiding the Content-Encoding it gets back.
+	acceptEncoding = "br, gzip"
+)
+
+type Fetcher interface {
+	Fetch(ctx context.Context, u *url.URL) (*http.Response, error)
+}
+
+// Check fetches every HTML page crawled and the scripts and stylesheets they load from the site,
+// reporting text served without gzip or brotli and scripts and stylesheets above a size that look
+// unminified
+type Check struct {
+	fetcher         Fetcher
+	workers         int
+	minifyThreshold int
+}
+
+func NewCheck(fetcher Fetcher, workers, minifyThreshold int) *Check {
+	return &Check{fetcher: fetcher, workers: max(workers, 1), minifyThreshold: minifyThreshold}
+}
+
+func (c *Check) Name() string {
+	return "compression"
+}
+
+func (c *Check) Run(ctx context.Context, pages []audit.Page) ([]audit.Finding, error) {
+	targets := []string{}
+	seen := map[string]struct{}{}
+	add := func(u string) {
+		if _, ok := seen[u]; !ok {
+			seen[u] = struct{}{}
+			targets = append(targets, u)
+		}
+	}
+	for _, page := range pages {
+		if page.StatusCode < 200 || page.StatusCode > 299 {
+			continue
+		}
+		if mediaType, _, _ := mime.ParseMediaType(page.Headers["Content-Type"]); mediaType == "text/html" {
+			add(page.URL)
+		}
+		for _, asset := range page.Assets {
+			add(asset)
+		}
+	}
+	findings := []audit.Finding{}
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range c.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				found := c.inspect(ctx, target)
+				mu.Lock()
+				findings = append(findings, found...)
+				mu.Unlock()
+			}
+		}()
+	}
+send:
+	for _, target := range targets {
+		select {
+		case jobs <- target:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(findings, func(x, y audit.Finding) int {
+		return cmp.Or(cmp.Compare(x.URL, y.URL), cmp.Compare(x.Check, y.Check))
+	})
+	return findings, nil
+}
+
+// inspect fetches target asking for compression. Failed fetches and responses that are not text
+// are left to the checks that look for them.
+func (c *Check) inspect(ctx context.Context, target string) []audit.Finding {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil
+	}
+	response, err := c.fetcher.Fetch(fetcher.WithHeader(ctx, "Accept-Encoding", acceptEncoding), u)
+	if err != nil {
+		return nil
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	kind, ok := textKind(mediaType)
+	if !ok {
+		return nil
+	}
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+	var body []byte
+	switch encoding {
+	case "", "identity":
+		body, err = io.ReadAll(io.LimitReader(response.Body, maxReadBytes))
+	case "gzip":
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(response.Body); err == nil {
+			body, err = io.ReadAll(io.LimitReader(r, maxReadBytes))
+		}
+	default:
+		// Brotli has no decoder in the standard library, but the size on the wire is known
+		written, _ := io.Copy(io.Discard, io.LimitReader(response.Body, maxReadBytes))
+		if kind == "html" || written < minCompressBytes {
+			return nil
+		}
+		body, err = c.uncompressed(ctx, u)
+	}
+	if err != nil {
+		return nil
+	}
+	findings := []audit.Finding{}
+	if (encoding == "" || encoding == "identity") && len(body) >= minCompressBytes {
+		findings = append(findings, audit.Finding{
+			Check:  CheckUncompressed,
+			URL:    target,
+			Detail: fmt.Sprintf("%s of %d bytes served without gzip or brotli", mediaType, len(body)),
+		})
+	}
+	if kind != "html" && len(body) >= c.minifyThreshold && unminified(body) {
+		findings = append(findings, audit.Finding{
+			Check:  CheckUnminified,
+			URL:    target,
+			Detail: fmt.Sprintf("%s of %d bytes does not look minified", mediaType, len(body)),
+		})
+	}
+	return findings
+}
+
+// uncompressed fetches u again without compression, to read an asset sent with an encoding that
+// cannot be decoded
+func (c *Check) uncompressed(ctx context.Context, u *url.URL) ([]byte, error) {
+	response, err := c.fetcher.Fetch(fetcher.WithHeader(ctx, "Accept-Encoding", "identity"), u)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Encoding") != "" {
+		return nil, fmt.Errorf("no uncompressed response for %s", u)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, maxReadBytes))
+}
+
+func textKind(mediaType string) (string, bool) {
+	switch mediaType {
+	case "text/html":
+		return "html", true
+	case "text/css":
+		return "css", true
+	case "application/javascript", "text/javascript", "application/x-javascript":
+		return "js", true
+	}
+	return "", false
+}
+
+// unminified looks at how a script or stylesheet is laid out. Minifiers put everything on a few
+// very long lines, so short lines that are largely whitespace give away source as written.
+func unminified(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	lines := bytes.Count(body, []byte("\n")) + 1
+	whitespace := 0
+	for _, b := range body {
+		if b == ' ' || b == '\t' || b == '\n' || b == '\r' {
+			whitespace++
+		}
+	}
+	return len(body)/lines < 200 && whitespace*100/len(body) >= 15
+}
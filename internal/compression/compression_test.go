@@ -0,0 +1,86 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/fetcher"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := w.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func TestUnminified(t *testing.T) {
+	source := strings.Repeat("function add(a, b) {\n    return a + b;\n}\n\n", 50)
+	minified := strings.Repeat("function add(a,b){return a+b}", 50)
+	require.True(t, unminified([]byte(source)))
+	require.False(t, unminified([]byte(minified)))
+	require.False(t, unminified(nil))
+}
+
+func TestCheck_Run(t *testing.T) {
+	html := "<html>" + strings.Repeat("<p>Hello</p>\n", 100) + "</html>"
+	source := strings.Repeat(".button {\n    color: red;\n}\n\n", 100)
+	minified := strings.Repeat(".button{color:red}", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted := r.Header.Get("Accept-Encoding")
+		switch r.URL.Path {
+		case "/plain":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(html))
+		case "/gzipped":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped(t, html))
+		case "/source.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte(source))
+		case "/minified.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped(t, minified))
+		case "/brotli.css":
+			w.Header().Set("Content-Type", "text/css")
+			if strings.Contains(accepted, "br") {
+				w.Header().Set("Content-Encoding", "br")
+				w.Write(bytes.Repeat([]byte{0xff}, 2048))
+				return
+			}
+			w.Write([]byte(source))
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte{0}, 4096))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	html200 := map[string]string{"Content-Type": "text/html; charset=utf-8"}
+	pages := []audit.Page{
+		{URL: server.URL + "/plain", StatusCode: http.StatusOK, Headers: html200, Assets: []string{server.URL + "/source.css", server.URL + "/minified.css"}},
+		{URL: server.URL + "/gzipped", StatusCode: http.StatusOK, Headers: html200, Assets: []string{server.URL + "/brotli.css", server.URL + "/source.css", server.URL + "/image.png"}},
+		{URL: server.URL + "/missing", StatusCode: http.StatusNotFound, Headers: html200},
+	}
+	check := NewCheck(fetcher.NewHTTPFetcher("agent"), 2, 1024)
+	findings, err := check.Run(context.Background(), pages)
+	require.NoError(t, err)
+	require.Equal(t, []audit.Finding{
+		{Check: CheckUnminified, URL: server.URL + "/brotli.css", Detail: "text/css of 2900 bytes does not look minified"},
+		{Check: CheckUncompressed, URL: server.URL + "/plain", Detail: "text/html of 1313 bytes served without gzip or brotli"},
+		{Check: CheckUncompressed, URL: server.URL + "/source.css", Detail: "text/css of 2900 bytes served without gzip or brotli"},
+		{Check: CheckUnminified, URL: server.URL + "/source.css", Detail: "text/css of 2900 bytes does not look minified"},
+	}, findings)
+}
@@ -0,0 +1,135 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const prefetchTimeout = 5 * time.Second
+
+type Lookup func(ctx context.Context, host string) ([]string, error)
+
+type Option func(*Cache)
+
+// Cache resolves hostnames once per TTL for every connection dialled through it
+type Cache struct {
+	ttl      time.Duration
+	lookup   Lookup
+	dialer   *net.Dialer
+	entries  map[string]entry
+	inFlight map[string]bool
+	prefetch chan struct{}
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+type entry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func New(ttl time.Duration, options ...Option) *Cache {
+	c := &Cache{
+		ttl:      ttl,
+		lookup:   net.DefaultResolver.LookupHost,
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		entries:  make(map[string]entry),
+		inFlight: make(map[string]bool),
+		prefetch: make(chan struct{}, 4),
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func WithLookup(lookup Lookup) Option {
+	return func(c *Cache) {
+		c.lookup = lookup
+	}
+}
+
+// WithPrefetchConcurrency bounds the lookups Prefetch runs at once
+func WithPrefetchConcurrency(n int) Option {
+	return func(c *Cache) {
+		c.prefetch = make(chan struct{}, max(n, 1))
+	}
+}
+
+func (c *Cache) Resolve(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	cached, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && c.now().Before(cached.expires) {
+		return cached.addrs, nil
+	}
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[host] = entry{addrs: addrs, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+// Prefetch resolves host in the background if it is not cached, skipping it when the
+// prefetch concurrency is exhausted
+func (c *Cache) Prefetch(host string) {
+	if host == "" || net.ParseIP(host) != nil {
+		return
+	}
+	c.mu.Lock()
+	cached, ok := c.entries[host]
+	if (ok && c.now().Before(cached.expires)) || c.inFlight[host] {
+		c.mu.Unlock()
+		return
+	}
+	select {
+	case c.prefetch <- struct{}{}:
+	default:
+		c.mu.Unlock()
+		return
+	}
+	c.inFlight[host] = true
+	c.mu.Unlock()
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.inFlight, host)
+			c.mu.Unlock()
+			<-c.prefetch
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+		defer cancel()
+		c.Resolve(ctx, host)
+	}()
+}
+
+// DialContext dials the cached addresses of the host in turn, for use as http.Transport.DialContext
+func (c *Cache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, address)
+	}
+	addrs, err := c.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingLookup struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	addrs   []string
+	err     error
+	release chan struct{}
+}
+
+func (c *countingLookup) lookup(ctx context.Context, host string) ([]string, error) {
+	if c.release != nil {
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	c.calls[host]++
+	return c.addrs, c.err
+}
+
+func (c *countingLookup) count(host string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[host]
+}
+
+func TestCache_Resolve(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := &countingLookup{addrs: []string{"10.0.0.1"}}
+	c := New(time.Minute, WithLookup(l.lookup))
+	c.now = func() time.Time { return now }
+	for range 3 {
+		addrs, err := c.Resolve(context.Background(), "example.com")
+		require.NoError(t, err)
+		require.Equal(t, []string{"10.0.0.1"}, addrs)
+	}
+	require.Equal(t, 1, l.count("example.com"))
+	now = now.Add(time.Minute)
+	_, err := c.Resolve(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Equal(t, 2, l.count("example.com"))
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		l := &countingLookup{err: errors.New("no such host")}
+		c := New(time.Minute, WithLookup(l.lookup))
+		for range 2 {
+			_, err := c.Resolve(context.Background(), "missing.example.com")
+			require.Error(t, err)
+		}
+		require.Equal(t, 2, l.count("missing.example.com"))
+	})
+}
+
+func TestCache_Prefetch(t *testing.T) {
+	l := &countingLookup{addrs: []string{"10.0.0.1"}, release: make(chan struct{})}
+	c := New(time.Minute, WithLookup(l.lookup), WithPrefetchConcurrency(1))
+	c.Prefetch("a.example.com")
+	c.Prefetch("a.example.com")
+	c.Prefetch("b.example.com")
+	c.Prefetch("127.0.0.1")
+	close(l.release)
+	for range 100 {
+		c.mu.Lock()
+		_, ok := c.entries["a.example.com"]
+		c.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_, err := c.Resolve(context.Background(), "a.example.com")
+	require.NoError(t, err)
+	require.Equal(t, 1, l.count("a.example.com"))
+	// Skipped while the single prefetch slot was busy
+	require.Equal(t, 0, l.count("b.example.com"))
+}
+
+func TestCache_DialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	l := &countingLookup{addrs: []string{"127.0.0.2", serverURL.Hostname()}}
+	c := New(time.Minute, WithLookup(l.lookup))
+	c.dialer.Timeout = time.Second
+	client := &http.Client{Transport: &http.Transport{DialContext: c.DialContext, DisableKeepAlives: true}}
+	for range 2 {
+		response, err := client.Get("http://site.test:" + serverURL.Port())
+		require.NoError(t, err)
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		require.Equal(t, "site.test:"+serverURL.Port(), string(body))
+	}
+	require.Equal(t, 1, l.count("site.test"))
+	_, err := c.DialContext(context.Background(), "tcp", "no-port")
+	var addrErr *net.AddrError
+	require.True(t, errors.As(err, &addrErr))
+}
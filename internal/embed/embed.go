@@ -0,0 +1,148 @@
+package embed
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+const CheckBrokenEmbed = "broken-embed"
+
+// maxDrainBytes is how much of a response body is read so the connection can be reused
+const maxDrainBytes = 64 << 10
+
+type Fetcher interface {
+	Fetch(ctx context.Context, u *url.URL) (*http.Response, error)
+}
+
+// Check fetches the embedded content of every page and reports embeds that fail to load.
+// Players for YouTube and Vimeo load even for removed videos, so those are looked up with the
+// provider's oEmbed endpoint, which tells removed and private videos apart.
+type Check struct {
+	fetcher Fetcher
+	workers int
+}
+
+func NewCheck(fetcher Fetcher, workers int) *Check {
+	return &Check{fetcher: fetcher, workers: max(workers, 1)}
+}
+
+func (c *Check) Name() string {
+	return "embeds"
+}
+
+func (c *Check) Run(ctx context.Context, pages []audit.Page) ([]audit.Finding, error) {
+	embeds := []string{}
+	seen := map[string]struct{}{}
+	for _, page := range pages {
+		for _, embed := range page.Embeds {
+			if _, ok := seen[embed]; !ok {
+				seen[embed] = struct{}{}
+				embeds = append(embeds, embed)
+			}
+		}
+	}
+	problems := make(map[string]string, len(embeds))
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range c.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for embed := range jobs {
+				if problem := c.probe(ctx, embed); problem != "" {
+					mu.Lock()
+					problems[embed] = problem
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+send:
+	for _, embed := range embeds {
+		select {
+		case jobs <- embed:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	findings := []audit.Finding{}
+	for _, page := range pages {
+		for _, embed := range page.Embeds {
+			if problem, ok := problems[embed]; ok {
+				findings = append(findings, audit.Finding{Check: CheckBrokenEmbed, URL: page.URL, Detail: embed + " " + problem})
+			}
+		}
+	}
+	slices.SortStableFunc(findings, func(x, y audit.Finding) int {
+		return cmp.Compare(x.URL, y.URL)
+	})
+	return findings, nil
+}
+
+// probe returns why embed is unavailable, or nothing when it loads
+func (c *Check) probe(ctx context.Context, embed string) string {
+	target, err := url.Parse(embed)
+	if err != nil {
+		return "is not a valid url"
+	}
+	oEmbed := oEmbedURL(target)
+	if oEmbed != nil {
+		target = oEmbed
+	}
+	response, err := c.fetcher.Fetch(ctx, target)
+	if err != nil {
+		return fmt.Sprintf("failed to load: %v", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxDrainBytes))
+	response.Body.Close()
+	code := response.StatusCode
+	switch {
+	case oEmbed != nil && code == http.StatusNotFound:
+		return "has been removed"
+	case oEmbed != nil && (code == http.StatusUnauthorized || code == http.StatusForbidden):
+		return "is private or cannot be embedded"
+	case code >= http.StatusBadRequest:
+		return fmt.Sprintf("returned %d", code)
+	}
+	return ""
+}
+
+// oEmbedURL returns the oEmbed lookup for a YouTube or Vimeo player url, or nil for other embeds
+func oEmbedURL(u *url.URL) *url.URL {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	var endpoint, page string
+	switch host {
+	case "youtube.com", "youtube-nocookie.com":
+		id, ok := strings.CutPrefix(u.Path, "/embed/")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			return nil
+		}
+		endpoint, page = "https://www.youtube.com/oembed", "https://www.youtube.com/watch?v="+id
+	case "player.vimeo.com":
+		id, ok := strings.CutPrefix(u.Path, "/video/")
+		if !ok || id == "" || strings.Contains(id, "/") {
+			return nil
+		}
+		endpoint, page = "https://vimeo.com/api/oembed.json", "https://vimeo.com/"+id
+	default:
+		return nil
+	}
+	lookup, _ := url.Parse(endpoint)
+	lookup.RawQuery = url.Values{"url": {page}, "format": {"json"}}.Encode()
+	return lookup
+}
@@ -0,0 +1,84 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+type mockFetcher struct {
+	codes map[string]int
+}
+
+func (m *mockFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	code, ok := m.codes[u.String()]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestOEmbedURL(t *testing.T) {
+	tests := []struct {
+		embed string
+		want  string
+	}{
+		{embed: "https://www.youtube.com/embed/abc123?rel=0", want: "https://www.youtube.com/oembed?format=json&url=https%3A%2F%2Fwww.youtube.com%2Fwatch%3Fv%3Dabc123"},
+		{embed: "https://www.youtube-nocookie.com/embed/abc123", want: "https://www.youtube.com/oembed?format=json&url=https%3A%2F%2Fwww.youtube.com%2Fwatch%3Fv%3Dabc123"},
+		{embed: "https://player.vimeo.com/video/42", want: "https://vimeo.com/api/oembed.json?format=json&url=https%3A%2F%2Fvimeo.com%2F42"},
+		{embed: "https://www.youtube.com/embed/", want: ""},
+		{embed: "https://www.google.com/maps/embed?pb=1", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.embed, func(t *testing.T) {
+			u, err := url.Parse(test.embed)
+			require.NoError(t, err)
+			got := oEmbedURL(u)
+			if test.want == "" {
+				require.Nil(t, got)
+				return
+			}
+			require.Equal(t, test.want, got.String())
+		})
+	}
+}
+
+func TestCheck_Run(t *testing.T) {
+	removed := "https://www.youtube.com/oembed?format=json&url=https%3A%2F%2Fwww.youtube.com%2Fwatch%3Fv%3Dgone"
+	private := "https://vimeo.com/api/oembed.json?format=json&url=https%3A%2F%2Fvimeo.com%2F7"
+	fetcher := &mockFetcher{codes: map[string]int{
+		removed:                                  http.StatusNotFound,
+		private:                                  http.StatusForbidden,
+		"https://www.google.com/maps/embed?pb=1": http.StatusOK,
+		"https://example.com/media/old.mp4":      http.StatusNotFound,
+	}}
+	pages := []audit.Page{
+		{URL: "https://example.com/", Embeds: []string{"https://www.youtube.com/embed/gone", "https://www.google.com/maps/embed?pb=1"}},
+		{URL: "https://example.com/about", Embeds: []string{"https://player.vimeo.com/video/7", "https://example.com/media/old.mp4", "https://down.example.net/widget"}},
+		{URL: "https://example.com/contact", Embeds: []string{"https://www.youtube.com/embed/gone"}},
+	}
+	findings, err := NewCheck(fetcher, 2).Run(context.Background(), pages)
+	require.NoError(t, err)
+	require.Equal(t, []audit.Finding{
+		{Check: CheckBrokenEmbed, URL: "https://example.com/", Detail: "https://www.youtube.com/embed/gone has been removed"},
+		{Check: CheckBrokenEmbed, URL: "https://example.com/about", Detail: "https://player.vimeo.com/video/7 is private or cannot be embedded"},
+		{Check: CheckBrokenEmbed, URL: "https://example.com/about", Detail: "https://example.com/media/old.mp4 returned 404"},
+		{Check: CheckBrokenEmbed, URL: "https://example.com/about", Detail: "https://down.example.net/widget failed to load: no such host"},
+		{Check: CheckBrokenEmbed, URL: "https://example.com/contact", Detail: "https://www.youtube.com/embed/gone has been removed"},
+	}, findings)
+}
+
+func TestCheck_RunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pages := []audit.Page{{URL: "https://example.com/", Embeds: []string{"https://example.com/a.mp4"}}}
+	_, err := NewCheck(&mockFetcher{}, 1).Run(ctx, pages)
+	require.True(t, errors.Is(err, context.Canceled))
+}
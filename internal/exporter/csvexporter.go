@@ -0,0 +1,108 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+type CSVExporter struct {
+	path string
+}
+
+func NewCSVExporter(path string) *CSVExporter {
+	return &CSVExporter{path: path}
+}
+
+// Export writes the links to edges.csv as source,target,weight and each url's metadata to
+// nodes.csv, ready for spreadsheets, Gephi or pandas
+func (c *CSVExporter) Export(gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	if err := os.MkdirAll(c.path, 0755); err != nil {
+		return err
+	}
+	if err := writeCSV(path.Join(c.path, "edges.csv"), func(w *csv.Writer) error {
+		return WriteEdgesCSV(w, gr)
+	}); err != nil {
+		return err
+	}
+	return writeCSV(path.Join(c.path, "nodes.csv"), func(w *csv.Writer) error {
+		return WriteNodesCSV(w, gr, nodes)
+	})
+}
+
+func writeCSV(name string, write func(w *csv.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	buffered := bufio.NewWriter(f)
+	w := csv.NewWriter(buffered)
+	if err := write(w); err != nil {
+		f.Close()
+		return err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func WriteEdgesCSV(w *csv.Writer, gr *graph.Graph[string]) error {
+	if err := w.Write([]string{"source", "target", "weight"}); err != nil {
+		return err
+	}
+	for _, node := range gr.Nodes() {
+		neighbours, _ := gr.Neighbours(node)
+		for _, neighbour := range neighbours {
+			if err := w.Write([]string{node, neighbour.Link, strconv.Itoa(neighbour.Weight)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteNodesCSV writes a row for every url in the graph, leaving columns empty where nothing was
+// recorded
+func WriteNodesCSV(w *csv.Writer, gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	header := []string{"url", "status_code", "depth", "title", "lang", "canonical", "in_sitemap", "fetch_ms", "content_length", "failure"}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, u := range gr.Nodes() {
+		n := nodes[u]
+		if err := w.Write([]string{
+			u,
+			optionalInt(int64(n.StatusCode)),
+			strconv.Itoa(n.Depth),
+			n.Title,
+			n.Lang,
+			n.Canonical,
+			strconv.FormatBool(n.InSitemap),
+			optionalInt(n.FetchMillis),
+			optionalInt(n.ContentLength),
+			string(n.Failure),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func optionalInt(i int64) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.FormatInt(i, 10)
+}
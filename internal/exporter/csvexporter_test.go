@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func TestCSVExporter_Export(t *testing.T) {
+	t.Run("errors when creating directory fails", func(t *testing.T) {
+		conflictingPath := filepath.Join(t.TempDir(), "somefile")
+		require.NoError(t, os.WriteFile(conflictingPath, []byte("hi"), 0644))
+		require.Error(t, NewCSVExporter(conflictingPath).Export(graph.New[string](), nil))
+	})
+	t.Run("writes edges and nodes", func(t *testing.T) {
+		tempDirectory := t.TempDir()
+		g := graph.New[string]()
+		g.AddEdge("https://example.com/", "https://example.com/a,b", 2)
+		g.AddNode("https://example.com/c")
+		nodes := map[string]audit.Node{
+			"https://example.com/":    {URL: "https://example.com/", StatusCode: 200, Title: `Home "page"`, InSitemap: true, FetchMillis: 12, ContentLength: 512},
+			"https://example.com/a,b": {URL: "https://example.com/a,b", StatusCode: 404, Depth: 1, Failure: audit.FailureClientError},
+		}
+		require.NoError(t, NewCSVExporter(tempDirectory).Export(g, nodes))
+		edges, err := os.ReadFile(filepath.Join(tempDirectory, "edges.csv"))
+		require.NoError(t, err)
+		require.Equal(t, "source,target,weight\nhttps://example.com/,\"https://example.com/a,b\",2\n", string(edges))
+		nodesCSV, err := os.ReadFile(filepath.Join(tempDirectory, "nodes.csv"))
+		require.NoError(t, err)
+		require.Equal(t, `url,status_code,depth,title,lang,canonical,in_sitemap,fetch_ms,content_length,failure
+https://example.com/,200,0,"Home ""page""",,,true,12,512,
+"https://example.com/a,b",404,1,,,,false,,,4xx
+https://example.com/c,,0,,,,false,,,
+`, string(nodesCSV))
+	})
+}
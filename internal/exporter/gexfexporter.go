@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+type GEXFExporter struct {
+	path string
+}
+
+func NewGEXFExporter(path string) *GEXFExporter {
+	return &GEXFExporter{path: path}
+}
+
+// Export writes the graph to graph.gexf for Gephi
+func (g *GEXFExporter) Export(gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	return writeFile(path.Join(g.path, "graph.gexf"), func(w io.Writer) error {
+		return WriteGEXF(w, gr, nodes)
+	})
+}
+
+// WriteGEXF labels each node with its title, or its url when it has none, and keeps its metadata
+// as attribute values, leaving out empty ones. Links are weighted edges.
+func WriteGEXF(w io.Writer, gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString("<gexf xmlns=\"http://gexf.net/1.3\" version=\"1.3\">\n")
+	b.WriteString("  <graph defaultedgetype=\"directed\">\n")
+	b.WriteString("    <attributes class=\"node\">\n")
+	for i, field := range nodeFields {
+		kind := field.kind
+		if kind == "int" {
+			kind = "integer"
+		}
+		fmt.Fprintf(&b, "      <attribute id=\"%d\" title=\"%s\" type=\"%s\"/>\n", i, field.name, kind)
+	}
+	b.WriteString("    </attributes>\n    <nodes>\n")
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	for _, u := range gr.Nodes() {
+		b.Reset()
+		label := nodes[u].Title
+		if label == "" {
+			label = u
+		}
+		fmt.Fprintf(&b, "      <node id=\"%s\" label=\"%s\"><attvalues>", escapeXML(u), escapeXML(label))
+		for i, field := range nodeFields {
+			if value := field.value(nodes[u]); value != "" {
+				fmt.Fprintf(&b, "<attvalue for=\"%d\" value=\"%s\"/>", i, escapeXML(value))
+			}
+		}
+		b.WriteString("</attvalues></node>\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "    </nodes>\n    <edges>\n"); err != nil {
+		return err
+	}
+	id := 0
+	for _, u := range gr.Nodes() {
+		neighbours, _ := gr.Neighbours(u)
+		for _, neighbour := range neighbours {
+			if _, err := fmt.Fprintf(w, "      <edge id=\"%d\" source=\"%s\" target=\"%s\" weight=\"%d\"/>\n", id, escapeXML(u), escapeXML(neighbour.Link), neighbour.Weight); err != nil {
+				return err
+			}
+			id++
+		}
+	}
+	_, err := io.WriteString(w, "    </edges>\n  </graph>\n</gexf>\n")
+	return err
+}
@@ -0,0 +1,55 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func TestGEXFExporter_Export(t *testing.T) {
+	t.Run("errors when creating directory fails", func(t *testing.T) {
+		conflictingPath := filepath.Join(t.TempDir(), "somefile")
+		require.NoError(t, os.WriteFile(conflictingPath, []byte("hi"), 0644))
+		require.Error(t, NewGEXFExporter(conflictingPath).Export(graph.New[string](), nil))
+	})
+	t.Run("writes labelled nodes with their metadata and weighted edges", func(t *testing.T) {
+		tempDirectory := t.TempDir()
+		g := graph.New[string]()
+		g.AddEdge("https://example.com/", "https://example.com/a", 3)
+		nodes := map[string]audit.Node{
+			"https://example.com/":  {URL: "https://example.com/", StatusCode: 200, Title: `Home "page"`, Lang: "en"},
+			"https://example.com/a": {URL: "https://example.com/a", StatusCode: 404, Depth: 1, Failure: audit.FailureClientError},
+		}
+		require.NoError(t, NewGEXFExporter(tempDirectory).Export(g, nodes))
+		data, err := os.ReadFile(filepath.Join(tempDirectory, "graph.gexf"))
+		require.NoError(t, err)
+		require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<gexf xmlns="http://gexf.net/1.3" version="1.3">
+  <graph defaultedgetype="directed">
+    <attributes class="node">
+      <attribute id="0" title="status_code" type="integer"/>
+      <attribute id="1" title="depth" type="integer"/>
+      <attribute id="2" title="title" type="string"/>
+      <attribute id="3" title="lang" type="string"/>
+      <attribute id="4" title="canonical" type="string"/>
+      <attribute id="5" title="in_sitemap" type="boolean"/>
+      <attribute id="6" title="fetch_ms" type="long"/>
+      <attribute id="7" title="content_length" type="long"/>
+      <attribute id="8" title="failure" type="string"/>
+    </attributes>
+    <nodes>
+      <node id="https://example.com/" label="Home &#34;page&#34;"><attvalues><attvalue for="0" value="200"/><attvalue for="1" value="0"/><attvalue for="2" value="Home &#34;page&#34;"/><attvalue for="3" value="en"/><attvalue for="5" value="false"/></attvalues></node>
+      <node id="https://example.com/a" label="https://example.com/a"><attvalues><attvalue for="0" value="404"/><attvalue for="1" value="1"/><attvalue for="5" value="false"/><attvalue for="8" value="4xx"/></attvalues></node>
+    </nodes>
+    <edges>
+      <edge id="0" source="https://example.com/" target="https://example.com/a" weight="3"/>
+    </edges>
+  </graph>
+</gexf>
+`, string(data))
+	})
+}
@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+// nodeField is node metadata kept as a typed attribute by the GraphML and GEXF exports
+type nodeField struct {
+	name string
+	// kind is the GraphML type, GEXF names int and boolean the same way
+	kind  string
+	value func(n audit.Node) string
+}
+
+var nodeFields = []nodeField{
+	{name: "status_code", kind: "int", value: func(n audit.Node) string { return optionalInt(int64(n.StatusCode)) }},
+	{name: "depth", kind: "int", value: func(n audit.Node) string { return strconv.Itoa(n.Depth) }},
+	{name: "title", kind: "string", value: func(n audit.Node) string { return n.Title }},
+	{name: "lang", kind: "string", value: func(n audit.Node) string { return n.Lang }},
+	{name: "canonical", kind: "string", value: func(n audit.Node) string { return n.Canonical }},
+	{name: "in_sitemap", kind: "boolean", value: func(n audit.Node) string { return strconv.FormatBool(n.InSitemap) }},
+	{name: "fetch_ms", kind: "long", value: func(n audit.Node) string { return optionalInt(n.FetchMillis) }},
+	{name: "content_length", kind: "long", value: func(n audit.Node) string { return optionalInt(n.ContentLength) }},
+	{name: "failure", kind: "string", value: func(n audit.Node) string { return string(n.Failure) }},
+}
+
+type GraphMLExporter struct {
+	path string
+}
+
+func NewGraphMLExporter(path string) *GraphMLExporter {
+	return &GraphMLExporter{path: path}
+}
+
+// Export writes the graph to graph.graphml for yEd and Gephi
+func (g *GraphMLExporter) Export(gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	return writeFile(path.Join(g.path, "graph.graphml"), func(w io.Writer) error {
+		return WriteGraphML(w, gr, nodes)
+	})
+}
+
+// writeFile creates dir/name and streams write's output to it
+func writeFile(name string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteGraphML writes each node's metadata as data keyed by field, leaving out empty values, and
+// each link's weight
+func WriteGraphML(w io.Writer, gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString("<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	for _, field := range nodeFields {
+		fmt.Fprintf(&b, "  <key id=\"%s\" for=\"node\" attr.name=\"%s\" attr.type=\"%s\"/>\n", field.name, field.name, field.kind)
+	}
+	b.WriteString("  <key id=\"weight\" for=\"edge\" attr.name=\"weight\" attr.type=\"int\"/>\n")
+	b.WriteString("  <graph id=\"G\" edgedefault=\"directed\">\n")
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	for _, u := range gr.Nodes() {
+		b.Reset()
+		fmt.Fprintf(&b, "    <node id=\"%s\">", escapeXML(u))
+		for _, field := range nodeFields {
+			if value := field.value(nodes[u]); value != "" {
+				fmt.Fprintf(&b, "<data key=\"%s\">%s</data>", field.name, escapeXML(value))
+			}
+		}
+		b.WriteString("</node>\n")
+		neighbours, _ := gr.Neighbours(u)
+		for _, neighbour := range neighbours {
+			fmt.Fprintf(&b, "    <edge source=\"%s\" target=\"%s\"><data key=\"weight\">%d</data></edge>\n", escapeXML(u), escapeXML(neighbour.Link), neighbour.Weight)
+		}
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "  </graph>\n</graphml>\n")
+	return err
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
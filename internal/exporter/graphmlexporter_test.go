@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func TestGraphMLExporter_Export(t *testing.T) {
+	t.Run("errors when creating directory fails", func(t *testing.T) {
+		conflictingPath := filepath.Join(t.TempDir(), "somefile")
+		require.NoError(t, os.WriteFile(conflictingPath, []byte("hi"), 0644))
+		require.Error(t, NewGraphMLExporter(conflictingPath).Export(graph.New[string](), nil))
+	})
+	t.Run("writes nodes with their metadata and weighted edges", func(t *testing.T) {
+		tempDirectory := t.TempDir()
+		g := graph.New[string]()
+		g.AddEdge("https://example.com/", "https://example.com/?a=1&b=2", 2)
+		nodes := map[string]audit.Node{
+			"https://example.com/": {URL: "https://example.com/", StatusCode: 200, Title: "Fish & <Chips>", InSitemap: true, FetchMillis: 12},
+		}
+		require.NoError(t, NewGraphMLExporter(tempDirectory).Export(g, nodes))
+		data, err := os.ReadFile(filepath.Join(tempDirectory, "graph.graphml"))
+		require.NoError(t, err)
+		require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="status_code" for="node" attr.name="status_code" attr.type="int"/>
+  <key id="depth" for="node" attr.name="depth" attr.type="int"/>
+  <key id="title" for="node" attr.name="title" attr.type="string"/>
+  <key id="lang" for="node" attr.name="lang" attr.type="string"/>
+  <key id="canonical" for="node" attr.name="canonical" attr.type="string"/>
+  <key id="in_sitemap" for="node" attr.name="in_sitemap" attr.type="boolean"/>
+  <key id="fetch_ms" for="node" attr.name="fetch_ms" attr.type="long"/>
+  <key id="content_length" for="node" attr.name="content_length" attr.type="long"/>
+  <key id="failure" for="node" attr.name="failure" attr.type="string"/>
+  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>
+  <graph id="G" edgedefault="directed">
+    <node id="https://example.com/"><data key="status_code">200</data><data key="depth">0</data><data key="title">Fish &amp; &lt;Chips&gt;</data><data key="in_sitemap">true</data><data key="fetch_ms">12</data></node>
+    <edge source="https://example.com/" target="https://example.com/?a=1&amp;b=2"><data key="weight">2</data></edge>
+    <node id="https://example.com/?a=1&amp;b=2"><data key="depth">0</data><data key="in_sitemap">false</data></node>
+  </graph>
+</graphml>
+`, string(data))
+	})
+}
@@ -1,12 +1,15 @@
 package exporter
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
 
 	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
 )
 
 type GraphVizExporter struct {
@@ -17,22 +20,73 @@ func NewGraphVizExporter(path string) *GraphVizExporter {
 	return &GraphVizExporter{path: path}
 }
 
-func (g *GraphVizExporter) Export(gr *graph.Graph[string]) error {
-	builder := strings.Builder{}
-	builder.WriteString("digraph G{\n")
-	builder.WriteString("  rankdir=\"LR\";\n")
-	builder.WriteString("  node [shape=circle];\n")
+// Export streams the graph to graph.dot so memory does not grow with the size of the document
+func (g *GraphVizExporter) Export(gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	if err := os.MkdirAll(g.path, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path.Join(g.path, "graph.dot"))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := WriteGraphViz(w, gr, nodes); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteGraphViz writes each node with its metadata as attributes, using the title as a tooltip
+func WriteGraphViz(w io.Writer, gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	if _, err := io.WriteString(w, "digraph G{\n  rankdir=\"LR\";\n  node [shape=circle];\n"); err != nil {
+		return err
+	}
 	for _, node := range gr.Nodes() {
-		builder.WriteString(fmt.Sprintf("  \"%v\";\n", node))
+		if _, err := fmt.Fprintf(w, "  %s%s;\n", quote(node), attributes(nodes[node])); err != nil {
+			return err
+		}
 		neighbours, _ := gr.Neighbours(node)
 		for _, neighbour := range neighbours {
-			builder.WriteString(fmt.Sprintf("  \"%v\" -> \"%v\" [label=\"%d\"];\n", node, neighbour.Link, neighbour.Weight))
+			if _, err := fmt.Fprintf(w, "  %s -> %s [label=\"%d\"];\n", quote(node), quote(neighbour.Link), neighbour.Weight); err != nil {
+				return err
+			}
 		}
 	}
-	builder.WriteString("}\n")
-	if err := os.MkdirAll(g.path, 0755); err != nil {
-		return err
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+func attributes(n audit.Node) string {
+	attrs := []string{}
+	if n.StatusCode != 0 {
+		attrs = append(attrs, fmt.Sprintf("status=%d", n.StatusCode))
+	}
+	attrs = append(attrs, fmt.Sprintf("depth=%d", n.Depth))
+	if n.Title != "" {
+		attrs = append(attrs, "tooltip="+quote(n.Title))
+	}
+	if n.FetchMillis != 0 {
+		attrs = append(attrs, fmt.Sprintf("fetch_ms=%d", n.FetchMillis))
 	}
-	contents := builder.String()
-	return os.WriteFile(path.Join(g.path, "graph.dot"), []byte(contents), 0644)
+	if n.ContentLength != 0 {
+		attrs = append(attrs, fmt.Sprintf("content_length=%d", n.ContentLength))
+	}
+	if n.Failure != "" {
+		attrs = append(attrs, "failure="+quote(string(n.Failure)))
+	}
+	if n.StatusCode == 0 && n.Depth == 0 && n.Title == "" && n.FetchMillis == 0 && n.ContentLength == 0 && n.Failure == "" {
+		return ""
+	}
+	return " [" + strings.Join(attrs, ", ") + "]"
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func quote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
 }
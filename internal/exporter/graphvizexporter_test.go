@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/salsgithub/godst/graph"
 	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
 )
 
 func TestGraphVizExporter_Export(t *testing.T) {
@@ -18,7 +20,7 @@ func TestGraphVizExporter_Export(t *testing.T) {
 		require.NoError(t, err)
 		gve := NewGraphVizExporter(conflictingPath)
 		g := graph.New[string]()
-		err = gve.Export(g)
+		err = gve.Export(g, nil)
 		require.Error(t, err)
 	})
 	t.Run("errors when file write fails", func(t *testing.T) {
@@ -28,14 +30,14 @@ func TestGraphVizExporter_Export(t *testing.T) {
 		require.NoError(t, err)
 		gve := NewGraphVizExporter(tempDirectory)
 		g := graph.New[string]()
-		err = gve.Export(g)
+		err = gve.Export(g, nil)
 		require.Error(t, err)
 	})
 	t.Run("handles an empty graph", func(t *testing.T) {
 		tempDirectory := t.TempDir()
 		gve := NewGraphVizExporter(tempDirectory)
 		g := graph.New[string]()
-		err := gve.Export(g)
+		err := gve.Export(g, nil)
 		require.NoError(t, err)
 		filePath := filepath.Join(tempDirectory, "graph.dot")
 		b, err := os.ReadFile(filePath)
@@ -60,7 +62,7 @@ func TestGraphVizExporter_Export(t *testing.T) {
 		g := graph.New[string]()
 		g.AddEdge("A", "B", 10)
 		g.AddNode("C")
-		err := gve.Export(g)
+		err := gve.Export(g, nil)
 		require.NoError(t, err)
 		filePath := filepath.Join(tempDirectory, "graph.dot")
 		b, err := os.ReadFile(filePath)
@@ -83,4 +85,27 @@ func TestGraphVizExporter_Export(t *testing.T) {
 		}
 		require.Equal(t, wantLines, gotLines)
 	})
+	t.Run("writes node metadata as attributes", func(t *testing.T) {
+		g := graph.New[string]()
+		g.AddEdge("A", "B", 1)
+		nodes := map[string]audit.Node{
+			"A": {URL: "A", StatusCode: 200, Title: `Say "hi"`, FetchMillis: 15, ContentLength: 2048},
+			"B": {URL: "B", Depth: 1},
+		}
+		var b strings.Builder
+		require.NoError(t, WriteGraphViz(&b, g, nodes))
+		require.Contains(t, b.String(), `"A" [status=200, depth=0, tooltip="Say \"hi\"", fetch_ms=15, content_length=2048];`)
+		require.Contains(t, b.String(), `"B" [depth=1];`)
+	})
+	t.Run("stops on write errors", func(t *testing.T) {
+		g := graph.New[string]()
+		g.AddEdge("A", "B", 1)
+		require.Error(t, WriteGraphViz(failingWriter{}, g, nil))
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
 }
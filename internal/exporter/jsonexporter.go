@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+// jsonNode is a node's metadata with the id both D3 and Cytoscape.js key nodes by
+type jsonNode struct {
+	ID string `json:"id"`
+	audit.Node
+}
+
+type jsonLink struct {
+	ID     string `json:"id,omitempty"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+type nodeLinkGraph struct {
+	Directed bool       `json:"directed"`
+	Nodes    []jsonNode `json:"nodes"`
+	Links    []jsonLink `json:"links"`
+}
+
+type cytoscapeElement[T any] struct {
+	Data T `json:"data"`
+}
+
+type cytoscapeGraph struct {
+	Elements struct {
+		Nodes []cytoscapeElement[jsonNode] `json:"nodes"`
+		Edges []cytoscapeElement[jsonLink] `json:"edges"`
+	} `json:"elements"`
+}
+
+type JSONExporter struct {
+	path string
+}
+
+func NewJSONExporter(path string) *JSONExporter {
+	return &JSONExporter{path: path}
+}
+
+// Export writes the graph to graph.json for D3 and cytoscape.json for Cytoscape.js
+func (j *JSONExporter) Export(gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	err := writeFile(path.Join(j.path, "graph.json"), func(w io.Writer) error {
+		return WriteNodeLinkJSON(w, gr, nodes)
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(path.Join(j.path, "cytoscape.json"), func(w io.Writer) error {
+		return WriteCytoscapeJSON(w, gr, nodes)
+	})
+}
+
+// WriteNodeLinkJSON writes the node-link layout read by d3-force and networkx, with nodes keyed by
+// url and links by the urls at their ends
+func WriteNodeLinkJSON(w io.Writer, gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	g := nodeLinkGraph{Directed: true, Nodes: jsonNodes(gr, nodes), Links: jsonLinks(gr)}
+	for i := range g.Links {
+		g.Links[i].ID = ""
+	}
+	return json.NewEncoder(w).Encode(g)
+}
+
+// WriteCytoscapeJSON writes the elements layout accepted by cytoscape() and cy.json()
+func WriteCytoscapeJSON(w io.Writer, gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	var g cytoscapeGraph
+	g.Elements.Nodes = []cytoscapeElement[jsonNode]{}
+	g.Elements.Edges = []cytoscapeElement[jsonLink]{}
+	for _, node := range jsonNodes(gr, nodes) {
+		g.Elements.Nodes = append(g.Elements.Nodes, cytoscapeElement[jsonNode]{Data: node})
+	}
+	for _, link := range jsonLinks(gr) {
+		g.Elements.Edges = append(g.Elements.Edges, cytoscapeElement[jsonLink]{Data: link})
+	}
+	return json.NewEncoder(w).Encode(g)
+}
+
+func jsonNodes(gr *graph.Graph[string], nodes map[string]audit.Node) []jsonNode {
+	out := []jsonNode{}
+	for _, u := range gr.Nodes() {
+		node, ok := nodes[u]
+		if !ok {
+			node = audit.Node{URL: u}
+		}
+		out = append(out, jsonNode{ID: u, Node: node})
+	}
+	return out
+}
+
+// jsonLinks numbers each link, as Cytoscape.js needs every element to have an id
+func jsonLinks(gr *graph.Graph[string]) []jsonLink {
+	links := []jsonLink{}
+	for _, u := range gr.Nodes() {
+		neighbours, _ := gr.Neighbours(u)
+		for _, neighbour := range neighbours {
+			links = append(links, jsonLink{ID: fmt.Sprintf("e%d", len(links)), Source: u, Target: neighbour.Link, Weight: neighbour.Weight})
+		}
+	}
+	return links
+}
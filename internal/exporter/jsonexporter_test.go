@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func TestJSONExporter_Export(t *testing.T) {
+	t.Run("errors when creating directory fails", func(t *testing.T) {
+		conflictingPath := filepath.Join(t.TempDir(), "somefile")
+		require.NoError(t, os.WriteFile(conflictingPath, []byte("hi"), 0644))
+		require.Error(t, NewJSONExporter(conflictingPath).Export(graph.New[string](), nil))
+	})
+	t.Run("writes node-link and cytoscape json", func(t *testing.T) {
+		tempDirectory := t.TempDir()
+		g := graph.New[string]()
+		g.AddEdge("https://example.com/", "https://example.com/a", 2)
+		nodes := map[string]audit.Node{
+			"https://example.com/": {URL: "https://example.com/", StatusCode: 200, Title: "Home", InSitemap: true},
+		}
+		require.NoError(t, NewJSONExporter(tempDirectory).Export(g, nodes))
+		nodeLink, err := os.ReadFile(filepath.Join(tempDirectory, "graph.json"))
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"directed": true,
+			"nodes": [
+				{"id": "https://example.com/", "url": "https://example.com/", "status_code": 200, "depth": 0, "title": "Home", "in_sitemap": true},
+				{"id": "https://example.com/a", "url": "https://example.com/a", "depth": 0}
+			],
+			"links": [{"source": "https://example.com/", "target": "https://example.com/a", "weight": 2}]
+		}`, string(nodeLink))
+		cytoscape, err := os.ReadFile(filepath.Join(tempDirectory, "cytoscape.json"))
+		require.NoError(t, err)
+		require.JSONEq(t, `{"elements": {
+			"nodes": [
+				{"data": {"id": "https://example.com/", "url": "https://example.com/", "status_code": 200, "depth": 0, "title": "Home", "in_sitemap": true}},
+				{"data": {"id": "https://example.com/a", "url": "https://example.com/a", "depth": 0}}
+			],
+			"edges": [{"data": {"id": "e0", "source": "https://example.com/", "target": "https://example.com/a", "weight": 2}}]
+		}}`, string(cytoscape))
+	})
+}
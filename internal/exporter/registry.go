@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+var ErrUnknownExporter = errors.New("unknown exporter")
+
+type Exporter interface {
+	Export(gr *graph.Graph[string], nodes map[string]audit.Node) error
+}
+
+// Factory creates an exporter writing its files to dir
+type Factory func(dir string) Exporter
+
+var factories = map[string]Factory{
+	"graphviz": func(dir string) Exporter { return NewGraphVizExporter(dir) },
+	"csv":      func(dir string) Exporter { return NewCSVExporter(dir) },
+	"graphml":  func(dir string) Exporter { return NewGraphMLExporter(dir) },
+	"gexf":     func(dir string) Exporter { return NewGEXFExporter(dir) },
+	"json":     func(dir string) Exporter { return NewJSONExporter(dir) },
+}
+
+// Register makes an exporter selectable by name, replacing any registered under it. It is meant to
+// be called during initialisation, before exporters are created.
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// Names returns the registered exporters in order
+func Names() []string {
+	return slices.Sorted(maps.Keys(factories))
+}
+
+// New creates the exporters named, in order and once each, writing to dir
+func New(names []string, dir string) ([]Exporter, error) {
+	exporters := []Exporter{}
+	created := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || created[name] {
+			continue
+		}
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q, expected one of %s", ErrUnknownExporter, name, strings.Join(Names(), ", "))
+		}
+		created[name] = true
+		exporters = append(exporters, factory(dir))
+	}
+	return exporters, nil
+}
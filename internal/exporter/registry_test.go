@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+type exportFunc func(gr *graph.Graph[string], nodes map[string]audit.Node) error
+
+func (f exportFunc) Export(gr *graph.Graph[string], nodes map[string]audit.Node) error {
+	return f(gr, nodes)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("creates exporters in order once each", func(t *testing.T) {
+		exporters, err := New([]string{"csv", " GraphViz ", "", "csv"}, "out")
+		require.NoError(t, err)
+		require.Equal(t, []Exporter{NewCSVExporter("out"), NewGraphVizExporter("out")}, exporters)
+	})
+	t.Run("errors on unknown names", func(t *testing.T) {
+		_, err := New([]string{"graphviz", "pdf"}, "out")
+		require.True(t, errors.Is(err, ErrUnknownExporter))
+		require.Contains(t, err.Error(), "csv, gexf, graphml, graphviz, json")
+	})
+	t.Run("creates registered exporters", func(t *testing.T) {
+		var dirs []string
+		Register("test", func(dir string) Exporter {
+			dirs = append(dirs, dir)
+			return exportFunc(func(*graph.Graph[string], map[string]audit.Node) error { return nil })
+		})
+		defer delete(factories, "test")
+		exporters, err := New([]string{"test"}, "elsewhere")
+		require.NoError(t, err)
+		require.Len(t, exporters, 1)
+		require.Equal(t, []string{"elsewhere"}, dirs)
+		require.Contains(t, Names(), "test")
+	})
+}
@@ -1,9 +1,12 @@
 package extractor
 
 import (
+	"context"
 	"io"
 	"net/url"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/salsgithub/godst/set"
@@ -32,6 +35,62 @@ var normaliseExtension = func(ext string) string {
 const (
 	hyperTextReference string = "href"
 	anchorTag          string = "a"
+	source             string = "src"
+	titleTag           string = "title"
+	svgTag             string = "svg"
+	hreflang           string = "hreflang"
+	htmlTag            string = "html"
+	langAttribute      string = "lang"
+	metaTag            string = "meta"
+)
+
+// embedTags are elements whose src is content embedded in the page rather than a link
+var embedTags = []string{"iframe", "embed", "video", "audio"}
+
+// Resource kinds name what an element loads into a page
+const (
+	ResourceScript = "script"
+	ResourceStyle  = "style"
+	ResourceImage  = "image"
+	ResourceFrame  = "frame"
+	ResourceMedia  = "media"
+	ResourceObject = "object"
+	ResourceFont   = "font"
+)
+
+// Resource is a url an element loads into the page rather than links to
+type Resource struct {
+	Kind string
+	URL  string
+}
+
+// Alternate is a translation or regional version of a page declared with a link element's hreflang,
+// or a version for other devices, such as a separate mobile site, declared with its media
+type Alternate struct {
+	Lang  string
+	Media string
+	URL   string
+}
+
+// resourceTags maps elements that load content to the kind loaded and the attribute holding its
+// url. Links only load a resource when they are stylesheets.
+var resourceTags = map[string]struct{ kind, attribute string }{
+	"script": {ResourceScript, source},
+	"link":   {ResourceStyle, hyperTextReference},
+	"img":    {ResourceImage, source},
+	"iframe": {ResourceFrame, source},
+	"video":  {ResourceMedia, source},
+	"audio":  {ResourceMedia, source},
+	"source": {ResourceMedia, source},
+	"embed":  {ResourceObject, source},
+	"object": {ResourceObject, "data"},
+}
+
+const (
+	// expectedLinks pre-sizes the result for a typical page
+	expectedLinks = 64
+	// ctxCheckInterval is how many tokens are read between checks for cancellation
+	ctxCheckInterval = 256
 )
 
 type Option func(*LinkExtractor)
@@ -69,37 +128,231 @@ func WithAppendIgnoredExtensions(extensions []string) Option {
 	}
 }
 
-func (l *LinkExtractor) Extract(u *url.URL, body io.Reader) ([]string, error) {
-	links := set.New[string]()
+// Details is everything read from a page in one pass. LinkCounts holds how many times each of
+// Links appears on the page.
+type Details struct {
+	Links      []string
+	LinkCounts map[string]int
+	Embeds     []string
+	Resources  []Resource
+	Alternates []Alternate
+	Title      string
+	// Lang is the language declared on the html element
+	Lang string
+	// Canonical is the page's rel=canonical url and Robots the content of its robots meta tag
+	Canonical string
+	Robots    string
+	// Refresh is where a <meta http-equiv="refresh"> redirects to after RefreshDelay seconds. The
+	// target is also a link, being where the page sends visitors.
+	Refresh      string
+	RefreshDelay int
+}
+
+// Extract stops with the context's error as soon as it is cancelled, even part way through a
+// page. Links are returned once each in the order they first appear.
+func (l *LinkExtractor) Extract(ctx context.Context, u *url.URL, body io.Reader) ([]string, error) {
+	details, err := l.ExtractDetails(ctx, u, body)
+	return details.Links, err
+}
+
+// ExtractDetails is Extract also returning the page's title, language, canonical url and robots
+// meta tag, the sources of iframes, embeds, video and audio, every resource the page loads and its
+// alternates. Ignored extensions do not apply to any of them.
+func (l *LinkExtractor) ExtractDetails(ctx context.Context, u *url.URL, body io.Reader) (Details, error) {
+	d := Details{
+		Links:      make([]string, 0, expectedLinks),
+		LinkCounts: make(map[string]int, expectedLinks),
+		Embeds:     []string{},
+		Resources:  []Resource{},
+		Alternates: []Alternate{},
+	}
+	seenEmbeds := map[string]struct{}{}
+	seenResources := map[Resource]struct{}{}
+	// Titles inside inline svg images name the image, not the page
+	svgDepth := 0
 	tokenizer := html.NewTokenizer(body)
-	for {
-		tokenType := tokenizer.Next()
-		switch tokenType {
+	for tokens := 0; ; tokens++ {
+		if tokens%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return Details{}, err
+			}
+		}
+		switch tokenType := tokenizer.Next(); tokenType {
 		case html.ErrorToken:
 			err := tokenizer.Err()
 			if err == io.EOF {
-				return links.Values(), nil
+				return d, nil
 			}
-			return nil, err
-		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
-			token := tokenizer.Token()
-			if token.Data != anchorTag {
-				continue
+			return Details{}, err
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == svgTag && svgDepth > 0 {
+				svgDepth--
 			}
-			for _, attribute := range token.Attr {
-				if attribute.Key != hyperTextReference {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttributes := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case tag == svgTag:
+				if tokenType == html.StartTagToken {
+					svgDepth++
+				}
+			case tag == htmlTag:
+				for hasAttributes && d.Lang == "" {
+					var key, value []byte
+					key, value, hasAttributes = tokenizer.TagAttr()
+					if string(key) == langAttribute {
+						d.Lang = strings.TrimSpace(string(value))
+					}
+				}
+			case tag == metaTag:
+				var name, httpEquiv, content string
+				for hasAttributes {
+					var key, value []byte
+					key, value, hasAttributes = tokenizer.TagAttr()
+					switch string(key) {
+					case "name":
+						name = strings.ToLower(strings.TrimSpace(string(value)))
+					case "http-equiv":
+						httpEquiv = strings.ToLower(strings.TrimSpace(string(value)))
+					case "content":
+						content = string(value)
+					}
+				}
+				if name == "robots" && d.Robots == "" {
+					d.Robots = strings.TrimSpace(content)
+				}
+				if httpEquiv == "refresh" && d.Refresh == "" {
+					delay, target, ok := parseRefresh(content)
+					if !ok {
+						continue
+					}
+					link, ok := l.resolve(u, target)
+					if !ok {
+						continue
+					}
+					d.Refresh, d.RefreshDelay = link, delay
+					d.AddLink(link)
+				}
+			case tag == titleTag && d.Title == "" && svgDepth == 0:
+				if tokenizer.Next() == html.TextToken {
+					d.Title = strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+				}
+			case tag == anchorTag:
+				for hasAttributes {
+					var key, value []byte
+					key, value, hasAttributes = tokenizer.TagAttr()
+					if string(key) != hyperTextReference {
+						continue
+					}
+					link, ok := l.resolve(u, string(value))
+					if !ok {
+						continue
+					}
+					d.AddLink(link)
+				}
+			default:
+				loaded, ok := resourceTags[tag]
+				if !ok {
 					continue
 				}
-				fileExtension := strings.ToLower(path.Ext(attribute.Val))
-				if fileExtension != "" && l.ignores.Contains(fileExtension) {
+				var target, lang, media string
+				var rel []string
+				for hasAttributes {
+					var key, value []byte
+					key, value, hasAttributes = tokenizer.TagAttr()
+					switch string(key) {
+					case loaded.attribute:
+						target = strings.TrimSpace(string(value))
+					case "rel":
+						rel = strings.Fields(strings.ToLower(string(value)))
+					case hreflang:
+						lang = strings.TrimSpace(string(value))
+					case "media":
+						media = strings.TrimSpace(string(value))
+					}
+				}
+				if target == "" {
 					continue
 				}
-				hrefURL, err := url.Parse(attribute.Val)
+				ref, err := url.Parse(target)
 				if err != nil {
 					continue
 				}
-				links.Add(u.ResolveReference(hrefURL).String())
+				resolved := u.ResolveReference(ref).String()
+				if tag == "link" && lang != "" && slices.Contains(rel, "alternate") {
+					d.Alternates = append(d.Alternates, Alternate{Lang: lang, URL: resolved})
+				}
+				// The other version is crawled like a link so its canonical can be checked
+				if tag == "link" && lang == "" && media != "" && slices.Contains(rel, "alternate") {
+					d.Alternates = append(d.Alternates, Alternate{Media: media, URL: resolved})
+					if link, ok := l.resolve(u, target); ok {
+						d.AddLink(link)
+					}
+				}
+				if tag == "link" && d.Canonical == "" && slices.Contains(rel, "canonical") {
+					d.Canonical = resolved
+				}
+				if loaded.kind == ResourceStyle && !slices.Contains(rel, "stylesheet") {
+					continue
+				}
+				if _, ok := seenEmbeds[resolved]; !ok && slices.Contains(embedTags, tag) {
+					seenEmbeds[resolved] = struct{}{}
+					d.Embeds = append(d.Embeds, resolved)
+				}
+				resource := Resource{Kind: loaded.kind, URL: resolved}
+				if _, ok := seenResources[resource]; !ok {
+					seenResources[resource] = struct{}{}
+					d.Resources = append(d.Resources, resource)
+				}
 			}
 		}
 	}
 }
+
+// AddLink counts a link, adding it to Links the first time it is seen. It is also used for links
+// found outside the page's HTML, such as the Location of a redirect.
+func (d *Details) AddLink(link string) {
+	if d.LinkCounts == nil {
+		d.LinkCounts = map[string]int{}
+	}
+	if d.LinkCounts[link] == 0 {
+		d.Links = append(d.Links, link)
+	}
+	d.LinkCounts[link]++
+}
+
+// parseRefresh reads a refresh's content, such as 5; url=/moved, returning false when it only
+// reloads the page
+func parseRefresh(content string) (int, string, bool) {
+	delay, target, _ := strings.Cut(content, ";")
+	if !strings.Contains(content, ";") {
+		delay, target, _ = strings.Cut(content, ",")
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(delay), 64)
+	if err != nil || seconds < 0 {
+		return 0, "", false
+	}
+	target = strings.TrimSpace(target)
+	if len(target) > 3 && strings.EqualFold(target[:3], "url") {
+		if rest := strings.TrimSpace(target[3:]); strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	target = strings.Trim(target, `"'`)
+	if target == "" {
+		return 0, "", false
+	}
+	return int(seconds), target, true
+}
+
+func (l *LinkExtractor) resolve(u *url.URL, href string) (string, bool) {
+	fileExtension := strings.ToLower(path.Ext(href))
+	if fileExtension != "" && l.ignores.Contains(fileExtension) {
+		return "", false
+	}
+	hrefURL, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	return u.ResolveReference(hrefURL).String(), true
+}
@@ -2,8 +2,11 @@ package extractor
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -50,13 +53,18 @@ func TestLinkExtractor_Extract(t *testing.T) {
 			html: `<html><body><a href="https://other.com"></a><a href="https://sub.example.com"></a></body></html>`,
 			want: []string{"https://other.com", "https://sub.example.com"},
 		},
+		{
+			name: "Declarative shadow roots of custom elements",
+			html: `<site-nav><template shadowrootmode="open"><a href="/docs">Docs</a><slot></slot></template><a href="/blog">Blog</a></site-nav>`,
+			want: []string{"https://example.com/docs", "https://example.com/blog"},
+		},
 	}
 	base, _ := url.Parse("https://example.com")
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			e := NewLinkExtractor(WithDefaultIgnores())
 			reader := bytes.NewReader([]byte(test.html))
-			links, err := e.Extract(base, reader)
+			links, err := e.Extract(context.Background(), base, reader)
 			require.NoError(t, err)
 			require.ElementsMatch(t, links, test.want)
 		})
@@ -80,13 +88,117 @@ func TestExtractor_WithAppendIgnoredExtensions(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			e := NewLinkExtractor(WithAppendIgnoredExtensions([]string{"dat"}))
 			reader := bytes.NewReader([]byte(test.html))
-			links, err := e.Extract(u, reader)
+			links, err := e.Extract(context.Background(), u, reader)
 			require.NoError(t, err)
 			require.ElementsMatch(t, links, test.want)
 		})
 	}
 }
 
+func TestExtractor_ExtractDetails(t *testing.T) {
+	u, _ := url.Parse("https://example.com/page")
+	e := NewLinkExtractor(WithDefaultIgnores())
+	html := `<html lang="de-DE"><head><meta name="Robots" content="noindex, follow"><link rel="canonical" href="/page?ref=canonical"><title>
+		Home &amp; Away
+	</title></head><svg><title>Logo</title></svg><a href="/a">A</a>
+		<iframe src="https://www.youtube.com/embed/abc"></iframe>
+		<video src="/media/intro.mp4"></video>
+		<embed src="https://maps.example.net/embed?q=1"/>
+		<iframe src="https://www.youtube.com/embed/abc"></iframe>
+		<img src="/logo.png"><a href="/a">A again</a>
+		<script src="https://cdn.example.net/app.js"></script><script>inline()</script>
+		<link rel="icon" href="/favicon.ico"><link href="https://fonts.example.net/css" rel="Preload StyleSheet">
+		<link rel="alternate" hreflang="de" href="/de/page"><link rel="alternate" hreflang="x-default" href="https://example.com/page">
+		<link rel="alternate" type="application/rss+xml" href="/feed">`
+	details, err := e.ExtractDetails(context.Background(), u, strings.NewReader(html))
+	require.NoError(t, err)
+	require.Equal(t, "Home & Away", details.Title)
+	require.Equal(t, "de-DE", details.Lang)
+	require.Equal(t, "https://example.com/page?ref=canonical", details.Canonical)
+	require.Equal(t, "noindex, follow", details.Robots)
+	require.Equal(t, []string{"https://example.com/a"}, details.Links)
+	require.Equal(t, map[string]int{"https://example.com/a": 2}, details.LinkCounts)
+	require.Equal(t, []string{"https://www.youtube.com/embed/abc", "https://example.com/media/intro.mp4", "https://maps.example.net/embed?q=1"}, details.Embeds)
+	require.Equal(t, []Resource{
+		{Kind: ResourceFrame, URL: "https://www.youtube.com/embed/abc"},
+		{Kind: ResourceMedia, URL: "https://example.com/media/intro.mp4"},
+		{Kind: ResourceObject, URL: "https://maps.example.net/embed?q=1"},
+		{Kind: ResourceImage, URL: "https://example.com/logo.png"},
+		{Kind: ResourceScript, URL: "https://cdn.example.net/app.js"},
+		{Kind: ResourceStyle, URL: "https://fonts.example.net/css"},
+	}, details.Resources)
+	require.Equal(t, []Alternate{
+		{Lang: "de", URL: "https://example.com/de/page"},
+		{Lang: "x-default", URL: "https://example.com/page"},
+	}, details.Alternates)
+	links, err := e.Extract(context.Background(), u, strings.NewReader(html))
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/a"}, links)
+}
+
+func TestExtractor_MetaRefresh(t *testing.T) {
+	u, _ := url.Parse("https://example.com/old")
+	e := NewLinkExtractor(WithDefaultIgnores())
+	tests := []struct {
+		content string
+		want    string
+		delay   int
+	}{
+		{content: "0; url=/new", want: "https://example.com/new"},
+		{content: `5;URL='https://other.example.com/'`, want: "https://other.example.com/", delay: 5},
+		{content: "3, /comma", want: "https://example.com/comma", delay: 3},
+		{content: "30"},
+		{content: "soon; url=/new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			html := `<meta http-equiv="Refresh" content="` + tt.content + `"><a href="/a">a</a>`
+			details, err := e.ExtractDetails(context.Background(), u, strings.NewReader(html))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, details.Refresh)
+			require.Equal(t, tt.delay, details.RefreshDelay)
+			if tt.want != "" {
+				require.Equal(t, []string{tt.want, "https://example.com/a"}, details.Links)
+			}
+		})
+	}
+}
+
+func TestExtractor_MediaAlternates(t *testing.T) {
+	u, _ := url.Parse("https://example.com/page")
+	e := NewLinkExtractor(WithDefaultIgnores())
+	html := `<link rel="alternate" media="only screen and (max-width: 640px)" href="https://m.example.com/page">
+		<link rel="alternate" hreflang="de" media="print" href="/de/page"><source media="(min-width: 800px)" src="/wide.mp4">`
+	details, err := e.ExtractDetails(context.Background(), u, strings.NewReader(html))
+	require.NoError(t, err)
+	require.Equal(t, []Alternate{
+		{Media: "only screen and (max-width: 640px)", URL: "https://m.example.com/page"},
+		{Lang: "de", URL: "https://example.com/de/page"},
+	}, details.Alternates)
+	require.Equal(t, []string{"https://m.example.com/page"}, details.Links)
+}
+
+func TestExtractor_LinkHeader(t *testing.T) {
+	u, _ := url.Parse("https://example.com/list?page=2")
+	links := ParseLinkHeader(u, []string{
+		`<https://example.com/list>; rel="canonical", </list?page=3>; rel=next, <bad; rel=prev`,
+		`<https://example.com/de/list?a=1,2>; rel="alternate"; hreflang="de", </font.woff2>; rel=preload; as=font; crossorigin`,
+	})
+	require.Equal(t, []HeaderLink{
+		{URL: "https://example.com/list", Rel: []string{"canonical"}, Params: map[string]string{}},
+		{URL: "https://example.com/list?page=3", Rel: []string{"next"}, Params: map[string]string{}},
+		{URL: "https://example.com/de/list?a=1,2", Rel: []string{"alternate"}, Params: map[string]string{"hreflang": "de"}},
+		{URL: "https://example.com/font.woff2", Rel: []string{"preload"}, Params: map[string]string{"as": "font", "crossorigin": ""}},
+	}, links)
+
+	details := &Details{Canonical: "https://example.com/own"}
+	details.MergeHeaderLinks(links)
+	require.Equal(t, "https://example.com/own", details.Canonical)
+	require.Equal(t, []string{"https://example.com/list?page=3"}, details.Links)
+	require.Equal(t, []Alternate{{Lang: "de", URL: "https://example.com/de/list?a=1,2"}}, details.Alternates)
+	require.Equal(t, []Resource{{Kind: ResourceFont, URL: "https://example.com/font.woff2"}}, details.Resources)
+}
+
 type errorReader struct{}
 
 func (e *errorReader) Read(b []byte) (int, error) {
@@ -97,6 +209,42 @@ func TestExtractor_ErrorOnRead(t *testing.T) {
 	u, _ := url.Parse("https://example.com")
 	e := NewLinkExtractor()
 	reader := &errorReader{}
-	_, err := e.Extract(u, reader)
+	_, err := e.Extract(context.Background(), u, reader)
 	require.Error(t, err)
 }
+
+func TestExtractor_Cancelled(t *testing.T) {
+	u, _ := url.Parse("https://example.com")
+	e := NewLinkExtractor()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := e.Extract(ctx, u, bytes.NewReader([]byte(`<a href="/a">A</a>`)))
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func largeDocument(links int) []byte {
+	var b strings.Builder
+	b.WriteString("<html><head><title>Large</title></head><body>")
+	for i := range links {
+		fmt.Fprintf(&b, `<div class="item"><p>Paragraph %d with <em>emphasis</em></p><a class="link" href="/page/%d?ref=%d">Page %d</a><img src="/img/%d.png"></div>`, i, i, i%10, i, i)
+	}
+	b.WriteString("</body></html>")
+	return []byte(b.String())
+}
+
+func BenchmarkLinkExtractor_Extract(b *testing.B) {
+	u, _ := url.Parse("https://example.com")
+	e := NewLinkExtractor(WithDefaultIgnores())
+	for _, links := range []int{100, 10000} {
+		document := largeDocument(links)
+		b.Run(fmt.Sprintf("%d links", links), func(b *testing.B) {
+			b.SetBytes(int64(len(document)))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := e.Extract(context.Background(), u, bytes.NewReader(document)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package extractor
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// HeaderLink is one link of a Link response header
+type HeaderLink struct {
+	URL string
+	Rel []string
+	// Params holds the other parameters, such as hreflang, media and as, keyed in lower case
+	Params map[string]string
+}
+
+// preloadKinds maps the as parameter of a preload to the kind of resource loaded
+var preloadKinds = map[string]string{
+	"script":   ResourceScript,
+	"style":    ResourceStyle,
+	"image":    ResourceImage,
+	"font":     ResourceFont,
+	"audio":    ResourceMedia,
+	"video":    ResourceMedia,
+	"track":    ResourceMedia,
+	"iframe":   ResourceFrame,
+	"document": ResourceFrame,
+	"object":   ResourceObject,
+	"embed":    ResourceObject,
+}
+
+// ParseLinkHeader reads the links of Link headers, such as <https://example.com/>; rel="canonical",
+// resolving them against u. Links that cannot be parsed are skipped.
+func ParseLinkHeader(u *url.URL, values []string) []HeaderLink {
+	links := []HeaderLink{}
+	for _, value := range values {
+		for value != "" {
+			var link HeaderLink
+			var ok bool
+			link, value, ok = nextHeaderLink(u, value)
+			if ok {
+				links = append(links, link)
+			}
+		}
+	}
+	return links
+}
+
+// nextHeaderLink reads the link at the start of value and returns the rest after its comma. Commas
+// inside the url or a quoted parameter do not end the link.
+func nextHeaderLink(u *url.URL, value string) (HeaderLink, string, bool) {
+	value = strings.TrimLeft(value, " \t,")
+	if !strings.HasPrefix(value, "<") {
+		_, rest, _ := strings.Cut(value, ",")
+		return HeaderLink{}, rest, false
+	}
+	target, value, ok := strings.Cut(value[1:], ">")
+	if !ok {
+		return HeaderLink{}, "", false
+	}
+	link := HeaderLink{Params: map[string]string{}}
+	for {
+		value = strings.TrimLeft(value, " \t")
+		if !strings.HasPrefix(value, ";") {
+			break
+		}
+		var key, param string
+		key, param, value = nextParam(value[1:])
+		if key == "rel" {
+			link.Rel = strings.Fields(strings.ToLower(param))
+		} else if key != "" {
+			link.Params[key] = param
+		}
+	}
+	_, rest, _ := strings.Cut(value, ",")
+	ref, err := url.Parse(strings.TrimSpace(target))
+	if err != nil {
+		return HeaderLink{}, rest, false
+	}
+	link.URL = u.ResolveReference(ref).String()
+	return link, rest, true
+}
+
+// nextParam reads a key=value or key="quoted value" parameter up to the next ; or ,
+func nextParam(value string) (string, string, string) {
+	end := strings.IndexAny(value, "=;,")
+	if end < 0 {
+		return strings.ToLower(strings.TrimSpace(value)), "", ""
+	}
+	key := strings.ToLower(strings.TrimSpace(value[:end]))
+	if value[end] != '=' {
+		return key, "", value[end:]
+	}
+	value = strings.TrimLeft(value[end+1:], " \t")
+	if strings.HasPrefix(value, `"`) {
+		quoted, rest, _ := strings.Cut(value[1:], `"`)
+		return key, quoted, rest
+	}
+	end = strings.IndexAny(value, ";,")
+	if end < 0 {
+		return key, strings.TrimSpace(value), ""
+	}
+	return key, strings.TrimSpace(value[:end]), value[end:]
+}
+
+// MergeHeaderLinks adds what Link headers say about the page to what it says itself, which wins
+// where both give a canonical url. Pagination and alternates are links like any other, and
+// preloads are resources the page loads.
+func (d *Details) MergeHeaderLinks(links []HeaderLink) {
+	for _, link := range links {
+		switch {
+		case slices.Contains(link.Rel, "canonical"):
+			if d.Canonical == "" {
+				d.Canonical = link.URL
+			}
+		case slices.Contains(link.Rel, "alternate") && link.Params[hreflang] != "":
+			alternate := Alternate{Lang: link.Params[hreflang], URL: link.URL}
+			if !slices.Contains(d.Alternates, alternate) {
+				d.Alternates = append(d.Alternates, alternate)
+			}
+		case slices.Contains(link.Rel, "alternate") && link.Params["media"] != "":
+			alternate := Alternate{Media: link.Params["media"], URL: link.URL}
+			if !slices.Contains(d.Alternates, alternate) {
+				d.Alternates = append(d.Alternates, alternate)
+				d.AddLink(link.URL)
+			}
+		case slices.Contains(link.Rel, "next") || slices.Contains(link.Rel, "prev") || slices.Contains(link.Rel, "previous"):
+			if d.LinkCounts[link.URL] == 0 {
+				d.AddLink(link.URL)
+			}
+		case slices.Contains(link.Rel, "preload"):
+			kind, ok := preloadKinds[strings.ToLower(link.Params["as"])]
+			resource := Resource{Kind: kind, URL: link.URL}
+			if ok && !slices.Contains(d.Resources, resource) {
+				d.Resources = append(d.Resources, resource)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var ErrInvalidConnectTo = errors.New("invalid connect to")
+
+// ParseConnectTo reads comma separated host=address pairs. The address is an IP or internal
+// hostname, with a port when it differs from the one requested.
+func ParseConnectTo(s string) (map[string]string, error) {
+	targets := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		host, address, ok := strings.Cut(pair, "=")
+		host, address = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(address)
+		if !ok || host == "" || address == "" || strings.ContainsAny(host, ":/") {
+			return nil, fmt.Errorf("%w: %q, expected host=address", ErrInvalidConnectTo, pair)
+		}
+		targets[host] = address
+	}
+	return targets, nil
+}
+
+// WithConnectTo sends requests for each host to another address, keeping the url so the Host
+// header, SNI and certificate checks are those of the host. This audits a site behind a load
+// balancer, or not yet in DNS, exactly as it will be served. It must come after WithDNSCache.
+func WithConnectTo(targets map[string]string) Option {
+	return func(h *HTTPFetcher) {
+		if len(targets) == 0 {
+			return
+		}
+		dial := h.transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		h.transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return dial(ctx, network, address)
+			}
+			target, ok := targets[strings.ToLower(host)]
+			if !ok {
+				return dial(ctx, network, address)
+			}
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				target = net.JoinHostPort(strings.Trim(target, "[]"), port)
+			}
+			return dial(ctx, network, target)
+		}
+	}
+}
@@ -5,17 +5,106 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"salsgithub.com/site-audit/internal/dnscache"
+	"salsgithub.com/site-audit/internal/policy"
 )
 
+type Option func(*HTTPFetcher)
+
+type headersKey struct{}
+
 type HTTPFetcher struct {
-	client *http.Client
-	agent  string
+	client    *http.Client
+	transport *http.Transport
+	agent     string
+	policies  *policy.Set
+	login     *Login
+	cookies   []*http.Cookie
 }
 
-func NewHTTPFetcher(agent string) *HTTPFetcher {
-	return &HTTPFetcher{
-		client: &http.Client{Timeout: 5 * time.Second},
-		agent:  agent,
+func NewHTTPFetcher(agent string, options ...Option) *HTTPFetcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	h := &HTTPFetcher{
+		client:    &http.Client{Timeout: 5 * time.Second, Transport: transport},
+		transport: transport,
+		agent:     agent,
+	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+// Derive returns a fetcher with h's settings, policies, cookies and login session and options
+// applied on top, such as a redirect policy of its own. It dials as h does, through a transport of
+// its own, so options changing the transport leave h alone.
+func (h *HTTPFetcher) Derive(options ...Option) *HTTPFetcher {
+	transport := h.transport.Clone()
+	client := *h.client
+	client.Transport = transport
+	d := *h
+	d.client = &client
+	d.transport = transport
+	for _, option := range options {
+		option(&d)
+	}
+	return &d
+}
+
+// WithMaxConnsPerHost bounds open connections to each host and keeps as many idle for reuse,
+// which should match the number of workers fetching concurrently
+func WithMaxConnsPerHost(n int) Option {
+	return func(h *HTTPFetcher) {
+		h.transport.MaxConnsPerHost = n
+		h.transport.MaxIdleConnsPerHost = n
+	}
+}
+
+// WithDNSCache dials through cache so each host is resolved once per TTL
+func WithDNSCache(cache *dnscache.Cache) Option {
+	return func(h *HTTPFetcher) {
+		h.transport.DialContext = cache.DialContext
+	}
+}
+
+// RedirectPolicy decides which redirects are followed. A redirect that is not followed is returned
+// as the 3xx response itself, leaving the caller to record or crawl its Location.
+type RedirectPolicy struct {
+	// Follow is whether redirects are followed at all
+	Follow bool
+	// MaxHops is how many redirects are followed for one fetch
+	MaxHops int
+	// CrossHost is whether redirects to another host are followed
+	CrossHost bool
+}
+
+// WithRedirectPolicy replaces the client's default of following up to 10 redirects, then failing
+func WithRedirectPolicy(p RedirectPolicy) Option {
+	return func(h *HTTPFetcher) {
+		h.client.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+			switch {
+			case !p.Follow, len(via) > p.MaxHops:
+				return http.ErrUseLastResponse
+			case !p.CrossHost && request.URL.Hostname() != via[0].URL.Hostname():
+				return http.ErrUseLastResponse
+			}
+			return nil
+		}
+	}
+}
+
+// WithCookies sends cookies with every request, such as the one recording cookie consent so pages
+// are fetched as a visitor who has accepted the banner sees them
+func WithCookies(cookies []*http.Cookie) Option {
+	return func(h *HTTPFetcher) {
+		h.cookies = cookies
+	}
+}
+
+func WithPolicies(policies *policy.Set) Option {
+	return func(h *HTTPFetcher) {
+		h.policies = policies
 	}
 }
 
@@ -25,5 +114,30 @@ func (h *HTTPFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, er
 		return nil, err
 	}
 	request.Header.Set("User-Agent", h.agent)
+	for _, cookie := range h.cookies {
+		request.AddCookie(cookie)
+	}
+	p := h.policies.Match(u.Hostname())
+	if err := p.Apply(request); err != nil {
+		return nil, err
+	}
+	if err := p.Wait(ctx); err != nil {
+		return nil, err
+	}
+	if headers, ok := ctx.Value(headersKey{}).(http.Header); ok {
+		for key, values := range headers {
+			request.Header[key] = values
+		}
+	}
 	return h.client.Do(request)
 }
+
+// WithHeader asks fetches made with the returned context to send a header, over any set by a policy
+func WithHeader(ctx context.Context, key, value string) context.Context {
+	headers := http.Header{}
+	if existing, ok := ctx.Value(headersKey{}).(http.Header); ok {
+		headers = existing.Clone()
+	}
+	headers.Set(key, value)
+	return context.WithValue(ctx, headersKey{}, headers)
+}
@@ -2,14 +2,21 @@ package fetcher
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/policy"
 )
 
 func TestHTTPFetcher_New(t *testing.T) {
@@ -56,3 +63,274 @@ func TestHTTPFetcher_Fetch(t *testing.T) {
 		require.Contains(t, err.Error(), context.DeadlineExceeded.Error())
 	})
 }
+
+func TestHTTPFetcher_WithHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Accept-Language") + " " + r.Header.Get("X-Test")))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	ctx := WithHeader(WithHeader(t.Context(), "Accept-Language", "de"), "X-Test", "yes")
+	response, err := NewHTTPFetcher("agent").Fetch(ctx, u)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "de yes", string(body))
+}
+
+func TestHTTPFetcher_Cookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Cookie")))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	cookies, err := http.ParseCookie("consent=accepted; tracking=none")
+	require.NoError(t, err)
+	response, err := NewHTTPFetcher("agent", WithCookies(cookies)).Fetch(t.Context(), u)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "consent=accepted; tracking=none", string(body))
+}
+
+func TestHTTPFetcher_RedirectPolicy(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("other"))
+	}))
+	defer other.Close()
+	// The other server is reached through another host name for the same address
+	otherHost := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1":
+			http.Redirect(w, r, "/2", http.StatusMovedPermanently)
+		case "/2":
+			http.Redirect(w, r, "/3", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, otherHost+"/", http.StatusMovedPermanently)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+	tests := []struct {
+		name     string
+		policy   RedirectPolicy
+		path     string
+		wantCode int
+		wantURL  string
+	}{
+		{name: "follows the chain", policy: RedirectPolicy{Follow: true, MaxHops: 10, CrossHost: true}, path: "/1", wantCode: http.StatusOK, wantURL: "/3"},
+		{name: "stops at max hops", policy: RedirectPolicy{Follow: true, MaxHops: 1, CrossHost: true}, path: "/1", wantCode: http.StatusFound, wantURL: "/2"},
+		{name: "does not follow", policy: RedirectPolicy{MaxHops: 10}, path: "/1", wantCode: http.StatusMovedPermanently, wantURL: "/1"},
+		{name: "follows the same host", policy: RedirectPolicy{Follow: true, MaxHops: 10}, path: "/1", wantCode: http.StatusOK, wantURL: "/3"},
+		{name: "stops at another host", policy: RedirectPolicy{Follow: true, MaxHops: 10}, path: "/away", wantCode: http.StatusMovedPermanently, wantURL: "/away"},
+		{name: "follows to another host", policy: RedirectPolicy{Follow: true, MaxHops: 10, CrossHost: true}, path: "/away", wantCode: http.StatusOK, wantURL: "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewHTTPFetcher("agent", WithRedirectPolicy(tt.policy))
+			u, _ := url.Parse(server.URL + tt.path)
+			response, err := f.Fetch(t.Context(), u)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, tt.wantCode, response.StatusCode)
+			require.Equal(t, tt.wantURL, response.Request.URL.Path)
+		})
+	}
+}
+
+func TestHTTPFetcher_Policies(t *testing.T) {
+	var gotAuth, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotHeader = r.Header.Get("X-Env")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	policies, err := policy.New([]*policy.Policy{{
+		Host:    "127.0.0.1",
+		Headers: map[string]string{"X-Env": "staging"},
+		Auth:    &policy.Auth{Type: policy.AuthBearer, Token: "secret"},
+	}})
+	require.NoError(t, err)
+	f := NewHTTPFetcher("agent", WithPolicies(policies))
+	u, _ := url.Parse(server.URL)
+	response, err := f.Fetch(t.Context(), u)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, "Bearer secret", gotAuth)
+	require.Equal(t, "staging", gotHeader)
+}
+
+func TestHTTPFetcher_ConnectionReuse(t *testing.T) {
+	var mu sync.Mutex
+	opened := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			opened++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+	f := NewHTTPFetcher("agent", WithMaxConnsPerHost(4))
+	u, _ := url.Parse(server.URL)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				response, err := f.Fetch(t.Context(), u)
+				require.NoError(t, err)
+				io.Copy(io.Discard, response.Body)
+				response.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	require.True(t, opened <= 4, "opened %d connections", opened)
+}
+
+func loginServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<form method="post"><input type="hidden" name="token" value="t0k3n"><input name="user"></form>`))
+	})
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("user") != "auditor" || r.FormValue("password") != "secret" || r.FormValue("token") != "t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		http.Redirect(w, r, "/account", http.StatusSeeOther)
+	})
+	mux.HandleFunc("GET /account", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("Sign out"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHTTPFetcher_Login(t *testing.T) {
+	server := loginServer()
+	defer server.Close()
+	newLogin := func(password string) *Login {
+		return &Login{
+			URL:       server.URL + "/login",
+			Fields:    map[string]string{"user": "auditor", "password": password},
+			CSRFField: "token",
+			Success:   LoginSuccess{Contains: "Sign out", Cookie: "session"},
+		}
+	}
+	t.Run("session is used for later requests", func(t *testing.T) {
+		f := NewHTTPFetcher("agent", WithLogin(newLogin("secret")))
+		require.NoError(t, f.Login(t.Context()))
+		u, _ := url.Parse(server.URL + "/account")
+		response, err := f.Fetch(t.Context(), u)
+		require.NoError(t, err)
+		response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+	t.Run("derived fetchers share the session", func(t *testing.T) {
+		f := NewHTTPFetcher("agent", WithLogin(newLogin("secret")))
+		require.NoError(t, f.Login(t.Context()))
+		derived := f.Derive(WithRedirectPolicy(RedirectPolicy{}))
+		u, _ := url.Parse(server.URL + "/account")
+		response, err := derived.Fetch(t.Context(), u)
+		require.NoError(t, err)
+		response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Nil(t, f.client.CheckRedirect)
+	})
+	t.Run("wrong credentials fail", func(t *testing.T) {
+		f := NewHTTPFetcher("agent", WithLogin(newLogin("wrong")))
+		err := f.Login(t.Context())
+		require.True(t, errors.Is(err, ErrLoginFailed))
+	})
+	t.Run("missing csrf field fails", func(t *testing.T) {
+		l := newLogin("secret")
+		l.CSRFField = "missing"
+		err := NewHTTPFetcher("agent", WithLogin(l)).Login(t.Context())
+		require.True(t, errors.Is(err, ErrLoginFailed))
+	})
+	t.Run("unmet success check fails", func(t *testing.T) {
+		l := newLogin("secret")
+		l.Success.Contains = "Welcome"
+		err := NewHTTPFetcher("agent", WithLogin(l)).Login(t.Context())
+		require.True(t, errors.Is(err, ErrLoginFailed))
+	})
+	t.Run("no login configured", func(t *testing.T) {
+		require.NoError(t, NewHTTPFetcher("agent").Login(t.Context()))
+	})
+}
+
+func TestLoadLogin(t *testing.T) {
+	t.Setenv("LOGIN_PASSWORD", "secret")
+	write := func(contents string) string {
+		path := filepath.Join(t.TempDir(), "login.json")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+		return path
+	}
+	l, err := LoadLogin(write(`{"url": "https://example.com/login", "fields": {"password": "${LOGIN_PASSWORD}"}}`))
+	require.NoError(t, err)
+	require.Equal(t, "secret", l.Fields["password"])
+	for _, contents := range []string{
+		`{`,
+		`{"url": "/login", "fields": {"a": "b"}}`,
+		`{"url": "https://example.com/login", "action": "/submit", "fields": {"a": "b"}}`,
+		`{"url": "https://example.com/login"}`,
+	} {
+		_, err := LoadLogin(write(contents))
+		require.True(t, errors.Is(err, ErrInvalidLogin), contents)
+	}
+	_, err = LoadLogin(filepath.Join(t.TempDir(), "missing.json"))
+	require.True(t, errors.Is(err, ErrInvalidLogin))
+}
+
+func TestHTTPFetcher_ConnectTo(t *testing.T) {
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+	targets, err := ParseConnectTo("www.prelaunch.test=" + address)
+	require.NoError(t, err)
+	f := NewHTTPFetcher("agent", WithConnectTo(targets))
+	u, _ := url.Parse("http://www.prelaunch.test/page")
+	response, err := f.Fetch(t.Context(), u)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, "www.prelaunch.test", host)
+	t.Run("port of the request is kept", func(t *testing.T) {
+		_, port, _ := net.SplitHostPort(address)
+		targets, err := ParseConnectTo("www.prelaunch.test=127.0.0.1")
+		require.NoError(t, err)
+		f := NewHTTPFetcher("agent", WithConnectTo(targets))
+		u, _ := url.Parse("http://www.prelaunch.test:" + port + "/page")
+		response, err := f.Fetch(t.Context(), u)
+		require.NoError(t, err)
+		response.Body.Close()
+		require.Equal(t, "www.prelaunch.test:"+port, host)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, invalid := range []string{"www.example.com", "=10.0.0.1", "www.example.com:443=10.0.0.1"} {
+			_, err := ParseConnectTo(invalid)
+			require.True(t, errors.Is(err, ErrInvalidConnectTo), invalid)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrInvalidLogin = errors.New("invalid login")
+	ErrLoginFailed  = errors.New("login failed")
+)
+
+const maxLoginBody = 1 << 20
+
+// Login describes a form submitted before crawling to obtain session cookies
+type Login struct {
+	URL       string            `json:"url"`
+	Action    string            `json:"action,omitempty"`
+	Fields    map[string]string `json:"fields"`
+	CSRFField string            `json:"csrf_field,omitempty"`
+	Success   LoginSuccess      `json:"success"`
+}
+
+// LoginSuccess is checked against the response to the form, after redirects are followed
+type LoginSuccess struct {
+	Status   int    `json:"status,omitempty"`
+	Contains string `json:"contains,omitempty"`
+	Cookie   string `json:"cookie,omitempty"`
+}
+
+// LoadLogin reads a JSON login file, expanding ${VAR} references so credentials can be kept in the environment
+func LoadLogin(path string) (*Login, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLogin, err)
+	}
+	var l Login
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(b))), &l); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLogin, err)
+	}
+	if err := l.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLogin, err)
+	}
+	return &l, nil
+}
+
+func (l *Login) validate() error {
+	if !absolute(l.URL) {
+		return fmt.Errorf("url %q must be absolute", l.URL)
+	}
+	if l.Action != "" && !absolute(l.Action) {
+		return fmt.Errorf("action %q must be absolute", l.Action)
+	}
+	if len(l.Fields) == 0 {
+		return errors.New("no fields to submit")
+	}
+	return nil
+}
+
+func absolute(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.IsAbs()
+}
+
+// WithLogin keeps cookies between requests so the session from Login is used for the crawl
+func WithLogin(l *Login) Option {
+	return func(h *HTTPFetcher) {
+		h.login = l
+		jar, _ := cookiejar.New(nil)
+		h.client.Jar = jar
+	}
+}
+
+// Login submits the login form, when one is configured, and checks the response for success
+func (h *HTTPFetcher) Login(ctx context.Context) error {
+	l := h.login
+	if l == nil {
+		return nil
+	}
+	form := url.Values{}
+	for name, value := range l.Fields {
+		form.Set(name, value)
+	}
+	if l.CSRFField != "" {
+		token, err := h.csrfToken(ctx, l.URL, l.CSRFField)
+		if err != nil {
+			return err
+		}
+		form.Set(l.CSRFField, token)
+	}
+	action := l.Action
+	if action == "" {
+		action = l.URL
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, action, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("User-Agent", h.agent)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := h.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrLoginFailed, err)
+	}
+	defer response.Body.Close()
+	return l.Success.check(response, h.client.Jar)
+}
+
+func (s LoginSuccess) check(response *http.Response, jar http.CookieJar) error {
+	switch {
+	case s.Status != 0 && response.StatusCode != s.Status:
+		return fmt.Errorf("%w: status %d, expected %d", ErrLoginFailed, response.StatusCode, s.Status)
+	case s.Status == 0 && (response.StatusCode < 200 || response.StatusCode > 299):
+		return fmt.Errorf("%w: status %d", ErrLoginFailed, response.StatusCode)
+	}
+	if s.Contains != "" {
+		body, err := io.ReadAll(io.LimitReader(response.Body, maxLoginBody))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrLoginFailed, err)
+		}
+		if !strings.Contains(string(body), s.Contains) {
+			return fmt.Errorf("%w: response does not contain %q", ErrLoginFailed, s.Contains)
+		}
+	}
+	if s.Cookie != "" {
+		for _, cookie := range jar.Cookies(response.Request.URL) {
+			if cookie.Name == s.Cookie {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: cookie %q not set", ErrLoginFailed, s.Cookie)
+	}
+	return nil
+}
+
+// csrfToken reads the value of the named input from the login form page
+func (h *HTTPFetcher) csrfToken(ctx context.Context, formURL, field string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, formURL, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("User-Agent", h.agent)
+	response, err := h.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrLoginFailed, err)
+	}
+	defer response.Body.Close()
+	tokenizer := html.NewTokenizer(io.LimitReader(response.Body, maxLoginBody))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return "", fmt.Errorf("%w: form field %q not found on %s", ErrLoginFailed, field, formURL)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttributes := tokenizer.TagName()
+			if string(name) != "input" {
+				continue
+			}
+			var inputName, value string
+			for hasAttributes {
+				var key, val []byte
+				key, val, hasAttributes = tokenizer.TagAttr()
+				switch string(key) {
+				case "name":
+					inputName = string(val)
+				case "value":
+					value = string(val)
+				}
+			}
+			if inputName == field {
+				return value, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+var ErrInvalidHistory = errors.New("invalid history")
+
+type Run struct {
+	Site      string        `json:"site"`
+	CreatedAt time.Time     `json:"created_at"`
+	Summary   audit.Summary `json:"summary"`
+	Pages     []Page        `json:"pages,omitempty"`
+}
+
+type Point struct {
+	CreatedAt    time.Time `json:"created_at"`
+	Score        int       `json:"score"`
+	Visited      int       `json:"visited"`
+	BrokenLinks  int       `json:"broken_links"`
+	ServerErrors int       `json:"server_errors"`
+	FetchErrors  int       `json:"fetch_errors"`
+	NewFindings  int       `json:"new_findings"`
+}
+
+type historyFile struct {
+	Runs []Run `json:"runs"`
+}
+
+// Load returns the runs in path, or none when the file does not exist yet
+func Load(path string) ([]Run, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidHistory, err)
+	}
+	var file historyFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidHistory, err)
+	}
+	return file.Runs, nil
+}
+
+// Append records run in path, keeping only the latest retain runs of its site when retain is
+// positive. The urls of runs older than the latest retainPages of its site are dropped, leaving
+// their summary, when retainPages is positive.
+func Append(path string, run Run, retain, retainPages int) error {
+	runs, err := Load(path)
+	if err != nil {
+		return err
+	}
+	runs = append(runs, run)
+	if retain > 0 {
+		runs = prune(runs, run.Site, retain)
+	}
+	if retainPages > 0 {
+		compact(runs, run.Site, retainPages)
+	}
+	b, err := json.MarshalIndent(historyFile{Runs: runs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func prune(runs []Run, site string, retain int) []Run {
+	count := 0
+	for _, run := range runs {
+		if run.Site == site {
+			count++
+		}
+	}
+	kept := make([]Run, 0, len(runs))
+	for _, run := range runs {
+		if run.Site == site && count > retain {
+			count--
+			continue
+		}
+		kept = append(kept, run)
+	}
+	return kept
+}
+
+// compact drops the urls of all but the latest retain runs of site that recorded them
+func compact(runs []Run, site string, retain int) {
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Site != site || len(runs[i].Pages) == 0 {
+			continue
+		}
+		if retain > 0 {
+			retain--
+			continue
+		}
+		runs[i].Pages = nil
+	}
+}
+
+// Sites returns the distinct sites in runs, sorted
+func Sites(runs []Run) []string {
+	sites := []string{}
+	for _, run := range runs {
+		if !slices.Contains(sites, run.Site) {
+			sites = append(sites, run.Site)
+		}
+	}
+	slices.Sort(sites)
+	return sites
+}
+
+// Trend returns the runs of site as points ordered oldest first
+func Trend(runs []Run, site string) []Point {
+	points := []Point{}
+	for _, run := range runs {
+		if run.Site != site {
+			continue
+		}
+		points = append(points, Point{
+			CreatedAt:    run.CreatedAt,
+			Score:        run.Summary.HealthScore(),
+			Visited:      run.Summary.Visited,
+			BrokenLinks:  run.Summary.BrokenLinks,
+			ServerErrors: run.Summary.ServerErrors,
+			FetchErrors:  run.Summary.FetchErrors,
+			NewFindings:  run.Summary.NewFindings,
+		})
+	}
+	slices.SortStableFunc(points, func(x, y Point) int {
+		return x.CreatedAt.Compare(y.CreatedAt)
+	})
+	return points
+}
+
+func WriteJSON(w io.Writer, points []Point) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(points)
+}
+
+func WriteCSV(w io.Writer, points []Point) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"created_at", "score", "visited", "broken_links", "server_errors", "fetch_errors", "new_findings"})
+	for _, p := range points {
+		writer.Write([]string{
+			p.CreatedAt.Format(time.RFC3339),
+			strconv.Itoa(p.Score),
+			strconv.Itoa(p.Visited),
+			strconv.Itoa(p.BrokenLinks),
+			strconv.Itoa(p.ServerErrors),
+			strconv.Itoa(p.FetchErrors),
+			strconv.Itoa(p.NewFindings),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,69 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func run(site string, day, broken int) Run {
+	return Run{
+		Site:      site,
+		CreatedAt: time.Date(2025, 1, day, 0, 0, 0, 0, time.UTC),
+		Summary:   audit.Summary{Visited: 10, BrokenLinks: broken, StatusCodes: map[int]int{200: 10 - broken, 404: broken}},
+	}
+}
+
+func TestAppendLoad(t *testing.T) {
+	t.Run("missing file is empty", func(t *testing.T) {
+		runs, err := Load(filepath.Join(t.TempDir(), "history.json"))
+		require.NoError(t, err)
+		require.Empty(t, runs)
+	})
+	t.Run("malformed file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+		_, err := Load(path)
+		require.True(t, errors.Is(err, ErrInvalidHistory))
+		require.True(t, errors.Is(Append(path, run("a", 1, 0), 0, 0), ErrInvalidHistory))
+	})
+	t.Run("retains the latest runs per site", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.json")
+		require.NoError(t, Append(path, run("https://a.com/", 1, 1), 2, 0))
+		require.NoError(t, Append(path, run("https://b.com/", 1, 5), 2, 0))
+		require.NoError(t, Append(path, run("https://a.com/", 2, 2), 2, 0))
+		require.NoError(t, Append(path, run("https://a.com/", 3, 3), 2, 0))
+		runs, err := Load(path)
+		require.NoError(t, err)
+		require.Equal(t, []Run{run("https://b.com/", 1, 5), run("https://a.com/", 2, 2), run("https://a.com/", 3, 3)}, runs)
+		require.Equal(t, []string{"https://a.com/", "https://b.com/"}, Sites(runs))
+	})
+}
+
+func TestTrend(t *testing.T) {
+	runs := []Run{run("a", 3, 3), run("b", 1, 9), run("a", 1, 1)}
+	points := Trend(runs, "a")
+	require.Len(t, points, 2)
+	require.Equal(t, 1, points[0].BrokenLinks)
+	require.Equal(t, 3, points[1].BrokenLinks)
+	require.Equal(t, 90, points[0].Score)
+	require.Equal(t, 70, points[1].Score)
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteCSV(&buf, points))
+		require.Equal(t, "created_at,score,visited,broken_links,server_errors,fetch_errors,new_findings\n"+
+			"2025-01-01T00:00:00Z,90,10,1,0,0,0\n"+
+			"2025-01-03T00:00:00Z,70,10,3,0,0,0\n", buf.String())
+	})
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteJSON(&buf, points))
+		require.Contains(t, buf.String(), `"broken_links": 3`)
+	})
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page is the state of a url in one run, recorded when AUDIT_HISTORY_URLS is set
+type Page struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Title      string `json:"title,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+}
+
+// Flip is a url whose status changed between two runs of a site
+type Flip struct {
+	URL   string    `json:"url"`
+	Title string    `json:"title,omitempty"`
+	From  int       `json:"from"`
+	To    int       `json:"to"`
+	Since time.Time `json:"since"`
+	At    time.Time `json:"at"`
+}
+
+// Flips compares the urls of the latest run of site with the last run recorded at or before since,
+// or the first run when there is none that old. Only runs with urls recorded are compared. from and
+// to select the statuses changed from and to, with 0 matching any status. Urls missing from either
+// run are left out. Flips are sorted by url.
+func Flips(runs []Run, site string, since time.Time, from, to int) []Flip {
+	recorded := []Run{}
+	for _, run := range runs {
+		if run.Site == site && len(run.Pages) > 0 {
+			recorded = append(recorded, run)
+		}
+	}
+	flips := []Flip{}
+	if len(recorded) < 2 {
+		return flips
+	}
+	slices.SortStableFunc(recorded, func(x, y Run) int {
+		return x.CreatedAt.Compare(y.CreatedAt)
+	})
+	before, latest := recorded[0], recorded[len(recorded)-1]
+	for _, run := range recorded[:len(recorded)-1] {
+		if !run.CreatedAt.After(since) {
+			before = run
+		}
+	}
+	statuses := make(map[string]int, len(before.Pages))
+	for _, page := range before.Pages {
+		statuses[page.URL] = page.StatusCode
+	}
+	for _, page := range latest.Pages {
+		previous, ok := statuses[page.URL]
+		if !ok || previous == page.StatusCode || (from != 0 && previous != from) || (to != 0 && page.StatusCode != to) {
+			continue
+		}
+		flips = append(flips, Flip{URL: page.URL, Title: page.Title, From: previous, To: page.StatusCode, Since: before.CreatedAt, At: latest.CreatedAt})
+	}
+	slices.SortFunc(flips, func(x, y Flip) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	return flips
+}
+
+func WriteFlipsJSON(w io.Writer, flips []Flip) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(flips)
+}
+
+func WriteFlipsCSV(w io.Writer, flips []Flip) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"url", "title", "from", "to", "since", "at"})
+	for _, f := range flips {
+		writer.Write([]string{f.URL, f.Title, strconv.Itoa(f.From), strconv.Itoa(f.To), f.Since.Format(time.RFC3339), f.At.Format(time.RFC3339)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
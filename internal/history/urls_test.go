@@ -0,0 +1,69 @@
+package history
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func pagesRun(site string, day int, pages ...Page) Run {
+	r := run(site, day, 0)
+	r.Pages = pages
+	return r
+}
+
+func TestAppend_CompactsPages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	page := Page{URL: "https://a.com/x", StatusCode: 200, Hash: "abc"}
+	require.NoError(t, Append(path, pagesRun("https://a.com/", 1, page), 0, 2))
+	require.NoError(t, Append(path, pagesRun("https://b.com/", 1, page), 0, 2))
+	require.NoError(t, Append(path, pagesRun("https://a.com/", 2, page), 0, 2))
+	require.NoError(t, Append(path, run("https://a.com/", 3, 0), 0, 2))
+	require.NoError(t, Append(path, pagesRun("https://a.com/", 4, page), 0, 2))
+	runs, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, []Run{
+		run("https://a.com/", 1, 0),
+		pagesRun("https://b.com/", 1, page),
+		pagesRun("https://a.com/", 2, page),
+		run("https://a.com/", 3, 0),
+		pagesRun("https://a.com/", 4, page),
+	}, runs)
+}
+
+func TestFlips(t *testing.T) {
+	runs := []Run{
+		pagesRun("https://a.com/", 1, Page{URL: "https://a.com/x", StatusCode: 200}, Page{URL: "https://a.com/y", StatusCode: 200}, Page{URL: "https://a.com/z", StatusCode: 500}),
+		pagesRun("https://a.com/", 10, Page{URL: "https://a.com/x", StatusCode: 404}, Page{URL: "https://a.com/y", StatusCode: 200}, Page{URL: "https://a.com/z", StatusCode: 500}),
+		run("https://a.com/", 15, 0),
+		pagesRun("https://b.com/", 18, Page{URL: "https://b.com/x", StatusCode: 404}),
+		pagesRun("https://a.com/", 20, Page{URL: "https://a.com/x", StatusCode: 404, Title: "Gone"}, Page{URL: "https://a.com/y", StatusCode: 404}, Page{URL: "https://a.com/z", StatusCode: 200}, Page{URL: "https://a.com/new", StatusCode: 404}),
+	}
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	t.Run("since a run", func(t *testing.T) {
+		require.Equal(t, []Flip{
+			{URL: "https://a.com/x", Title: "Gone", From: 200, To: 404, Since: day(1), At: day(20)},
+			{URL: "https://a.com/y", From: 200, To: 404, Since: day(1), At: day(20)},
+		}, Flips(runs, "https://a.com/", day(5), 200, 404))
+	})
+	t.Run("any status since a later run", func(t *testing.T) {
+		require.Equal(t, []Flip{
+			{URL: "https://a.com/y", From: 200, To: 404, Since: day(10), At: day(20)},
+			{URL: "https://a.com/z", From: 500, To: 200, Since: day(10), At: day(20)},
+		}, Flips(runs, "https://a.com/", day(12), 0, 0))
+	})
+	t.Run("since before the first run compares with it", func(t *testing.T) {
+		require.Len(t, Flips(runs, "https://a.com/", day(0), 500, 0), 1)
+	})
+	t.Run("single run", func(t *testing.T) {
+		require.Empty(t, Flips(runs, "https://b.com/", day(0), 0, 0))
+	})
+	t.Run("csv", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, WriteFlipsCSV(&b, Flips(runs, "https://a.com/", day(12), 500, 0)))
+		require.Equal(t, "url,title,from,to,since,at\nhttps://a.com/z,,500,200,2025-01-10T00:00:00Z,2025-01-20T00:00:00Z\n", b.String())
+	})
+}
@@ -0,0 +1,208 @@
+package linkrot
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"salsgithub.com/site-audit/internal/policy"
+)
+
+var ErrInvalidStore = errors.New("invalid link rot store")
+
+// maxDrainBytes is how much of a response body is read so the connection can be reused
+const maxDrainBytes = 64 << 10
+
+type Fetcher interface {
+	Fetch(ctx context.Context, u *url.URL) (*http.Response, error)
+}
+
+// Link is an external url with the result of its last check
+type Link struct {
+	URL         string    `json:"url"`
+	Source      string    `json:"source"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastChecked time.Time `json:"last_checked,omitzero"`
+	DeadSince   time.Time `json:"dead_since,omitzero"`
+	// Treatment is how the crawl policies treat StatusCode, when a status code rule applies
+	Treatment string `json:"treatment,omitempty"`
+	// Archived is a snapshot of the page from before it died, when one was looked up
+	Archived string `json:"archived,omitempty"`
+}
+
+// Dead reports whether the last check failed outright or the link is gone. Other client errors,
+// such as 403 and 429 from bot protection, do not say the page no longer exists, and a status code
+// rule can say either way.
+func (l Link) Dead() bool {
+	if l.LastChecked.IsZero() {
+		return false
+	}
+	switch l.Treatment {
+	case policy.TreatOK, policy.TreatExpected:
+		return false
+	case policy.TreatBroken:
+		return true
+	}
+	return l.Error != "" || l.StatusCode == http.StatusNotFound || l.StatusCode == http.StatusGone || l.StatusCode >= http.StatusInternalServerError
+}
+
+type Store struct {
+	Links map[string]*Link `json:"links"`
+	// Treatment looks up how a status code from a link is treated, such as a policy.Set's
+	Treatment func(u *url.URL, code int) string `json:"-"`
+	// Archive looks up an archived copy of a dead link, such as a Wayback's, once while it stays dead
+	Archive func(ctx context.Context, rawURL string) (string, error) `json:"-"`
+}
+
+// Load returns the store in path, or an empty one when the file does not exist yet
+func Load(path string) (*Store, error) {
+	s := &Store{Links: map[string]*Link{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidStore, err)
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidStore, err)
+	}
+	if s.Links == nil {
+		s.Links = map[string]*Link{}
+	}
+	return s, nil
+}
+
+func (s *Store) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Add records rawURL as found on source, keeping the first source of a url already known
+func (s *Store) Add(rawURL, source string, now time.Time) {
+	if _, ok := s.Links[rawURL]; ok {
+		return
+	}
+	s.Links[rawURL] = &Link{URL: rawURL, Source: source, FirstSeen: now}
+}
+
+// Check fetches every link with up to workers requests at once and returns the links that were
+// alive or unchecked before and are dead now, sorted by url
+func (s *Store) Check(ctx context.Context, f Fetcher, workers int, now time.Time) []Link {
+	links := make([]*Link, 0, len(s.Links))
+	for _, l := range s.Links {
+		links = append(links, l)
+	}
+	slices.SortFunc(links, func(x, y *Link) int {
+		return cmp.Compare(x.URL, y.URL)
+	})
+	jobs := make(chan *Link)
+	newlyDead := []Link{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for l := range jobs {
+				if check(ctx, f, l, now, s.Treatment) {
+					s.archive(ctx, l)
+					mu.Lock()
+					newlyDead = append(newlyDead, *l)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+send:
+	for _, l := range links {
+		select {
+		case jobs <- l:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	slices.SortFunc(newlyDead, func(x, y Link) int {
+		return cmp.Compare(x.URL, y.URL)
+	})
+	return newlyDead
+}
+
+// archive looks up a copy of a newly dead link, leaving Archived empty when the lookup fails
+func (s *Store) archive(ctx context.Context, l *Link) {
+	if s.Archive == nil || l.Archived != "" {
+		return
+	}
+	snapshot, err := s.Archive(ctx, l.URL)
+	if err != nil {
+		return
+	}
+	l.Archived = snapshot
+}
+
+// check updates l with the result of fetching it and reports whether it has just died. A check
+// cut short by ctx leaves l as it was.
+func check(ctx context.Context, f Fetcher, l *Link, now time.Time, treatment func(*url.URL, int) string) bool {
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return false
+	}
+	wasDead := l.Dead()
+	response, err := f.Fetch(ctx, u)
+	if ctx.Err() != nil {
+		if err == nil {
+			response.Body.Close()
+		}
+		return false
+	}
+	l.LastChecked = now
+	l.StatusCode, l.Error, l.Treatment = 0, "", ""
+	if err != nil {
+		l.Error = err.Error()
+	} else {
+		l.StatusCode = response.StatusCode
+		if treatment != nil {
+			l.Treatment = treatment(u, response.StatusCode)
+		}
+		io.Copy(io.Discard, io.LimitReader(response.Body, maxDrainBytes))
+		response.Body.Close()
+	}
+	if !l.Dead() {
+		l.DeadSince, l.Archived = time.Time{}, ""
+		return false
+	}
+	if wasDead {
+		return false
+	}
+	l.DeadSince = now
+	return true
+}
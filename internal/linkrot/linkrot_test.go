@@ -0,0 +1,184 @@
+package linkrot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/policy"
+)
+
+type mockFetcher struct {
+	codes map[string]int
+}
+
+func (m *mockFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	code, ok := m.codes[u.String()]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("returns an empty store when the file does not exist", func(t *testing.T) {
+		s, err := Load(filepath.Join(t.TempDir(), "links.json"))
+		require.NoError(t, err)
+		require.Empty(t, s.Links)
+	})
+	t.Run("errors on invalid json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "links.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+		_, err := Load(path)
+		require.True(t, errors.Is(err, ErrInvalidStore))
+	})
+	t.Run("round trips a saved store", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "links.json")
+		now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+		s, err := Load(path)
+		require.NoError(t, err)
+		s.Add("https://other.com/a", "https://example.com/", now)
+		s.Add("https://other.com/a", "https://example.com/later", now.Add(time.Hour))
+		require.NoError(t, s.Save(path))
+		loaded, err := Load(path)
+		require.NoError(t, err)
+		require.Equal(t, s.Links, loaded.Links)
+		require.Equal(t, "https://example.com/", loaded.Links["https://other.com/a"].Source)
+	})
+}
+
+func TestStore_Check(t *testing.T) {
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	s := &Store{Links: map[string]*Link{}}
+	for _, u := range []string{"https://a.com", "https://b.com", "https://c.com", "https://d.com", "https://e.com"} {
+		s.Add(u, "https://example.com/", first)
+	}
+	f := &mockFetcher{codes: map[string]int{
+		"https://a.com": http.StatusOK,
+		"https://b.com": http.StatusNotFound,
+		"https://c.com": http.StatusOK,
+		"https://d.com": http.StatusForbidden,
+	}}
+	dead := s.Check(context.Background(), f, 2, first)
+	require.Equal(t, []string{"https://b.com", "https://e.com"}, urls(dead))
+	require.Equal(t, "connection refused", s.Links["https://e.com"].Error)
+	require.False(t, s.Links["https://d.com"].Dead())
+
+	f.codes["https://b.com"] = http.StatusOK
+	f.codes["https://c.com"] = http.StatusGone
+	dead = s.Check(context.Background(), f, 2, second)
+	require.Equal(t, []string{"https://c.com"}, urls(dead))
+	require.True(t, s.Links["https://b.com"].DeadSince.IsZero())
+	require.Equal(t, first, s.Links["https://e.com"].DeadSince)
+	require.Equal(t, second, s.Links["https://c.com"].DeadSince)
+}
+
+func TestStore_CheckTreatment(t *testing.T) {
+	s := &Store{Links: map[string]*Link{}, Treatment: func(u *url.URL, code int) string {
+		switch {
+		case u.Host == "linkedin.com" && code == 999:
+			return policy.TreatOK
+		case code == http.StatusForbidden:
+			return policy.TreatBroken
+		}
+		return ""
+	}}
+	for _, u := range []string{"https://linkedin.com", "https://a.com", "https://b.com"} {
+		s.Add(u, "https://example.com/", time.Now())
+	}
+	f := &mockFetcher{codes: map[string]int{
+		"https://linkedin.com": 999,
+		"https://a.com":        http.StatusForbidden,
+		"https://b.com":        999,
+	}}
+	dead := s.Check(context.Background(), f, 1, time.Now())
+	require.Equal(t, []string{"https://a.com", "https://b.com"}, urls(dead))
+	require.Equal(t, policy.TreatOK, s.Links["https://linkedin.com"].Treatment)
+}
+
+func TestStore_CheckArchive(t *testing.T) {
+	s := &Store{Links: map[string]*Link{}, Archive: func(ctx context.Context, rawURL string) (string, error) {
+		if rawURL == "https://b.com" {
+			return "", errors.New("archive unavailable")
+		}
+		return "https://web.archive.org/web/2024/" + rawURL, nil
+	}}
+	for _, u := range []string{"https://a.com", "https://b.com", "https://c.com"} {
+		s.Add(u, "https://example.com/", time.Now())
+	}
+	f := &mockFetcher{codes: map[string]int{"https://a.com": http.StatusNotFound, "https://b.com": http.StatusNotFound, "https://c.com": http.StatusOK}}
+	dead := s.Check(context.Background(), f, 1, time.Now())
+	require.Equal(t, []string{"https://a.com", "https://b.com"}, urls(dead))
+	require.Equal(t, "https://web.archive.org/web/2024/https://a.com", dead[0].Archived)
+	require.Empty(t, dead[1].Archived)
+
+	f.codes["https://a.com"] = http.StatusOK
+	s.Check(context.Background(), f, 1, time.Now())
+	require.Empty(t, s.Links["https://a.com"].Archived)
+}
+
+func TestWayback_Snapshot(t *testing.T) {
+	responses := map[string]string{
+		"https://archived.com/page": `{"archived_snapshots": {"closest": {"available": true, "url": "http://web.archive.org/web/20240101000000/https://archived.com/page", "timestamp": "20240101000000", "status": "200"}}}`,
+		"https://missing.com/":      `{"archived_snapshots": {}}`,
+		"https://error.com/":        `{"archived_snapshots": {"closest": {"available": true, "url": "http://web.archive.org/web/2024/https://error.com/", "status": "404"}}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Query().Get("url")]
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	w := NewWayback(httpFetcher{}, WithEndpoint(server.URL+"/wayback/available"))
+	snapshot, err := w.Snapshot(context.Background(), "https://archived.com/page")
+	require.NoError(t, err)
+	require.Equal(t, "http://web.archive.org/web/20240101000000/https://archived.com/page", snapshot)
+	for _, u := range []string{"https://missing.com/", "https://error.com/"} {
+		snapshot, err := w.Snapshot(context.Background(), u)
+		require.NoError(t, err)
+		require.Empty(t, snapshot)
+	}
+	_, err = w.Snapshot(context.Background(), "https://down.com/")
+	require.Error(t, err)
+}
+
+type httpFetcher struct{}
+
+func (httpFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(request)
+}
+
+func TestStore_CheckCancelled(t *testing.T) {
+	s := &Store{Links: map[string]*Link{}}
+	s.Add("https://a.com", "https://example.com/", time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dead := s.Check(ctx, &mockFetcher{}, 1, time.Now())
+	require.Empty(t, dead)
+	require.True(t, s.Links["https://a.com"].LastChecked.IsZero())
+}
+
+func urls(links []Link) []string {
+	values := []string{}
+	for _, l := range links {
+		values = append(values, l.URL)
+	}
+	return values
+}
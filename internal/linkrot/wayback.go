@@ -0,0 +1,76 @@
+package linkrot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// waybackAPI is the Internet Archive's availability API, which answers with the closest snapshot
+const waybackAPI = "https://archive.org/wayback/available"
+
+// maxArchiveBytes bounds the availability API's response
+const maxArchiveBytes = 64 << 10
+
+type WaybackOption func(*Wayback)
+
+// Wayback looks up archived copies of dead links in the Internet Archive
+type Wayback struct {
+	fetcher  Fetcher
+	endpoint string
+}
+
+func NewWayback(f Fetcher, options ...WaybackOption) *Wayback {
+	w := &Wayback{fetcher: f, endpoint: waybackAPI}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// WithEndpoint queries another availability API, such as a mirror or a test server
+func WithEndpoint(endpoint string) WaybackOption {
+	return func(w *Wayback) {
+		w.endpoint = endpoint
+	}
+}
+
+type availability struct {
+	ArchivedSnapshots struct {
+		Closest *struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// Snapshot returns the url of the closest archived copy of rawURL that was served successfully, or
+// an empty string when there is none
+func (w *Wayback) Snapshot(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(w.endpoint)
+	if err != nil {
+		return "", err
+	}
+	u.RawQuery = url.Values{"url": {rawURL}}.Encode()
+	response, err := w.fetcher.Fetch(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("wayback availability returned status %d", response.StatusCode)
+	}
+	var a availability
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxArchiveBytes)).Decode(&a); err != nil {
+		return "", fmt.Errorf("error decoding wayback availability: %w", err)
+	}
+	closest := a.ArchivedSnapshots.Closest
+	if closest == nil || !closest.Available || closest.Status != "200" {
+		return "", nil
+	}
+	return closest.URL, nil
+}
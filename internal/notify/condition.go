@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+var ErrInvalidCondition = errors.New("invalid condition")
+
+var operators = map[string]func(a, b int) bool{
+	">":  func(a, b int) bool { return a > b },
+	">=": func(a, b int) bool { return a >= b },
+	"<":  func(a, b int) bool { return a < b },
+	"<=": func(a, b int) bool { return a <= b },
+	"==": func(a, b int) bool { return a == b },
+	"!=": func(a, b int) bool { return a != b },
+}
+
+type comparison struct {
+	metric   string
+	operator string
+	value    int
+}
+
+type Condition struct {
+	comparisons []comparison
+}
+
+// ParseCondition parses comparisons such as "broken_links > 0 and status_503 >= 1"
+func ParseCondition(s string) (*Condition, error) {
+	c := &Condition{}
+	for _, part := range strings.Split(s, " and ") {
+		fields := strings.Fields(part)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%w: %q, expected <metric> <operator> <number>", ErrInvalidCondition, part)
+		}
+		if !isMetric(fields[0]) {
+			return nil, fmt.Errorf("%w: unknown metric %q", ErrInvalidCondition, fields[0])
+		}
+		if _, ok := operators[fields[1]]; !ok {
+			return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidCondition, fields[1])
+		}
+		value, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a number", ErrInvalidCondition, fields[2])
+		}
+		c.comparisons = append(c.comparisons, comparison{metric: fields[0], operator: fields[1], value: value})
+	}
+	return c, nil
+}
+
+func (c *Condition) Matches(summary audit.Summary) bool {
+	m := Metrics(summary)
+	for _, comparison := range c.comparisons {
+		if !operators[comparison.operator](m[comparison.metric], comparison.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func Metrics(summary audit.Summary) map[string]int {
+	m := map[string]int{
+		"visited":       summary.Visited,
+		"broken_links":  summary.BrokenLinks,
+		"server_errors": summary.ServerErrors,
+		"fetch_errors":  summary.FetchErrors,
+		"new_findings":  summary.NewFindings,
+	}
+	for code, count := range summary.StatusCodes {
+		m["status_"+strconv.Itoa(code)] = count
+	}
+	return m
+}
+
+func isMetric(name string) bool {
+	if _, ok := Metrics(audit.Summary{})[name]; ok {
+		return true
+	}
+	code, ok := strings.CutPrefix(name, "status_")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(code)
+	return err == nil
+}
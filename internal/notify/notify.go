@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+var ErrInvalidRule = errors.New("invalid notification rule")
+
+const (
+	ChannelSlack   = "slack"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+var sendMail = smtp.SendMail
+
+type Channel struct {
+	Type     string   `json:"type"`
+	URL      string   `json:"url,omitempty"`
+	To       []string `json:"to,omitempty"`
+	From     string   `json:"from,omitempty"`
+	SMTPHost string   `json:"smtp_host,omitempty"`
+	SMTPPort int      `json:"smtp_port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+type Rule struct {
+	Name      string  `json:"name"`
+	When      string  `json:"when"`
+	Channel   Channel `json:"channel"`
+	condition *Condition
+}
+
+type rulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+type Message struct {
+	Rule     string        `json:"rule"`
+	StartURL string        `json:"start_url"`
+	Text     string        `json:"text"`
+	Summary  audit.Summary `json:"summary"`
+}
+
+type Engine struct {
+	rules  []Rule
+	client *http.Client
+}
+
+// LoadRules reads a JSON rules file, expanding ${VAR} references so secrets can be kept in the environment
+func LoadRules(path string) (*Engine, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	var file rulesFile
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(b))), &file); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	return NewEngine(file.Rules)
+}
+
+func NewEngine(rules []Rule) (*Engine, error) {
+	for i := range rules {
+		rule := &rules[i]
+		condition, err := ParseCondition(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidRule, rule.Name, err)
+		}
+		rule.condition = condition
+		if err := rule.Channel.validate(); err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidRule, rule.Name, err)
+		}
+	}
+	return &Engine{rules: rules, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (e *Engine) Evaluate(ctx context.Context, startURL string, summary audit.Summary) ([]string, error) {
+	triggered := []string{}
+	var errs []error
+	for _, rule := range e.rules {
+		if !rule.condition.Matches(summary) {
+			continue
+		}
+		triggered = append(triggered, rule.Name)
+		message := Message{
+			Rule:     rule.Name,
+			StartURL: startURL,
+			Text:     fmt.Sprintf("site-audit rule %q triggered for %s (%s): %s", rule.Name, startURL, rule.When, formatMetrics(summary)),
+			Summary:  summary,
+		}
+		if err := e.send(ctx, rule.Channel, message); err != nil {
+			errs = append(errs, fmt.Errorf("rule %q: %w", rule.Name, err))
+		}
+	}
+	return triggered, errors.Join(errs...)
+}
+
+func (e *Engine) send(ctx context.Context, channel Channel, message Message) error {
+	switch channel.Type {
+	case ChannelSlack:
+		return e.post(ctx, channel.URL, map[string]string{"text": message.Text})
+	case ChannelWebhook:
+		return e.post(ctx, channel.URL, message)
+	case ChannelEmail:
+		address := fmt.Sprintf("%s:%d", channel.SMTPHost, channel.SMTPPort)
+		var auth smtp.Auth
+		if channel.Username != "" {
+			auth = smtp.PlainAuth("", channel.Username, channel.Password, channel.SMTPHost)
+		}
+		body := fmt.Sprintf("To: %s\r\nFrom: %s\r\nSubject: site-audit: %s\r\n\r\n%s\r\n", strings.Join(channel.To, ", "), channel.From, message.Rule, message.Text)
+		return sendMail(address, auth, channel.From, channel.To, []byte(body))
+	default:
+		return fmt.Errorf("unknown channel type %q", channel.Type)
+	}
+}
+
+func (e *Engine) post(ctx context.Context, u string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("received status %d", response.StatusCode)
+	}
+	return nil
+}
+
+func (c Channel) validate() error {
+	switch c.Type {
+	case ChannelSlack, ChannelWebhook:
+		if c.URL == "" {
+			return fmt.Errorf("%s channel requires a url", c.Type)
+		}
+	case ChannelEmail:
+		if c.SMTPHost == "" || c.SMTPPort == 0 || c.From == "" || len(c.To) == 0 {
+			return errors.New("email channel requires smtp_host, smtp_port, from and to")
+		}
+	default:
+		return fmt.Errorf("unknown channel type %q", c.Type)
+	}
+	return nil
+}
+
+func formatMetrics(summary audit.Summary) string {
+	m := Metrics(summary)
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, m[name]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+var testSummary = audit.Summary{
+	Visited:      10,
+	StatusCodes:  map[int]int{200: 7, 404: 2, 503: 1},
+	BrokenLinks:  3,
+	ServerErrors: 1,
+}
+
+func TestNotify_ParseCondition(t *testing.T) {
+	tests := []struct {
+		name    string
+		when    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "greater than", when: "broken_links > 0", want: true},
+		{name: "not matched", when: "server_errors > 1", want: false},
+		{name: "status metric", when: "status_404 == 2", want: true},
+		{name: "missing status metric is zero", when: "status_500 == 0", want: true},
+		{name: "conjunction", when: "broken_links >= 3 and visited < 11", want: true},
+		{name: "conjunction not matched", when: "broken_links >= 3 and visited != 10", want: false},
+		{name: "unknown metric", when: "score < 80", wantErr: true},
+		{name: "unknown operator", when: "visited ~ 1", wantErr: true},
+		{name: "not a number", when: "visited > many", wantErr: true},
+		{name: "malformed", when: "visited", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := ParseCondition(test.when)
+			if test.wantErr {
+				require.True(t, errors.Is(err, ErrInvalidCondition))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, c.Matches(testSummary))
+		})
+	}
+}
+
+func TestNotify_NewEngine(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+	}{
+		{name: "invalid condition", rules: []Rule{{Name: "a", When: "nope", Channel: Channel{Type: ChannelSlack, URL: "http://x"}}}},
+		{name: "unknown channel", rules: []Rule{{Name: "a", When: "visited > 0", Channel: Channel{Type: "pager"}}}},
+		{name: "slack without url", rules: []Rule{{Name: "a", When: "visited > 0", Channel: Channel{Type: ChannelSlack}}}},
+		{name: "incomplete email", rules: []Rule{{Name: "a", When: "visited > 0", Channel: Channel{Type: ChannelEmail, To: []string{"ops@example.com"}}}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewEngine(test.rules)
+			require.True(t, errors.Is(err, ErrInvalidRule))
+		})
+	}
+}
+
+func TestNotify_LoadRules(t *testing.T) {
+	t.Run("expands environment", func(t *testing.T) {
+		t.Setenv("TEST_SLACK_URL", "https://hooks.example.com/slack")
+		path := filepath.Join(t.TempDir(), "rules.json")
+		rules := `{"rules":[{"name":"seo","when":"broken_links > 0","channel":{"type":"slack","url":"${TEST_SLACK_URL}"}}]}`
+		require.NoError(t, os.WriteFile(path, []byte(rules), 0644))
+		e, err := LoadRules(path)
+		require.NoError(t, err)
+		require.Equal(t, "https://hooks.example.com/slack", e.rules[0].Channel.URL)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadRules(filepath.Join(t.TempDir(), "missing.json"))
+		require.True(t, errors.Is(err, ErrInvalidRule))
+	})
+	t.Run("invalid json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+		_, err := LoadRules(path)
+		require.True(t, errors.Is(err, ErrInvalidRule))
+	})
+}
+
+func TestNotify_Evaluate(t *testing.T) {
+	t.Run("sends to matching http channels", func(t *testing.T) {
+		received := map[string]map[string]any{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			payload := map[string]any{}
+			require.NoError(t, json.Unmarshal(body, &payload))
+			received[r.URL.Path] = payload
+		}))
+		defer server.Close()
+		e, err := NewEngine([]Rule{
+			{Name: "seo", When: "broken_links > 0", Channel: Channel{Type: ChannelSlack, URL: server.URL + "/slack"}},
+			{Name: "ops", When: "server_errors > 0", Channel: Channel{Type: ChannelWebhook, URL: server.URL + "/hook"}},
+			{Name: "quiet", When: "fetch_errors > 0", Channel: Channel{Type: ChannelWebhook, URL: server.URL + "/quiet"}},
+		})
+		require.NoError(t, err)
+		triggered, err := e.Evaluate(context.Background(), "https://example.com", testSummary)
+		require.NoError(t, err)
+		require.Equal(t, []string{"seo", "ops"}, triggered)
+		require.Contains(t, received["/slack"]["text"], `rule "seo" triggered for https://example.com`)
+		require.Contains(t, received["/slack"]["text"], "broken_links=3")
+		require.Equal(t, "ops", received["/hook"]["rule"])
+		require.NotContains(t, received, "/quiet")
+	})
+	t.Run("reports delivery errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		e, err := NewEngine([]Rule{{Name: "seo", When: "visited > 0", Channel: Channel{Type: ChannelSlack, URL: server.URL}}})
+		require.NoError(t, err)
+		_, err = e.Evaluate(context.Background(), "https://example.com", testSummary)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "received status 500")
+	})
+	t.Run("sends email", func(t *testing.T) {
+		original := sendMail
+		defer func() { sendMail = original }()
+		var gotAddress string
+		var gotTo []string
+		var gotBody string
+		sendMail = func(address string, auth smtp.Auth, from string, to []string, body []byte) error {
+			gotAddress, gotTo, gotBody = address, to, string(body)
+			require.NotNil(t, auth)
+			return nil
+		}
+		e, err := NewEngine([]Rule{{Name: "ops", When: "status_503 > 0", Channel: Channel{
+			Type:     ChannelEmail,
+			To:       []string{"ops@example.com"},
+			From:     "audit@example.com",
+			SMTPHost: "smtp.example.com",
+			SMTPPort: 587,
+			Username: "user",
+			Password: "pass",
+		}}})
+		require.NoError(t, err)
+		_, err = e.Evaluate(context.Background(), "https://example.com", testSummary)
+		require.NoError(t, err)
+		require.Equal(t, "smtp.example.com:587", gotAddress)
+		require.Equal(t, []string{"ops@example.com"}, gotTo)
+		require.Contains(t, gotBody, "Subject: site-audit: ops")
+	})
+}
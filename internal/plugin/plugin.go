@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+const defaultTimeout = 30 * time.Second
+
+var ErrEmptyCommand = errors.New("empty plugin command")
+
+type Option func(*command)
+
+type command struct {
+	name    string
+	args    []string
+	timeout time.Duration
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *command) {
+		c.timeout = timeout
+	}
+}
+
+func newCommand(commandLine string, options ...Option) (*command, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, ErrEmptyCommand
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("plugin command %s not found: %w", fields[0], err)
+	}
+	c := &command{name: fields[0], args: fields[1:], timeout: defaultTimeout}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+func (c *command) run(ctx context.Context, input any) ([]byte, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding plugin input: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.name, c.args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", c.name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+type checkInput struct {
+	Pages []audit.Page `json:"pages"`
+}
+
+type checkOutput struct {
+	Findings []audit.Finding `json:"findings"`
+}
+
+type ExecCheck struct {
+	command *command
+}
+
+func NewExecCheck(commandLine string, options ...Option) (*ExecCheck, error) {
+	c, err := newCommand(commandLine, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &ExecCheck{command: c}, nil
+}
+
+func (e *ExecCheck) Name() string {
+	return filepath.Base(e.command.name)
+}
+
+func (e *ExecCheck) Run(ctx context.Context, pages []audit.Page) ([]audit.Finding, error) {
+	out, err := e.command.run(ctx, checkInput{Pages: pages})
+	if err != nil {
+		return nil, err
+	}
+	var output checkOutput
+	if err := json.Unmarshal(out, &output); err != nil {
+		return nil, fmt.Errorf("error decoding plugin output: %w", err)
+	}
+	for i := range output.Findings {
+		if output.Findings[i].Check == "" {
+			output.Findings[i].Check = e.Name()
+		}
+	}
+	return output.Findings, nil
+}
+
+type edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// exportInput keeps nodes as plain urls for existing plugins, with their metadata alongside
+type exportInput struct {
+	Nodes    []string     `json:"nodes"`
+	Metadata []audit.Node `json:"metadata"`
+	Edges    []edge       `json:"edges"`
+}
+
+type ExecExporter struct {
+	command *command
+}
+
+func NewExecExporter(commandLine string, options ...Option) (*ExecExporter, error) {
+	c, err := newCommand(commandLine, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &ExecExporter{command: c}, nil
+}
+
+func (e *ExecExporter) Export(g *graph.Graph[string], nodes map[string]audit.Node) error {
+	input := exportInput{Nodes: g.Nodes(), Metadata: []audit.Node{}, Edges: []edge{}}
+	for _, node := range input.Nodes {
+		metadata := nodes[node]
+		metadata.URL = node
+		input.Metadata = append(input.Metadata, metadata)
+		neighbours, _ := g.Neighbours(node)
+		for _, neighbour := range neighbours {
+			input.Edges = append(input.Edges, edge{Source: node, Target: neighbour.Link, Weight: neighbour.Weight})
+		}
+	}
+	_, err := e.command.run(context.Background(), input)
+	return err
+}
+
+func Split(commandLines string) []string {
+	commands := []string{}
+	for _, commandLine := range strings.Split(commandLines, ",") {
+		if commandLine = strings.TrimSpace(commandLine); commandLine != "" {
+			commands = append(commands, commandLine)
+		}
+	}
+	return commands
+}
@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755)
+	require.NoError(t, err)
+	return path
+}
+
+func TestPlugin_Split(t *testing.T) {
+	require.Equal(t, []string{}, Split(""))
+	require.Equal(t, []string{"a --flag", "b"}, Split(" a --flag, ,b"))
+}
+
+func TestPlugin_NewEmptyCommand(t *testing.T) {
+	_, err := NewExecCheck("  ")
+	require.Equal(t, ErrEmptyCommand, err)
+	_, err = NewExecExporter("")
+	require.Equal(t, ErrEmptyCommand, err)
+}
+
+func TestPlugin_NewMissingCommand(t *testing.T) {
+	_, err := NewExecCheck(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not found")
+}
+
+func TestExecCheck_Run(t *testing.T) {
+	pages := []audit.Page{{URL: "https://example.com/", StatusCode: 200, Links: []string{}}}
+	t.Run("decodes findings and defaults check name", func(t *testing.T) {
+		script := writeScript(t, `cat > /dev/null
+echo '{"findings":[{"url":"https://example.com/","detail":"custom"},{"check":"named","url":"https://example.com/"}]}'`)
+		check, err := NewExecCheck(script)
+		require.NoError(t, err)
+		require.Equal(t, "plugin.sh", check.Name())
+		findings, err := check.Run(context.Background(), pages)
+		require.NoError(t, err)
+		require.Equal(t, []audit.Finding{
+			{Check: "plugin.sh", URL: "https://example.com/", Detail: "custom"},
+			{Check: "named", URL: "https://example.com/"},
+		}, findings)
+	})
+	t.Run("receives pages on stdin", func(t *testing.T) {
+		script := writeScript(t, `grep -q '"status_code":200' && echo '{"findings":[]}'`)
+		check, err := NewExecCheck(script)
+		require.NoError(t, err)
+		findings, err := check.Run(context.Background(), pages)
+		require.NoError(t, err)
+		require.Empty(t, findings)
+	})
+	t.Run("non-zero exit is an error", func(t *testing.T) {
+		script := writeScript(t, `echo "broken" >&2; exit 1`)
+		check, err := NewExecCheck(script)
+		require.NoError(t, err)
+		_, err = check.Run(context.Background(), pages)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "broken")
+	})
+	t.Run("invalid output is an error", func(t *testing.T) {
+		script := writeScript(t, `echo "not json"`)
+		check, err := NewExecCheck(script)
+		require.NoError(t, err)
+		_, err = check.Run(context.Background(), pages)
+		require.Error(t, err)
+	})
+	t.Run("timeout stops the plugin", func(t *testing.T) {
+		script := writeScript(t, `exec sleep 5`)
+		check, err := NewExecCheck(script, WithTimeout(50*time.Millisecond))
+		require.NoError(t, err)
+		_, err = check.Run(context.Background(), pages)
+		require.Error(t, err)
+	})
+}
+
+func TestExecExporter_Export(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "graph.json")
+	script := writeScript(t, "cat > "+output)
+	e, err := NewExecExporter(script)
+	require.NoError(t, err)
+	g := graph.New[string]()
+	g.AddEdge("A", "B", 1)
+	nodes := map[string]audit.Node{"A": {URL: "A", StatusCode: 200, Title: "Home"}}
+	require.NoError(t, e.Export(g, nodes))
+	b, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"nodes":["A","B"],
+		"metadata":[{"url":"A","status_code":200,"depth":0,"title":"Home"},{"url":"B","depth":0}],
+		"edges":[{"source":"A","target":"B","weight":1}]
+	}`, string(b))
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrTokenRequest = errors.New("oauth2 token request failed")
+
+// tokenExpiryMargin refreshes a token this long before it expires so it never lapses mid-request
+const tokenExpiryMargin = 30 * time.Second
+
+var tokenClient = &http.Client{Timeout: 10 * time.Second}
+
+// tokenSource fetches OAuth2 access tokens with the client credentials grant, or the refresh
+// token grant when a refresh token is configured, and caches them until they expire
+type tokenSource struct {
+	accessToken  string
+	refreshToken string
+	expires      time.Time
+	now          func() time.Time
+	mu           sync.Mutex
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (a *Auth) oauth2Token(ctx context.Context) (string, error) {
+	a.once.Do(func() {
+		a.tokens = &tokenSource{refreshToken: a.RefreshToken, now: time.Now}
+	})
+	return a.tokens.token(ctx, a)
+}
+
+func (s *tokenSource) token(ctx context.Context, a *Auth) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && (s.expires.IsZero() || s.now().Before(s.expires.Add(-tokenExpiryMargin))) {
+		return s.accessToken, nil
+	}
+	form := url.Values{}
+	if s.refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", s.refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if a.ClientID != "" {
+		request.SetBasicAuth(url.QueryEscape(a.ClientID), url.QueryEscape(a.ClientSecret))
+	}
+	response, err := tokenClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: status %d", ErrTokenRequest, response.StatusCode)
+	}
+	var t tokenResponse
+	if err := json.NewDecoder(response.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	if t.AccessToken == "" {
+		return "", fmt.Errorf("%w: no access token in response", ErrTokenRequest)
+	}
+	s.accessToken = t.AccessToken
+	s.expires = time.Time{}
+	if t.ExpiresIn > 0 {
+		s.expires = s.now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	if t.RefreshToken != "" {
+		s.refreshToken = t.RefreshToken
+	}
+	return s.accessToken, nil
+}
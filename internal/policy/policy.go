@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidPolicy = errors.New("invalid crawl policy")
+
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthOAuth2 = "oauth2"
+)
+
+const (
+	// TreatOK and TreatExpected keep a status from being reported as broken, TreatExpected saying
+	// it is the right answer for the page, such as a login wall, rather than a quirk of the host
+	TreatOK       = "ok"
+	TreatExpected = "expected"
+	TreatBroken   = "broken"
+)
+
+// StatusRule changes how a status code from the policy's hosts is treated. Path limits the rule to
+// urls under a path prefix, where a trailing * is ignored.
+type StatusRule struct {
+	Code  int    `json:"code"`
+	Path  string `json:"path,omitempty"`
+	Treat string `json:"treat"`
+}
+
+type Auth struct {
+	Type         string   `json:"type"`
+	Username     string   `json:"username,omitempty"`
+	Password     string   `json:"password,omitempty"`
+	Token        string   `json:"token,omitempty"`
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	RefreshToken string   `json:"refresh_token,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	tokens       *tokenSource
+	once         sync.Once
+}
+
+type Policy struct {
+	Host      string            `json:"host"`
+	RateLimit float64           `json:"rate_limit,omitempty"`
+	MaxPages  int               `json:"max_pages,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Auth      *Auth             `json:"auth,omitempty"`
+
+	StatusCodes []StatusRule `json:"status_codes,omitempty"`
+
+	next time.Time
+	mu   sync.Mutex
+}
+
+type Set struct {
+	policies []*Policy
+}
+
+type policiesFile struct {
+	Policies []*Policy `json:"policies"`
+}
+
+// Load reads a JSON policies file, expanding ${VAR} references so credentials can be kept in the environment
+func Load(path string) (*Set, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	var file policiesFile
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(b))), &file); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	return New(file.Policies)
+}
+
+func New(policies []*Policy) (*Set, error) {
+	for _, p := range policies {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("%w for host %q: %w", ErrInvalidPolicy, p.Host, err)
+		}
+	}
+	return &Set{policies: policies}, nil
+}
+
+// Match returns the first policy whose host pattern matches, or nil
+func (s *Set) Match(host string) *Policy {
+	if s == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	for _, p := range s.policies {
+		if ok, _ := path.Match(strings.ToLower(p.Host), host); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// Treatment returns how the policy matching u's host treats code, or an empty string when no rule
+// applies and the status stands as it is
+func (s *Set) Treatment(u *url.URL, code int) string {
+	p := s.Match(u.Hostname())
+	if p == nil {
+		return ""
+	}
+	for _, rule := range p.StatusCodes {
+		if rule.Code == code && strings.HasPrefix(u.Path, strings.TrimSuffix(rule.Path, "*")) {
+			return rule.Treat
+		}
+	}
+	return ""
+}
+
+// Apply adds the policy's headers and credentials to r, fetching an OAuth2 token if one is due
+func (p *Policy) Apply(r *http.Request) error {
+	if p == nil {
+		return nil
+	}
+	for key, value := range p.Headers {
+		r.Header.Set(key, value)
+	}
+	if p.Auth == nil {
+		return nil
+	}
+	switch p.Auth.Type {
+	case AuthBasic:
+		r.SetBasicAuth(p.Auth.Username, p.Auth.Password)
+	case AuthBearer:
+		r.Header.Set("Authorization", "Bearer "+p.Auth.Token)
+	case AuthOAuth2:
+		token, err := p.Auth.oauth2Token(r.Context())
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// Wait blocks until the policy's rate limit allows another request
+func (p *Policy) Wait(ctx context.Context) error {
+	if p == nil || p.RateLimit <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / p.RateLimit)
+	p.mu.Lock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	delay := p.next.Sub(now)
+	p.next = p.next.Add(interval)
+	p.mu.Unlock()
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *Policy) validate() error {
+	if p.Host == "" {
+		return errors.New("host is required")
+	}
+	if _, err := path.Match(p.Host, ""); err != nil {
+		return fmt.Errorf("invalid host pattern: %w", err)
+	}
+	if p.RateLimit < 0 {
+		return errors.New("rate_limit must be zero or more")
+	}
+	if p.MaxPages < 0 {
+		return errors.New("max_pages must be zero or more")
+	}
+	for _, rule := range p.StatusCodes {
+		if rule.Code < 100 || rule.Code > 999 {
+			return fmt.Errorf("invalid status code %d", rule.Code)
+		}
+		switch rule.Treat {
+		case TreatOK, TreatExpected, TreatBroken:
+		default:
+			return fmt.Errorf("status code %d treatment %q must be ok, expected or broken", rule.Code, rule.Treat)
+		}
+	}
+	if p.Auth == nil {
+		return nil
+	}
+	switch p.Auth.Type {
+	case AuthBasic:
+		if p.Auth.Username == "" {
+			return errors.New("basic auth requires a username")
+		}
+	case AuthBearer:
+		if p.Auth.Token == "" {
+			return errors.New("bearer auth requires a token")
+		}
+	case AuthOAuth2:
+		if u, err := url.Parse(p.Auth.TokenURL); err != nil || !u.IsAbs() {
+			return errors.New("oauth2 auth requires an absolute token_url")
+		}
+		if p.Auth.ClientID == "" && p.Auth.RefreshToken == "" {
+			return errors.New("oauth2 auth requires a client_id or refresh_token")
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q", p.Auth.Type)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("expands environment variables", func(t *testing.T) {
+		t.Setenv("TEST_POLICY_PASSWORD", "hunter2")
+		path := filepath.Join(t.TempDir(), "policies.json")
+		contents := `{"policies":[{"host":"*.example.com","auth":{"type":"basic","username":"qa","password":"${TEST_POLICY_PASSWORD}"}}]}`
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+		s, err := Load(path)
+		require.NoError(t, err)
+		p := s.Match("docs.example.com")
+		require.NotNil(t, p)
+		require.Equal(t, "hunter2", p.Auth.Password)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+		require.True(t, errors.Is(err, ErrInvalidPolicy))
+	})
+	t.Run("malformed json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "policies.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+		_, err := Load(path)
+		require.True(t, errors.Is(err, ErrInvalidPolicy))
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *Policy
+		want   string
+	}{
+		{name: "missing host", policy: &Policy{}, want: "host is required"},
+		{name: "bad pattern", policy: &Policy{Host: "["}, want: "invalid host pattern"},
+		{name: "negative rate limit", policy: &Policy{Host: "a", RateLimit: -1}, want: "rate_limit"},
+		{name: "negative max pages", policy: &Policy{Host: "a", MaxPages: -1}, want: "max_pages"},
+		{name: "basic without username", policy: &Policy{Host: "a", Auth: &Auth{Type: AuthBasic}}, want: "username"},
+		{name: "bearer without token", policy: &Policy{Host: "a", Auth: &Auth{Type: AuthBearer}}, want: "token"},
+		{name: "oauth2 without token url", policy: &Policy{Host: "a", Auth: &Auth{Type: AuthOAuth2, ClientID: "id"}}, want: "token_url"},
+		{name: "oauth2 without client", policy: &Policy{Host: "a", Auth: &Auth{Type: AuthOAuth2, TokenURL: "https://auth.example.com/token"}}, want: "client_id"},
+		{name: "unknown auth", policy: &Policy{Host: "a", Auth: &Auth{Type: "digest"}}, want: "unknown auth type"},
+		{name: "bad status code", policy: &Policy{Host: "a", StatusCodes: []StatusRule{{Code: 42, Treat: TreatOK}}}, want: "invalid status code 42"},
+		{name: "unknown treatment", policy: &Policy{Host: "a", StatusCodes: []StatusRule{{Code: 403, Treat: "ignore"}}}, want: "must be ok, expected or broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]*Policy{tt.policy})
+			require.True(t, errors.Is(err, ErrInvalidPolicy))
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestSet_Match(t *testing.T) {
+	s, err := New([]*Policy{
+		{Host: "docs.example.com", MaxPages: 1},
+		{Host: "*.example.com", MaxPages: 2},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, s.Match("DOCS.example.com").MaxPages)
+	require.Equal(t, 2, s.Match("blog.example.com").MaxPages)
+	require.Nil(t, s.Match("example.org"))
+	var empty *Set
+	require.Nil(t, empty.Match("example.com"))
+}
+
+func TestSet_Treatment(t *testing.T) {
+	s, err := New([]*Policy{
+		{Host: "*linkedin.com", StatusCodes: []StatusRule{{Code: 999, Treat: TreatOK}}},
+		{Host: "example.com", StatusCodes: []StatusRule{{Code: 401, Path: "/account/*", Treat: TreatExpected}, {Code: 403, Treat: TreatBroken}}},
+	})
+	require.NoError(t, err)
+	tests := []struct {
+		url  string
+		code int
+		want string
+	}{
+		{url: "https://www.linkedin.com/company/x", code: 999, want: TreatOK},
+		{url: "https://www.linkedin.com/company/x", code: 404},
+		{url: "https://example.com/account/settings", code: 401, want: TreatExpected},
+		{url: "https://example.com/admin", code: 401},
+		{url: "https://example.com/admin", code: 403, want: TreatBroken},
+		{url: "https://example.org/", code: 403},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		require.Equal(t, tt.want, s.Treatment(u, tt.code), tt.url)
+	}
+}
+
+func TestPolicy_Apply(t *testing.T) {
+	t.Run("basic auth and headers", func(t *testing.T) {
+		p := &Policy{Headers: map[string]string{"X-Env": "staging"}, Auth: &Auth{Type: AuthBasic, Username: "qa", Password: "pw"}}
+		r, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		p.Apply(r)
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "qa", username)
+		require.Equal(t, "pw", password)
+		require.Equal(t, "staging", r.Header.Get("X-Env"))
+	})
+	t.Run("oauth2 client credentials are cached until expiry", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			id, secret, _ := r.BasicAuth()
+			require.Equal(t, "client", id)
+			require.Equal(t, "secret", secret)
+			require.Equal(t, "client_credentials", r.FormValue("grant_type"))
+			require.Equal(t, "docs:read", r.FormValue("scope"))
+			fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, requests)
+		}))
+		defer server.Close()
+		p := &Policy{Auth: &Auth{Type: AuthOAuth2, TokenURL: server.URL, ClientID: "client", ClientSecret: "secret", Scopes: []string{"docs:read"}}}
+		for range 2 {
+			r, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+			require.NoError(t, p.Apply(r))
+			require.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		}
+		require.Equal(t, 1, requests)
+	})
+	t.Run("oauth2 refresh token is rotated", func(t *testing.T) {
+		var refreshTokens []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "refresh_token", r.FormValue("grant_type"))
+			refreshTokens = append(refreshTokens, r.FormValue("refresh_token"))
+			fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 10, "refresh_token": "refresh-%d"}`, len(refreshTokens), len(refreshTokens))
+		}))
+		defer server.Close()
+		p := &Policy{Auth: &Auth{Type: AuthOAuth2, TokenURL: server.URL, RefreshToken: "initial"}}
+		for i := range 2 {
+			r, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+			require.NoError(t, p.Apply(r))
+			require.Equal(t, fmt.Sprintf("Bearer token-%d", i+1), r.Header.Get("Authorization"))
+		}
+		require.Equal(t, []string{"initial", "refresh-1"}, refreshTokens)
+	})
+	t.Run("oauth2 token errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		p := &Policy{Auth: &Auth{Type: AuthOAuth2, TokenURL: server.URL, ClientID: "client"}}
+		r, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		require.True(t, errors.Is(p.Apply(r), ErrTokenRequest))
+	})
+	t.Run("nil policy is a no-op", func(t *testing.T) {
+		var p *Policy
+		r, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		p.Apply(r)
+		require.Empty(t, r.Header)
+		require.NoError(t, p.Wait(context.Background()))
+	})
+}
+
+func TestPolicy_Wait(t *testing.T) {
+	t.Run("spaces requests by the rate limit", func(t *testing.T) {
+		p := &Policy{RateLimit: 20}
+		start := time.Now()
+		for range 3 {
+			require.NoError(t, p.Wait(context.Background()))
+		}
+		require.True(t, time.Since(start) >= 90*time.Millisecond)
+	})
+	t.Run("context cancellation", func(t *testing.T) {
+		p := &Policy{RateLimit: 0.1}
+		require.NoError(t, p.Wait(context.Background()))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.True(t, errors.Is(p.Wait(ctx), context.DeadlineExceeded))
+	})
+}
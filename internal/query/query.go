@@ -0,0 +1,100 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/salsgithub/godst/graph"
+)
+
+var (
+	ErrNodeNotFound = errors.New("node not found")
+	ErrNoPath       = errors.New("no path found")
+)
+
+func ShortestPath(g *graph.Graph[string], from, to string) ([]string, error) {
+	if _, ok := g.Neighbours(from); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, from)
+	}
+	if _, ok := g.Neighbours(to); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, to)
+	}
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node == to {
+			path := []string{}
+			for step := to; step != ""; step = previous[step] {
+				path = append(path, step)
+			}
+			slices.Reverse(path)
+			return path, nil
+		}
+		neighbours, _ := g.Neighbours(node)
+		for _, neighbour := range neighbours {
+			if _, seen := previous[neighbour.Link]; seen {
+				continue
+			}
+			previous[neighbour.Link] = node
+			queue = append(queue, neighbour.Link)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s to %s", ErrNoPath, from, to)
+}
+
+func LinkingTo(g *graph.Graph[string], target string) ([]string, error) {
+	if _, ok := g.Neighbours(target); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, target)
+	}
+	sources := []string{}
+	for _, node := range g.Nodes() {
+		neighbours, _ := g.Neighbours(node)
+		for _, neighbour := range neighbours {
+			if neighbour.Link == target {
+				sources = append(sources, node)
+				break
+			}
+		}
+	}
+	return sources, nil
+}
+
+func Orphans(g *graph.Graph[string]) []string {
+	linked := make(map[string]bool)
+	nodes := g.Nodes()
+	for _, node := range nodes {
+		neighbours, _ := g.Neighbours(node)
+		for _, neighbour := range neighbours {
+			if neighbour.Link != node {
+				linked[neighbour.Link] = true
+			}
+		}
+	}
+	orphans := []string{}
+	for _, node := range nodes {
+		if !linked[node] {
+			orphans = append(orphans, node)
+		}
+	}
+	return orphans
+}
+
+func Subtree(g *graph.Graph[string], prefix string) []string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	nodes := []string{}
+	for _, node := range g.Nodes() {
+		u, err := url.Parse(node)
+		if err != nil {
+			continue
+		}
+		if u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/") {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+)
+
+func testGraph() *graph.Graph[string] {
+	g := graph.New[string]()
+	g.AddEdge("https://example.com/", "https://example.com/blog", 1)
+	g.AddEdge("https://example.com/", "https://example.com/about", 1)
+	g.AddEdge("https://example.com/blog", "https://example.com/blog/post-a", 1)
+	g.AddEdge("https://example.com/blog/post-a", "https://example.com/blog/post-b", 1)
+	g.AddEdge("https://example.com/about", "https://example.com/blog/post-b", 1)
+	g.AddEdge("https://example.com/about", "https://example.com/about", 1)
+	g.AddNode("https://example.com/landing")
+	return g
+}
+
+func TestQuery_ShortestPath(t *testing.T) {
+	g := testGraph()
+	t.Run("finds fewest hops", func(t *testing.T) {
+		path, err := ShortestPath(g, "https://example.com/", "https://example.com/blog/post-b")
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://example.com/", "https://example.com/about", "https://example.com/blog/post-b"}, path)
+	})
+	t.Run("path to self", func(t *testing.T) {
+		path, err := ShortestPath(g, "https://example.com/", "https://example.com/")
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://example.com/"}, path)
+	})
+	t.Run("no path", func(t *testing.T) {
+		_, err := ShortestPath(g, "https://example.com/blog", "https://example.com/about")
+		require.True(t, errors.Is(err, ErrNoPath))
+	})
+	t.Run("unknown nodes", func(t *testing.T) {
+		_, err := ShortestPath(g, "https://example.com/missing", "https://example.com/")
+		require.True(t, errors.Is(err, ErrNodeNotFound))
+		_, err = ShortestPath(g, "https://example.com/", "https://example.com/missing")
+		require.True(t, errors.Is(err, ErrNodeNotFound))
+	})
+}
+
+func TestQuery_LinkingTo(t *testing.T) {
+	g := testGraph()
+	sources, err := LinkingTo(g, "https://example.com/blog/post-b")
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/about", "https://example.com/blog/post-a"}, sources)
+	_, err = LinkingTo(g, "https://example.com/missing")
+	require.True(t, errors.Is(err, ErrNodeNotFound))
+}
+
+func TestQuery_Orphans(t *testing.T) {
+	require.Equal(t, []string{"https://example.com/", "https://example.com/landing"}, Orphans(testGraph()))
+}
+
+func TestQuery_Subtree(t *testing.T) {
+	g := testGraph()
+	require.Equal(t, []string{"https://example.com/blog", "https://example.com/blog/post-a", "https://example.com/blog/post-b"}, Subtree(g, "/blog/"))
+	require.Equal(t, []string{}, Subtree(g, "/missing"))
+	require.Len(t, Subtree(g, "/"), g.Len())
+}
@@ -0,0 +1,62 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var ErrInvalidRule = errors.New("invalid rewrite rule")
+
+// Rule replaces matches of Pattern in a url with Replacement, which can refer to capture groups
+// as $1 or ${name}
+type Rule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+	pattern     *regexp.Regexp
+}
+
+type Rules struct {
+	rules []*Rule
+}
+
+type rulesFile struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// Load reads a JSON file of rewrite rules
+func Load(path string) (*Rules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	var file rulesFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	return New(file.Rules)
+}
+
+func New(rules []*Rule) (*Rules, error) {
+	for _, r := range rules {
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("%w: empty pattern", ErrInvalidRule)
+		}
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidRule, r.Pattern, err)
+		}
+		r.pattern = pattern
+	}
+	return &Rules{rules: rules}, nil
+}
+
+// Rewrite applies every rule in order, each to the result of the last
+func (r *Rules) Rewrite(u string) string {
+	for _, rule := range r.rules {
+		u = rule.pattern.ReplaceAllString(u, rule.Replacement)
+	}
+	return u
+}
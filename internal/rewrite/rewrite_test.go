@@ -0,0 +1,64 @@
+package rewrite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("valid file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rewrites.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"rules": [{"pattern": "^http://", "replacement": "https://"}]}`), 0o644))
+		rules, err := Load(path)
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/", rules.Rewrite("http://example.com/"))
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+		require.True(t, errors.Is(err, ErrInvalidRule))
+	})
+	t.Run("invalid json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rewrites.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"rules": [`), 0o644))
+		_, err := Load(path)
+		require.True(t, errors.Is(err, ErrInvalidRule))
+	})
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []*Rule
+	}{
+		{name: "empty pattern", rules: []*Rule{{Replacement: "https://"}}},
+		{name: "invalid pattern", rules: []*Rule{{Pattern: "^(http"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(test.rules)
+			require.True(t, errors.Is(err, ErrInvalidRule))
+		})
+	}
+}
+
+func TestRules_Rewrite(t *testing.T) {
+	rules, err := New([]*Rule{
+		{Pattern: "^http://", Replacement: "https://"},
+		{Pattern: `^https://m\.example\.com`, Replacement: "https://example.com"},
+		{Pattern: `^https://mirror[0-9]+\.example\.com/(?P<path>.*)$`, Replacement: "https://example.com/${path}"},
+	})
+	require.NoError(t, err)
+	tests := map[string]string{
+		"http://example.com/a":                 "https://example.com/a",
+		"http://m.example.com/b?c=1":           "https://example.com/b?c=1",
+		"https://mirror2.example.com/docs/":    "https://example.com/docs/",
+		"https://other.com/http://example.com": "https://other.com/http://example.com",
+	}
+	for in, want := range tests {
+		require.Equal(t, want, rules.Rewrite(in), in)
+	}
+}
@@ -0,0 +1,139 @@
+package screenshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrEmptyCommand = errors.New("empty screenshot command")
+
+const defaultTimeout = 30 * time.Second
+
+// Renderer loads a page as a browser would and returns a PNG screenshot of it. A headless browser
+// implements it, either in process or through CommandRenderer.
+type Renderer interface {
+	Screenshot(ctx context.Context, u string) ([]byte, error)
+}
+
+// CommandRenderer runs a command, such as a headless browser, for each page. {url} in its arguments
+// is replaced by the page and {output} by the file the command must write the PNG to.
+type CommandRenderer struct {
+	name    string
+	args    []string
+	timeout time.Duration
+}
+
+func NewCommandRenderer(commandLine string) (*CommandRenderer, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, ErrEmptyCommand
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("screenshot command %s not found: %w", fields[0], err)
+	}
+	return &CommandRenderer{name: fields[0], args: fields[1:], timeout: defaultTimeout}, nil
+}
+
+func (c *CommandRenderer) Screenshot(ctx context.Context, u string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "site-audit-screenshot-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "screenshot.png")
+	replacer := strings.NewReplacer("{url}", u, "{output}", output)
+	args := make([]string, len(c.args))
+	for i, arg := range c.args {
+		args[i] = replacer.Replace(arg)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.name, args...)
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("screenshot command %s failed: %w: %s", c.name, err, strings.TrimSpace(stderr.String()))
+	}
+	return os.ReadFile(output)
+}
+
+// Shot is a page's screenshot, with File relative to the screenshot directory
+type Shot struct {
+	URL   string `json:"url"`
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Sample picks up to limit urls spread evenly across them in order, or all of them when limit is 0
+func Sample(urls []string, limit int) []string {
+	urls = slices.Sorted(slices.Values(urls))
+	if limit <= 0 || len(urls) <= limit {
+		return urls
+	}
+	sampled := make([]string, 0, limit)
+	for i := range limit {
+		sampled = append(sampled, urls[i*len(urls)/limit])
+	}
+	return sampled
+}
+
+// Capture screenshots each url into dir, naming files after a hash of the url so reruns replace
+// them, and writes index.html linking every screenshot to its page. A page that fails to render
+// is recorded with its error rather than stopping the capture.
+func Capture(ctx context.Context, renderer Renderer, urls []string, dir string) ([]Shot, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	shots := make([]Shot, 0, len(urls))
+	for _, u := range urls {
+		if err := ctx.Err(); err != nil {
+			return shots, err
+		}
+		shot := Shot{URL: u}
+		png, err := renderer.Screenshot(ctx, u)
+		if err == nil {
+			sum := sha256.Sum256([]byte(u))
+			shot.File = hex.EncodeToString(sum[:8]) + ".png"
+			err = os.WriteFile(filepath.Join(dir, shot.File), png, 0644)
+		}
+		if err != nil {
+			shot.File = ""
+			shot.Error = err.Error()
+		}
+		shots = append(shots, shot)
+	}
+	f, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return shots, err
+	}
+	defer f.Close()
+	return shots, index.Execute(f, shots)
+}
+
+var index = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Screenshots</title></head>
+<body>
+{{- range .}}
+<figure>
+{{- if .File}}
+<a href="{{.File}}"><img src="{{.File}}" alt="{{.URL}}" width="320"></a>
+{{- end}}
+<figcaption><a href="{{.URL}}">{{.URL}}</a>{{if .Error}} {{.Error}}{{end}}</figcaption>
+</figure>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,65 @@
+package screenshot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stubRenderer map[string][]byte
+
+func (s stubRenderer) Screenshot(ctx context.Context, u string) ([]byte, error) {
+	if png, ok := s[u]; ok {
+		return png, nil
+	}
+	return nil, errors.New("render failed")
+}
+
+func TestSample(t *testing.T) {
+	urls := []string{"e", "a", "c", "b", "d", "f"}
+	require.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, Sample(urls, 0))
+	require.Equal(t, []string{"a", "c", "e"}, Sample(urls, 3))
+	require.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, Sample(urls, 10))
+}
+
+func TestCapture(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "screenshots")
+	renderer := stubRenderer{"https://example.com/": []byte("png")}
+	shots, err := Capture(context.Background(), renderer, []string{"https://example.com/", "https://example.com/broken"}, dir)
+	require.NoError(t, err)
+	require.Len(t, shots, 2)
+	require.NotEmpty(t, shots[0].File)
+	b, err := os.ReadFile(filepath.Join(dir, shots[0].File))
+	require.NoError(t, err)
+	require.Equal(t, "png", string(b))
+	require.Equal(t, Shot{URL: "https://example.com/broken", Error: "render failed"}, shots[1])
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	require.NoError(t, err)
+	require.Contains(t, string(index), `<img src="`+shots[0].File+`"`)
+	require.Contains(t, string(index), `<a href="https://example.com/broken">https://example.com/broken</a> render failed`)
+}
+
+func TestCommandRenderer(t *testing.T) {
+	_, err := NewCommandRenderer(" ")
+	require.Equal(t, ErrEmptyCommand, err)
+	_, err = NewCommandRenderer(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+	script := filepath.Join(t.TempDir(), "browser.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nprintf \"%s\" \"$1\" > \"$2\"\n"), 0755))
+	renderer, err := NewCommandRenderer(script + " {url} {output}")
+	require.NoError(t, err)
+	png, err := renderer.Screenshot(context.Background(), "https://example.com/")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/", string(png))
+	failing := filepath.Join(t.TempDir(), "failing.sh")
+	require.NoError(t, os.WriteFile(failing, []byte("#!/bin/sh\necho crashed >&2\nexit 1\n"), 0755))
+	renderer, err = NewCommandRenderer(failing)
+	require.NoError(t, err)
+	_, err = renderer.Screenshot(context.Background(), "https://example.com/")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "crashed")
+}
@@ -0,0 +1,135 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+const entrypoint = "check"
+
+var (
+	ErrMissingEntrypoint = errors.New("script does not define a check function")
+	ErrInvalidResult     = errors.New("script check must return None, a string or a list of strings")
+)
+
+type Check struct {
+	name string
+	fn   starlark.Callable
+}
+
+func NewCheck(path string) (*Check, error) {
+	thread := &starlark.Thread{Name: path}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error loading script %s: %w", path, err)
+	}
+	fn, ok := globals[entrypoint].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMissingEntrypoint, path)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &Check{name: name, fn: fn}, nil
+}
+
+func (c *Check) Name() string {
+	return c.name
+}
+
+func (c *Check) Run(ctx context.Context, pages []audit.Page) ([]audit.Finding, error) {
+	findings := []audit.Finding{}
+	thread := &starlark.Thread{Name: c.name}
+	stop := context.AfterFunc(ctx, func() {
+		thread.Cancel(ctx.Err().Error())
+	})
+	defer stop()
+	for _, page := range pages {
+		result, err := starlark.Call(thread, c.fn, starlark.Tuple{pageValue(page)}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error running script on %s: %w", page.URL, err)
+		}
+		details, err := toDetails(result)
+		if err != nil {
+			return nil, fmt.Errorf("%w: got %s for %s", err, result.Type(), page.URL)
+		}
+		for _, detail := range details {
+			findings = append(findings, audit.Finding{Check: c.name, URL: page.URL, Detail: detail})
+		}
+	}
+	return findings, nil
+}
+
+func pageValue(page audit.Page) *starlarkstruct.Struct {
+	headers := starlark.NewDict(len(page.Headers))
+	for name, value := range page.Headers {
+		headers.SetKey(starlark.String(name), starlark.String(value))
+	}
+	headers.Freeze()
+	thirdParty := make([]starlark.Value, 0, len(page.ThirdParty))
+	for _, resource := range page.ThirdParty {
+		thirdParty = append(thirdParty, starlarkstruct.FromStringDict(starlark.String("resource"), starlark.StringDict{
+			"kind":   starlark.String(resource.Kind),
+			"origin": starlark.String(resource.Origin),
+		}))
+	}
+	alternates := make([]starlark.Value, 0, len(page.Alternates))
+	for _, alternate := range page.Alternates {
+		alternates = append(alternates, starlarkstruct.FromStringDict(starlark.String("alternate"), starlark.StringDict{
+			"hreflang": starlark.String(alternate.Lang),
+			"media":    starlark.String(alternate.Media),
+			"url":      starlark.String(alternate.URL),
+		}))
+	}
+	return starlarkstruct.FromStringDict(starlark.String("page"), starlark.StringDict{
+		"url":            starlark.String(page.URL),
+		"status_code":    starlark.MakeInt(page.StatusCode),
+		"depth":          starlark.MakeInt(page.Depth),
+		"title":          starlark.String(page.Title),
+		"lang":           starlark.String(page.Lang),
+		"canonical":      starlark.String(page.Canonical),
+		"content_type":   starlark.String(page.Headers["Content-Type"]),
+		"content_length": starlark.MakeInt64(page.ContentLength),
+		"headers":        headers,
+		"links":          stringTuple(page.Links),
+		"embeds":         stringTuple(page.Embeds),
+		"assets":         stringTuple(page.Assets),
+		"third_party":    starlark.Tuple(thirdParty),
+		"alternates":     starlark.Tuple(alternates),
+	})
+}
+
+func stringTuple(values []string) starlark.Tuple {
+	tuple := make(starlark.Tuple, 0, len(values))
+	for _, value := range values {
+		tuple = append(tuple, starlark.String(value))
+	}
+	return tuple
+}
+
+func toDetails(value starlark.Value) ([]string, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.String:
+		return []string{string(v)}, nil
+	case starlark.Indexable:
+		details := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			s, ok := v.Index(i).(starlark.String)
+			if !ok {
+				return nil, ErrInvalidResult
+			}
+			details = append(details, string(s))
+		}
+		return details, nil
+	default:
+		return nil, ErrInvalidResult
+	}
+}
@@ -0,0 +1,158 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func writeScript(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(body), 0644))
+	return path
+}
+
+func TestScript_NewCheck(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		_, err := NewCheck(filepath.Join(t.TempDir(), "missing.star"))
+		require.Error(t, err)
+	})
+	t.Run("syntax error", func(t *testing.T) {
+		_, err := NewCheck(writeScript(t, "bad.star", "def check(page)\n"))
+		require.Error(t, err)
+	})
+	t.Run("missing entrypoint", func(t *testing.T) {
+		_, err := NewCheck(writeScript(t, "empty.star", "x = 1\n"))
+		require.True(t, errors.Is(err, ErrMissingEntrypoint))
+	})
+	t.Run("name from file", func(t *testing.T) {
+		c, err := NewCheck(writeScript(t, "no-errors.star", "def check(page):\n    return None\n"))
+		require.NoError(t, err)
+		require.Equal(t, "no-errors", c.Name())
+	})
+}
+
+func TestScript_Run(t *testing.T) {
+	pages := []audit.Page{
+		{URL: "https://example.com/", StatusCode: 200, Links: []string{"https://example.com/a"}},
+		{URL: "https://example.com/a", StatusCode: 500, Links: []string{}},
+	}
+	tests := []struct {
+		name    string
+		body    string
+		want    []audit.Finding
+		wantErr bool
+	}{
+		{
+			name: "no findings",
+			body: "def check(page):\n    return None\n",
+			want: []audit.Finding{},
+		},
+		{
+			name: "single string",
+			body: "def check(page):\n    if page.status_code >= 500:\n        return \"server error\"\n",
+			want: []audit.Finding{{Check: "rule", URL: "https://example.com/a", Detail: "server error"}},
+		},
+		{
+			name: "list of strings",
+			body: "def check(page):\n    return [\"links %d\" % len(page.links), page.url]\n",
+			want: []audit.Finding{
+				{Check: "rule", URL: "https://example.com/", Detail: "links 1"},
+				{Check: "rule", URL: "https://example.com/", Detail: "https://example.com/"},
+				{Check: "rule", URL: "https://example.com/a", Detail: "links 0"},
+				{Check: "rule", URL: "https://example.com/a", Detail: "https://example.com/a"},
+			},
+		},
+		{
+			name:    "invalid result",
+			body:    "def check(page):\n    return 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid list element",
+			body:    "def check(page):\n    return [1]\n",
+			wantErr: true,
+		},
+		{
+			name:    "runtime error",
+			body:    "def check(page):\n    return page.missing\n",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := NewCheck(writeScript(t, "rule.star", test.body))
+			require.NoError(t, err)
+			findings, err := c.Run(context.Background(), pages)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, findings)
+		})
+	}
+}
+
+func TestScript_RunPageMetadata(t *testing.T) {
+	page := audit.Page{
+		URL:           "https://example.com/",
+		StatusCode:    200,
+		Depth:         1,
+		Title:         "Home",
+		Lang:          "en",
+		Canonical:     "https://example.com/",
+		ContentLength: 512,
+		Links:         []string{},
+		Headers:       map[string]string{"Content-Type": "text/html", "Cache-Control": "no-store"},
+		Embeds:        []string{"https://example.com/frame"},
+		Assets:        []string{"https://example.com/app.js"},
+		ThirdParty:    []audit.Resource{{Kind: "script", Origin: "https://cdn.example.net"}},
+		Alternates:    []audit.Alternate{{Lang: "fr", URL: "https://example.com/fr/"}},
+	}
+	body := `def check(page):
+    return [
+        "%s %s %s %d %d" % (page.title, page.lang, page.canonical, page.depth, page.content_length),
+        page.content_type,
+        page.headers["Cache-Control"],
+        page.embeds[0],
+        page.assets[0],
+        "%s %s" % (page.third_party[0].kind, page.third_party[0].origin),
+        "%s %s" % (page.alternates[0].hreflang, page.alternates[0].url),
+        str(page.headers.get("ETag")),
+    ]
+`
+	c, err := NewCheck(writeScript(t, "rule.star", body))
+	require.NoError(t, err)
+	findings, err := c.Run(context.Background(), []audit.Page{page})
+	require.NoError(t, err)
+	details := []string{}
+	for _, finding := range findings {
+		details = append(details, finding.Detail)
+	}
+	require.Equal(t, []string{
+		"Home en https://example.com/ 1 512",
+		"text/html",
+		"no-store",
+		"https://example.com/frame",
+		"https://example.com/app.js",
+		"script https://cdn.example.net",
+		"fr https://example.com/fr/",
+		"None",
+	}, details)
+}
+
+func TestScript_RunCancelled(t *testing.T) {
+	c, err := NewCheck(writeScript(t, "loop.star", "def check(page):\n    for i in range(1000000000):\n        pass\n"))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Run(ctx, []audit.Page{{URL: "https://example.com/"}})
+	require.Error(t, err)
+}
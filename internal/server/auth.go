@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid server token")
+
+type tenantKey struct{}
+
+type Credential struct {
+	tenant string
+	token  []byte
+}
+
+// ParseTokens parses a comma-separated list of tenant:token pairs
+func ParseTokens(s string) ([]Credential, error) {
+	credentials := []Credential{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		tenant, token, ok := strings.Cut(pair, ":")
+		if !ok || tenant == "" || token == "" {
+			return nil, fmt.Errorf("%w: expected tenant:token", ErrInvalidToken)
+		}
+		credentials = append(credentials, Credential{tenant: tenant, token: []byte(token)})
+	}
+	return credentials, nil
+}
+
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.Header.Get("X-API-Key")
+	}
+	if token == "" {
+		return "", false
+	}
+	tenant := ""
+	for _, c := range s.credentials {
+		if subtle.ConstantTimeCompare(c.token, []byte(token)) == 1 {
+			tenant = c.tenant
+		}
+	}
+	return tenant, tenant != ""
+}
+
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.credentials) == 0 {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, defaultTenant)))
+			return
+		}
+		tenant, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="site-audit"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		if wait, ok := s.limiter.allow(tenant); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant)))
+	})
+}
+
+func tenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter is a token bucket per key refilled continuously at perMinute tokens a minute
+type limiter struct {
+	perMinute int
+	buckets   map[string]*bucket
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+func newLimiter(perMinute int) *limiter {
+	return &limiter{perMinute: perMinute, buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (l *limiter) allow(key string) (time.Duration, bool) {
+	if l.perMinute <= 0 {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	capacity := float64(l.perMinute)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	perSecond := capacity / 60
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / perSecond * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobFinished  JobStatus = "finished"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+const errInterrupted = "interrupted by server shutdown"
+
+type Job struct {
+	ID         string         `json:"id"`
+	Tenant     string         `json:"tenant"`
+	StartURL   string         `json:"start_url"`
+	Priority   int            `json:"priority"`
+	Status     JobStatus      `json:"status"`
+	QueuedAt   time.Time      `json:"queued_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Summary    *audit.Summary `json:"summary,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+func (j *Job) done() bool {
+	return j.Status == JobFinished || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// enqueue must be called with s.mu held
+func (s *Server) enqueue(job *Job, auditor *audit.Audit) {
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.auditors[job.ID] = auditor
+	s.schedule()
+}
+
+// schedule starts queued jobs by priority, then age, while global and per-tenant capacity remains. It must be called with s.mu held
+func (s *Server) schedule() {
+	s.prune()
+	queued := []*Job{}
+	running := 0
+	perTenant := make(map[string]int)
+	for _, id := range s.order {
+		job := s.jobs[id]
+		switch job.Status {
+		case JobQueued:
+			queued = append(queued, job)
+		case JobRunning:
+			running++
+			perTenant[job.Tenant]++
+		}
+	}
+	slices.SortStableFunc(queued, func(x, y *Job) int {
+		return y.Priority - x.Priority
+	})
+	for _, job := range queued {
+		if s.maxJobs > 0 && running >= s.maxJobs {
+			break
+		}
+		if s.maxTenantJobs > 0 && perTenant[job.Tenant] >= s.maxTenantJobs {
+			continue
+		}
+		auditor := s.auditors[job.ID]
+		startedAt := time.Now().UTC()
+		job.StartedAt = &startedAt
+		job.Status = JobRunning
+		running++
+		perTenant[job.Tenant]++
+		s.logger.Info("Audit job started", "id", job.ID, "tenant", job.Tenant, "start_url", job.StartURL)
+		s.wg.Add(1)
+		go s.run(job, auditor)
+	}
+	s.persist()
+}
+
+// run crawls a job, telling the server's hooks when it starts and ends
+func (s *Server) run(job *Job, auditor *audit.Audit) {
+	defer s.wg.Done()
+	s.mu.Lock()
+	started := *job
+	s.mu.Unlock()
+	for _, hook := range s.hooks {
+		hook.JobStarted(started)
+	}
+	err := auditor.Start(s.ctx)
+	ended := s.finish(job, auditor, err)
+	for _, hook := range s.hooks {
+		hook.JobEnded(ended)
+	}
+}
+
+// finish records how a job ended, keeping only its results so the auditor's graph can be freed,
+// and returns a copy of the job
+func (s *Server) finish(job *Job, auditor *audit.Audit, err error) Job {
+	summary := auditor.Summary()
+	finished := &finishedJob{results: collectResults(auditor), progress: auditor.Progress()}
+	finishedAt := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.auditors, job.ID)
+	s.finished[job.ID] = finished
+	job.FinishedAt = &finishedAt
+	job.Summary = &summary
+	switch {
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	case s.cancelled.Contains(job.ID):
+		job.Status = JobCancelled
+	case s.ctx.Err() != nil:
+		job.Status = JobFailed
+		job.Error = errInterrupted
+	default:
+		job.Status = JobFinished
+	}
+	s.cancelled.Remove(job.ID)
+	s.logger.Info("Audit job complete", "id", job.ID, "tenant", job.Tenant, "status", job.Status)
+	if s.ctx.Err() == nil {
+		s.schedule()
+	} else {
+		s.persist()
+	}
+	return *job
+}
+
+// cancel must be called with s.mu held
+func (s *Server) cancel(job *Job) {
+	s.cancelled.Add(job.ID)
+	switch job.Status {
+	case JobQueued:
+		finishedAt := time.Now().UTC()
+		job.FinishedAt = &finishedAt
+		job.Status = JobCancelled
+		s.auditors[job.ID].Cancel()
+		delete(s.auditors, job.ID)
+		s.cancelled.Remove(job.ID)
+		s.persist()
+	case JobRunning:
+		s.auditors[job.ID].Cancel()
+	}
+	s.logger.Info("Audit job cancelled", "id", job.ID, "tenant", job.Tenant)
+}
+
+// prune drops the records of jobs that finished longer ago than the retention period. It must be
+// called with s.mu held
+func (s *Server) prune() {
+	if s.jobRetention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-s.jobRetention)
+	s.order = slices.DeleteFunc(s.order, func(id string) bool {
+		job := s.jobs[id]
+		if !job.done() || job.FinishedAt == nil || job.FinishedAt.After(cutoff) {
+			return false
+		}
+		delete(s.jobs, id)
+		delete(s.finished, id)
+		return true
+	})
+}
+
+// persist writes the job history to disk. It must be called with s.mu held
+func (s *Server) persist() {
+	if s.historyFile == "" {
+		return
+	}
+	jobs := make([]*Job, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, s.jobs[id])
+	}
+	b, err := json.MarshalIndent(jobs, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.historyFile), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(s.historyFile, b, 0644)
+	}
+	if err != nil {
+		s.logger.Error("Error persisting job history", "path", s.historyFile, "err", err)
+	}
+}
+
+// restore loads the job history, marking jobs that never completed as failed at the time of the
+// restore so they expire like any other
+func (s *Server) restore() error {
+	if s.historyFile == "" {
+		return nil
+	}
+	b, err := os.ReadFile(s.historyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var jobs []*Job
+	if err := json.Unmarshal(b, &jobs); err != nil {
+		return err
+	}
+	restoredAt := time.Now().UTC()
+	for _, job := range jobs {
+		if !job.done() {
+			job.Status = JobFailed
+			job.Error = errInterrupted
+			job.FinishedAt = &restoredAt
+		}
+		s.jobs[job.ID] = job
+		s.order = append(s.order, job.ID)
+		if id, err := strconv.Atoi(job.ID); err == nil && id > s.nextID {
+			s.nextID = id
+		}
+	}
+	s.prune()
+	return nil
+}
@@ -0,0 +1,436 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/salsgithub/godst/set"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+const defaultTenant = "default"
+
+var ErrUnauthenticatedPublicAddress = errors.New("refusing to listen on a non-loopback address without server tokens")
+
+type AuditFactory func(config audit.Config) (*audit.Audit, error)
+
+type jobRequest struct {
+	StartURL   string `json:"start_url"`
+	MaxDepth   *int   `json:"max_depth,omitempty"`
+	MaxWorkers *int   `json:"max_workers,omitempty"`
+	Priority   int    `json:"priority"`
+}
+
+type seedsRequest struct {
+	URLs []string `json:"urls"`
+}
+
+type Option func(*Server)
+
+// Hook is told when a job starts crawling and once it has ended, with the job's summary and status.
+// Hooks are called from the job's goroutine, so a slow hook delays the job but not the server.
+type Hook interface {
+	JobStarted(job Job)
+	JobEnded(job Job)
+}
+
+type Server struct {
+	base          audit.Config
+	factory       AuditFactory
+	credentials   []Credential
+	limiter       *limiter
+	logger        *slog.Logger
+	maxJobs       int
+	maxTenantJobs int
+	historyFile   string
+	jobRetention  time.Duration
+	hooks         []Hook
+	jobs          map[string]*Job
+	order         []string
+	auditors      map[string]*audit.Audit
+	finished      map[string]*finishedJob
+	cancelled     *set.Set[string]
+	nextID        int
+	ctx           context.Context
+	wg            sync.WaitGroup
+	mu            sync.Mutex
+}
+
+func New(ctx context.Context, base audit.Config, factory AuditFactory, options ...Option) (*Server, error) {
+	s := &Server{
+		base:      base,
+		factory:   factory,
+		limiter:   newLimiter(0),
+		logger:    slog.Default(),
+		jobs:      make(map[string]*Job),
+		auditors:  make(map[string]*audit.Audit),
+		finished:  make(map[string]*finishedJob),
+		cancelled: set.New[string](),
+		ctx:       ctx,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if err := s.restore(); err != nil {
+		return nil, fmt.Errorf("error restoring job history: %w", err)
+	}
+	return s, nil
+}
+
+func WithCredentials(credentials []Credential) Option {
+	return func(s *Server) {
+		s.credentials = credentials
+	}
+}
+
+func WithRateLimit(perMinute int) Option {
+	return func(s *Server) {
+		s.limiter = newLimiter(perMinute)
+	}
+}
+
+func WithMaxJobs(maxJobs, maxTenantJobs int) Option {
+	return func(s *Server) {
+		s.maxJobs = maxJobs
+		s.maxTenantJobs = maxTenantJobs
+	}
+}
+
+func WithHistoryFile(path string) Option {
+	return func(s *Server) {
+		s.historyFile = path
+	}
+}
+
+// WithJobRetention drops the records of jobs finished longer ago than retention, keeping them
+// forever when 0
+func WithJobRetention(retention time.Duration) Option {
+	return func(s *Server) {
+		s.jobRetention = retention
+	}
+}
+
+func WithHooks(hooks ...Hook) Option {
+	return func(s *Server) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// CheckAddress guards against exposing an unauthenticated server beyond localhost
+func (s *Server) CheckAddress(address string) error {
+	if len(s.credentials) > 0 {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnauthenticatedPublicAddress, address)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /audits", s.createJob)
+	mux.HandleFunc("GET /audits", s.listJobs)
+	mux.HandleFunc("GET /audits/{id}", s.getJob)
+	mux.HandleFunc("POST /audits/{id}/cancel", s.cancelJob)
+	mux.HandleFunc("GET /audits/{id}/progress", s.getProgress)
+	mux.HandleFunc("GET /audits/{id}/events", s.streamEvents)
+	mux.HandleFunc("POST /audits/{id}/seeds", s.addSeeds)
+	mux.HandleFunc("GET /audits/{id}/results", s.getResults)
+	return s.withAuth(mux)
+}
+
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var request jobRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.mu.Unlock()
+	config := s.jobConfig(request, id)
+	auditor, err := s.factory(config)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	job := &Job{
+		ID:       id,
+		Tenant:   tenantFrom(r.Context()),
+		StartURL: config.StartURL,
+		Priority: request.Priority,
+		Status:   JobQueued,
+		QueuedAt: time.Now().UTC(),
+	}
+	s.enqueue(job, auditor)
+	response := *job
+	s.mu.Unlock()
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// jobConfig is the server's config for a job. Requests can lower the server's depth and worker
+// limits but not raise them, and files the audit writes as it crawls are given a path of their own
+// so concurrent jobs do not overwrite each other.
+func (s *Server) jobConfig(request jobRequest, id string) audit.Config {
+	config := s.base
+	config.StartURL = request.StartURL
+	if request.MaxDepth != nil {
+		config.MaxDepth = min(*request.MaxDepth, s.base.MaxDepth)
+	}
+	if request.MaxWorkers != nil {
+		config.MaxWorkers = min(*request.MaxWorkers, s.base.MaxWorkers)
+	}
+	if config.GraphLogFile != "" {
+		ext := filepath.Ext(config.GraphLogFile)
+		config.GraphLogFile = strings.TrimSuffix(config.GraphLogFile, ext) + "-" + id + ext
+	}
+	return config
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	tenant := tenantFrom(r.Context())
+	s.mu.Lock()
+	jobs := []Job{}
+	for _, id := range s.order {
+		if job := s.jobs[id]; job.Tenant == tenant {
+			jobs = append(jobs, *job)
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// lookup finds a job owned by the requesting tenant. It must be called with s.mu held
+func (s *Server) lookup(r *http.Request) (*Job, bool) {
+	job, ok := s.jobs[r.PathValue("id")]
+	if !ok || job.Tenant != tenantFrom(r.Context()) {
+		return nil, false
+	}
+	return job, true
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.lookup(r)
+	var response Job
+	if ok {
+		response = *job
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "audit not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.lookup(r)
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "audit not found")
+		return
+	}
+	if job.done() {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "audit already "+string(job.Status))
+		return
+	}
+	s.cancel(job)
+	response := *job
+	s.mu.Unlock()
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// auditorFor returns the auditor of a job still queued or running, or what was kept of it once it
+// ended. Neither is set for jobs restored from the history, whose results were not persisted.
+func (s *Server) auditorFor(w http.ResponseWriter, r *http.Request) (*audit.Audit, *finishedJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lookup(r); !ok {
+		writeError(w, http.StatusNotFound, "audit not found")
+		return nil, nil, false
+	}
+	if auditor, ok := s.auditors[r.PathValue("id")]; ok {
+		return auditor, nil, true
+	}
+	if finished, ok := s.finished[r.PathValue("id")]; ok {
+		return nil, finished, true
+	}
+	writeError(w, http.StatusGone, "audit results are no longer available")
+	return nil, nil, false
+}
+
+func (s *Server) getProgress(w http.ResponseWriter, r *http.Request) {
+	auditor, finished, ok := s.auditorFor(w, r)
+	if !ok {
+		return
+	}
+	if finished != nil {
+		writeJSON(w, http.StatusOK, finished.progress)
+		return
+	}
+	writeJSON(w, http.StatusOK, auditor.Progress())
+}
+
+func (s *Server) addSeeds(w http.ResponseWriter, r *http.Request) {
+	var request seedsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.URLs) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	auditor, finished, ok := s.auditorFor(w, r)
+	if !ok {
+		return
+	}
+	if finished != nil {
+		writeError(w, http.StatusConflict, audit.ErrCrawlFinished.Error())
+		return
+	}
+	added, err := auditor.AddSeeds(request.URLs...)
+	switch {
+	case errors.Is(err, audit.ErrCrawlFinished):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeJSON(w, http.StatusAccepted, map[string]int{"added": added})
+	}
+}
+
+// streamEvents sends an audit's page events as server-sent events until the crawl is done or
+// the client goes away
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	auditor, finished, ok := s.auditorFor(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if finished != nil {
+		fmt.Fprint(w, "event: done\ndata: {}\n\n")
+		flusher.Flush()
+		return
+	}
+	events, unsubscribe := auditor.Subscribe()
+	defer unsubscribe()
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				fmt.Fprint(w, "event: done\ndata: {}\n\n")
+				flusher.Flush()
+				return
+			}
+			b, err := json.Marshal(event)
+			if err != nil {
+				s.logger.Error("Event encoding error", "err", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: page\ndata: %s\n\n", b)
+			flusher.Flush()
+		}
+	}
+}
+
+type results struct {
+	Summary  audit.Summary   `json:"summary"`
+	Pages    []audit.Page    `json:"pages"`
+	Findings []audit.Finding `json:"findings"`
+	// FindingGroups merges findings on url variants and groups them by check and url template
+	FindingGroups []audit.FindingGroup   `json:"finding_groups"`
+	Disallowed    []audit.DisallowedLink `json:"disallowed"`
+	Politeness    audit.Politeness       `json:"politeness"`
+	// QueryParams is only filled in once the crawl has finished
+	QueryParams []audit.ParamImpact    `json:"query_params"`
+	Contacts    []audit.ContactLink    `json:"contacts"`
+	Outbound    []audit.OutboundDomain `json:"outbound"`
+	Redirects   []audit.Redirect       `json:"redirects"`
+	BrokenLinks []audit.BrokenLink     `json:"broken_links"`
+}
+
+// finishedJob is what is kept of a job's auditor once the job ends, so its results can still be
+// fetched without holding on to the crawl's graph
+type finishedJob struct {
+	results  results
+	progress audit.Progress
+}
+
+func (s *Server) getResults(w http.ResponseWriter, r *http.Request) {
+	auditor, finished, ok := s.auditorFor(w, r)
+	if !ok {
+		return
+	}
+	if finished != nil {
+		writeJSON(w, http.StatusOK, finished.results)
+		return
+	}
+	writeJSON(w, http.StatusOK, collectResults(auditor))
+}
+
+func collectResults(auditor *audit.Audit) results {
+	findings := auditor.Findings()
+	return results{
+		Summary:       auditor.Summary(),
+		Pages:         auditor.Pages(),
+		Findings:      findings,
+		FindingGroups: audit.GroupFindings(findings),
+		Disallowed:    auditor.Disallowed(),
+		Politeness:    auditor.Politeness(),
+		QueryParams:   auditor.QueryParams(),
+		Contacts:      auditor.Contacts(),
+		Outbound:      auditor.OutboundDomains(),
+		Redirects:     auditor.Redirects(),
+		BrokenLinks:   auditor.BrokenLinks(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
@@ -0,0 +1,483 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+type stubFetcher struct{}
+
+func (s *stubFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+type stubExtractor struct{}
+
+func (s *stubExtractor) Extract(ctx context.Context, u *url.URL, body io.Reader) ([]string, error) {
+	return nil, nil
+}
+
+var baseConfig = audit.Config{
+	LogLevel:   "error",
+	Agent:      "agent",
+	MaxWorkers: 1,
+	MaxDepth:   1,
+}
+
+func stubFactory(config audit.Config) (*audit.Audit, error) {
+	return audit.New(config, &stubFetcher{}, &stubExtractor{})
+}
+
+func newTestServer(t *testing.T, options ...Option) *Server {
+	t.Helper()
+	options = append(options, WithLogger(slog.New(slog.DiscardHandler)))
+	s, err := New(context.Background(), baseConfig, stubFactory, options...)
+	require.NoError(t, err)
+	t.Cleanup(s.Wait)
+	return s
+}
+
+func do(t *testing.T, h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServer_ParseTokens(t *testing.T) {
+	credentials, err := ParseTokens("a:one, b:two,")
+	require.NoError(t, err)
+	require.Len(t, credentials, 2)
+	require.Equal(t, "b", credentials[1].tenant)
+	_, err = ParseTokens("missing-separator")
+	require.Error(t, err)
+	_, err = ParseTokens(":token")
+	require.Error(t, err)
+}
+
+func TestServer_CheckAddress(t *testing.T) {
+	s := newTestServer(t)
+	require.NoError(t, s.CheckAddress("localhost:8080"))
+	require.NoError(t, s.CheckAddress("127.0.0.1:8080"))
+	require.NoError(t, s.CheckAddress("[::1]:8080"))
+	require.Error(t, s.CheckAddress(":8080"))
+	require.Error(t, s.CheckAddress("0.0.0.0:8080"))
+	credentials, _ := ParseTokens("a:one")
+	s = newTestServer(t, WithCredentials(credentials))
+	require.NoError(t, s.CheckAddress(":8080"))
+}
+
+func TestServer_Auth(t *testing.T) {
+	credentials, _ := ParseTokens("a:one,b:two")
+	h := newTestServer(t, WithCredentials(credentials)).Handler()
+	t.Run("missing token", func(t *testing.T) {
+		response := do(t, h, http.MethodGet, "/audits", "", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.NotEmpty(t, response.Header().Get("WWW-Authenticate"))
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		response := do(t, h, http.MethodGet, "/audits", "three", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+	t.Run("bearer token", func(t *testing.T) {
+		response := do(t, h, http.MethodGet, "/audits", "one", "")
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+	t.Run("api key header", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/audits", nil)
+		request.Header.Set("X-API-Key", "two")
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	credentials, _ := ParseTokens("a:one,b:two")
+	s := newTestServer(t, WithCredentials(credentials), WithRateLimit(2))
+	now := time.Now()
+	s.limiter.now = func() time.Time { return now }
+	h := s.Handler()
+	require.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/audits", "one", "").Code)
+	require.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/audits", "one", "").Code)
+	limited := do(t, h, http.MethodGet, "/audits", "one", "")
+	require.Equal(t, http.StatusTooManyRequests, limited.Code)
+	require.Equal(t, "30", limited.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/audits", "two", "").Code)
+	now = now.Add(30 * time.Second)
+	require.Equal(t, http.StatusOK, do(t, h, http.MethodGet, "/audits", "one", "").Code)
+}
+
+func waitForJob(t *testing.T, h http.Handler, token, id string) Job {
+	t.Helper()
+	var job Job
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		response := do(t, h, http.MethodGet, "/audits/"+id, token, "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &job))
+		if job.Status != JobRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not complete", id)
+	return job
+}
+
+func TestServer_Jobs(t *testing.T) {
+	credentials, _ := ParseTokens("a:one,b:two")
+	h := newTestServer(t, WithCredentials(credentials)).Handler()
+	t.Run("invalid body", func(t *testing.T) {
+		response := do(t, h, http.MethodPost, "/audits", "one", "{")
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+	t.Run("invalid config", func(t *testing.T) {
+		response := do(t, h, http.MethodPost, "/audits", "one", `{"start_url":"example.com"}`)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+		require.Contains(t, response.Body.String(), "invalid start url scheme")
+	})
+	t.Run("runs job scoped to tenant", func(t *testing.T) {
+		response := do(t, h, http.MethodPost, "/audits", "one", `{"start_url":"https://example.com","max_depth":1}`)
+		require.Equal(t, http.StatusAccepted, response.Code)
+		var created Job
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+		require.Equal(t, "a", created.Tenant)
+		job := waitForJob(t, h, "one", created.ID)
+		require.Equal(t, JobFinished, job.Status)
+		require.NotNil(t, job.Summary)
+		require.Equal(t, 1, job.Summary.Visited)
+		require.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/audits/"+created.ID, "two", "").Code)
+		var jobs []Job
+		require.NoError(t, json.Unmarshal(do(t, h, http.MethodGet, "/audits", "two", "").Body.Bytes(), &jobs))
+		require.Empty(t, jobs)
+		require.NoError(t, json.Unmarshal(do(t, h, http.MethodGet, "/audits", "one", "").Body.Bytes(), &jobs))
+		require.Len(t, jobs, 1)
+	})
+	t.Run("unknown job", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/audits/999", "one", "").Code)
+	})
+}
+
+func TestServer_JobConfig(t *testing.T) {
+	base := baseConfig
+	base.MaxWorkers, base.MaxDepth = 4, 3
+	base.GraphLogFile = filepath.Join("out", "graph.jsonl")
+	s, err := New(context.Background(), base, stubFactory)
+	require.NoError(t, err)
+	depth, workers := 10, 100
+	config := s.jobConfig(jobRequest{StartURL: "https://example.com", MaxDepth: &depth, MaxWorkers: &workers}, "7")
+	require.Equal(t, 3, config.MaxDepth)
+	require.Equal(t, 4, config.MaxWorkers)
+	require.Equal(t, filepath.Join("out", "graph-7.jsonl"), config.GraphLogFile)
+	depth, workers = 1, 2
+	config = s.jobConfig(jobRequest{StartURL: "https://example.com", MaxDepth: &depth, MaxWorkers: &workers}, "8")
+	require.Equal(t, 1, config.MaxDepth)
+	require.Equal(t, 2, config.MaxWorkers)
+	require.Equal(t, "https://example.com", config.StartURL)
+}
+
+type blockingFetcher struct {
+	release chan struct{}
+}
+
+func (b *blockingFetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func newBlockingServer(t *testing.T, options ...Option) (*Server, chan struct{}) {
+	t.Helper()
+	release := make(chan struct{})
+	factory := func(config audit.Config) (*audit.Audit, error) {
+		return audit.New(config, &blockingFetcher{release: release}, &stubExtractor{})
+	}
+	options = append(options, WithLogger(slog.New(slog.DiscardHandler)))
+	s, err := New(context.Background(), baseConfig, factory, options...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		close(release)
+		s.Wait()
+	})
+	return s, release
+}
+
+func createJob(t *testing.T, h http.Handler, token, body string) string {
+	t.Helper()
+	response := do(t, h, http.MethodPost, "/audits", token, body)
+	require.Equal(t, http.StatusAccepted, response.Code)
+	var job Job
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &job))
+	return job.ID
+}
+
+func jobStatus(s *Server, id string) JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id].Status
+}
+
+func waitForStatus(t *testing.T, s *Server, id string, status JobStatus) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if jobStatus(s, id) == status {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s status %s, want %s", id, jobStatus(s, id), status)
+}
+
+func TestServer_Queue(t *testing.T) {
+	credentials, _ := ParseTokens("a:one,b:two")
+	t.Run("per tenant limit", func(t *testing.T) {
+		s, release := newBlockingServer(t, WithCredentials(credentials), WithMaxJobs(4, 1))
+		h := s.Handler()
+		first := createJob(t, h, "one", `{"start_url":"https://example.com"}`)
+		second := createJob(t, h, "one", `{"start_url":"https://example.com"}`)
+		other := createJob(t, h, "two", `{"start_url":"https://example.com"}`)
+		require.Equal(t, JobRunning, jobStatus(s, first))
+		require.Equal(t, JobQueued, jobStatus(s, second))
+		require.Equal(t, JobRunning, jobStatus(s, other))
+		release <- struct{}{}
+		release <- struct{}{}
+		waitForStatus(t, s, second, JobRunning)
+		release <- struct{}{}
+		waitForStatus(t, s, second, JobFinished)
+	})
+	t.Run("global limit and priority", func(t *testing.T) {
+		s, release := newBlockingServer(t, WithCredentials(credentials), WithMaxJobs(1, 0))
+		h := s.Handler()
+		first := createJob(t, h, "one", `{"start_url":"https://example.com"}`)
+		low := createJob(t, h, "one", `{"start_url":"https://example.com","priority":0}`)
+		high := createJob(t, h, "two", `{"start_url":"https://example.com","priority":5}`)
+		require.Equal(t, JobRunning, jobStatus(s, first))
+		require.Equal(t, JobQueued, jobStatus(s, low))
+		require.Equal(t, JobQueued, jobStatus(s, high))
+		release <- struct{}{}
+		waitForStatus(t, s, high, JobRunning)
+		require.Equal(t, JobQueued, jobStatus(s, low))
+		release <- struct{}{}
+		waitForStatus(t, s, low, JobRunning)
+	})
+}
+
+func TestServer_History(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "jobs.json")
+	s, release := newBlockingServer(t, WithHistoryFile(path), WithMaxJobs(1, 0))
+	h := s.Handler()
+	finished := createJob(t, h, "", `{"start_url":"https://example.com"}`)
+	interrupted := createJob(t, h, "", `{"start_url":"https://example.com"}`)
+	release <- struct{}{}
+	waitForStatus(t, s, finished, JobFinished)
+	waitForStatus(t, s, interrupted, JobRunning)
+	restored := newTestServer(t, WithHistoryFile(path))
+	require.Equal(t, JobFinished, restored.jobs[finished].Status)
+	require.Equal(t, JobFailed, restored.jobs[interrupted].Status)
+	require.Equal(t, errInterrupted, restored.jobs[interrupted].Error)
+	require.NotNil(t, restored.jobs[interrupted].FinishedAt)
+	next := createJob(t, restored.Handler(), "", `{"start_url":"https://example.com"}`)
+	require.Equal(t, "3", next)
+	t.Run("invalid history", func(t *testing.T) {
+		invalid := filepath.Join(t.TempDir(), "jobs.json")
+		require.NoError(t, os.WriteFile(invalid, []byte("{"), 0644))
+		_, err := New(context.Background(), baseConfig, stubFactory, WithHistoryFile(invalid))
+		require.Error(t, err)
+	})
+}
+
+type recordingHook struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *recordingHook) JobStarted(job Job) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, "started "+job.ID+" "+string(job.Status))
+}
+
+func (h *recordingHook) JobEnded(job Job) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, fmt.Sprintf("ended %s %s %t", job.ID, job.Status, job.Summary != nil))
+}
+
+func (h *recordingHook) recorded() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.events)
+}
+
+func TestServer_Hooks(t *testing.T) {
+	hook := &recordingHook{}
+	s, release := newBlockingServer(t, WithHooks(hook), WithMaxJobs(1, 0))
+	h := s.Handler()
+	finished := createJob(t, h, "", `{"start_url":"https://example.com"}`)
+	cancelled := createJob(t, h, "", `{"start_url":"https://example.com"}`)
+	release <- struct{}{}
+	waitForStatus(t, s, cancelled, JobRunning)
+	require.Equal(t, http.StatusAccepted, do(t, h, http.MethodPost, "/audits/"+cancelled+"/cancel", "", "").Code)
+	waitForStatus(t, s, cancelled, JobCancelled)
+	s.Wait()
+	require.Equal(t, []string{
+		"started " + finished + " running",
+		"ended " + finished + " finished true",
+		"started " + cancelled + " running",
+		"ended " + cancelled + " cancelled true",
+	}, hook.recorded())
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	factory := func(config audit.Config) (*audit.Audit, error) {
+		return audit.New(config, &blockingFetcher{release: make(chan struct{})}, &stubExtractor{})
+	}
+	s, err := New(ctx, baseConfig, factory, WithHistoryFile(path), WithLogger(slog.New(slog.DiscardHandler)))
+	require.NoError(t, err)
+	id := createJob(t, s.Handler(), "", `{"start_url":"https://example.com"}`)
+	cancel()
+	s.Wait()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var jobs []Job
+	require.NoError(t, json.Unmarshal(b, &jobs))
+	require.Len(t, jobs, 1)
+	require.Equal(t, id, jobs[0].ID)
+	require.Equal(t, JobFailed, jobs[0].Status)
+	require.Equal(t, errInterrupted, jobs[0].Error)
+}
+
+func TestServer_JobRetention(t *testing.T) {
+	s, release := newBlockingServer(t, WithJobRetention(time.Hour), WithMaxJobs(1, 0))
+	h := s.Handler()
+	old := createJob(t, h, "", `{"start_url":"https://example.com"}`)
+	recent := createJob(t, h, "", `{"start_url":"https://example.com"}`)
+	release <- struct{}{}
+	waitForStatus(t, s, old, JobFinished)
+	s.mu.Lock()
+	finishedAt := time.Now().Add(-2 * time.Hour)
+	s.jobs[old].FinishedAt = &finishedAt
+	s.mu.Unlock()
+	createJob(t, h, "", `{"start_url":"https://example.com"}`)
+	require.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/audits/"+old, "", "").Code)
+	require.Equal(t, JobRunning, jobStatus(s, recent))
+}
+
+func TestServer_CancelProgressResults(t *testing.T) {
+	credentials, _ := ParseTokens("a:one,b:two")
+	t.Run("cancel running job", func(t *testing.T) {
+		s, _ := newBlockingServer(t, WithCredentials(credentials), WithMaxJobs(1, 0))
+		h := s.Handler()
+		running := createJob(t, h, "one", `{"start_url":"https://example.com"}`)
+		queued := createJob(t, h, "one", `{"start_url":"https://example.com"}`)
+		require.Equal(t, http.StatusNotFound, do(t, h, http.MethodPost, "/audits/"+running+"/cancel", "two", "").Code)
+		require.Equal(t, http.StatusAccepted, do(t, h, http.MethodPost, "/audits/"+queued+"/cancel", "one", "").Code)
+		require.Equal(t, JobCancelled, jobStatus(s, queued))
+		require.Equal(t, http.StatusAccepted, do(t, h, http.MethodPost, "/audits/"+running+"/cancel", "one", "").Code)
+		waitForStatus(t, s, running, JobCancelled)
+		require.Equal(t, http.StatusConflict, do(t, h, http.MethodPost, "/audits/"+running+"/cancel", "one", "").Code)
+	})
+	t.Run("progress and partial results", func(t *testing.T) {
+		s, release := newBlockingServer(t, WithCredentials(credentials))
+		h := s.Handler()
+		id := createJob(t, h, "one", `{"start_url":"https://example.com"}`)
+		response := do(t, h, http.MethodGet, "/audits/"+id+"/progress", "one", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		var progress audit.Progress
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &progress))
+		require.False(t, progress.Done)
+		response = do(t, h, http.MethodGet, "/audits/"+id+"/results", "one", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		var got results
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &got))
+		require.Empty(t, got.Pages)
+		require.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/audits/"+id+"/results", "two", "").Code)
+		release <- struct{}{}
+		job := waitForJob(t, h, "one", id)
+		require.Equal(t, JobFinished, job.Status)
+		require.Equal(t, 1, job.Summary.Visited)
+		response = do(t, h, http.MethodGet, "/audits/"+id+"/results", "one", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &got))
+		require.Len(t, got.Pages, 1)
+		require.Equal(t, 1, got.Summary.Visited)
+		response = do(t, h, http.MethodGet, "/audits/"+id+"/progress", "one", "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &progress))
+		require.True(t, progress.Done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		require.Empty(t, s.auditors)
+	})
+	t.Run("seeds", func(t *testing.T) {
+		s, release := newBlockingServer(t, WithCredentials(credentials))
+		h := s.Handler()
+		id := createJob(t, h, "one", `{"start_url":"https://example.com"}`)
+		require.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPost, "/audits/"+id+"/seeds", "one", `{"urls":[]}`).Code)
+		require.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPost, "/audits/"+id+"/seeds", "one", `{"urls":["https://other.com/"]}`).Code)
+		require.Equal(t, http.StatusNotFound, do(t, h, http.MethodPost, "/audits/"+id+"/seeds", "two", `{"urls":["https://example.com/a"]}`).Code)
+		response := do(t, h, http.MethodPost, "/audits/"+id+"/seeds", "one", `{"urls":["https://example.com/a"]}`)
+		require.Equal(t, http.StatusAccepted, response.Code)
+		require.JSONEq(t, `{"added":1}`, response.Body.String())
+		release <- struct{}{}
+		release <- struct{}{}
+		waitForStatus(t, s, id, JobFinished)
+		require.Equal(t, http.StatusConflict, do(t, h, http.MethodPost, "/audits/"+id+"/seeds", "one", `{"urls":["https://example.com/b"]}`).Code)
+	})
+	t.Run("events", func(t *testing.T) {
+		s, release := newBlockingServer(t, WithCredentials(credentials))
+		server := httptest.NewServer(s.Handler())
+		defer server.Close()
+		id := createJob(t, s.Handler(), "one", `{"start_url":"https://example.com"}`)
+		request, err := http.NewRequest(http.MethodGet, server.URL+"/audits/"+id+"/events", nil)
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer one")
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+		release <- struct{}{}
+		b, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.Equal(t, "event: page\ndata: {\"url\":\"https://example.com/\",\"status\":200,\"depth\":0}\n\nevent: done\ndata: {}\n\n", string(b))
+		require.Equal(t, http.StatusNotFound, do(t, s.Handler(), http.MethodGet, "/audits/"+id+"/events", "two", "").Code)
+		waitForStatus(t, s, id, JobFinished)
+		require.Equal(t, "event: done\ndata: {}\n\n", do(t, s.Handler(), http.MethodGet, "/audits/"+id+"/events", "one", "").Body.String())
+	})
+	t.Run("results unavailable after restore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jobs.json")
+		s := newTestServer(t, WithHistoryFile(path))
+		id := createJob(t, s.Handler(), "", `{"start_url":"https://example.com"}`)
+		waitForStatus(t, s, id, JobFinished)
+		restored := newTestServer(t, WithHistoryFile(path))
+		require.Equal(t, http.StatusGone, do(t, restored.Handler(), http.MethodGet, "/audits/"+id+"/progress", "", "").Code)
+	})
+}
@@ -0,0 +1,288 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/query"
+	"salsgithub.com/site-audit/internal/script"
+	"salsgithub.com/site-audit/internal/snapshot"
+)
+
+const prompt = "site-audit> "
+
+const help = `commands:
+  open <url>            make the url the current node
+  info [url]            show status and link counts of a node
+  out [url]             list links from a node
+  in [url]              list pages linking to a node
+  follow <n>            follow the nth outgoing link of the current node
+  back                  return to the previous node
+  path <from> <to>      shortest path of links between two urls
+  orphans               pages no other page links to
+  subtree <path>        pages under the url path
+  findings [url]        findings recorded in the snapshot
+  check <script>        run a Starlark check script against the snapshot
+  help                  show this help
+  exit                  leave the shell`
+
+var errExit = errors.New("exit")
+
+type Shell struct {
+	snapshot *snapshot.Snapshot
+	graph    *graph.Graph[string]
+	current  string
+	history  []string
+	out      io.Writer
+}
+
+func New(s *snapshot.Snapshot, out io.Writer) *Shell {
+	return &Shell{
+		snapshot: s,
+		graph:    s.Graph(),
+		current:  s.StartURL,
+		out:      out,
+	}
+}
+
+func (s *Shell) Run(ctx context.Context, in io.Reader) error {
+	fmt.Fprintf(s.out, "Loaded %d pages and %d links from crawl of %s\n", len(s.snapshot.Nodes), len(s.snapshot.Edges), s.snapshot.StartURL)
+	fmt.Fprintln(s.out, "Type help for a list of commands")
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(s.out, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return scanner.Err()
+		}
+		err := s.Execute(ctx, scanner.Text())
+		if errors.Is(err, errExit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(s.out, "error: %v\n", err)
+		}
+	}
+}
+
+func (s *Shell) Execute(ctx context.Context, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	command, args := fields[0], fields[1:]
+	switch command {
+	case "open":
+		return s.open(args)
+	case "info":
+		return s.info(args)
+	case "out":
+		return s.outgoing(args)
+	case "in":
+		return s.incoming(args)
+	case "follow":
+		return s.follow(args)
+	case "back":
+		return s.back()
+	case "path":
+		return s.path(args)
+	case "orphans":
+		s.printList(query.Orphans(s.graph))
+		return nil
+	case "subtree":
+		if len(args) != 1 {
+			return errors.New("subtree requires <path>")
+		}
+		s.printList(query.Subtree(s.graph, args[0]))
+		return nil
+	case "findings":
+		return s.findings(args)
+	case "check":
+		return s.check(ctx, args)
+	case "help":
+		fmt.Fprintln(s.out, help)
+		return nil
+	case "exit", "quit":
+		return errExit
+	default:
+		return fmt.Errorf("unknown command %q, type help for a list of commands", command)
+	}
+}
+
+func (s *Shell) resolve(args []string) (string, error) {
+	if len(args) == 0 {
+		if s.current == "" {
+			return "", errors.New("no current node, use open <url>")
+		}
+		return s.current, nil
+	}
+	u, err := audit.CanonicalURL(args[0])
+	if err != nil {
+		return "", err
+	}
+	if _, ok := s.graph.Neighbours(u); !ok {
+		return "", fmt.Errorf("%w: %s", query.ErrNodeNotFound, u)
+	}
+	return u, nil
+}
+
+func (s *Shell) moveTo(u string) {
+	if s.current != "" {
+		s.history = append(s.history, s.current)
+	}
+	s.current = u
+	fmt.Fprintln(s.out, u)
+}
+
+func (s *Shell) open(args []string) error {
+	if len(args) != 1 {
+		return errors.New("open requires <url>")
+	}
+	u, err := s.resolve(args)
+	if err != nil {
+		return err
+	}
+	s.moveTo(u)
+	return nil
+}
+
+func (s *Shell) info(args []string) error {
+	u, err := s.resolve(args)
+	if err != nil {
+		return err
+	}
+	outgoing, _ := s.graph.Neighbours(u)
+	incoming, _ := query.LinkingTo(s.graph, u)
+	status := "not fetched"
+	if code, ok := s.snapshot.StatusCode(u); ok {
+		status = strconv.Itoa(code)
+	}
+	fmt.Fprintf(s.out, "url:      %s\nstatus:   %s\noutgoing: %d\nincoming: %d\n", u, status, len(outgoing), len(incoming))
+	return nil
+}
+
+func (s *Shell) outgoing(args []string) error {
+	u, err := s.resolve(args)
+	if err != nil {
+		return err
+	}
+	neighbours, _ := s.graph.Neighbours(u)
+	for i, neighbour := range neighbours {
+		fmt.Fprintf(s.out, "%3d  %s\n", i+1, neighbour.Link)
+	}
+	return nil
+}
+
+func (s *Shell) incoming(args []string) error {
+	u, err := s.resolve(args)
+	if err != nil {
+		return err
+	}
+	sources, err := query.LinkingTo(s.graph, u)
+	if err != nil {
+		return err
+	}
+	s.printList(sources)
+	return nil
+}
+
+func (s *Shell) follow(args []string) error {
+	if len(args) != 1 {
+		return errors.New("follow requires <n>")
+	}
+	u, err := s.resolve(nil)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(args[0])
+	neighbours, _ := s.graph.Neighbours(u)
+	if err != nil || n < 1 || n > len(neighbours) {
+		return fmt.Errorf("invalid link number %q, expected 1 to %d", args[0], len(neighbours))
+	}
+	s.moveTo(neighbours[n-1].Link)
+	return nil
+}
+
+func (s *Shell) back() error {
+	if len(s.history) == 0 {
+		return errors.New("no previous node")
+	}
+	last := len(s.history) - 1
+	s.current = s.history[last]
+	s.history = s.history[:last]
+	fmt.Fprintln(s.out, s.current)
+	return nil
+}
+
+func (s *Shell) path(args []string) error {
+	if len(args) != 2 {
+		return errors.New("path requires <from> <to>")
+	}
+	from, err := s.resolve(args[:1])
+	if err != nil {
+		return err
+	}
+	to, err := s.resolve(args[1:])
+	if err != nil {
+		return err
+	}
+	path, err := query.ShortestPath(s.graph, from, to)
+	if err != nil {
+		return err
+	}
+	s.printList(path)
+	return nil
+}
+
+func (s *Shell) findings(args []string) error {
+	u := ""
+	if len(args) > 0 {
+		resolved, err := s.resolve(args)
+		if err != nil {
+			return err
+		}
+		u = resolved
+	}
+	for _, finding := range s.snapshot.Findings {
+		if u == "" || finding.URL == u {
+			printFinding(s.out, finding)
+		}
+	}
+	return nil
+}
+
+func (s *Shell) check(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("check requires <script>")
+	}
+	c, err := script.NewCheck(args[0])
+	if err != nil {
+		return err
+	}
+	findings, err := c.Run(ctx, s.snapshot.Pages())
+	if err != nil {
+		return err
+	}
+	for _, finding := range findings {
+		printFinding(s.out, finding)
+	}
+	fmt.Fprintf(s.out, "%d findings\n", len(findings))
+	return nil
+}
+
+func (s *Shell) printList(items []string) {
+	for _, item := range items {
+		fmt.Fprintln(s.out, item)
+	}
+}
+
+func printFinding(w io.Writer, finding audit.Finding) {
+	fmt.Fprintf(w, "[%s] %s %s\n", finding.Check, finding.URL, finding.Detail)
+}
@@ -0,0 +1,105 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/snapshot"
+)
+
+func testSnapshot() *snapshot.Snapshot {
+	return &snapshot.Snapshot{
+		StartURL: "https://example.com/",
+		Nodes: []snapshot.Node{
+			{URL: "https://example.com/", StatusCode: 200},
+			{URL: "https://example.com/a", StatusCode: 200},
+			{URL: "https://example.com/b", StatusCode: 404},
+		},
+		Edges: []snapshot.Edge{
+			{Source: "https://example.com/", Target: "https://example.com/a", Weight: 1},
+			{Source: "https://example.com/a", Target: "https://example.com/b", Weight: 1},
+		},
+		Findings: []audit.Finding{
+			{Check: audit.CheckBrokenLink, URL: "https://example.com/b", Detail: "status 404"},
+		},
+	}
+}
+
+func run(t *testing.T, input string) string {
+	t.Helper()
+	out := &bytes.Buffer{}
+	err := New(testSnapshot(), out).Run(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	return out.String()
+}
+
+func TestShell_Run(t *testing.T) {
+	t.Run("exits at end of input", func(t *testing.T) {
+		out := run(t, "")
+		require.Contains(t, out, "Loaded 3 pages and 2 links")
+	})
+	t.Run("exit command stops reading", func(t *testing.T) {
+		out := run(t, "exit\ninfo\n")
+		require.NotContains(t, out, "url:")
+	})
+	t.Run("unknown command reports error and continues", func(t *testing.T) {
+		out := run(t, "nope\nhelp\n")
+		require.Contains(t, out, `error: unknown command "nope"`)
+		require.Contains(t, out, "commands:")
+	})
+}
+
+func TestShell_Navigation(t *testing.T) {
+	t.Run("info on start node", func(t *testing.T) {
+		out := run(t, "info\n")
+		require.Contains(t, out, "url:      https://example.com/\nstatus:   200\noutgoing: 1\nincoming: 0")
+	})
+	t.Run("follow and back", func(t *testing.T) {
+		out := run(t, "follow 1\nout\nfollow 1\nin\nback\nback\n")
+		require.Contains(t, out, "  1  https://example.com/b")
+		require.Contains(t, out, "site-audit> https://example.com/a\nsite-audit> https://example.com/\n")
+	})
+	t.Run("follow out of range", func(t *testing.T) {
+		out := run(t, "follow 2\n")
+		require.Contains(t, out, "error: invalid link number")
+	})
+	t.Run("back without history", func(t *testing.T) {
+		out := run(t, "back\n")
+		require.Contains(t, out, "error: no previous node")
+	})
+	t.Run("open unknown node", func(t *testing.T) {
+		out := run(t, "open https://example.com/missing\n")
+		require.Contains(t, out, "error: node not found")
+	})
+	t.Run("open known node without trailing slash", func(t *testing.T) {
+		out := run(t, "open https://example.com/b/\ninfo\n")
+		require.Contains(t, out, "status:   404")
+	})
+}
+
+func TestShell_Queries(t *testing.T) {
+	t.Run("path", func(t *testing.T) {
+		out := run(t, "path https://example.com https://example.com/b\n")
+		require.Contains(t, out, "https://example.com/\nhttps://example.com/a\nhttps://example.com/b\n")
+	})
+	t.Run("orphans", func(t *testing.T) {
+		out := run(t, "orphans\n")
+		require.Contains(t, out, "site-audit> https://example.com/\nsite-audit> ")
+	})
+	t.Run("findings for node", func(t *testing.T) {
+		out := run(t, "findings https://example.com/a\nfindings\n")
+		require.Equal(t, 1, strings.Count(out, "[broken-link] https://example.com/b status 404"))
+	})
+	t.Run("check script", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rule.star")
+		require.NoError(t, os.WriteFile(path, []byte("def check(page):\n    if page.status_code == 404:\n        return \"missing\"\n"), 0644))
+		out := run(t, "check "+path+"\n")
+		require.Contains(t, out, "[rule] https://example.com/b missing\n1 findings")
+	})
+}
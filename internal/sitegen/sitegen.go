@@ -0,0 +1,143 @@
+package sitegen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Site serves a generated site of numbered pages for tests and benchmarks. Page 0 is the home
+// page at "/", and page i links to pages i*FanOut+1 to i*FanOut+FanOut along with the home page,
+// so the site forms a tree FanOut wide that a crawler reaches level by level.
+type Site struct {
+	pages   int
+	fanOut  int
+	latency time.Duration
+	errors  map[int]bool
+}
+
+type Option func(*config)
+
+type config struct {
+	pages     int
+	fanOut    int
+	latency   time.Duration
+	errorRate float64
+	seed      uint64
+}
+
+func WithPages(n int) Option {
+	return func(c *config) {
+		c.pages = n
+	}
+}
+
+func WithFanOut(n int) Option {
+	return func(c *config) {
+		c.fanOut = n
+	}
+}
+
+// WithLatency delays every response
+func WithLatency(d time.Duration) Option {
+	return func(c *config) {
+		c.latency = d
+	}
+}
+
+// WithErrorRate makes roughly this fraction of pages, other than the home page, return 500
+func WithErrorRate(rate float64) Option {
+	return func(c *config) {
+		c.errorRate = rate
+	}
+}
+
+// WithSeed picks which pages fail, so the same seed always generates the same site
+func WithSeed(seed uint64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+func New(options ...Option) *Site {
+	c := &config{pages: 100, fanOut: 5, seed: 1}
+	for _, option := range options {
+		option(c)
+	}
+	s := &Site{
+		pages:   max(c.pages, 1),
+		fanOut:  max(c.fanOut, 1),
+		latency: c.latency,
+		errors:  make(map[int]bool),
+	}
+	random := rand.New(rand.NewPCG(c.seed, c.seed))
+	for i := 1; i < s.pages; i++ {
+		if random.Float64() < c.errorRate {
+			s.errors[i] = true
+		}
+	}
+	return s
+}
+
+func (s *Site) Pages() int {
+	return s.pages
+}
+
+// Errors is the number of pages that return 500
+func (s *Site) Errors() int {
+	return len(s.errors)
+}
+
+// Path of page i
+func Path(i int) string {
+	if i == 0 {
+		return "/"
+	}
+	return fmt.Sprintf("/page/%d", i)
+}
+
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.latency > 0 {
+		select {
+		case <-time.After(s.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	i, ok := s.page(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if s.errors[i] {
+		http.Error(w, "generated error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><head><title>Page %d</title></head><body>\n", i)
+	fmt.Fprintf(&b, "<a href=\"%s\">Home</a>\n", Path(0))
+	for child := i*s.fanOut + 1; child <= i*s.fanOut+s.fanOut && child < s.pages; child++ {
+		fmt.Fprintf(&b, "<a href=\"%s\">Page %d</a>\n", Path(child), child)
+	}
+	b.WriteString("</body></html>\n")
+	w.Write([]byte(b.String()))
+}
+
+func (s *Site) page(path string) (int, bool) {
+	if path == "/" {
+		return 0, true
+	}
+	n, ok := strings.CutPrefix(path, "/page/")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(n)
+	if err != nil || i <= 0 || i >= s.pages {
+		return 0, false
+	}
+	return i, true
+}
@@ -0,0 +1,62 @@
+package sitegen
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	require.NoError(t, err)
+	return recorder.Code, string(body)
+}
+
+func TestSite(t *testing.T) {
+	t.Run("pages link to their children and home", func(t *testing.T) {
+		s := New(WithPages(10), WithFanOut(3))
+		code, body := get(t, s, "/")
+		require.Equal(t, http.StatusOK, code)
+		require.Contains(t, body, `href="/page/1"`)
+		require.Contains(t, body, `href="/page/3"`)
+		require.NotContains(t, body, `href="/page/4"`)
+		code, body = get(t, s, "/page/2")
+		require.Equal(t, http.StatusOK, code)
+		require.Contains(t, body, `href="/"`)
+		require.Contains(t, body, `href="/page/9"`)
+		require.NotContains(t, body, `href="/page/10"`)
+	})
+	t.Run("unknown pages are not found", func(t *testing.T) {
+		s := New(WithPages(10))
+		for _, path := range []string{"/page/10", "/page/0", "/page/x", "/robots.txt"} {
+			code, _ := get(t, s, path)
+			require.Equal(t, http.StatusNotFound, code, path)
+		}
+	})
+	t.Run("error rate is deterministic per seed", func(t *testing.T) {
+		s := New(WithPages(1000), WithErrorRate(0.1), WithSeed(7))
+		require.True(t, s.Errors() > 50 && s.Errors() < 150, "errors: %d", s.Errors())
+		require.Equal(t, s.Errors(), New(WithPages(1000), WithErrorRate(0.1), WithSeed(7)).Errors())
+		failing := 0
+		for i := range s.Pages() {
+			if code, _ := get(t, s, Path(i)); code == http.StatusInternalServerError {
+				failing++
+			}
+		}
+		require.Equal(t, s.Errors(), failing)
+		code, _ := get(t, New(WithErrorRate(1)), "/")
+		require.Equal(t, http.StatusOK, code)
+	})
+	t.Run("latency", func(t *testing.T) {
+		s := New(WithLatency(20 * time.Millisecond))
+		start := time.Now()
+		get(t, s, "/")
+		require.True(t, time.Since(start) >= 20*time.Millisecond)
+	})
+}
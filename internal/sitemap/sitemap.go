@@ -0,0 +1,232 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MaxURLs is the protocol limit of urls in a single sitemap file
+const MaxURLs = 50000
+
+const (
+	namespace      = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+)
+
+var (
+	ErrNoURLs         = errors.New("no urls to write")
+	ErrInvalidSitemap = errors.New("invalid sitemap")
+)
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	XHTML   string   `xml:"xmlns:xhtml,attr,omitempty"`
+	URLs    []entry  `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Sitemaps []entry  `xml:"sitemap"`
+}
+
+type entry struct {
+	Loc   string      `xml:"loc"`
+	Links []xhtmlLink `xml:"xhtml:link,omitempty"`
+}
+
+type xhtmlLink struct {
+	Rel      string `xml:"rel,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+// Alternate is a translation or regional version of a page, declared by the page with hreflang
+type Alternate struct {
+	Lang string
+	URL  string
+}
+
+type Option func(*writer)
+
+type writer struct {
+	clusters map[string][]Alternate
+	// listed holds every url in the sitemap, as only those are annotated as alternates
+	listed map[string]bool
+}
+
+// WithAlternates annotates each url with every version of it in its hreflang cluster, itself
+// included. declared holds the alternates each crawled page lists, keyed by page url.
+func WithAlternates(declared map[string][]Alternate) Option {
+	return func(w *writer) {
+		w.clusters = hreflangClusters(declared)
+	}
+}
+
+// Write writes sitemap.xml to dir, splitting into numbered sitemaps referenced from a sitemap index
+// under baseURL when there are more than MaxURLs urls. It returns the paths of the files written.
+func Write(dir, baseURL string, urls []string, options ...Option) ([]string, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoURLs
+	}
+	w := &writer{listed: make(map[string]bool)}
+	for _, option := range options {
+		option(w)
+	}
+	if w.clusters != nil {
+		for _, u := range urls {
+			w.listed[u] = true
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating sitemap directory: %w", err)
+	}
+	if len(urls) <= MaxURLs {
+		path := filepath.Join(dir, "sitemap.xml")
+		if err := writeXML(path, w.newURLSet(urls)); err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+	written := []string{}
+	index := sitemapIndex{XMLNS: namespace}
+	for i := 0; i*MaxURLs < len(urls); i++ {
+		chunk := urls[i*MaxURLs : min((i+1)*MaxURLs, len(urls))]
+		name := fmt.Sprintf("sitemap-%d.xml", i+1)
+		path := filepath.Join(dir, name)
+		if err := writeXML(path, w.newURLSet(chunk)); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+		index.Sitemaps = append(index.Sitemaps, entry{Loc: strings.TrimSuffix(baseURL, "/") + "/" + name})
+	}
+	path := filepath.Join(dir, "sitemap.xml")
+	if err := writeXML(path, index); err != nil {
+		return nil, err
+	}
+	return append(written, path), nil
+}
+
+func (w *writer) newURLSet(urls []string) urlSet {
+	set := urlSet{XMLNS: namespace, URLs: make([]entry, 0, len(urls))}
+	if w.clusters != nil {
+		set.XHTML = xhtmlNamespace
+	}
+	for _, u := range urls {
+		e := entry{Loc: u}
+		for _, alternate := range w.clusters[u] {
+			if w.listed[alternate.URL] {
+				e.Links = append(e.Links, xhtmlLink{Rel: "alternate", HrefLang: alternate.Lang, Href: alternate.URL})
+			}
+		}
+		set.URLs = append(set.URLs, e)
+	}
+	return set
+}
+
+// hreflangClusters joins pages declaring each other as alternates, directly or through other
+// pages, and gives every page in a cluster all of the cluster's alternates sorted by language
+func hreflangClusters(declared map[string][]Alternate) map[string][]Alternate {
+	parent := map[string]string{}
+	var find func(u string) string
+	find = func(u string) string {
+		p, ok := parent[u]
+		if !ok || p == u {
+			parent[u] = u
+			return u
+		}
+		root := find(p)
+		parent[u] = root
+		return root
+	}
+	for page, alternates := range declared {
+		for _, alternate := range alternates {
+			parent[find(alternate.URL)] = find(page)
+		}
+	}
+	members := map[string][]Alternate{}
+	for page, alternates := range declared {
+		root := find(page)
+		for _, alternate := range alternates {
+			if !slices.Contains(members[root], alternate) {
+				members[root] = append(members[root], alternate)
+			}
+		}
+	}
+	for _, alternates := range members {
+		slices.SortFunc(alternates, func(x, y Alternate) int {
+			if c := strings.Compare(x.Lang, y.Lang); c != 0 {
+				return c
+			}
+			return strings.Compare(x.URL, y.URL)
+		})
+	}
+	clusters := map[string][]Alternate{}
+	for u := range parent {
+		if alternates := members[find(u)]; len(alternates) > 0 {
+			clusters[u] = alternates
+		}
+	}
+	return clusters
+}
+
+func writeXML(path string, v any) error {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding sitemap: %w", err)
+	}
+	if err := os.WriteFile(path, append([]byte(xml.Header), append(b, '\n')...), 0o644); err != nil {
+		return fmt.Errorf("error writing sitemap: %w", err)
+	}
+	return nil
+}
+
+// document reads either a urlset or a sitemap index
+type document struct {
+	XMLName  xml.Name
+	URLs     []entry `xml:"url"`
+	Sitemaps []entry `xml:"sitemap"`
+}
+
+// Parse reads a sitemap, gzipped or not, returning the page urls it lists or, for a sitemap index,
+// the sitemaps it references
+func Parse(r io.Reader) (urls []string, sitemaps []string, err error) {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSitemap, err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+	var d document
+	if err := xml.NewDecoder(r).Decode(&d); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSitemap, err)
+	}
+	if d.XMLName.Local != "urlset" && d.XMLName.Local != "sitemapindex" {
+		return nil, nil, fmt.Errorf("%w: unexpected root element %q", ErrInvalidSitemap, d.XMLName.Local)
+	}
+	return locations(d.URLs), locations(d.Sitemaps), nil
+}
+
+func locations(entries []entry) []string {
+	locs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if loc := strings.TrimSpace(e.Loc); loc != "" {
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
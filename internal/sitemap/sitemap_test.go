@@ -0,0 +1,131 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	t.Run("single sitemap", func(t *testing.T) {
+		dir := t.TempDir()
+		written, err := Write(dir, "https://example.com", []string{"https://example.com/", "https://example.com/a?b=1&c=2"})
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join(dir, "sitemap.xml")}, written)
+		b, err := os.ReadFile(written[0])
+		require.NoError(t, err)
+		require.Contains(t, string(b), "<loc>https://example.com/a?b=1&amp;c=2</loc>")
+		var set urlSet
+		require.NoError(t, xml.Unmarshal(b, &set))
+		require.Len(t, set.URLs, 2)
+	})
+	t.Run("splits into an index past the limit", func(t *testing.T) {
+		dir := t.TempDir()
+		urls := make([]string, MaxURLs+1)
+		for i := range urls {
+			urls[i] = fmt.Sprintf("https://example.com/%d", i)
+		}
+		written, err := Write(dir, "https://example.com/", urls)
+		require.NoError(t, err)
+		require.Len(t, written, 3)
+		b, err := os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+		require.NoError(t, err)
+		var index sitemapIndex
+		require.NoError(t, xml.Unmarshal(b, &index))
+		require.Equal(t, []entry{{Loc: "https://example.com/sitemap-1.xml"}, {Loc: "https://example.com/sitemap-2.xml"}}, index.Sitemaps)
+		b, err = os.ReadFile(filepath.Join(dir, "sitemap-2.xml"))
+		require.NoError(t, err)
+		var set urlSet
+		require.NoError(t, xml.Unmarshal(b, &set))
+		require.Equal(t, []entry{{Loc: fmt.Sprintf("https://example.com/%d", MaxURLs)}}, set.URLs)
+	})
+	t.Run("hreflang alternates", func(t *testing.T) {
+		dir := t.TempDir()
+		declared := map[string][]Alternate{
+			"https://example.com/": {{Lang: "en", URL: "https://example.com/"}, {Lang: "de", URL: "https://example.com/de/"}},
+			// The french page only declares itself and the german page, joining the cluster through it
+			"https://example.com/fr/": {{Lang: "fr", URL: "https://example.com/fr/"}, {Lang: "de", URL: "https://example.com/de/"}},
+			"https://example.com/b":   {{Lang: "x-default", URL: "https://example.com/missing"}},
+		}
+		urls := []string{"https://example.com/", "https://example.com/de/", "https://example.com/fr/", "https://example.com/b"}
+		written, err := Write(dir, "https://example.com", urls, WithAlternates(declared))
+		require.NoError(t, err)
+		b, err := os.ReadFile(written[0])
+		require.NoError(t, err)
+		require.Contains(t, string(b), `xmlns:xhtml="http://www.w3.org/1999/xhtml"`)
+		require.Contains(t, string(b), `<xhtml:link rel="alternate" hreflang="fr" href="https://example.com/fr/"></xhtml:link>`)
+		require.NotContains(t, string(b), "https://example.com/missing")
+		var set struct {
+			URLs []struct {
+				Loc   string `xml:"loc"`
+				Links []struct {
+					HrefLang string `xml:"hreflang,attr"`
+				} `xml:"link"`
+			} `xml:"url"`
+		}
+		require.NoError(t, xml.Unmarshal(b, &set))
+		langs := map[string][]string{}
+		for _, u := range set.URLs {
+			langs[u.Loc] = []string{}
+			for _, link := range u.Links {
+				langs[u.Loc] = append(langs[u.Loc], link.HrefLang)
+			}
+		}
+		cluster := []string{"de", "en", "fr"}
+		require.Equal(t, map[string][]string{
+			"https://example.com/":    cluster,
+			"https://example.com/de/": cluster,
+			"https://example.com/fr/": cluster,
+			"https://example.com/b":   {},
+		}, langs)
+	})
+	t.Run("no urls", func(t *testing.T) {
+		_, err := Write(t.TempDir(), "https://example.com", nil)
+		require.True(t, errors.Is(err, ErrNoURLs))
+	})
+}
+
+func TestParse(t *testing.T) {
+	urlSet := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>
+    https://example.com/</loc></url>
+  <url><loc>https://example.com/a?b=1&amp;c=2</loc><lastmod>2025-01-01</lastmod></url>
+</urlset>`
+	t.Run("url set", func(t *testing.T) {
+		urls, sitemaps, err := Parse(strings.NewReader(urlSet))
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://example.com/", "https://example.com/a?b=1&c=2"}, urls)
+		require.Empty(t, sitemaps)
+	})
+	t.Run("gzipped", func(t *testing.T) {
+		var b bytes.Buffer
+		gz := gzip.NewWriter(&b)
+		_, err := gz.Write([]byte(urlSet))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+		urls, _, err := Parse(&b)
+		require.NoError(t, err)
+		require.Len(t, urls, 2)
+	})
+	t.Run("index", func(t *testing.T) {
+		urls, sitemaps, err := Parse(strings.NewReader(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>https://example.com/sitemap-1.xml</loc></sitemap></sitemapindex>`))
+		require.NoError(t, err)
+		require.Empty(t, urls)
+		require.Equal(t, []string{"https://example.com/sitemap-1.xml"}, sitemaps)
+	})
+	t.Run("not a sitemap", func(t *testing.T) {
+		_, _, err := Parse(strings.NewReader(`<html><body>Not found</body></html>`))
+		require.True(t, errors.Is(err, ErrInvalidSitemap))
+		_, _, err = Parse(strings.NewReader(`not xml`))
+		require.True(t, errors.Is(err, ErrInvalidSitemap))
+	})
+}
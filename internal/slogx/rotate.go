@@ -0,0 +1,80 @@
+package slogx
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is a log file that is moved aside to path.1, path.2 and so on once it grows past
+// maxBytes, keeping at most maxFiles of the old files
+type RotatingFile struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+}
+
+// OpenRotatingFile appends to the file at path, never rotating it when maxBytes is 0
+func OpenRotatingFile(path string, maxBytes int64, maxFiles int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// rotate must be called with r.mu held. The oldest file past maxFiles is overwritten.
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return fmt.Errorf("error rotating log file: %w", err)
+	}
+	for i := r.maxFiles - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error rotating log file: %w", err)
+		}
+	}
+	if r.maxFiles > 0 {
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return fmt.Errorf("error rotating log file: %w", err)
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return fmt.Errorf("error rotating log file: %w", err)
+	}
+	return r.open()
+}
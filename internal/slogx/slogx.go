@@ -1,15 +1,55 @@
 package slogx
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
 )
 
-func New(level slog.Level) *slog.Logger {
-	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type Option func(*options)
+
+type options struct {
+	writer io.Writer
+	format string
+	quiet  bool
+}
+
+// WithWriter sends logs to w instead of stdout
+func WithWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.writer = w
+	}
+}
+
+// WithFormat chooses between FormatJSON, the default, and FormatText
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithQuiet drops everything below errors except records logged with a Summary context
+func WithQuiet(quiet bool) Option {
+	return func(o *options) {
+		o.quiet = quiet
+	}
+}
+
+func New(level slog.Level, opts ...Option) *slog.Logger {
+	o := options{writer: os.Stdout, format: FormatJSON}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	handlerOptions := &slog.HandlerOptions{
 		Level:     level,
 		AddSource: true,
 		ReplaceAttr: func(groups []string, attribute slog.Attr) slog.Attr {
@@ -22,7 +62,44 @@ func New(level slog.Level) *slog.Logger {
 				return attribute
 			}
 		},
-	}))
+	}
+	var handler slog.Handler
+	if o.format == FormatText {
+		handler = slog.NewTextHandler(o.writer, handlerOptions)
+	} else {
+		handler = slog.NewJSONHandler(o.writer, handlerOptions)
+	}
+	if o.quiet {
+		handler = &quietHandler{handler}
+	}
+	return slog.New(handler)
+}
+
+type summaryKey struct{}
+
+// Summary marks records logged with the returned context as part of the final summary, which a
+// quiet logger still writes
+func Summary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, summaryKey{}, true)
+}
+
+type quietHandler struct {
+	slog.Handler
+}
+
+func (h *quietHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level < slog.LevelError && ctx.Value(summaryKey{}) == nil {
+		return false
+	}
+	return h.Handler.Enabled(ctx, level)
+}
+
+func (h *quietHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &quietHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *quietHandler) WithGroup(name string) slog.Handler {
+	return &quietHandler{h.Handler.WithGroup(name)}
 }
 
 func formatTime(attribute slog.Attr) slog.Attr {
@@ -0,0 +1,44 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Quiet(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(slog.LevelInfo, WithWriter(&out), WithFormat(FormatText), WithQuiet(true)).With("worker", 1)
+	logger.Info("Fetching")
+	logger.Warn("Received non successful status code")
+	logger.Error("Failed to fetch url")
+	logger.InfoContext(Summary(context.Background()), "Auditing finished")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[0], `msg="Failed to fetch url" worker=1`)
+	require.Contains(t, lines[1], `msg="Auditing finished" worker=1`)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+	for name, want := range map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"} {
+		b, err := os.ReadFile(name)
+		require.NoError(t, err)
+		require.Equal(t, want, string(b))
+	}
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+}
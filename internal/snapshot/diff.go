@@ -0,0 +1,172 @@
+package snapshot
+
+import (
+	"slices"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+type StatusChange struct {
+	URL    string `json:"url"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// MetadataChange is a difference in a page's title, canonical url or language
+type MetadataChange struct {
+	URL    string `json:"url"`
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type Diff struct {
+	AddedNodes       []string         `json:"added_nodes"`
+	RemovedNodes     []string         `json:"removed_nodes"`
+	AddedEdges       []Edge           `json:"added_edges"`
+	RemovedEdges     []Edge           `json:"removed_edges"`
+	StatusChanges    []StatusChange   `json:"status_changes"`
+	MetadataChanges  []MetadataChange `json:"metadata_changes"`
+	NewFindings      []audit.Finding  `json:"new_findings"`
+	ResolvedFindings []audit.Finding  `json:"resolved_findings"`
+}
+
+func (d Diff) IsEmpty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0 &&
+		len(d.StatusChanges) == 0 && len(d.MetadataChanges) == 0 && len(d.NewFindings) == 0 && len(d.ResolvedFindings) == 0
+}
+
+func Compare(before, after *Snapshot) Diff {
+	d := Diff{
+		AddedNodes:       []string{},
+		RemovedNodes:     []string{},
+		AddedEdges:       []Edge{},
+		RemovedEdges:     []Edge{},
+		StatusChanges:    []StatusChange{},
+		MetadataChanges:  []MetadataChange{},
+		NewFindings:      []audit.Finding{},
+		ResolvedFindings: []audit.Finding{},
+	}
+	beforeNodes := nodeStatuses(before)
+	afterNodes := nodeStatuses(after)
+	for u, code := range afterNodes {
+		previous, ok := beforeNodes[u]
+		if !ok {
+			d.AddedNodes = append(d.AddedNodes, u)
+			continue
+		}
+		if previous != code {
+			d.StatusChanges = append(d.StatusChanges, StatusChange{URL: u, Before: previous, After: code})
+		}
+	}
+	for u := range beforeNodes {
+		if _, ok := afterNodes[u]; !ok {
+			d.RemovedNodes = append(d.RemovedNodes, u)
+		}
+	}
+	d.MetadataChanges = metadataChanges(before, after)
+	beforeEdges := edgeKeys(before)
+	afterEdges := edgeKeys(after)
+	for key, edge := range afterEdges {
+		if _, ok := beforeEdges[key]; !ok {
+			d.AddedEdges = append(d.AddedEdges, edge)
+		}
+	}
+	for key, edge := range beforeEdges {
+		if _, ok := afterEdges[key]; !ok {
+			d.RemovedEdges = append(d.RemovedEdges, edge)
+		}
+	}
+	beforeFindings := findingKeys(before)
+	afterFindings := findingKeys(after)
+	for key, finding := range afterFindings {
+		if _, ok := beforeFindings[key]; !ok {
+			d.NewFindings = append(d.NewFindings, finding)
+		}
+	}
+	for key, finding := range beforeFindings {
+		if _, ok := afterFindings[key]; !ok {
+			d.ResolvedFindings = append(d.ResolvedFindings, finding)
+		}
+	}
+	slices.Sort(d.AddedNodes)
+	slices.Sort(d.RemovedNodes)
+	slices.SortFunc(d.AddedEdges, compareEdges)
+	slices.SortFunc(d.RemovedEdges, compareEdges)
+	slices.SortFunc(d.StatusChanges, func(x, y StatusChange) int {
+		return strings.Compare(x.URL, y.URL)
+	})
+	slices.SortFunc(d.NewFindings, compareFindings)
+	slices.SortFunc(d.ResolvedFindings, compareFindings)
+	return d
+}
+
+// metadataChanges compares pages fetched in both snapshots
+func metadataChanges(before, after *Snapshot) []MetadataChange {
+	fetched := make(map[string]Node, len(before.Nodes))
+	for _, node := range before.Nodes {
+		if node.StatusCode != 0 {
+			fetched[node.URL] = node
+		}
+	}
+	changes := []MetadataChange{}
+	for _, node := range after.Nodes {
+		previous, ok := fetched[node.URL]
+		if !ok || node.StatusCode == 0 {
+			continue
+		}
+		for _, field := range []struct{ name, before, after string }{
+			{"title", previous.Title, node.Title},
+			{"canonical", previous.Canonical, node.Canonical},
+			{"lang", previous.Lang, node.Lang},
+		} {
+			if field.before != field.after {
+				changes = append(changes, MetadataChange{URL: node.URL, Field: field.name, Before: field.before, After: field.after})
+			}
+		}
+	}
+	slices.SortFunc(changes, func(x, y MetadataChange) int {
+		if c := strings.Compare(x.URL, y.URL); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Field, y.Field)
+	})
+	return changes
+}
+
+func nodeStatuses(s *Snapshot) map[string]int {
+	nodes := make(map[string]int, len(s.Nodes))
+	for _, node := range s.Nodes {
+		nodes[node.URL] = node.StatusCode
+	}
+	return nodes
+}
+
+func edgeKeys(s *Snapshot) map[string]Edge {
+	edges := make(map[string]Edge, len(s.Edges))
+	for _, edge := range s.Edges {
+		edges[edge.Source+" "+edge.Target] = edge
+	}
+	return edges
+}
+
+func findingKeys(s *Snapshot) map[string]audit.Finding {
+	findings := make(map[string]audit.Finding, len(s.Findings))
+	for _, finding := range s.Findings {
+		findings[finding.Key()] = finding
+	}
+	return findings
+}
+
+func compareEdges(x, y Edge) int {
+	if c := strings.Compare(x.Source, y.Source); c != 0 {
+		return c
+	}
+	return strings.Compare(x.Target, y.Target)
+}
+
+func compareFindings(x, y audit.Finding) int {
+	return strings.Compare(x.Key(), y.Key())
+}
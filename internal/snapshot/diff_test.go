@@ -0,0 +1,80 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func TestSnapshot_Compare(t *testing.T) {
+	before := &Snapshot{
+		Nodes: []Node{{URL: "A", StatusCode: 200}, {URL: "B", StatusCode: 200}, {URL: "C", StatusCode: 404}},
+		Edges: []Edge{{Source: "A", Target: "B", Weight: 1}, {Source: "A", Target: "C", Weight: 1}},
+		Findings: []audit.Finding{
+			{Check: audit.CheckBrokenLink, URL: "C", Detail: "status 404"},
+		},
+	}
+	after := &Snapshot{
+		Nodes: []Node{{URL: "A", StatusCode: 200}, {URL: "B", StatusCode: 500}, {URL: "D", StatusCode: 200}},
+		Edges: []Edge{{Source: "A", Target: "B", Weight: 1}, {Source: "A", Target: "D", Weight: 1}},
+		Findings: []audit.Finding{
+			{Check: audit.CheckBrokenLink, URL: "B", Detail: "status 500"},
+		},
+	}
+	t.Run("reports differences", func(t *testing.T) {
+		d := Compare(before, after)
+		require.False(t, d.IsEmpty())
+		require.Equal(t, []string{"D"}, d.AddedNodes)
+		require.Equal(t, []string{"C"}, d.RemovedNodes)
+		require.Equal(t, []Edge{{Source: "A", Target: "D", Weight: 1}}, d.AddedEdges)
+		require.Equal(t, []Edge{{Source: "A", Target: "C", Weight: 1}}, d.RemovedEdges)
+		require.Equal(t, []StatusChange{{URL: "B", Before: 200, After: 500}}, d.StatusChanges)
+		require.Equal(t, []audit.Finding{{Check: audit.CheckBrokenLink, URL: "B", Detail: "status 500"}}, d.NewFindings)
+		require.Equal(t, []audit.Finding{{Check: audit.CheckBrokenLink, URL: "C", Detail: "status 404"}}, d.ResolvedFindings)
+	})
+	t.Run("identical snapshots", func(t *testing.T) {
+		require.True(t, Compare(before, before).IsEmpty())
+	})
+}
+
+func TestSnapshot_CompareMetadata(t *testing.T) {
+	before := &Snapshot{Nodes: []Node{{URL: "A", StatusCode: 200, Title: "Home", Lang: "en"}, {URL: "B"}}}
+	after := &Snapshot{Nodes: []Node{{URL: "A", StatusCode: 200, Title: "Home (staging)", Lang: "en", Canonical: "A"}, {URL: "B", StatusCode: 200, Title: "B"}}}
+	d := Compare(before, after)
+	require.Equal(t, []MetadataChange{
+		{URL: "A", Field: "canonical", Before: "", After: "A"},
+		{URL: "A", Field: "title", Before: "Home", After: "Home (staging)"},
+	}, d.MetadataChanges)
+}
+
+func TestHostMap(t *testing.T) {
+	m, err := ParseHostMap("http://staging.example.com=https://example.com, cdn-staging.example.com=cdn.example.com")
+	require.NoError(t, err)
+	staging := &Snapshot{
+		StartURL: "http://staging.example.com/",
+		Nodes: []Node{
+			{URL: "http://staging.example.com/", StatusCode: 200, Canonical: "http://staging.example.com/"},
+			{URL: "https://cdn-staging.example.com/app.js", StatusCode: 200},
+			{URL: "https://other.com/", StatusCode: 200},
+		},
+		Edges:    []Edge{{Source: "http://staging.example.com/", Target: "https://other.com/", Weight: 1}},
+		Findings: []audit.Finding{{Check: "sitemap-not-indexable", URL: "http://staging.example.com/a", Detail: "canonical is http://staging.example.com/b"}},
+	}
+	mapped := m.Apply(staging)
+	require.Equal(t, "https://example.com/", mapped.StartURL)
+	require.Equal(t, []Node{
+		{URL: "https://example.com/", StatusCode: 200, Canonical: "https://example.com/"},
+		{URL: "https://cdn.example.com/app.js", StatusCode: 200},
+		{URL: "https://other.com/", StatusCode: 200},
+	}, mapped.Nodes)
+	require.Equal(t, []Edge{{Source: "https://example.com/", Target: "https://other.com/", Weight: 1}}, mapped.Edges)
+	require.Equal(t, "https://example.com/a", mapped.Findings[0].URL)
+	require.Equal(t, "canonical is https://example.com/b", mapped.Findings[0].Detail)
+	require.Equal(t, "http://staging.example.com/", staging.Nodes[0].URL)
+	for _, invalid := range []string{"staging.example.com", "a=b/path", "=example.com"} {
+		_, err := ParseHostMap(invalid)
+		require.True(t, errors.Is(err, ErrInvalidHostMap), invalid)
+	}
+}
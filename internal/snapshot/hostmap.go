@@ -0,0 +1,108 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+var ErrInvalidHostMap = errors.New("invalid host map")
+
+// HostMap maps the hosts of one deployment of a site to another, such as staging to production,
+// so the pages of both can be compared url by url
+type HostMap map[string]*url.URL
+
+// ParseHostMap reads comma separated from=to pairs. Either side is a host or an origin, so
+// http://staging.example.com=https://example.com also maps the scheme.
+func ParseHostMap(s string) (HostMap, error) {
+	m := HostMap{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		fromURL, fromErr := parseOrigin(from)
+		toURL, toErr := parseOrigin(to)
+		if !ok || fromErr != nil || toErr != nil {
+			return nil, fmt.Errorf("%w: %q, expected from=to", ErrInvalidHostMap, pair)
+		}
+		m[fromURL.Host] = toURL
+	}
+	return m, nil
+}
+
+func parseOrigin(s string) (*url.URL, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "://") {
+		s = "//" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+		return nil, errors.New("not a host or origin")
+	}
+	u.Host = strings.ToLower(u.Host)
+	return u, nil
+}
+
+// Apply returns a copy of s with its urls moved to the mapped hosts
+func (m HostMap) Apply(s *Snapshot) *Snapshot {
+	mapped := *s
+	mapped.StartURL = m.url(s.StartURL)
+	mapped.Nodes = make([]Node, 0, len(s.Nodes))
+	for _, node := range s.Nodes {
+		node.URL = m.url(node.URL)
+		node.Canonical = m.url(node.Canonical)
+		if node.Alternates != nil {
+			alternates := make([]audit.Alternate, 0, len(node.Alternates))
+			for _, alternate := range node.Alternates {
+				alternates = append(alternates, audit.Alternate{Lang: alternate.Lang, Media: alternate.Media, URL: m.url(alternate.URL)})
+			}
+			node.Alternates = alternates
+		}
+		mapped.Nodes = append(mapped.Nodes, node)
+	}
+	mapped.Edges = make([]Edge, 0, len(s.Edges))
+	for _, edge := range s.Edges {
+		mapped.Edges = append(mapped.Edges, Edge{Source: m.url(edge.Source), Target: m.url(edge.Target), Weight: edge.Weight})
+	}
+	mapped.Findings = make([]audit.Finding, 0, len(s.Findings))
+	for _, finding := range s.Findings {
+		finding.URL = m.url(finding.URL)
+		finding.Detail = m.text(finding.Detail)
+		mapped.Findings = append(mapped.Findings, finding)
+	}
+	return &mapped
+}
+
+// text maps the urls within a finding's detail
+func (m HostMap) text(s string) string {
+	for from, to := range m {
+		for _, scheme := range []string{"http", "https"} {
+			target := scheme
+			if to.Scheme != "" {
+				target = to.Scheme
+			}
+			s = strings.ReplaceAll(s, scheme+"://"+from, target+"://"+to.Host)
+		}
+	}
+	return s
+}
+
+func (m HostMap) url(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	to, ok := m[strings.ToLower(u.Host)]
+	if !ok {
+		return raw
+	}
+	u.Host = to.Host
+	if to.Scheme != "" {
+		u.Scheme = to.Scheme
+	}
+	return u.String()
+}
@@ -0,0 +1,222 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/salsgithub/godst/graph"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// Node carries the metadata recorded for each url, such as its status code, depth and title
+type Node = audit.Node
+
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+type Snapshot struct {
+	StartURL  string          `json:"start_url"`
+	CreatedAt time.Time       `json:"created_at"`
+	Nodes     []Node          `json:"nodes"`
+	Edges     []Edge          `json:"edges"`
+	Findings  []audit.Finding `json:"findings"`
+}
+
+func New(startURL string, g *graph.Graph[string], nodes map[string]audit.Node, findings []audit.Finding) *Snapshot {
+	s := &Snapshot{
+		StartURL:  startURL,
+		CreatedAt: time.Now().UTC(),
+		Nodes:     []Node{},
+		Edges:     []Edge{},
+		Findings:  findings,
+	}
+	if s.Findings == nil {
+		s.Findings = []audit.Finding{}
+	}
+	for _, node := range g.Nodes() {
+		s.Nodes = append(s.Nodes, nodeOf(node, nodes))
+		neighbours, _ := g.Neighbours(node)
+		for _, neighbour := range neighbours {
+			s.Edges = append(s.Edges, Edge{Source: node, Target: neighbour.Link, Weight: neighbour.Weight})
+		}
+	}
+	return s
+}
+
+func (s *Snapshot) Graph() *graph.Graph[string] {
+	g := graph.New[string]()
+	for _, node := range s.Nodes {
+		g.AddNode(node.URL)
+	}
+	for _, edge := range s.Edges {
+		g.AddEdge(edge.Source, edge.Target, edge.Weight)
+	}
+	return g
+}
+
+func (s *Snapshot) Pages() []audit.Page {
+	g := s.Graph()
+	pages := []audit.Page{}
+	for _, node := range s.Nodes {
+		if node.StatusCode == 0 {
+			continue
+		}
+		page := audit.Page{URL: node.URL, StatusCode: node.StatusCode, Links: []string{}, Alternates: node.Alternates}
+		neighbours, _ := g.Neighbours(node.URL)
+		for _, neighbour := range neighbours {
+			page.Links = append(page.Links, neighbour.Link)
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func (s *Snapshot) StatusCode(u string) (int, bool) {
+	for _, node := range s.Nodes {
+		if node.URL == u {
+			return node.StatusCode, node.StatusCode != 0
+		}
+	}
+	return 0, false
+}
+
+func Save(path string, s *Snapshot) error {
+	return write(path, s.StartURL, s.CreatedAt, slices.Values(s.Nodes), slices.Values(s.Edges), s.Findings)
+}
+
+// Write streams a snapshot of the graph straight to path, without holding its nodes and edges
+// in memory a second time
+func Write(path string, startURL string, g *graph.Graph[string], nodes map[string]audit.Node, findings []audit.Finding) error {
+	values := func(yield func(Node) bool) {
+		for _, node := range g.Nodes() {
+			if !yield(nodeOf(node, nodes)) {
+				return
+			}
+		}
+	}
+	edges := func(yield func(Edge) bool) {
+		for _, node := range g.Nodes() {
+			neighbours, _ := g.Neighbours(node)
+			for _, neighbour := range neighbours {
+				if !yield(Edge{Source: node, Target: neighbour.Link, Weight: neighbour.Weight}) {
+					return
+				}
+			}
+		}
+	}
+	return write(path, startURL, time.Now().UTC(), values, edges, findings)
+}
+
+func nodeOf(u string, nodes map[string]audit.Node) Node {
+	node := nodes[u]
+	node.URL = u
+	return node
+}
+
+func write(path string, startURL string, createdAt time.Time, nodes iter.Seq[Node], edges iter.Seq[Edge], findings []audit.Finding) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := &jsonWriter{w: bufio.NewWriter(f)}
+	w.raw("{\n")
+	w.field("start_url", startURL)
+	w.raw(",\n")
+	w.field("created_at", createdAt)
+	w.raw(",\n  \"nodes\": ")
+	writeArray(w, nodes)
+	w.raw(",\n  \"edges\": ")
+	writeArray(w, edges)
+	w.raw(",\n  \"findings\": ")
+	if findings == nil {
+		findings = []audit.Finding{}
+	}
+	writeArray(w, slices.Values(findings))
+	w.raw("\n}\n")
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	if w.err != nil {
+		f.Close()
+		return w.err
+	}
+	return f.Close()
+}
+
+// jsonWriter keeps the first error so a document can be written without checking every call
+type jsonWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (j *jsonWriter) raw(s string) {
+	if j.err == nil {
+		_, j.err = j.w.WriteString(s)
+	}
+}
+
+func (j *jsonWriter) value(v any) {
+	if j.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		j.err = err
+		return
+	}
+	_, j.err = j.w.Write(b)
+}
+
+func (j *jsonWriter) field(name string, v any) {
+	j.raw("  ")
+	j.value(name)
+	j.raw(": ")
+	j.value(v)
+}
+
+func writeArray[T any](j *jsonWriter, values iter.Seq[T]) {
+	j.raw("[")
+	first := true
+	for v := range values {
+		if j.err != nil {
+			return
+		}
+		if !first {
+			j.raw(",")
+		}
+		first = false
+		j.raw("\n    ")
+		j.value(v)
+	}
+	if !first {
+		j.raw("\n  ")
+	}
+	j.raw("]")
+}
+
+func Load(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/salsgithub/godst/graph"
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func TestSnapshot_New(t *testing.T) {
+	g := graph.New[string]()
+	g.AddEdge("https://example.com/", "https://example.com/a", 1)
+	g.AddEdge("https://example.com/", "https://example.com/b", 1)
+	nodes := map[string]audit.Node{
+		"https://example.com/":  {StatusCode: 200, Title: "Home", FetchMillis: 12, ContentLength: 512},
+		"https://example.com/a": {StatusCode: 404, Depth: 1},
+		"https://example.com/b": {Depth: 1},
+	}
+	s := New("https://example.com/", g, nodes, nil)
+	require.Equal(t, []Node{
+		{URL: "https://example.com/", StatusCode: 200, Title: "Home", FetchMillis: 12, ContentLength: 512},
+		{URL: "https://example.com/a", StatusCode: 404, Depth: 1},
+		{URL: "https://example.com/b", Depth: 1},
+	}, s.Nodes)
+	require.Equal(t, []Edge{
+		{Source: "https://example.com/", Target: "https://example.com/a", Weight: 1},
+		{Source: "https://example.com/", Target: "https://example.com/b", Weight: 1},
+	}, s.Edges)
+	require.Equal(t, []audit.Finding{}, s.Findings)
+	code, ok := s.StatusCode("https://example.com/a")
+	require.True(t, ok)
+	require.Equal(t, 404, code)
+	_, ok = s.StatusCode("https://example.com/b")
+	require.False(t, ok)
+	require.Equal(t, g.String(), s.Graph().String())
+	require.Equal(t, []audit.Page{
+		{URL: "https://example.com/", StatusCode: 200, Links: []string{"https://example.com/a", "https://example.com/b"}},
+		{URL: "https://example.com/a", StatusCode: 404, Links: []string{}},
+	}, s.Pages())
+}
+
+func TestSnapshot_SaveLoad(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		g := graph.New[string]()
+		g.AddEdge("A", "B", 2)
+		findings := []audit.Finding{{Check: "check", URL: "B", Detail: "detail"}}
+		s := New("A", g, nil, findings)
+		path := filepath.Join(t.TempDir(), "nested", "crawl.json")
+		require.NoError(t, Save(path, s))
+		loaded, err := Load(path)
+		require.NoError(t, err)
+		require.Equal(t, s.StartURL, loaded.StartURL)
+		require.True(t, s.CreatedAt.Equal(loaded.CreatedAt))
+		require.Equal(t, s.Nodes, loaded.Nodes)
+		require.Equal(t, s.Edges, loaded.Edges)
+		require.Equal(t, s.Findings, loaded.Findings)
+	})
+	t.Run("write streams the graph", func(t *testing.T) {
+		g := graph.New[string]()
+		g.AddEdge("A", "B", 2)
+		g.AddEdge("A", "C", 1)
+		nodes := map[string]audit.Node{"A": {StatusCode: 200, Title: "A"}, "B": {Depth: 1}}
+		path := filepath.Join(t.TempDir(), "crawl.json")
+		require.NoError(t, Write(path, "A", g, nodes, nil))
+		loaded, err := Load(path)
+		require.NoError(t, err)
+		want := New("A", g, nodes, nil)
+		require.Equal(t, want.StartURL, loaded.StartURL)
+		require.Equal(t, want.Nodes, loaded.Nodes)
+		require.Equal(t, want.Edges, loaded.Edges)
+		require.Equal(t, []audit.Finding{}, loaded.Findings)
+	})
+	t.Run("write empty graph", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "crawl.json")
+		require.NoError(t, Write(path, "A", graph.New[string](), nil, nil))
+		loaded, err := Load(path)
+		require.NoError(t, err)
+		require.Equal(t, []Node{}, loaded.Nodes)
+		require.Equal(t, []Edge{}, loaded.Edges)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+		require.True(t, errors.Is(err, ErrInvalidSnapshot))
+	})
+	t.Run("invalid contents", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "crawl.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0644))
+		_, err := Load(path)
+		require.True(t, errors.Is(err, ErrInvalidSnapshot))
+	})
+}
@@ -0,0 +1,153 @@
+// Package testsupport helps test code built on the auditor, with fetchers and extractors that
+// answer from memory and a fake site served over HTTP.
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Response is a canned response. A zero StatusCode is 200.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// Fetcher answers fetches from canned responses keyed by url, with a fresh body every time and
+// 404 for urls it does not know. Err, when set, fails every fetch.
+type Fetcher struct {
+	Responses map[string]Response
+	Err       error
+	mu        sync.Mutex
+	fetched   []string
+}
+
+// NewFetcher returns a fetcher answering each url with its page body and status 200
+func NewFetcher(pages map[string]string) *Fetcher {
+	f := &Fetcher{Responses: map[string]Response{}}
+	for u, body := range pages {
+		f.Responses[u] = Response{Body: body}
+	}
+	return f
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, u *url.URL) (*http.Response, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, u.String())
+	f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	r, ok := f.Responses[u.String()]
+	if !ok {
+		r = Response{StatusCode: http.StatusNotFound}
+	}
+	return r.http(&http.Request{Method: http.MethodGet, URL: u}), nil
+}
+
+// Fetched lists the urls fetched so far, in order
+func (f *Fetcher) Fetched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.fetched...)
+}
+
+func (r Response) http(request *http.Request) *http.Response {
+	code := r.StatusCode
+	if code == 0 {
+		code = http.StatusOK
+	}
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode:    code,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       request,
+	}
+}
+
+// Extractor finds the same links on every page, or fails with Err
+type Extractor struct {
+	Links []string
+	Err   error
+}
+
+func (e *Extractor) Extract(ctx context.Context, u *url.URL, body io.Reader) ([]string, error) {
+	return e.Links, e.Err
+}
+
+// Page describes a page of a Site. A zero Status is 200, or 301 with RedirectTo. Body replaces
+// the HTML otherwise generated from Title and Links.
+type Page struct {
+	Status     int
+	Title      string
+	Links      []string
+	RedirectTo string
+	Header     http.Header
+	Body       string
+}
+
+// Site serves pages keyed by path, with 404 for paths it does not know
+type Site struct {
+	pages map[string]Page
+}
+
+func NewSite(pages map[string]Page) *Site {
+	return &Site{pages: pages}
+}
+
+// Start serves the site until the test ends, returning its url
+func (s *Site) Start(t testing.TB) string {
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func (s *Site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, ok := s.pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	for key, values := range page.Header {
+		w.Header()[key] = values
+	}
+	if page.RedirectTo != "" {
+		code := page.Status
+		if code == 0 {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, page.RedirectTo, code)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	if page.Status != 0 {
+		w.WriteHeader(page.Status)
+	}
+	if page.Body != "" {
+		io.WriteString(w, page.Body)
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><head><title>%s</title></head><body>\n", html.EscapeString(page.Title))
+	for _, link := range page.Links {
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", html.EscapeString(link), html.EscapeString(link))
+	}
+	b.WriteString("</body></html>\n")
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,54 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+	"salsgithub.com/site-audit/internal/extractor"
+	"salsgithub.com/site-audit/internal/fetcher"
+)
+
+func TestFetcher(t *testing.T) {
+	f := NewFetcher(map[string]string{"https://example.com/": "home"})
+	u, _ := url.Parse("https://example.com/")
+	for range 2 {
+		response, err := f.Fetch(context.Background(), u)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(response.Body)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "home", string(body))
+	}
+	missing, _ := url.Parse("https://example.com/missing")
+	response, err := f.Fetch(context.Background(), missing)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+	require.Equal(t, []string{"https://example.com/", "https://example.com/", "https://example.com/missing"}, f.Fetched())
+	f.Err = errors.New("connection refused")
+	_, err = f.Fetch(context.Background(), u)
+	require.Error(t, err)
+}
+
+func TestSite(t *testing.T) {
+	site := NewSite(map[string]Page{
+		"/":      {Title: "Home", Links: []string{"/a", "/old", "/missing"}},
+		"/a":     {Status: http.StatusInternalServerError},
+		"/old":   {RedirectTo: "/new"},
+		"/new":   {Title: "New"},
+		"/other": {Title: "Unlinked"},
+	})
+	c := audit.Config{StartURL: site.Start(t), Agent: "agent", ValidSchemes: "http", MaxWorkers: 2, MaxDepth: 3}
+	a, err := audit.New(c, fetcher.NewHTTPFetcher("agent"), extractor.NewLinkExtractor())
+	require.NoError(t, err)
+	require.NoError(t, a.Start(context.Background()))
+	statuses := map[string]int{}
+	for _, page := range a.Pages() {
+		statuses[page.URL[len(c.StartURL):]] = page.StatusCode
+	}
+	require.Equal(t, map[string]int{"/": 200, "/a": 500, "/old": 200, "/missing": 404}, statuses)
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+const SignatureHeader = "X-Site-Audit-Signature"
+
+type Event string
+
+const (
+	EventStarted  Event = "audit.started"
+	EventFinished Event = "audit.finished"
+	EventFailed   Event = "audit.failed"
+)
+
+type Payload struct {
+	Event     Event          `json:"event"`
+	StartURL  string         `json:"start_url"`
+	Timestamp time.Time      `json:"timestamp"`
+	Summary   *audit.Summary `json:"summary,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+type Option func(*Notifier)
+
+type Notifier struct {
+	urls       []string
+	secret     []byte
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+func NewNotifier(urls []string, secret string, options ...Option) *Notifier {
+	n := &Notifier{
+		urls:       urls,
+		secret:     []byte(secret),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		maxRetries: 3,
+		backoff:    time.Second,
+	}
+	for _, option := range options {
+		option(n)
+	}
+	return n
+}
+
+func WithMaxRetries(maxRetries int) Option {
+	return func(n *Notifier) {
+		n.maxRetries = maxRetries
+	}
+}
+
+func WithBackoff(backoff time.Duration) Option {
+	return func(n *Notifier) {
+		n.backoff = backoff
+	}
+}
+
+func WithClient(client *http.Client) Option {
+	return func(n *Notifier) {
+		n.client = client
+	}
+}
+
+func (n *Notifier) Notify(ctx context.Context, payload Payload) error {
+	if len(n.urls) == 0 {
+		return nil
+	}
+	if payload.Timestamp.IsZero() {
+		payload.Timestamp = time.Now().UTC()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload: %w", err)
+	}
+	var errs []error
+	for _, u := range n.urls {
+		if err := n.send(ctx, u, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s failed: %w", u, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) send(ctx context.Context, u string, body []byte) error {
+	var err error
+	for attempt := range n.maxRetries + 1 {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(n.backoff * time.Duration(1<<(attempt-1))):
+			}
+		}
+		var retry bool
+		retry, err = n.post(ctx, u, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (n *Notifier) post(ctx context.Context, u string, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		request.Header.Set(SignatureHeader, n.Sign(body))
+	}
+	response, err := n.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode >= http.StatusInternalServerError || response.StatusCode == http.StatusTooManyRequests {
+		return true, fmt.Errorf("received status %d", response.StatusCode)
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return false, fmt.Errorf("received status %d", response.StatusCode)
+	}
+	return false, nil
+}
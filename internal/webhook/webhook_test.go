@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"salsgithub.com/site-audit/internal/audit"
+)
+
+func TestNotifier_Notify(t *testing.T) {
+	t.Run("no urls is a no-op", func(t *testing.T) {
+		n := NewNotifier(nil, "secret")
+		require.NoError(t, n.Notify(context.Background(), Payload{Event: EventStarted}))
+	})
+	t.Run("sends signed payload", func(t *testing.T) {
+		n := NewNotifier(nil, "secret")
+		var received Payload
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			require.Equal(t, n.Sign(body), r.Header.Get(SignatureHeader))
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.NoError(t, json.Unmarshal(body, &received))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		n = NewNotifier([]string{server.URL}, "secret")
+		summary := &audit.Summary{Visited: 3}
+		err := n.Notify(context.Background(), Payload{Event: EventFinished, StartURL: "https://example.com", Summary: summary})
+		require.NoError(t, err)
+		require.Equal(t, EventFinished, received.Event)
+		require.Equal(t, 3, received.Summary.Visited)
+		require.False(t, received.Timestamp.IsZero())
+	})
+	t.Run("unsigned without secret", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Empty(t, r.Header.Get(SignatureHeader))
+		}))
+		defer server.Close()
+		n := NewNotifier([]string{server.URL}, "")
+		require.NoError(t, n.Notify(context.Background(), Payload{Event: EventStarted}))
+	})
+	t.Run("retries server errors with backoff", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		n := NewNotifier([]string{server.URL}, "secret", WithBackoff(time.Millisecond))
+		require.NoError(t, n.Notify(context.Background(), Payload{Event: EventStarted}))
+		require.Equal(t, int32(3), attempts.Load())
+	})
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+		n := NewNotifier([]string{server.URL}, "secret", WithBackoff(time.Millisecond), WithMaxRetries(2))
+		err := n.Notify(context.Background(), Payload{Event: EventStarted})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "received status 429")
+		require.Equal(t, int32(3), attempts.Load())
+	})
+	t.Run("client errors are not retried", func(t *testing.T) {
+		var attempts atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+		n := NewNotifier([]string{server.URL}, "secret", WithBackoff(time.Millisecond))
+		require.Error(t, n.Notify(context.Background(), Payload{Event: EventStarted}))
+		require.Equal(t, int32(1), attempts.Load())
+	})
+	t.Run("cancelled context stops retrying", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		n := NewNotifier([]string{server.URL}, "secret", WithBackoff(time.Hour), WithClient(server.Client()))
+		time.AfterFunc(50*time.Millisecond, cancel)
+		err := n.Notify(ctx, Payload{Event: EventStarted})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), context.Canceled.Error())
+	})
+}